@@ -3,7 +3,9 @@ package main
 import (
 	"github.com/alecthomas/kong"
 	"github.com/block/spirit/pkg/buildinfo"
+	"github.com/block/spirit/pkg/checksum"
 	"github.com/block/spirit/pkg/datasync"
+	"github.com/block/spirit/pkg/dml"
 	spiritfmt "github.com/block/spirit/pkg/fmt"
 	"github.com/block/spirit/pkg/lint"
 	"github.com/block/spirit/pkg/migration"
@@ -19,13 +21,19 @@ var (
 )
 
 var cli struct {
-	Version buildinfo.VersionFlag `name:"version" short:"v" help:"Show version information and exit."`
-	Migrate migration.Migration   `cmd:"" help:"Run an online schema change on a table."`
-	Move    move.Move             `cmd:"" help:"Move tables between MySQL servers."`
-	Sync    datasync.Sync         `cmd:"" help:"[EXPERIMENTAL] Continuously sync tables from a source to a target (initial copy, then stream changes until cancelled)."`
-	Lint    lint.LintCmd          `cmd:"" help:"Lint an entire MySQL schema."`
-	Diff    lint.DiffCmd          `cmd:"" help:"Diff two MySQL schemas and lint the changes."`
-	Fmt     spiritfmt.FmtCmd      `cmd:"" help:"Canonicalize CREATE TABLE .sql files by round-tripping through MySQL."`
+	Version  buildinfo.VersionFlag `name:"version" short:"v" help:"Show version information and exit."`
+	Migrate  migration.Migration   `cmd:"" help:"Run an online schema change on a table."`
+	Revert   migration.Revert      `cmd:"" help:"Revert a migration that was run with --revert-window."`
+	Drop     migration.Drop        `cmd:"" help:"Gradually drop an _old table left behind by --skip-drop-after-cutover."`
+	DML      dml.DMLCmd            `cmd:"" name:"dml" help:"Run a single-table UPDATE or DELETE in throttled chunks."`
+	Archive  dml.ArchiveCmd        `cmd:"" help:"Archive or purge the rows of a table that match a condition, in throttled chunks."`
+	Checksum checksum.ChecksumCmd  `cmd:"" help:"Compare a table with a copy of it on the same or another server, and report the chunks that differ."`
+	Status   migration.Status      `cmd:"" help:"Show the spirit operations in flight on a server, from the tables they leave behind."`
+	Move     move.Move             `cmd:"" help:"Move tables between MySQL servers."`
+	Sync     datasync.Sync         `cmd:"" help:"[EXPERIMENTAL] Continuously sync tables from a source to a target (initial copy, then stream changes until cancelled)."`
+	Lint     lint.LintCmd          `cmd:"" help:"Lint an entire MySQL schema."`
+	Diff     lint.DiffCmd          `cmd:"" help:"Diff two MySQL schemas and lint the changes."`
+	Fmt      spiritfmt.FmtCmd      `cmd:"" help:"Canonicalize CREATE TABLE .sql files by round-tripping through MySQL."`
 }
 
 func main() {
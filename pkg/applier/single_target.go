@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -445,30 +446,35 @@ func (a *SingleTargetApplier) writeChunklet(ctx context.Context, chunkletData ch
 	// The intersected source and target column lists are parallel — row.values[i]
 	// is a value for source column sourceColumnNames[i], which corresponds to
 	// target column at the same ordinal in targetColumnList. With column renames
-	// the two lists differ; without renames they are identical.
+	// the two lists differ; without renames they are identical. The values of
+	// columns computed by an expression were already computed by the source
+	// SELECT, and follow the copied columns.
 	mapping := chunkletData.chunk.ColumnMapping
 	_, targetColumnList := mapping.Columns()
 	sourceColumnNames, _ := mapping.ColumnsSlice()
+	exprColumnNames, _ := mapping.Expressions()
+	valueNames := append(slices.Clip(sourceColumnNames), exprColumnNames...)
 
 	// Build VALUES clauses for all rows in the chunklet
 	var valuesClauses []string
 	for _, row := range chunkletData.rows {
-		if len(sourceColumnNames) != len(row.values) {
+		if len(valueNames) != len(row.values) {
 			return 0, fmt.Errorf("column count mismatch: chunk %s has %d columns, but chunklet has %d values",
-				chunkletData.chunk.String(), len(sourceColumnNames), len(row.values))
+				chunkletData.chunk.String(), len(valueNames), len(row.values))
 		}
 		var values []string
 		for i, value := range row.values {
 			// Type lookup uses the source table by the source column name —
 			// the value came from a source SELECT, and MySQL coerces on the
-			// destination INSERT if the target column type has widened.
-			columnType, ok := mapping.SourceTable().GetColumnMySQLType(sourceColumnNames[i])
+			// destination INSERT if the target column type has widened. A
+			// computed value has the type of its target column.
+			columnType, ok := mapping.ValueType(i)
 			if !ok {
-				return 0, fmt.Errorf("column %s not found in source table info", sourceColumnNames[i])
+				return 0, fmt.Errorf("column %s not found in source table info", valueNames[i])
 			}
 			datum, err := table.NewDatumFromValue(value, columnType)
 			if err != nil {
-				return 0, fmt.Errorf("failed to convert value to datum for column %s: %w", sourceColumnNames[i], err)
+				return 0, fmt.Errorf("failed to convert value to datum for column %s: %w", valueNames[i], err)
 			}
 			// datum.String() returns a complete pre-escaped SQL literal
 			// (NULL, a numeric, 0x… hex, or a "..."-quoted string). Safe
@@ -699,13 +705,23 @@ func (a *SingleTargetApplier) UpsertRows(ctx context.Context, mapping *table.Col
 	if err != nil {
 		return 0, err
 	}
-	_, targetColumnList := mapping.Columns()
+	sourceColumnList, targetColumnList := mapping.Columns()
 	sourceColumnNames, _ := mapping.ColumnsSlice()
 	// RowImage from the binlog contains ALL columns, including STORED
 	// generated columns, so we must index it via ordinal positions in
 	// the full column list — not via positions in NonGeneratedColumns.
 	// The sharded applier does the same; see sharded.go.
 	intersectedColumns := mapping.SourceOrdinalIndices()
+	if mapping.HasExpressions() {
+		// Expressions can only be computed by MySQL, from any of the
+		// source columns. So the whole row image is sent, and the
+		// expressions are selected from it (see below).
+		sourceColumnNames = mapping.SourceTable().Columns
+		intersectedColumns = make([]int, len(sourceColumnNames))
+		for i := range intersectedColumns {
+			intersectedColumns[i] = i
+		}
+	}
 
 	// Build the VALUES clause from the row images
 	var valuesClauses []string
@@ -750,6 +766,17 @@ func (a *SingleTargetApplier) UpsertRows(ctx context.Context, mapping *table.Col
 		targetColumnList,
 		strings.Join(valuesClauses, ", "),
 	)
+	if mapping.HasExpressions() {
+		// The same SELECT list the copier reads with, evaluated over a
+		// table value constructor with the source table's column names.
+		upsertStmt = fmt.Sprintf("REPLACE INTO %s (%s) SELECT %s FROM (VALUES ROW%s) AS src (%s)",
+			mapping.TargetTable().QuotedTableName,
+			targetColumnList,
+			sourceColumnList,
+			strings.Join(valuesClauses, ", ROW"),
+			table.QuoteColumns(sourceColumnNames),
+		)
+	}
 
 	a.logger.Debug("executing upsert", "rowCount", len(valuesClauses), "table", mapping.TargetTable().TableName, "path", "replace-into")
 
@@ -737,6 +737,66 @@ func TestSingleTargetApplierUpsertRowsWithGeneratedColumns(t *testing.T) {
 	require.Equal(t, "2026-05-20 17:16:12.123", updatedAt)
 }
 
+// TestSingleTargetApplierUpsertRowsWithExpressions tests that computed
+// columns are computed from the whole row image, including the source
+// columns that are not copied.
+func TestSingleTargetApplierUpsertRowsWithExpressions(t *testing.T) {
+	testutils.RunSQL(t, "DROP DATABASE IF EXISTS single_upsert_expr_test")
+	testutils.RunSQL(t, "CREATE DATABASE single_upsert_expr_test")
+
+	base, err := mysql.ParseDSN(testutils.DSN())
+	require.NoError(t, err)
+
+	target := base.Clone()
+	target.DBName = "single_upsert_expr_test"
+	targetDB, err := sql.Open("mysql", target.FormatDSN())
+	require.NoError(t, err)
+	defer utils.CloseAndLog(targetDB)
+
+	_, err = targetDB.ExecContext(t.Context(), `CREATE TABLE source_table (id INT PRIMARY KEY, first VARCHAR(50), last VARCHAR(50), amount INT)`)
+	require.NoError(t, err)
+	_, err = targetDB.ExecContext(t.Context(), `CREATE TABLE target_table (id INT PRIMARY KEY, full_name VARCHAR(101), amount INT)`)
+	require.NoError(t, err)
+
+	sourceTable := table.NewTableInfo(targetDB, target.DBName, "source_table")
+	require.NoError(t, sourceTable.SetInfo(t.Context()))
+	targetTable := table.NewTableInfo(targetDB, target.DBName, "target_table")
+	require.NoError(t, targetTable.SetInfo(t.Context()))
+	mapping := table.NewColumnMapping(sourceTable, targetTable, nil)
+	require.NoError(t, mapping.SetExpressions(map[string]string{
+		"full_name": "CONCAT(first, ' ', last)",
+		"amount":    "amount * 100",
+	}))
+
+	tar := Target{
+		DB:       targetDB,
+		Config:   target,
+		KeyRange: "0",
+	}
+	applier, err := NewSingleTargetApplier(tar, NewApplierDefaultConfig())
+	require.NoError(t, err)
+
+	upsertRows := []LogicalRow{
+		{RowImage: []any{int64(1), "Ada", "Lovelace", int64(1)}},
+		{RowImage: []any{int64(2), "Alan", "Turing", nil}},
+		{RowImage: []any{int64(3), "Grace", "Hopper", int64(3)}, IsDeleted: true},
+	}
+	_, err = applier.UpsertRows(t.Context(), mapping, upsertRows, nil)
+	require.NoError(t, err)
+
+	var count int
+	require.NoError(t, targetDB.QueryRowContext(t.Context(), "SELECT COUNT(*) FROM target_table").Scan(&count))
+	require.Equal(t, 2, count)
+	var fullName string
+	var amount sql.NullInt64
+	require.NoError(t, targetDB.QueryRowContext(t.Context(), "SELECT full_name, amount FROM target_table WHERE id = 1").Scan(&fullName, &amount))
+	require.Equal(t, "Ada Lovelace", fullName)
+	require.Equal(t, int64(100), amount.Int64)
+	require.NoError(t, targetDB.QueryRowContext(t.Context(), "SELECT full_name, amount FROM target_table WHERE id = 2").Scan(&fullName, &amount))
+	require.Equal(t, "Alan Turing", fullName)
+	require.False(t, amount.Valid)
+}
+
 // TestSingleTargetApplierUpsertRowsSkipDeleted tests that deleted rows are skipped
 func TestSingleTargetApplierUpsertRowsSkipDeleted(t *testing.T) {
 	testutils.RunSQL(t, "DROP DATABASE IF EXISTS single_upsert_deleted_test")
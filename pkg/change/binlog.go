@@ -52,6 +52,9 @@ type binlogClient struct {
 	// access; reach the subscriptions only through these methods.
 	subs *subscriptionRegistry
 
+	// lag is updated by readStream and reported through Stats.
+	lag lagTracker
+
 	// callerCancelFunc is an optional callback that is called when a DDL
 	// change is detected on a subscribed table, or when a fatal stream
 	// error occurs. The caller is expected to handle cancellation and
@@ -87,6 +90,10 @@ type binlogClient struct {
 	// cap. See DefaultSubscriptionSoftLimitBytes.
 	subscriptionSoftLimitBytes int64
 
+	pause Pauser // suspends the periodic flush, see ClientConfig.Pause
+
+	onRowChange func(tbl *table.TableInfo, row []any) // see ClientConfig.OnRowChange
+
 	flushedBinlogs atomic.Int64 // for testing binlog flushing frequency
 }
 
@@ -118,6 +125,8 @@ func NewBinlogClient(db *sql.DB, host string, username, password string, appl ap
 		serverID:                   config.ServerID,
 		applier:                    appl,
 		subscriptionSoftLimitBytes: softLimit,
+		pause:                      config.Pause,
+		onRowChange:                config.OnRowChange,
 	}
 }
 
@@ -254,6 +263,15 @@ func (c *binlogClient) GetDeltaLen() int {
 	return deltaLen
 }
 
+// Stats returns the reader lag and the pending changes per subscription.
+// Satisfies StatsProvider.
+func (c *binlogClient) Stats() Stats {
+	return Stats{
+		Lag:             c.lag.get(),
+		BufferedChanges: bufferedChanges(c.subs.Snapshot()),
+	}
+}
+
 func (c *binlogClient) getCurrentBinlogPosition(ctx context.Context) (mysql.Position, error) {
 	// We rotate the binary log before we start, so we can always safely just resume
 	// by reopening the binary log file at Position 4. This is required to get the table map.
@@ -594,6 +612,7 @@ func (c *binlogClient) readStream(ctx context.Context) {
 					"event_position", eventPos)
 				continue
 			}
+			c.lag.observe(ev.Header.Timestamp)
 			if err = c.processRowsEvent(ev, event); err != nil {
 				c.logger.Error("fatal error processing binlog rows event", "error", err)
 				c.fatalError()
@@ -617,9 +636,13 @@ func (c *binlogClient) readStream(ctx context.Context) {
 			for _, ddlTable := range ddlTables {
 				c.processDDLNotification(ddlTable.schema, ddlTable.table)
 			}
+		case *replication.XIDEvent:
+			// Transaction commit. Nothing to act on beyond the position
+			// update below, but it refreshes the lag for transactions
+			// that touched no subscribed table.
+			c.lag.observe(ev.Header.Timestamp)
 		case *replication.GTIDEvent,
 			*replication.TableMapEvent,
-			*replication.XIDEvent,
 			*replication.FormatDescriptionEvent,
 			*replication.PreviousGTIDsEvent:
 			// Known stream-housekeeping events. We don't act on them here; the
@@ -737,6 +760,7 @@ func (c *binlogClient) processRowsEvent(ev *replication.BinlogEvent, e *replicat
 		for i := 0; i < len(e.Rows); i += 2 {
 			beforeRow := e.Rows[i]
 			afterRow := e.Rows[i+1]
+			c.rowChanged(tbl, afterRow)
 
 			beforeKey, err := tbl.PrimaryKeyValues(beforeRow)
 			if err != nil {
@@ -765,6 +789,7 @@ func (c *binlogClient) processRowsEvent(ev *replication.BinlogEvent, e *replicat
 		}
 		switch eventType { //nolint:exhaustive
 		case eventTypeInsert:
+			c.rowChanged(tbl, row)
 			sub.HasChanged(key, row, false)
 		case eventTypeDelete:
 			sub.HasChanged(key, nil, true)
@@ -775,6 +800,13 @@ func (c *binlogClient) processRowsEvent(ev *replication.BinlogEvent, e *replicat
 	return nil
 }
 
+// rowChanged calls the OnRowChange callback, if there is one.
+func (c *binlogClient) rowChanged(tbl *table.TableInfo, row []any) {
+	if c.onRowChange != nil {
+		c.onRowChange(tbl, row)
+	}
+}
+
 // fatalError is called from within the readStream goroutine when a truly fatal
 // stream error occurs (e.g. unrecoverable stream error, minimal RBR detection,
 // or a fatal rows event error). It returns true if the caller acknowledged the
@@ -986,6 +1018,10 @@ func (c *binlogClient) runPeriodicFlush(ctx context.Context, interval time.Durat
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
+			if isPaused(c.pause) {
+				c.logger.Debug("skipping periodic flush of binary log while paused")
+				continue
+			}
 			startLoop := time.Now()
 			c.logger.Debug("starting periodic flush of binary log")
 			// The periodic flush does not respect the throttler since we want to advance the binlog position
@@ -1077,6 +1113,24 @@ func (c *binlogClient) SetWatermarkOptimization(ctx context.Context, newVal bool
 	return nil
 }
 
+// CurrentBinlogPosition returns the server's current binary log position,
+// in the format that StartFromPosition of a client created with
+// NewBinlogClient accepts. Unlike Start, it does not rotate the binary log,
+// so it can be called while holding table locks to get a position that no
+// write to the locked tables can precede.
+func CurrentBinlogPosition(ctx context.Context, db *sql.DB) (string, error) {
+	var binlogFile, fake string
+	var binlogPos uint32
+	err := db.QueryRowContext(ctx, "SHOW MASTER STATUS").Scan(&binlogFile, &binlogPos, &fake, &fake, &fake)
+	if err != nil {
+		// MySQL 8.2+ renamed the statement.
+		if err = db.QueryRowContext(ctx, "SHOW BINARY LOG STATUS").Scan(&binlogFile, &binlogPos, &fake, &fake, &fake); err != nil {
+			return "", err
+		}
+	}
+	return formatBinlogPosition(mysql.Position{Name: binlogFile, Pos: binlogPos}), nil
+}
+
 // formatBinlogPosition encodes a mysql.Position as the opaque string
 // returned by binlogClient.Position(). The format is "<binlog-file>:<offset>".
 func formatBinlogPosition(p mysql.Position) string {
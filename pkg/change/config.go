@@ -2,8 +2,10 @@ package change
 
 import (
 	"log/slog"
+	"time"
 
 	"github.com/block/spirit/pkg/dbconn"
+	"github.com/block/spirit/pkg/table"
 )
 
 type ClientConfig struct {
@@ -36,6 +38,18 @@ type ClientConfig struct {
 	// entirely (HasChanged will never block on memory). Zero (the
 	// zero-value default) means use DefaultSubscriptionSoftLimitBytes.
 	SubscriptionSoftLimitBytes int64
+
+	// Pause, when set, suspends the periodic flush while it is paused.
+	// Changes are still read and buffered in the subscriptions (up to
+	// their soft limit), so no position is lost. Explicit calls to Flush
+	// are not affected: holding those is up to the caller.
+	Pause Pauser
+
+	// OnRowChange, when set, is called with each row image that an INSERT
+	// or UPDATE writes to a subscribed table (the after image of an
+	// UPDATE), before it is handed to the subscription. It is called from
+	// the goroutine that reads the stream, so it must not block.
+	OnRowChange func(tbl *table.TableInfo, row []any)
 }
 
 // NewClientDefaultConfig returns a default config for the copier.
@@ -45,3 +59,18 @@ func NewClientDefaultConfig() *ClientConfig {
 		ServerID: NewServerID(),
 	}
 }
+
+// Pauser reports whether an operator has paused the task that the client
+// reads changes for. It is implemented by status.Pause.
+type Pauser interface {
+	Paused() (paused bool, since time.Time)
+}
+
+// isPaused reports whether pause is paused. A nil pause never is.
+func isPaused(pause Pauser) bool {
+	if pause == nil {
+		return false
+	}
+	paused, _ := pause.Paused()
+	return paused
+}
@@ -49,6 +49,9 @@ type gtidClient struct {
 
 	subs *subscriptionRegistry
 
+	// lag is updated by readStream and reported through Stats.
+	lag lagTracker
+
 	callerCancelFunc func() bool
 	ddlFilterSchema  string
 	ddlFilterTables  map[string]struct{}
@@ -82,6 +85,10 @@ type gtidClient struct {
 	streamWG   sync.WaitGroup
 
 	subscriptionSoftLimitBytes int64
+
+	pause Pauser // suspends the periodic flush, see ClientConfig.Pause
+
+	onRowChange func(tbl *table.TableInfo, row []any) // see ClientConfig.OnRowChange
 }
 
 // NewGTIDClient constructs the GTID-backed change.Source. It mirrors
@@ -113,6 +120,8 @@ func NewGTIDClient(db *sql.DB, host string, username, password string, appl appl
 		serverID:                   config.ServerID,
 		applier:                    appl,
 		subscriptionSoftLimitBytes: softLimit,
+		pause:                      config.Pause,
+		onRowChange:                config.OnRowChange,
 	}
 }
 
@@ -152,6 +161,21 @@ func (c *gtidClient) getCurrentGTIDSet(ctx context.Context) (mysql.GTIDSet, erro
 	return gset, nil
 }
 
+// CurrentGTIDPosition returns the server's @@GLOBAL.gtid_executed, in the
+// format that StartFromPosition of a client created with NewGTIDClient
+// accepts. See CurrentBinlogPosition.
+func CurrentGTIDPosition(ctx context.Context, db *sql.DB) (string, error) {
+	var gtidStr string
+	if err := db.QueryRowContext(ctx, "SELECT @@GLOBAL.gtid_executed").Scan(&gtidStr); err != nil {
+		return "", fmt.Errorf("failed to read @@GLOBAL.gtid_executed (is gtid_mode=ON?): %w", err)
+	}
+	gset, err := mysql.ParseMysqlGTIDSet(normalizeGTIDString(gtidStr))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse @@GLOBAL.gtid_executed %q: %w", gtidStr, err)
+	}
+	return gset.String(), nil
+}
+
 // getPurgedGTIDSet reads @@GLOBAL.gtid_purged. A GTID set we want to
 // resume from must be a superset of gtid_purged; if not, the source has
 // dropped binary logs containing changes we need.
@@ -512,7 +536,9 @@ func (c *gtidClient) readStream(ctx context.Context) {
 			// Transaction commit (InnoDB). Promote the pending GTID
 			// into bufferedGTID — only now is it safe to resume past it.
 			c.promotePendingGTID()
+			c.lag.observe(ev.Header.Timestamp)
 		case *replication.RowsEvent:
+			c.lag.observe(ev.Header.Timestamp)
 			if err = c.processRowsEvent(ev, event); err != nil {
 				c.logger.Error("fatal error processing GTID rows event", "error", err)
 				c.fatalError()
@@ -647,6 +673,7 @@ func (c *gtidClient) processRowsEvent(ev *replication.BinlogEvent, e *replicatio
 		for i := 0; i < len(e.Rows); i += 2 {
 			beforeRow := e.Rows[i]
 			afterRow := e.Rows[i+1]
+			c.rowChanged(tbl, afterRow)
 			beforeKey, err := tbl.PrimaryKeyValues(beforeRow)
 			if err != nil {
 				return err
@@ -672,6 +699,7 @@ func (c *gtidClient) processRowsEvent(ev *replication.BinlogEvent, e *replicatio
 		}
 		switch eventType { //nolint:exhaustive
 		case eventTypeInsert:
+			c.rowChanged(tbl, row)
 			sub.HasChanged(key, row, false)
 		case eventTypeDelete:
 			sub.HasChanged(key, nil, true)
@@ -682,6 +710,13 @@ func (c *gtidClient) processRowsEvent(ev *replication.BinlogEvent, e *replicatio
 	return nil
 }
 
+// rowChanged calls the OnRowChange callback, if there is one.
+func (c *gtidClient) rowChanged(tbl *table.TableInfo, row []any) {
+	if c.onRowChange != nil {
+		c.onRowChange(tbl, row)
+	}
+}
+
 func (c *gtidClient) fatalError() bool {
 	if c.callerCancelFunc != nil {
 		return c.callerCancelFunc()
@@ -698,6 +733,15 @@ func (c *gtidClient) GetDeltaLen() int {
 	return deltaLen
 }
 
+// Stats returns the reader lag and the pending changes per subscription.
+// Satisfies StatsProvider.
+func (c *gtidClient) Stats() Stats {
+	return Stats{
+		Lag:             c.lag.get(),
+		BufferedChanges: bufferedChanges(c.subs.Snapshot()),
+	}
+}
+
 func (c *gtidClient) Close() {
 	c.isClosed.Store(true)
 
@@ -831,6 +875,10 @@ func (c *gtidClient) runPeriodicFlush(ctx context.Context, interval time.Duratio
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
+			if isPaused(c.pause) {
+				c.logger.Debug("skipping periodic flush of GTID changeset while paused")
+				continue
+			}
 			startLoop := time.Now()
 			c.logger.Debug("starting periodic flush of GTID changeset")
 			if err := c.flush(ctx, false, nil); err != nil {
@@ -0,0 +1,82 @@
+package change
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/block/spirit/pkg/metrics"
+)
+
+// Stats is a point-in-time view of a Source, used for metrics.
+type Stats struct {
+	// Lag is how far the reader is behind the source, measured when the
+	// most recent row or commit event was read. Binlog timestamps have
+	// second resolution, so values under a second are noise. When the
+	// source is idle no events are read and Lag keeps its last value,
+	// which is what was true when the reader last had work to do.
+	Lag time.Duration
+	// BufferedChanges is the number of pending changes held by each
+	// subscription, keyed by the "schema.table" of the table the
+	// subscription reads from. Their sum is GetDeltaLen().
+	BufferedChanges map[string]int
+}
+
+// StatsProvider is implemented by sources that can report Stats. It is
+// kept out of the Source interface so out-of-tree implementations (which
+// may have no notion of lag) keep compiling; callers should type-assert.
+type StatsProvider interface {
+	Stats() Stats
+}
+
+// MetricValues converts s into gauges for a metrics.Sink.
+func (s Stats) MetricValues() []metrics.MetricValue {
+	values := make([]metrics.MetricValue, 0, len(s.BufferedChanges)+1)
+	values = append(values, metrics.MetricValue{
+		Name:  metrics.BinlogLagMetricName,
+		Type:  metrics.GAUGE,
+		Value: s.Lag.Seconds(),
+	})
+	for tbl, n := range s.BufferedChanges {
+		values = append(values, metrics.MetricValue{
+			Name:   metrics.SubscriptionBufferedChangesMetricName,
+			Type:   metrics.GAUGE,
+			Value:  float64(n),
+			Labels: map[string]string{metrics.TableLabel: tbl},
+		})
+	}
+	return values
+}
+
+// lagTracker records the reader lag from binlog event header timestamps.
+// It is written by the reader goroutine and read by metrics collection.
+type lagTracker struct {
+	lag atomic.Int64 // nanoseconds
+}
+
+// observe records the lag of an event with header timestamp ts (unix
+// seconds). Artificial events (e.g. the rotate/format-description events
+// sent on connect) and heartbeats carry a zero timestamp and are ignored.
+func (l *lagTracker) observe(ts uint32) {
+	if ts == 0 {
+		return
+	}
+	// Clock skew between us and the server can make this negative.
+	l.lag.Store(int64(max(0, time.Since(time.Unix(int64(ts), 0)))))
+}
+
+func (l *lagTracker) get() time.Duration {
+	return time.Duration(l.lag.Load())
+}
+
+// bufferedChanges returns the pending change count of each subscription.
+func bufferedChanges(subs []Subscription) map[string]int {
+	out := make(map[string]int, len(subs))
+	for _, sub := range subs {
+		tables := sub.Tables()
+		if len(tables) == 0 {
+			continue
+		}
+		out[tables[0].SchemaName+"."+tables[0].TableName] += sub.Length()
+	}
+	return out
+}
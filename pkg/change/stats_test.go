@@ -0,0 +1,42 @@
+package change
+
+import (
+	"testing"
+	"time"
+
+	"github.com/block/spirit/pkg/metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLagTracker(t *testing.T) {
+	var l lagTracker
+	require.Zero(t, l.get())
+
+	l.observe(uint32(time.Now().Add(-30 * time.Second).Unix()))
+	require.InDelta(t, 30, l.get().Seconds(), 2)
+
+	// Artificial events and heartbeats have no timestamp and must not
+	// reset the lag.
+	l.observe(0)
+	require.InDelta(t, 30, l.get().Seconds(), 2)
+
+	// A timestamp ahead of our clock is clamped rather than negative.
+	l.observe(uint32(time.Now().Add(time.Minute).Unix()))
+	require.Zero(t, l.get())
+}
+
+func TestStatsMetricValues(t *testing.T) {
+	values := Stats{
+		Lag:             1500 * time.Millisecond,
+		BufferedChanges: map[string]int{"test.t1": 10},
+	}.MetricValues()
+	require.Equal(t, []metrics.MetricValue{
+		{Name: metrics.BinlogLagMetricName, Type: metrics.GAUGE, Value: 1.5},
+		{
+			Name:   metrics.SubscriptionBufferedChangesMetricName,
+			Type:   metrics.GAUGE,
+			Value:  10,
+			Labels: map[string]string{metrics.TableLabel: "test.t1"},
+		},
+	}, values)
+}
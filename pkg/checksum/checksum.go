@@ -103,6 +103,22 @@ type Checker interface {
 	// checksum loop uses it to decide whether a sentinel-drop swallow is
 	// safe.
 	DifferencesFound() uint64
+	// ChunksRemaining estimates how many chunks the current pass still
+	// has to compare. It is reported as a metric.
+	ChunksRemaining() uint64
+}
+
+// estimateChunksRemaining extrapolates the chunks left in a pass from the
+// average rows per chunk so far. Chunkers size chunks dynamically, so the
+// total chunk count is not known up front. Before the first chunk
+// completes there is nothing to extrapolate from and it returns 0.
+func estimateChunksRemaining(chunker table.Chunker) uint64 {
+	rowsProcessed, chunksProcessed, totalRows := chunker.Progress()
+	if rowsProcessed == 0 || chunksProcessed == 0 || totalRows <= rowsProcessed {
+		return 0
+	}
+	remaining := totalRows - rowsProcessed
+	return (remaining*chunksProcessed + rowsProcessed - 1) / rowsProcessed
 }
 
 type CheckerConfig struct {
@@ -115,6 +131,11 @@ type CheckerConfig struct {
 	MaxRetries      int
 	Applier         applier.Applier // optional; indicates it is a distributed checker
 	YieldTimeout    time.Duration   // maximum duration for a single checksum pass before yielding to release long-running transactions
+	// DiffFile is optional; when set, each chunk that differs is narrowed
+	// down to the rows that differ, which are written to it in the format
+	// of DiffFormat ("json" or "sql") before the chunk is recopied.
+	DiffFile   string
+	DiffFormat string
 }
 
 func NewCheckerDefaultConfig() *CheckerConfig {
@@ -129,6 +150,26 @@ func NewCheckerDefaultConfig() *CheckerConfig {
 	}
 }
 
+// openDiffFile creates the diff file at path, if there is one, for a run
+// of a checker. The returned func closes it.
+func openDiffFile(path, format string, logger *slog.Logger) (*diffWriter, func(), error) {
+	if path == "" {
+		return nil, func() {}, nil
+	}
+	diffs, err := newDiffWriter(path, format)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create the checksum diff file: %w", err)
+	}
+	return diffs, func() {
+		if diffs.rows > 0 {
+			logger.Warn("wrote the rows that differ", "file", path, "rows", diffs.rows)
+		}
+		if err := diffs.Close(); err != nil {
+			logger.Error("failed to write the checksum diff file", "file", path, "error", err)
+		}
+	}, nil
+}
+
 // NewChecker creates a new checksum object.
 // sourceDBs contains the source database connections (one for single-source migrations,
 // multiple for N:M moves). The distributed checker aggregates checksums across all sources.
@@ -163,6 +204,8 @@ func NewChecker(sourceDBs []*sql.DB, chunker table.Chunker, feeds []change.Sourc
 			fixDifferences: config.FixDifferences,
 			maxRetries:     config.MaxRetries,
 			applier:        config.Applier,
+			diffFile:       config.DiffFile,
+			diffFormat:     config.DiffFormat,
 		}, nil
 	}
 	return &SingleChecker{
@@ -175,5 +218,7 @@ func NewChecker(sourceDBs []*sql.DB, chunker table.Chunker, feeds []change.Sourc
 		fixDifferences: config.FixDifferences,
 		maxRetries:     config.MaxRetries,
 		yieldTimeout:   config.YieldTimeout,
+		diffFile:       config.DiffFile,
+		diffFormat:     config.DiffFormat,
 	}, nil
 }
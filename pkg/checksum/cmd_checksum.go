@@ -0,0 +1,391 @@
+package checksum
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/block/spirit/pkg/applier"
+	"github.com/block/spirit/pkg/change"
+	"github.com/block/spirit/pkg/dbconn"
+	"github.com/block/spirit/pkg/table"
+	"github.com/block/spirit/pkg/utils"
+	"github.com/go-sql-driver/mysql"
+	"golang.org/x/sync/errgroup"
+)
+
+// maxFixPasses is how many passes `spirit checksum --fix` makes over the
+// table, recopying the chunks that differ, before it gives up.
+const maxFixPasses = 3
+
+// ChecksumCmd is the kong CLI entry point of `spirit checksum`. It compares
+// a table with a table on the same or another server, e.g. a copy made by
+// `spirit move`, a replica's copy, or an _old table left behind by a
+// migration, one chunk at a time, and reports the chunks that differ.
+type ChecksumCmd struct {
+	SourceDSN       string        `name:"source-dsn" help:"The server and database of the source table." required:""`
+	SourceTable     string        `name:"source-table" help:"The table to compare." required:""`
+	TargetDSN       string        `name:"target-dsn" help:"The server and database of the target table. Defaults to --source-dsn" optional:""`
+	TargetTable     string        `name:"target-table" help:"The table to compare it with. Defaults to --source-table" optional:""`
+	Threads         int           `name:"threads" help:"How many chunks to checksum in parallel" default:"4"`
+	TargetChunkTime time.Duration `name:"target-chunk-time" help:"How long each chunk should take to checksum" default:"1s"`
+	Live            bool          `name:"live" help:"Apply the changes to the source table to the target table from the binlog while comparing, so that a table that is written to is compared consistently. Nothing else may write to the target table" default:"false"`
+	Fix             bool          `name:"fix" help:"Recopy the chunks that differ from the source table to the target table" default:"false"`
+	DiffFile        string        `name:"diff-file" help:"Write the rows that differ, with their source and target row images, to this file" optional:""`
+	DiffFormat      string        `name:"diff-format" help:"The format of --diff-file: json, or sql statements that make the target's rows match the source's" enum:"json,sql" default:"json"`
+}
+
+// Validate is called by Kong after parsing to check for invalid flag values.
+func (c *ChecksumCmd) Validate() error {
+	if c.Threads <= 0 {
+		return fmt.Errorf("--threads must be positive, got %d", c.Threads)
+	}
+	if c.TargetChunkTime < 0 {
+		return fmt.Errorf("--target-chunk-time must be non-negative, got %s", c.TargetChunkTime)
+	}
+	if cmp.Or(c.TargetDSN, c.SourceDSN) == c.SourceDSN && cmp.Or(c.TargetTable, c.SourceTable) == c.SourceTable {
+		return errors.New("the source and target tables are the same table: set --target-dsn or --target-table")
+	}
+	return nil
+}
+
+// Run compares the tables, and returns an error that lists the chunks that
+// differ if there are any. With --fix, the chunks that differ are recopied
+// and the table is compared again, until a pass finds no differences.
+func (c *ChecksumCmd) Run() error {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	logger := slog.Default()
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	dbConfig := dbconn.NewDBConfig()
+	targetDSN := cmp.Or(c.TargetDSN, c.SourceDSN)
+	sourceCfg, err := mysql.ParseDSN(c.SourceDSN)
+	if err != nil {
+		return fmt.Errorf("--source-dsn: %w", err)
+	}
+	targetCfg, err := mysql.ParseDSN(targetDSN)
+	if err != nil {
+		return fmt.Errorf("--target-dsn: %w", err)
+	}
+	sourceDB, err := dbconn.New(c.SourceDSN, dbConfig)
+	if err != nil {
+		return fmt.Errorf("failed to connect to source database: %w", err)
+	}
+	defer utils.CloseAndLog(sourceDB)
+	targetDB, err := dbconn.New(targetDSN, dbConfig)
+	if err != nil {
+		return fmt.Errorf("failed to connect to target database: %w", err)
+	}
+	defer utils.CloseAndLog(targetDB)
+
+	src := table.NewTableInfo(sourceDB, sourceCfg.DBName, c.SourceTable)
+	if err := src.SetInfo(ctx); err != nil {
+		return fmt.Errorf("source table %s: %w", c.SourceTable, err)
+	}
+	tgt := table.NewTableInfo(targetDB, targetCfg.DBName, cmp.Or(c.TargetTable, c.SourceTable))
+	if err := tgt.SetInfo(ctx); err != nil {
+		return fmt.Errorf("target table %s: %w", tgt.TableName, err)
+	}
+	// The chunks are ranges of the source table's primary key, which are
+	// only the same rows of the target table if it has the same key.
+	if !slices.Equal(src.KeyColumns, tgt.KeyColumns) {
+		return fmt.Errorf("the primary key of target table %s (%s) is not the primary key of source table %s (%s)",
+			tgt.TableName, strings.Join(tgt.KeyColumns, ", "), src.TableName, strings.Join(src.KeyColumns, ", "))
+	}
+	chunker, err := table.NewChunker(src, table.ChunkerConfig{
+		NewTable:        tgt,
+		TargetChunkTime: c.TargetChunkTime,
+		Logger:          logger,
+	})
+	if err != nil {
+		return err
+	}
+
+	checker := &tableChecker{
+		sourceDB:    sourceDB,
+		targetDB:    targetDB,
+		source:      src,
+		target:      tgt,
+		chunker:     chunker,
+		concurrency: c.Threads,
+		dbConfig:    dbConfig,
+		logger:      logger,
+	}
+	if c.DiffFile != "" {
+		diffs, err := newDiffWriter(c.DiffFile, c.DiffFormat)
+		if err != nil {
+			return err
+		}
+		checker.diffs = diffs
+		defer func() {
+			if err := diffs.Close(); err != nil {
+				logger.Error("failed to write the diff file", "file", c.DiffFile, "error", err)
+			}
+		}()
+	}
+	// The applier writes the changes of --live, and the recopies of --fix,
+	// to the target table.
+	if c.Live || c.Fix {
+		appl, err := applier.NewSingleTargetApplier(applier.Target{DB: targetDB, Config: targetCfg}, &applier.ApplierConfig{
+			Logger:   logger,
+			DBConfig: dbConfig,
+		})
+		if err != nil {
+			return err
+		}
+		if err := appl.Start(ctx); err != nil {
+			return err
+		}
+		defer func() { _ = appl.Stop() }()
+		if c.Fix {
+			if checker.recopier, err = NewMySQLRecopier(sourceDB, targetDB, appl, dbConfig, logger); err != nil {
+				return err
+			}
+		}
+		if c.Live {
+			replConfig := change.NewClientDefaultConfig()
+			replConfig.Logger = logger
+			replConfig.DBConfig = dbConfig
+			replConfig.CancelFunc = func() bool {
+				cancel()
+				return true
+			}
+			feed := change.NewBinlogClient(sourceDB, sourceCfg.Addr, sourceCfg.User, sourceCfg.Passwd, appl, replConfig)
+			defer feed.Close()
+			if err := feed.AddSubscription(src, tgt, chunker); err != nil {
+				return err
+			}
+			if err := feed.Start(ctx); err != nil {
+				return err
+			}
+			// There is no copy, so every change has to be applied
+			// regardless of the chunker's watermark.
+			if err := feed.SetWatermarkOptimization(ctx, false); err != nil {
+				return err
+			}
+			checker.feed = feed
+		}
+	}
+
+	if err := chunker.Open(); err != nil {
+		return err
+	}
+	defer utils.CloseAndLog(chunker)
+	for pass := 1; ; pass++ {
+		mismatches, err := checker.run(ctx)
+		if err != nil {
+			return err
+		}
+		if len(mismatches) == 0 {
+			logger.Info("checksum passed", "source-table", src.TableName, "target-table", tgt.TableName, "pass", pass)
+			return nil
+		}
+		if pass == 1 && checker.diffs != nil {
+			logger.Warn("wrote the rows that differ", "file", c.DiffFile, "rows", checker.diffs.rows)
+			// The rows that the later passes find differ after they were
+			// recopied, which the first pass already reported.
+			checker.diffs = nil
+		}
+		if !c.Fix || pass == maxFixPasses {
+			return mismatchError(src, tgt, mismatches, c.Fix)
+		}
+		logger.Warn("recopied the chunks that differ, comparing the tables again", "chunks", len(mismatches), "pass", pass)
+		if err := chunker.Reset(); err != nil {
+			return err
+		}
+	}
+}
+
+// mismatchError returns the error that reports the chunks that differ.
+func mismatchError(src, tgt *table.TableInfo, mismatches []string, fixed bool) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d chunks of table %s differ from table %s", len(mismatches), src.TableName, tgt.TableName)
+	if fixed {
+		fmt.Fprintf(&sb, " after %d passes of recopying them", maxFixPasses)
+	}
+	sb.WriteString(":")
+	for _, m := range mismatches {
+		sb.WriteString("\n  " + m)
+	}
+	return errors.New(sb.String())
+}
+
+// tableChecker compares a source table with a target table on the same or
+// another server, with a consistent snapshot of each. Unlike the Checker of
+// a migration, it doesn't stop at the first chunk that differs, but reports
+// every one. The source table's primary key is used for the chunks on both
+// sides.
+type tableChecker struct {
+	sourceDB, targetDB *sql.DB
+	source, target     *table.TableInfo
+	chunker            table.Chunker
+	concurrency        int
+	dbConfig           *dbconn.DBConfig
+	logger             *slog.Logger
+	// feed is optional; it applies the changes to the source table to the
+	// target table (see --live).
+	feed change.Source
+	// recopier is optional; it recopies the chunks that differ (see --fix).
+	recopier Recopier
+	// diffs is optional; it records the rows that differ (see --diff-file).
+	diffs *diffWriter
+}
+
+// run makes one pass over the chunker, and returns a description of each
+// chunk that differs. The chunks that differ are recopied if there is a
+// recopier.
+func (c *tableChecker) run(ctx context.Context) ([]string, error) {
+	sourcePool, targetPool, err := c.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		utils.CloseAndLog(sourcePool)
+		utils.CloseAndLog(targetPool)
+	}()
+	c.logger.Info("tables unlocked, starting checksum", "source-table", c.source.TableName, "target-table", c.target.TableName)
+	if c.feed != nil {
+		c.feed.StartPeriodicFlush(ctx, change.DefaultFlushInterval)
+		defer c.feed.StopPeriodicFlush()
+	}
+
+	var mu sync.Mutex
+	var mismatches []string
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(c.concurrency)
+	for !c.chunker.IsRead() && gCtx.Err() == nil {
+		g.Go(func() error {
+			chunk, err := c.chunker.Next()
+			if err != nil {
+				if errors.Is(err, table.ErrTableIsRead) {
+					return nil
+				}
+				return err
+			}
+			mismatch, err := c.checksumChunk(gCtx, sourcePool, targetPool, chunk)
+			if err != nil || mismatch == "" {
+				return err
+			}
+			mu.Lock()
+			mismatches = append(mismatches, mismatch)
+			mu.Unlock()
+			if c.recopier != nil {
+				return c.recopier.Recopy(gCtx, chunk)
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	slices.Sort(mismatches)
+	return mismatches, nil
+}
+
+// snapshot locks both tables, so that the feed (if any) can catch up with
+// the source table, and opens a pool of REPEATABLE READ transactions on
+// each side while they are locked, so that the chunks of both sides are
+// read as of the same point.
+func (c *tableChecker) snapshot(ctx context.Context) (sourcePool, targetPool *dbconn.TrxPool, err error) {
+	// Catch up before locking, to keep the lock short.
+	if c.feed != nil {
+		if err := c.feed.Flush(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+	c.logger.Info("starting checksum operation, this will require a table lock on both tables")
+	sourceLock, err := dbconn.NewTableLock(ctx, c.sourceDB, []*table.TableInfo{c.source}, c.dbConfig, c.logger)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to lock source table: %w", err)
+	}
+	defer utils.CloseAndLogWithContext(ctx, sourceLock)
+	targetLock, err := dbconn.NewTableLock(ctx, c.targetDB, []*table.TableInfo{c.target}, c.dbConfig, c.logger)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to lock target table: %w", err)
+	}
+	defer utils.CloseAndLogWithContext(ctx, targetLock)
+	if c.feed != nil {
+		if err := c.feed.FlushUnderTableLock(ctx, []*dbconn.TableLock{targetLock}); err != nil {
+			return nil, nil, err
+		}
+		if !c.feed.AllChangesFlushed() {
+			return nil, nil, change.ErrChangesNotFlushed
+		}
+	}
+	if sourcePool, err = dbconn.NewTrxPool(ctx, c.sourceDB, c.concurrency, c.dbConfig); err != nil {
+		return nil, nil, err
+	}
+	if targetPool, err = dbconn.NewTrxPool(ctx, c.targetDB, c.concurrency, c.dbConfig); err != nil {
+		utils.CloseAndLog(sourcePool)
+		return nil, nil, err
+	}
+	return sourcePool, targetPool, nil
+}
+
+// checksumChunk compares a chunk of the source table with the target
+// table, and returns a description of how it differs, or "" if it doesn't.
+// If there is a diff file, the rows that differ are written to it.
+func (c *tableChecker) checksumChunk(ctx context.Context, sourcePool, targetPool *dbconn.TrxPool, chunk *table.Chunk) (string, error) {
+	startTime := time.Now()
+	srcTrx, err := sourcePool.Get()
+	if err != nil {
+		return "", err
+	}
+	defer sourcePool.Put(srcTrx)
+	tgtTrx, err := targetPool.Get()
+	if err != nil {
+		return "", err
+	}
+	defer targetPool.Put(tgtTrx)
+	srcCRC, tgtCRC, srcCount, tgtCount, err := checksumRange(ctx, srcTrx, tgtTrx, chunk)
+	if err != nil {
+		return "", err
+	}
+	c.chunker.Feedback(chunk, time.Since(startTime), srcCount)
+	mismatch := compareChunk(srcCRC, tgtCRC, srcCount, tgtCount)
+	if !mismatch.mismatched() {
+		return "", nil
+	}
+	reason := mismatch.reason(srcCount, tgtCount)
+	c.logger.Warn("chunk verification failed", "chunk", chunk.String(), "reason", reason,
+		"sourceChecksum", srcCRC, "targetChecksum", tgtCRC, "sourceCount", srcCount, "targetCount", tgtCount)
+	if c.diffs == nil {
+		return chunk.String() + ": " + reason, nil
+	}
+	diffs, err := bisectChunk(ctx, srcTrx, tgtTrx, chunk, srcCount, tgtCount)
+	if err != nil {
+		return "", fmt.Errorf("failed to find the rows that differ in chunk %s: %w", chunk.String(), err)
+	}
+	if err := c.diffs.write(chunk, diffs); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s: %s, %d rows differ", chunk.String(), reason, len(diffs)), nil
+}
+
+// checksumRange returns the checksum and row count of chunk on each side.
+func checksumRange(ctx context.Context, srcTrx, tgtTrx *sql.Tx, chunk *table.Chunk) (srcCRC, tgtCRC int64, srcCount, tgtCount uint64, err error) {
+	sourceCols, targetCols, err := chunk.ColumnMapping.ChecksumExprs()
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+	query := "SELECT BIT_XOR(CRC32(CONCAT(%s))) AS checksum, COUNT(*) AS c FROM %s WHERE %s"
+	if err := srcTrx.QueryRowContext(ctx, fmt.Sprintf(query, sourceCols, chunk.Table.QuotedTableName, chunk.String())).Scan(&srcCRC, &srcCount); err != nil {
+		return 0, 0, 0, 0, fmt.Errorf("failed to checksum source chunk: %w", err)
+	}
+	if err := tgtTrx.QueryRowContext(ctx, fmt.Sprintf(query, targetCols, chunk.NewTable.QuotedTableName, chunk.String())).Scan(&tgtCRC, &tgtCount); err != nil {
+		return 0, 0, 0, 0, fmt.Errorf("failed to checksum target chunk: %w", err)
+	}
+	return srcCRC, tgtCRC, srcCount, tgtCount, nil
+}
@@ -0,0 +1,95 @@
+package checksum
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/block/spirit/pkg/table"
+	"github.com/block/spirit/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecksumCmdValidate(t *testing.T) {
+	dsn := "root@tcp(127.0.0.1:3306)/test"
+	require.NoError(t, (&ChecksumCmd{SourceDSN: dsn, SourceTable: "t1", TargetTable: "t2", Threads: 4}).Validate())
+	require.NoError(t, (&ChecksumCmd{SourceDSN: dsn, SourceTable: "t1", TargetDSN: "root@tcp(127.0.0.1:3307)/test", Threads: 4}).Validate())
+	require.ErrorContains(t, (&ChecksumCmd{SourceDSN: dsn, SourceTable: "t1", Threads: 4}).Validate(), "the same table")
+	require.ErrorContains(t, (&ChecksumCmd{SourceDSN: dsn, SourceTable: "t1", TargetDSN: dsn, TargetTable: "t1", Threads: 4}).Validate(), "the same table")
+	require.ErrorContains(t, (&ChecksumCmd{SourceDSN: dsn, SourceTable: "t1", TargetTable: "t2"}).Validate(), "--threads")
+}
+
+func TestDiffWriterJSON(t *testing.T) {
+	src := table.NewTableInfo(nil, "test", "t1")
+	tgt := table.NewTableInfo(nil, "test", "t1_copy")
+	src.NonGeneratedColumns = []string{"id", "b"}
+	tgt.NonGeneratedColumns = []string{"id", "b"}
+	chunk := &table.Chunk{Key: []string{"id"}, Table: src, NewTable: tgt, ColumnMapping: table.NewColumnMapping(src, tgt, nil)}
+
+	path := filepath.Join(t.TempDir(), "diff.json")
+	w, err := newDiffWriter(path, "json")
+	require.NoError(t, err)
+	require.NoError(t, w.write(chunk, []rowDiff{
+		{sourceRow: []any{int64(1), []byte("x")}, targetRow: []any{int64(1), []byte("y")}},
+		{sourceRow: []any{int64(2), []byte{0xff}}},
+		{targetRow: []any{int64(3), nil}},
+	}))
+	require.NoError(t, w.Close())
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	require.Len(t, lines, 3)
+	require.Equal(t, `{"chunk":"1=1","source":{"b":"x","id":1},"target":{"b":"y","id":1}}`, lines[0])
+	require.Equal(t, `{"chunk":"1=1","source":{"b":"0xff","id":2},"target":null}`, lines[1])
+	require.Equal(t, `{"chunk":"1=1","source":null,"target":{"b":null,"id":3}}`, lines[2])
+}
+
+func TestChecksumCmd(t *testing.T) {
+	testutils.RunSQL(t, "DROP TABLE IF EXISTS cmdchecksum, cmdchecksum_copy")
+	testutils.RunSQL(t, "CREATE TABLE cmdchecksum (id INT NOT NULL PRIMARY KEY, a INT, b VARCHAR(10))")
+	testutils.RunSQL(t, "CREATE TABLE cmdchecksum_copy (id INT NOT NULL PRIMARY KEY, a INT, b VARCHAR(10))")
+	testutils.RunSQL(t, `INSERT INTO cmdchecksum WITH RECURSIVE seq (n) AS
+		(SELECT 1 UNION ALL SELECT n + 1 FROM seq WHERE n < 2000) SELECT n, n, 'x' FROM seq`)
+	testutils.RunSQL(t, "INSERT INTO cmdchecksum_copy SELECT * FROM cmdchecksum")
+	defer testutils.RunSQL(t, "DROP TABLE IF EXISTS cmdchecksum, cmdchecksum_copy")
+
+	cmd := &ChecksumCmd{
+		SourceDSN:       testutils.DSN(),
+		SourceTable:     "cmdchecksum",
+		TargetTable:     "cmdchecksum_copy",
+		Threads:         2,
+		TargetChunkTime: 10 * time.Millisecond,
+	}
+	require.NoError(t, cmd.Run())
+
+	// Every chunk that differs is reported, not just the first one.
+	testutils.RunSQL(t, "UPDATE cmdchecksum_copy SET b = 'y' WHERE id = 10")
+	testutils.RunSQL(t, "DELETE FROM cmdchecksum_copy WHERE id = 1990")
+	err := cmd.Run()
+	require.ErrorContains(t, err, "2 chunks of table cmdchecksum differ from table cmdchecksum_copy")
+	require.ErrorContains(t, err, "row count mismatch (src=")
+
+	// --diff-file narrows the chunks down to the rows that differ.
+	cmd.DiffFile = filepath.Join(t.TempDir(), "diff.sql")
+	cmd.DiffFormat = "sql"
+	err = cmd.Run()
+	require.ErrorContains(t, err, "1 rows differ")
+	b, err := os.ReadFile(cmd.DiffFile)
+	require.NoError(t, err)
+	require.Contains(t, string(b), "REPLACE INTO `test`.`cmdchecksum_copy` (`id`, `a`, `b`) VALUES (10, 10, 'x');")
+	require.Contains(t, string(b), "REPLACE INTO `test`.`cmdchecksum_copy` (`id`, `a`, `b`) VALUES (1990, 1990, 'x');")
+	require.Contains(t, string(b), `-- target: {"a":10,"b":"y","id":10}`)
+	cmd.DiffFile = ""
+
+	// --fix recopies them.
+	cmd.Fix = true
+	require.NoError(t, cmd.Run())
+	cmd.Fix = false
+	require.NoError(t, cmd.Run())
+
+	// --live applies the changes to the source table to the target table.
+	cmd.Live = true
+	require.NoError(t, cmd.Run())
+}
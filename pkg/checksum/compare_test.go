@@ -3,6 +3,7 @@ package checksum
 import (
 	"testing"
 
+	"github.com/block/spirit/pkg/table"
 	"github.com/stretchr/testify/require"
 )
 
@@ -83,3 +84,21 @@ func TestCompareChunk(t *testing.T) {
 		})
 	}
 }
+
+// TestEstimateChunksRemaining checks the extrapolation from the average
+// chunk size seen so far.
+func TestEstimateChunksRemaining(t *testing.T) {
+	chunker := table.NewMockChunker("t1", 10500)
+	require.NoError(t, chunker.Open())
+	require.Zero(t, estimateChunksRemaining(chunker)) // nothing to extrapolate from yet
+
+	for range 3 {
+		_, err := chunker.Next()
+		require.NoError(t, err)
+	}
+	// 3000 rows in 3 chunks; 7500 remaining rows round up to 8 chunks.
+	require.Equal(t, uint64(8), estimateChunksRemaining(chunker))
+
+	chunker.MarkAsComplete()
+	require.Zero(t, estimateChunksRemaining(chunker))
+}
@@ -48,6 +48,9 @@ type DistributedChecker struct {
 	differencesFound atomic.Uint64
 	recopyLock       sync.Mutex
 	maxRetries       int
+	diffFile         string
+	diffFormat       string
+	diffs            *diffWriter // set while Run runs, if there is a diff file
 }
 
 var _ Checker = (*DistributedChecker)(nil)
@@ -71,12 +74,14 @@ func (c *DistributedChecker) ChecksumChunk(ctx context.Context, chunk *table.Chu
 	// The count is simply summed.
 	var sourceChecksum int64
 	var sourceCount uint64
+	srcTrxs := make([]*sql.Tx, 0, len(c.sourcePools))
 	for i := range c.sourcePools {
 		srcTrx, err := c.sourcePools[i].trxPool.Get()
 		if err != nil {
 			return fmt.Errorf("failed to get transaction for source %d: %w", i, err)
 		}
 		defer c.sourcePools[i].trxPool.Put(srcTrx)
+		srcTrxs = append(srcTrxs, srcTrx)
 
 		sourceQuery := fmt.Sprintf("SELECT BIT_XOR(CRC32(CONCAT(%s))) as checksum, count(*) as c FROM %s WHERE %s",
 			checksumColumns,
@@ -97,12 +102,14 @@ func (c *DistributedChecker) ChecksumChunk(ctx context.Context, chunk *table.Chu
 	// Same aggregation logic: XOR checksums, sum counts.
 	var targetChecksum int64
 	var targetCount uint64
+	targetTrxs := make([]*sql.Tx, 0, len(c.targetTrxPools))
 	for i, targetTrxPool := range c.targetTrxPools {
 		targetTrx, err := targetTrxPool.Get()
 		if err != nil {
 			return fmt.Errorf("failed to get transaction for target %d: %w", i, err)
 		}
 		defer targetTrxPool.Put(targetTrx)
+		targetTrxs = append(targetTrxs, targetTrx)
 
 		targetQuery := fmt.Sprintf("SELECT BIT_XOR(CRC32(CONCAT(%s))) as checksum, count(*) as c FROM %s WHERE %s",
 			checksumColumns,
@@ -140,9 +147,19 @@ func (c *DistributedChecker) ChecksumChunk(ctx context.Context, chunk *table.Chu
 			"sourceChecksum", sourceChecksum, "targetChecksum", targetChecksum,
 			"sourceCount", sourceCount, "targetCount", targetCount)
 
-		// For distributed case, we can't easily inspect differences across multiple sources/targets
-		// So we'll just log the mismatch and proceed to fix
-		c.logger.Warn("distributed chunk verification failed, will recopy chunk")
+		// The rows that differ can only be narrowed down between one source
+		// and one target, since a row's source and target aren't known.
+		switch {
+		case c.diffs != nil && len(srcTrxs) == 1 && len(targetTrxs) == 1:
+			if err := writeRowDiffs(ctx, c.diffs, srcTrxs[0], targetTrxs[0], chunk, sourceCount, targetCount); err != nil {
+				return err
+			}
+		case c.diffs != nil:
+			c.logger.Warn("can't write the rows that differ to the checksum diff file with more than one source or target, will recopy chunk",
+				"sources", len(srcTrxs), "targets", len(targetTrxs))
+		default:
+			c.logger.Warn("distributed chunk verification failed, will recopy chunk")
+		}
 
 		// Are we allowed to fix the differences? If not, return an error.
 		// This is mostly used by the test-suite.
@@ -317,6 +334,11 @@ func (c *DistributedChecker) DifferencesFound() uint64 {
 	return c.differencesFound.Load()
 }
 
+// ChunksRemaining estimates the chunks left in the current pass.
+func (c *DistributedChecker) ChunksRemaining() uint64 {
+	return estimateChunksRemaining(c.chunker)
+}
+
 func (c *DistributedChecker) setInvalid(newVal bool) {
 	c.Lock()
 	defer c.Unlock()
@@ -483,6 +505,13 @@ func (c *DistributedChecker) Run(ctx context.Context) error {
 		_ = c.applier.Stop()
 	}()
 
+	diffs, closeDiffs, err := openDiffFile(c.diffFile, c.diffFormat, c.logger)
+	if err != nil {
+		return err
+	}
+	c.diffs = diffs
+	defer closeDiffs()
+
 	// Try the checksum up to n times if differences are found and we can fix them
 	for attempt := 1; attempt <= c.maxRetries; attempt++ {
 		if attempt > 1 {
@@ -0,0 +1,336 @@
+package checksum
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/block/spirit/pkg/dbconn/sqlescape"
+	"github.com/block/spirit/pkg/table"
+	"github.com/block/spirit/pkg/utils"
+)
+
+// bisectLeafRows is the most rows that a range of a chunk that differs may
+// have on either side before bisectChunk compares it row by row, instead
+// of splitting it again.
+const bisectLeafRows = 100
+
+// rowDiff is a row that differs between the source and target tables.
+// sourceRow or targetRow is nil if the row is missing from that side. The
+// values are in the order of the chunk's ColumnMapping.
+type rowDiff struct {
+	sourceRow []any
+	targetRow []any
+}
+
+// bisectChunk finds the rows of chunk that differ between the source and
+// target tables. A chunk with more than bisectLeafRows rows on either side
+// is split in two at the middle key of its larger side, and only the
+// halves whose checksum differs are searched, so a chunk of a large table
+// with a few rows that differ is narrowed down in a few queries. The
+// ranges that are left are compared row by row. It reads through srcTrx
+// and tgtTrx, so that it sees the same rows as the checksum.
+func bisectChunk(ctx context.Context, srcTrx, tgtTrx *sql.Tx, chunk *table.Chunk, srcCount, tgtCount uint64) ([]rowDiff, error) {
+	if max(srcCount, tgtCount) <= bisectLeafRows {
+		return diffRows(ctx, srcTrx, tgtTrx, chunk)
+	}
+	trx, tbl, count := srcTrx, chunk.Table, srcCount
+	if tgtCount > srcCount {
+		trx, tbl, count = tgtTrx, chunk.NewTable, tgtCount
+	}
+	mid, err := middleKey(ctx, trx, tbl, chunk, count)
+	if err != nil {
+		return nil, err
+	}
+	lower, upper := *chunk, *chunk
+	lower.UpperBound = &table.Boundary{Value: mid, Inclusive: false}
+	upper.LowerBound = &table.Boundary{Value: mid, Inclusive: true}
+	var diffs []rowDiff
+	for _, half := range []*table.Chunk{&lower, &upper} {
+		srcCRC, tgtCRC, srcCount, tgtCount, err := checksumRange(ctx, srcTrx, tgtTrx, half)
+		if err != nil {
+			return nil, err
+		}
+		if !compareChunk(srcCRC, tgtCRC, srcCount, tgtCount).mismatched() {
+			continue
+		}
+		halfDiffs, err := bisectChunk(ctx, srcTrx, tgtTrx, half, srcCount, tgtCount)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, halfDiffs...)
+	}
+	return diffs, nil
+}
+
+// writeRowDiffs narrows a chunk that differs down to the rows that differ
+// with bisectChunk, and writes them to diffs.
+func writeRowDiffs(ctx context.Context, diffs *diffWriter, srcTrx, tgtTrx *sql.Tx, chunk *table.Chunk, srcCount, tgtCount uint64) error {
+	rows, err := bisectChunk(ctx, srcTrx, tgtTrx, chunk, srcCount, tgtCount)
+	if err != nil {
+		return fmt.Errorf("failed to find the rows that differ in chunk %s: %w", chunk.String(), err)
+	}
+	return diffs.write(chunk, rows)
+}
+
+// middleKey returns the key of the middle row of the count rows of chunk
+// in tbl. It is never the key of the first row, so both halves of a chunk
+// that is split at it are smaller than the chunk.
+func middleKey(ctx context.Context, trx *sql.Tx, tbl *table.TableInfo, chunk *table.Chunk, count uint64) ([]table.Datum, error) {
+	keyCols := table.QuoteColumns(chunk.Key)
+	values := make([]any, len(chunk.Key))
+	ptrs := make([]any, len(chunk.Key))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := trx.QueryRowContext(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY %s LIMIT 1 OFFSET %d",
+		keyCols, tbl.QuotedTableName, chunk.String(), keyCols, count/2)).Scan(ptrs...); err != nil {
+		return nil, fmt.Errorf("failed to find the middle key of chunk %s: %w", chunk.String(), err)
+	}
+	mid := make([]table.Datum, len(chunk.Key))
+	for i, col := range chunk.Key {
+		tp, ok := tbl.GetColumnMySQLType(col)
+		if !ok {
+			return nil, fmt.Errorf("column %s not found in table %s", col, tbl.TableName)
+		}
+		d, err := table.NewDatumFromValue(values[i], tp)
+		if err != nil {
+			return nil, err
+		}
+		mid[i] = d
+	}
+	return mid, nil
+}
+
+// diffRows compares the rows of chunk one by one, and returns the ones
+// that differ: those whose checksums differ, and those that are missing
+// from one side.
+func diffRows(ctx context.Context, srcTrx, tgtTrx *sql.Tx, chunk *table.Chunk) ([]rowDiff, error) {
+	sourceCRC, targetCRC, err := chunk.ColumnMapping.ChecksumExprs()
+	if err != nil {
+		return nil, err
+	}
+	sourceNames, targetNames := chunk.ColumnMapping.ColumnsSlice()
+	sourceCols, targetCols := table.QuoteColumns(sourceNames), table.QuoteColumns(targetNames)
+	keyCols := table.QuoteColumns(chunk.Key)
+	query := "SELECT CRC32(CONCAT(%s)), %s FROM %s WHERE %s ORDER BY %s"
+	srcCRCs, srcRows, srcKeys, err := readRowImages(ctx, srcTrx, fmt.Sprintf(query, sourceCRC, sourceCols, chunk.Table.QuotedTableName, chunk.String(), keyCols), sourceNames, chunk.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source rows: %w", err)
+	}
+	tgtCRCs, tgtRows, tgtKeys, err := readRowImages(ctx, tgtTrx, fmt.Sprintf(query, targetCRC, targetCols, chunk.NewTable.QuotedTableName, chunk.String(), keyCols), targetNames, chunk.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read target rows: %w", err)
+	}
+	var diffs []rowDiff
+	for _, key := range srcKeys {
+		if tgtRow, ok := tgtRows[key]; !ok || tgtCRCs[key] != srcCRCs[key] {
+			diffs = append(diffs, rowDiff{sourceRow: srcRows[key], targetRow: tgtRow})
+		}
+	}
+	for _, key := range tgtKeys {
+		if _, ok := srcRows[key]; !ok {
+			diffs = append(diffs, rowDiff{targetRow: tgtRows[key]})
+		}
+	}
+	return diffs, nil
+}
+
+// readRowImages reads the rows of query, whose first column is the row's
+// checksum and the rest are the columns of names. It returns the checksums and
+// rows by key, and the keys in the order they were read.
+func readRowImages(ctx context.Context, trx *sql.Tx, query string, names, keyCols []string) (map[string]int64, map[string][]any, []string, error) {
+	keyIdx := make([]int, len(keyCols))
+	for i, col := range keyCols {
+		if keyIdx[i] = slices.Index(names, col); keyIdx[i] < 0 {
+			return nil, nil, nil, fmt.Errorf("key column %s is not compared", col)
+		}
+	}
+	rows, err := trx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer utils.CloseAndLog(rows)
+	crcs := make(map[string]int64)
+	images := make(map[string][]any)
+	var keys []string
+	for rows.Next() {
+		var crc int64
+		values := make([]any, len(names))
+		ptrs := []any{&crc}
+		for i := range values {
+			ptrs = append(ptrs, &values[i])
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, nil, nil, err
+		}
+		keyValues := make([]string, len(keyIdx))
+		for i, idx := range keyIdx {
+			keyValues[i] = fmt.Sprintf("%v", jsonValue(values[idx]))
+		}
+		key := strings.Join(keyValues, ",")
+		crcs[key] = crc
+		images[key] = values
+		keys = append(keys, key)
+	}
+	return crcs, images, keys, rows.Err()
+}
+
+// diffWriter writes the rows that differ to a diff file (see --diff-file
+// and --checksum-diff-file), in one of two formats: "json" writes a JSON
+// object per row, and "sql" writes the statements that make the target
+// table's row match the source table's, with both row images in a comment,
+// so that the repair can be reviewed before it is run.
+type diffWriter struct {
+	sync.Mutex
+	f      *os.File
+	w      *bufio.Writer
+	format string
+	// headed are the target tables that the sql format has written the
+	// header of, before their first row.
+	headed map[*table.TableInfo]bool
+	rows   int
+}
+
+// newDiffWriter creates the file at path.
+func newDiffWriter(path, format string) (*diffWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &diffWriter{f: f, w: bufio.NewWriter(f), format: format, headed: make(map[*table.TableInfo]bool)}, nil
+}
+
+// write writes the rows of chunk that differ.
+func (w *diffWriter) write(chunk *table.Chunk, diffs []rowDiff) error {
+	w.Lock()
+	defer w.Unlock()
+	sourceNames, targetNames := chunk.ColumnMapping.ColumnsSlice()
+	if w.format == "sql" && len(diffs) > 0 && !w.headed[chunk.NewTable] {
+		if len(w.headed) > 0 {
+			fmt.Fprintln(w.w)
+		}
+		w.headed[chunk.NewTable] = true
+		fmt.Fprintf(w.w, "-- The rows of table %s.%s that differ from table %s.%s.\n", chunk.NewTable.SchemaName, chunk.NewTable.TableName, chunk.Table.SchemaName, chunk.Table.TableName)
+		fmt.Fprintf(w.w, "-- Review them before running these statements on the target, which make its rows match the source's.\n")
+	}
+	for _, d := range diffs {
+		sourceImage := rowImage(sourceNames, d.sourceRow)
+		targetImage := rowImage(targetNames, d.targetRow)
+		if w.format != "sql" {
+			b, err := json.Marshal(struct {
+				Chunk  string         `json:"chunk"`
+				Source map[string]any `json:"source"`
+				Target map[string]any `json:"target"`
+			}{chunk.String(), sourceImage, targetImage})
+			if err != nil {
+				return err
+			}
+			// A failed write to w.w is returned by Close.
+			fmt.Fprintf(w.w, "%s\n", b)
+			w.rows++
+			continue
+		}
+		sourceJSON, err := json.Marshal(sourceImage)
+		if err != nil {
+			return err
+		}
+		targetJSON, err := json.Marshal(targetImage)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w.w, "\n-- source: %s\n-- target: %s\n", sourceJSON, targetJSON)
+		stmt, err := w.repairStatement(chunk, d)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w.w, "%s;\n", stmt)
+		w.rows++
+	}
+	return nil
+}
+
+// repairStatement returns the statement that makes the target table's
+// row of d match the source table's: a REPLACE of the source row, or a
+// DELETE of a row that the source doesn't have.
+func (w *diffWriter) repairStatement(chunk *table.Chunk, d rowDiff) (string, error) {
+	sourceNames, targetNames := chunk.ColumnMapping.ColumnsSlice()
+	target := chunk.NewTable
+	targetName := sqlescape.MustEscapeSQL("%n.%n", target.SchemaName, target.TableName)
+	if d.sourceRow != nil {
+		values := make([]string, len(d.sourceRow))
+		for i, v := range d.sourceRow {
+			datum, err := sqlDatum(chunk.Table, sourceNames[i], v)
+			if err != nil {
+				return "", err
+			}
+			values[i] = datum
+		}
+		return fmt.Sprintf("REPLACE INTO %s (%s) VALUES (%s)", targetName, table.QuoteColumns(targetNames), strings.Join(values, ", ")), nil
+	}
+	conds := make([]string, len(chunk.Key))
+	for i, col := range chunk.Key {
+		datum, err := sqlDatum(target, col, d.targetRow[slices.Index(targetNames, col)])
+		if err != nil {
+			return "", err
+		}
+		conds[i] = fmt.Sprintf("%s = %s", table.QuoteColumns([]string{col}), datum)
+	}
+	return fmt.Sprintf("DELETE FROM %s WHERE %s", targetName, strings.Join(conds, " AND ")), nil
+}
+
+// Close flushes and closes the file.
+func (w *diffWriter) Close() error {
+	if err := w.w.Flush(); err != nil {
+		_ = w.f.Close()
+		return err
+	}
+	return w.f.Close()
+}
+
+// sqlDatum returns value of column col of tbl as an SQL literal.
+func sqlDatum(tbl *table.TableInfo, col string, value any) (string, error) {
+	tp, ok := tbl.GetColumnMySQLType(col)
+	if !ok {
+		return "", fmt.Errorf("column %s not found in table %s", col, tbl.TableName)
+	}
+	d, err := table.NewDatumFromValue(value, tp)
+	if err != nil {
+		return "", err
+	}
+	return d.String(), nil
+}
+
+// rowImage returns a row as a map of column names to values, or nil if
+// there is no row.
+func rowImage(names []string, row []any) map[string]any {
+	if row == nil {
+		return nil
+	}
+	image := make(map[string]any, len(names))
+	for i, name := range names {
+		image[name] = jsonValue(row[i])
+	}
+	return image
+}
+
+// jsonValue returns a value read by the driver as a value for a report:
+// bytes are a string, or 0x-prefixed hex if they are not valid UTF-8.
+func jsonValue(v any) any {
+	b, ok := v.([]byte)
+	if !ok {
+		return v
+	}
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	return "0x" + hex.EncodeToString(b)
+}
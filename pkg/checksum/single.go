@@ -40,6 +40,9 @@ type SingleChecker struct {
 	maxRetries       int
 	yieldTimeout     time.Duration
 	yieldsPerformed  atomic.Uint64 // number of yield/resume cycles performed
+	diffFile         string
+	diffFormat       string
+	diffs            *diffWriter // set while Run runs, if there is a diff file
 }
 
 var _ Checker = (*SingleChecker)(nil)
@@ -86,7 +89,12 @@ func (c *SingleChecker) ChecksumChunk(ctx context.Context, trxPool *dbconn.TrxPo
 		// to inspect closely and report on the differences.
 		c.differencesFound.Add(1)
 		c.logger.Warn("chunk verification failed", "chunk", chunk.String(), "reason", mismatch.reason(sourceCount, targetCount), "sourceChecksum", sourceChecksum, "targetChecksum", targetChecksum, "sourceCount", sourceCount, "targetCount", targetCount)
-		if err := c.inspectDifferences(ctx, trx, chunk); err != nil {
+		if c.diffs != nil {
+			// Both tables are read in the same transaction.
+			if err := writeRowDiffs(ctx, c.diffs, trx, trx, chunk, sourceCount, targetCount); err != nil {
+				return err
+			}
+		} else if err := c.inspectDifferences(ctx, trx, chunk); err != nil {
 			return err
 		}
 		// Are we allowed to fix the differences? If not, return an error.
@@ -324,6 +332,11 @@ func (c *SingleChecker) DifferencesFound() uint64 {
 	return c.differencesFound.Load()
 }
 
+// ChunksRemaining estimates the chunks left in the current pass.
+func (c *SingleChecker) ChunksRemaining() uint64 {
+	return estimateChunksRemaining(c.chunker)
+}
+
 func (c *SingleChecker) setInvalid(newVal bool) {
 	c.Lock()
 	defer c.Unlock()
@@ -380,6 +393,13 @@ func (c *SingleChecker) Run(ctx context.Context) error {
 		c.execTime = time.Since(startTime)
 	}()
 
+	diffs, closeDiffs, err := openDiffFile(c.diffFile, c.diffFormat, c.logger)
+	if err != nil {
+		return err
+	}
+	c.diffs = diffs
+	defer closeDiffs()
+
 	// Try the checksum up to n times if differences are found and we can fix them
 	var lastErr error
 	for attempt := 1; attempt <= c.maxRetries; attempt++ {
@@ -2,6 +2,8 @@ package checksum
 
 import (
 	"database/sql"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -194,6 +196,52 @@ func TestFixCorrupt(t *testing.T) {
 	require.Equal(t, uint64(0), singleChecker.differencesFound.Load())
 }
 
+func TestChecksumDiffFile(t *testing.T) {
+	testutils.RunSQL(t, "DROP TABLE IF EXISTS chkdifft1, _chkdifft1_new, _chkdifft1_chkpnt")
+	testutils.RunSQL(t, "CREATE TABLE chkdifft1 (a INT NOT NULL, b INT, c INT, PRIMARY KEY (a))")
+	testutils.RunSQL(t, "CREATE TABLE _chkdifft1_new (a INT NOT NULL, b INT, c INT, PRIMARY KEY (a))")
+	testutils.RunSQL(t, "CREATE TABLE _chkdifft1_chkpnt (a INT)") // for binlog advancement
+	testutils.RunSQL(t, "INSERT INTO chkdifft1 VALUES (1, 2, 3), (3, 4, 5)")
+	testutils.RunSQL(t, "INSERT INTO _chkdifft1_new VALUES (1, 2, 3), (3, 4, 6)")
+	testutils.RunSQL(t, "INSERT INTO _chkdifft1_new VALUES (2, 2, 3)") // corrupt
+
+	db, err := dbconn.New(testutils.DSN(), dbconn.NewDBConfig())
+	require.NoError(t, err)
+	defer utils.CloseAndLog(db)
+
+	t1 := table.NewTableInfo(db, "test", "chkdifft1")
+	require.NoError(t, t1.SetInfo(t.Context()))
+	t2 := table.NewTableInfo(db, "test", "_chkdifft1_new")
+	require.NoError(t, t2.SetInfo(t.Context()))
+
+	cfg, err := mysql.ParseDSN(testutils.DSN())
+	require.NoError(t, err)
+	feed := change.NewBinlogClient(db, cfg.Addr, cfg.User, cfg.Passwd, applier.NewSingleTargetForTest(t, db), change.NewClientDefaultConfig())
+	defer feed.Close()
+	chunker, err := table.NewChunker(t1, table.ChunkerConfig{NewTable: t2})
+	require.NoError(t, err)
+	require.NoError(t, feed.AddSubscription(t1, t2, chunker))
+	require.NoError(t, feed.Start(t.Context()))
+	require.NoError(t, chunker.Open())
+
+	// The rows that differ are written to the diff file before the chunk
+	// is recopied.
+	config := NewCheckerDefaultConfig()
+	config.FixDifferences = true
+	config.MaxRetries = 2
+	config.DiffFile = filepath.Join(t.TempDir(), "diff.sql")
+	config.DiffFormat = "sql"
+	checker, err := NewChecker([]*sql.DB{db}, chunker, []change.Source{feed}, config)
+	require.NoError(t, err)
+	require.NoError(t, checker.Run(t.Context()))
+	b, err := os.ReadFile(config.DiffFile)
+	require.NoError(t, err)
+	require.Contains(t, string(b), "-- The rows of table test._chkdifft1_new that differ from table test.chkdifft1.")
+	require.Contains(t, string(b), "DELETE FROM `test`.`_chkdifft1_new` WHERE `a` = 2;")
+	require.Contains(t, string(b), "REPLACE INTO `test`.`_chkdifft1_new` (`a`, `b`, `c`) VALUES (3, 4, 5);")
+	require.NotContains(t, string(b), "VALUES (1, 2, 3)")
+}
+
 func TestCorruptChecksum(t *testing.T) {
 	testutils.RunSQL(t, "DROP TABLE IF EXISTS chkpcorruptt1, _chkpcorruptt1_new, _chkpcorruptt1_chkpnt")
 	testutils.RunSQL(t, "CREATE TABLE chkpcorruptt1 (a INT NOT NULL, b INT, c INT, PRIMARY KEY (a))")
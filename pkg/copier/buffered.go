@@ -44,8 +44,26 @@ type buffered struct {
 	metricsSink      metrics.Sink
 	copierEtaHistory *copierEtaHistory
 	autoscale        AutoscaleConfig
+	pause            Pauser
+
+	// concurrency can be changed by SetConcurrency while the copy runs.
+	// readWorkers is the errgroup of the running copy (nil otherwise) and
+	// liveReaders the number of read workers in it that have not yet
+	// exited. A surplus worker exits at the top of its loop, and missing
+	// workers are added to the group, but only while liveReaders > 0:
+	// adding to an errgroup whose Wait may already have returned is not
+	// allowed. All three are guarded by the embedded mutex, as is
+	// writeThreads (0 = the applier's own start value).
+	readWorkers    *errgroup.Group
+	readWorkersCtx context.Context
+	liveReaders    int
+	writeThreads   int
 }
 
+// errReaderParked is returned by a read worker that exited because
+// SetConcurrency lowered the number of workers. It never leaves Run.
+var errReaderParked = errors.New("read worker parked")
+
 // Assert that buffered implements the Copier interface
 var _ Copier = (*buffered)(nil)
 
@@ -53,9 +71,10 @@ var _ Copier = (*buffered)(nil)
 func (c *buffered) readChunkData(ctx context.Context, chunk *table.Chunk) ([][]any, error) {
 	// Build the SELECT query to read full row data
 	columnList, _ := chunk.ColumnMapping.Columns()
-	query := fmt.Sprintf("SELECT %s FROM %s FORCE INDEX (PRIMARY) WHERE %s",
+	query := fmt.Sprintf("SELECT %s FROM %s FORCE INDEX (%s) WHERE %s",
 		columnList,
 		chunk.Table.QuotedTableName,
+		table.QuoteColumns([]string{chunk.Table.KeyName()}),
 		chunk.String(),
 	)
 
@@ -126,6 +145,15 @@ func (c *buffered) Run(ctx context.Context) error {
 	if err := c.applier.Start(ctx); err != nil {
 		return fmt.Errorf("failed to start applier: %w", err)
 	}
+	// The applier starts at its configured thread count; apply any change
+	// SetWriteThreads made before it was running.
+	c.Lock()
+	if c.writeThreads > 0 {
+		if scaler, ok := c.applier.(writeScaler); ok {
+			scaler.SetWriteWorkers(c.writeThreads)
+		}
+	}
+	c.Unlock()
 
 	// Experimental: start the write-thread autoscaler. It runs for the lifetime
 	// of the copy and stops when ctx is cancelled (deferred above). It only
@@ -138,15 +166,19 @@ func (c *buffered) Run(ctx context.Context) error {
 
 	// Start read workers
 	g, errGrpCtx := errgroup.WithContext(ctx)
+	c.Lock()
 	c.logger.Debug("starting read workers", "count", c.concurrency)
+	c.readWorkers, c.readWorkersCtx = g, errGrpCtx
 	for range c.concurrency {
-		g.Go(func() error {
-			return c.readWorker(errGrpCtx)
-		})
+		c.startReadWorker()
 	}
+	c.Unlock()
 
 	// Wait for all read workers to finish
 	err := g.Wait()
+	c.Lock()
+	c.readWorkers, c.readWorkersCtx = nil, nil
+	c.Unlock()
 
 	// Wait for the applier to finish processing all pending work
 	// This ensures all callbacks have been invoked before we return
@@ -201,11 +233,88 @@ func (c *buffered) autoscalerIfEnabled() *autoScaler {
 	return newAutoScaler(gradual, scaler, c.autoscale.StartThreads, c.autoscale.MaxThreads, c.logger, c.metricsSink)
 }
 
+// startReadWorker adds a read worker to the running copy. Caller must hold
+// the mutex and have checked that readWorkers is set.
+func (c *buffered) startReadWorker() {
+	c.liveReaders++
+	ctx := c.readWorkersCtx
+	c.readWorkers.Go(func() error {
+		err := c.readWorker(ctx)
+		if errors.Is(err, errReaderParked) {
+			return nil // already uncounted by shouldPark
+		}
+		c.Lock()
+		c.liveReaders--
+		c.Unlock()
+		return err
+	})
+}
+
+// SetConcurrency changes the number of read workers. Before Run it sets
+// the number Run starts with. During Run, new workers start immediately
+// and surplus workers exit once they have handed their current chunk to
+// the applier. Once the last worker has exited (the table is read) it
+// has no effect.
+func (c *buffered) SetConcurrency(n int) {
+	n = max(n, 1)
+	c.Lock()
+	defer c.Unlock()
+	c.concurrency = n
+	if c.readWorkers == nil || c.liveReaders == 0 {
+		return
+	}
+	for c.liveReaders < c.concurrency {
+		c.startReadWorker()
+	}
+}
+
+// shouldPark reports whether the calling read worker should exit because
+// there are more workers than the current concurrency. A worker that is
+// told to park is uncounted here, under the same lock as the check, so
+// that several workers can't all park for the same surplus.
+func (c *buffered) shouldPark() bool {
+	c.Lock()
+	defer c.Unlock()
+	if c.liveReaders > c.concurrency {
+		c.liveReaders--
+		return true
+	}
+	return false
+}
+
+// SetWriteThreads changes the number of applier write workers. It fails
+// when the autoscaler owns the write workers, or when the applier can't
+// change its worker count (ShardedApplier).
+func (c *buffered) SetWriteThreads(n int) error {
+	if c.autoscale.Enabled {
+		return errors.New("write threads are managed by the autoscaler (--enable-experimental-autoscaling)")
+	}
+	scaler, ok := c.applier.(writeScaler)
+	if !ok {
+		return errors.New("this applier does not support changing write threads")
+	}
+	n = max(n, 1)
+	c.Lock()
+	defer c.Unlock()
+	c.writeThreads = n
+	// A no-op unless the applier is running; Run applies writeThreads
+	// when it starts the applier.
+	scaler.SetWriteWorkers(n)
+	return nil
+}
+
 // readWorker reads chunks and sends them to the applier
 func (c *buffered) readWorker(ctx context.Context) error {
 	c.logger.Debug("readWorker started", "isRead", c.chunker.IsRead())
 
 	for !c.chunker.IsRead() && c.isHealthy(ctx) {
+		if c.shouldPark() {
+			c.logger.Debug("readWorker parked")
+			return errReaderParked
+		}
+		if err := waitWhilePaused(ctx, c.pause); err != nil {
+			return err
+		}
 		c.throttler.BlockWait(ctx)
 
 		c.logger.Debug("readWorker calling chunker.Next()")
@@ -237,7 +346,7 @@ func (c *buffered) readWorker(ctx context.Context) error {
 			c.chunker.Feedback(chunk, totalTime, 0)
 
 			// Send metrics for empty chunk
-			err := c.sendMetrics(ctx, totalTime, chunk.ChunkSize, 0)
+			err := c.sendMetrics(ctx, chunk, totalTime, 0)
 			if err != nil {
 				c.logger.Error("error sending metrics for empty chunk", "error", err)
 			}
@@ -278,7 +387,7 @@ func (c *buffered) readWorker(ctx context.Context) error {
 			c.chunker.Feedback(capturedChunk, totalTime, uint64(affectedRows))
 
 			// Send metrics with total processing time
-			metricsErr := c.sendMetrics(ctx, totalTime, capturedChunk.ChunkSize, uint64(affectedRows))
+			metricsErr := c.sendMetrics(ctx, capturedChunk, totalTime, uint64(affectedRows))
 			if metricsErr != nil {
 				c.logger.Error("error sending metrics from copier", "error", metricsErr)
 			}
@@ -369,6 +478,10 @@ func (c *buffered) GetETA() string {
 	return estimate.String()
 }
 
+func (c *buffered) GetLatestETA() (time.Duration, bool) {
+	return c.copierEtaHistory.latest()
+}
+
 func (c *buffered) estimateRowsPerSecondLoop(ctx context.Context) {
 	// We take >10 second averages because with parallel copy it bounces around a lot.
 	// Get progress from chunker since we no longer track rows locally
@@ -393,23 +506,27 @@ func (c *buffered) estimateRowsPerSecondLoop(ctx context.Context) {
 	}
 }
 
-func (c *buffered) sendMetrics(ctx context.Context, processingTime time.Duration, logicalRowsCount uint64, affectedRowsCount uint64) error {
+func (c *buffered) sendMetrics(ctx context.Context, chunk *table.Chunk, processingTime time.Duration, affectedRowsCount uint64) error {
+	labels := map[string]string{metrics.TableLabel: chunk.Table.TableName}
 	m := &metrics.Metrics{
 		Values: []metrics.MetricValue{
 			{
-				Name:  metrics.ChunkProcessingTimeMetricName,
-				Type:  metrics.GAUGE,
-				Value: float64(processingTime.Milliseconds()), // in milliseconds
+				Name:   metrics.ChunkProcessingTimeMetricName,
+				Type:   metrics.GAUGE,
+				Value:  float64(processingTime.Milliseconds()), // in milliseconds
+				Labels: labels,
 			},
 			{
-				Name:  metrics.ChunkLogicalRowsCountMetricName,
-				Type:  metrics.COUNTER,
-				Value: float64(logicalRowsCount),
+				Name:   metrics.ChunkLogicalRowsCountMetricName,
+				Type:   metrics.COUNTER,
+				Value:  float64(chunk.ChunkSize),
+				Labels: labels,
 			},
 			{
-				Name:  metrics.ChunkAffectedRowsCountMetricName,
-				Type:  metrics.COUNTER,
-				Value: float64(affectedRowsCount),
+				Name:   metrics.ChunkAffectedRowsCountMetricName,
+				Type:   metrics.COUNTER,
+				Value:  float64(affectedRowsCount),
+				Labels: labels,
 			},
 		},
 	}
@@ -2,16 +2,22 @@ package copier
 
 import (
 	"context"
+	"io"
+	"log/slog"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/block/spirit/pkg/applier"
 	"github.com/block/spirit/pkg/dbconn"
+	"github.com/block/spirit/pkg/status"
 	"github.com/block/spirit/pkg/table"
 	"github.com/block/spirit/pkg/testutils"
+	"github.com/block/spirit/pkg/throttler"
 	"github.com/block/spirit/pkg/utils"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
 )
 
 func TestBufferedCopier(t *testing.T) {
@@ -428,3 +434,101 @@ func TestBufferedCopierGeometry(t *testing.T) {
 		"SELECT BIT_XOR(CRC32(CONCAT(id, name, ST_AsText(location)))) FROM geomdst").Scan(&checksumDst))
 	require.Equal(t, checksumSrc, checksumDst, "geometry data checksum mismatch after buffered copy")
 }
+
+// gatedChunker blocks read workers in IsRead until release is closed,
+// and then reports the table as read from Next. It lets a test hold the
+// workers at the top of their loop without touching a database.
+type gatedChunker struct {
+	table.Chunker
+	release chan struct{}
+	blocked atomic.Int32
+	nexts   atomic.Int32
+}
+
+func (g *gatedChunker) IsRead() bool {
+	g.blocked.Add(1)
+	<-g.release
+	return false
+}
+
+func (g *gatedChunker) Next() (*table.Chunk, error) {
+	g.nexts.Add(1)
+	return nil, table.ErrTableIsRead
+}
+
+// TestBufferedSetConcurrency checks the read worker bookkeeping: raising
+// the concurrency starts workers in the running group straight away, and
+// lowering it parks exactly the surplus.
+func TestBufferedSetConcurrency(t *testing.T) {
+	chunker := &gatedChunker{release: make(chan struct{})}
+	c := &buffered{
+		logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		chunker:     chunker,
+		throttler:   &throttler.Noop{},
+		concurrency: 2,
+	}
+	g, ctx := errgroup.WithContext(t.Context())
+	c.Lock()
+	c.readWorkers, c.readWorkersCtx = g, ctx
+	for range c.concurrency {
+		c.startReadWorker()
+	}
+	c.Unlock()
+
+	c.SetConcurrency(4)
+	require.Eventually(t, func() bool { return chunker.blocked.Load() == 4 }, 5*time.Second, time.Millisecond)
+
+	// Three of the four workers park at the top of their loop, and only
+	// one goes on to ask the chunker for a chunk.
+	c.SetConcurrency(1)
+	close(chunker.release)
+	require.NoError(t, g.Wait())
+	require.Equal(t, int32(1), chunker.nexts.Load())
+	require.Zero(t, c.liveReaders)
+
+	// Once no worker is left it only changes the starting value.
+	c.SetConcurrency(8)
+	require.Equal(t, 8, c.concurrency)
+	require.Zero(t, c.liveReaders)
+}
+
+func TestBufferedPause(t *testing.T) {
+	chunker := &gatedChunker{release: make(chan struct{})}
+	close(chunker.release) // workers don't block in IsRead
+	pause := &status.Pause{}
+	pause.Pause()
+	c := &buffered{
+		logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		chunker:     chunker,
+		throttler:   &throttler.Noop{},
+		concurrency: 1,
+		pause:       pause,
+	}
+	done := make(chan error, 1)
+	go func() { done <- c.readWorker(t.Context()) }()
+
+	// The worker asks for no chunk while paused.
+	time.Sleep(50 * time.Millisecond)
+	require.Zero(t, chunker.nexts.Load())
+	pause.Resume()
+	require.NoError(t, <-done)
+	require.Equal(t, int32(1), chunker.nexts.Load())
+}
+
+func TestBufferedSetWriteThreads(t *testing.T) {
+	scaling := &fakeScalingApplier{}
+	c := &buffered{applier: scaling}
+	require.NoError(t, c.SetWriteThreads(6))
+	require.Equal(t, 6, scaling.n)
+	require.Equal(t, 6, c.writeThreads)
+	require.NoError(t, c.SetWriteThreads(0))
+	require.Equal(t, 1, scaling.n, "clamped to at least one thread")
+
+	// The autoscaler owns the pool.
+	c.autoscale.Enabled = true
+	require.Error(t, c.SetWriteThreads(2))
+
+	// The applier can't change its worker count.
+	c = &buffered{applier: &delayedCallbackApplier{}}
+	require.Error(t, c.SetWriteThreads(2))
+}
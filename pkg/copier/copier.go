@@ -30,11 +30,20 @@ const (
 type Copier interface {
 	Run(ctx context.Context) error
 	GetETA() string
+	// GetLatestETA returns the remaining copy time of the latest estimate
+	// that GetETA recorded, and false if it hasn't made one yet.
+	GetLatestETA() (time.Duration, bool)
 	GetChunker() table.Chunker
 	SetThrottler(throttler throttler.Throttler)
 	GetThrottler() throttler.Throttler
 	StartTime() time.Time
 	GetProgress() string
+	// SetConcurrency changes the number of copy (read) threads. It is safe
+	// to call while Run is in progress.
+	SetConcurrency(n int)
+	// SetWriteThreads changes the number of write threads of the applier.
+	// It returns an error if the copier has no write threads it can change.
+	SetWriteThreads(n int) error
 }
 
 type CopierConfig struct {
@@ -61,6 +70,25 @@ type CopierConfig struct {
 	// disabled (the default) the copier behaves exactly as before. See
 	// AutoscaleConfig and issue #831.
 	Autoscale AutoscaleConfig
+	// Pause, when set, holds the copy while it is paused: no new chunk is
+	// started until it is resumed. Chunks already started complete.
+	Pause Pauser
+}
+
+// Pauser holds the copy while an operator has paused it: Wait blocks
+// while it is paused. It is implemented by status.Pause, and is passed
+// in like the throttler, so that the copier doesn't depend on where the
+// pause comes from.
+type Pauser interface {
+	Wait(ctx context.Context) error
+}
+
+// waitWhilePaused blocks while pause is paused. A nil pause never is.
+func waitWhilePaused(ctx context.Context, pause Pauser) error {
+	if pause == nil {
+		return nil
+	}
+	return pause.Wait(ctx)
 }
 
 // AutoscaleConfig controls the experimental write-thread autoscaler driven by
@@ -113,6 +141,8 @@ func NewCopier(db *sql.DB, chunker table.Chunker, config *CopierConfig) (Copier,
 			metricsSink:      config.MetricsSink,
 			dbConfig:         config.DBConfig,
 			copierEtaHistory: newcopierEtaHistory(),
+			pause:            config.Pause,
+			slotFreed:        make(chan struct{}, 1),
 		}, nil
 	}
 	if config.Applier == nil {
@@ -129,5 +159,6 @@ func NewCopier(db *sql.DB, chunker table.Chunker, config *CopierConfig) (Copier,
 		copierEtaHistory: newcopierEtaHistory(),
 		applier:          config.Applier,
 		autoscale:        config.Autoscale,
+		pause:            config.Pause,
 	}, nil
 }
@@ -60,6 +60,21 @@ func (c *copierEtaHistory) addETA(eta copierETA) {
 	}
 }
 
+// latest returns the most recent estimate, less the time since it was
+// made, and false if there is none.
+func (c *copierEtaHistory) latest() (time.Duration, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	eta := c.latestEstimate
+	if eta == nil {
+		if len(c.etaHistory) == 0 {
+			return 0, false
+		}
+		eta = &c.etaHistory[len(c.etaHistory)-1]
+	}
+	return max(eta.estimate-time.Since(eta.asOf), 0), true
+}
+
 // Return a string showing the difference between the latest ETA and the oldest ETA in the format "+30h from 1d ago"
 func (c *copierEtaHistory) getComparison() string {
 	c.mutex.Lock()
@@ -47,3 +47,27 @@ func TestCopierETAHistory(t *testing.T) {
 	require.Len(t, history.etaHistory, 24)
 	require.Equal(t, "-30m from 23h ago", comparison)
 }
+
+func TestCopierETAHistoryLatest(t *testing.T) {
+	history := newcopierEtaHistory()
+	_, ok := history.latest()
+	require.False(t, ok)
+
+	// The first estimate only goes in the history.
+	history.addETA(copierETA{estimate: time.Hour, asOf: time.Now().Add(-10 * time.Minute)})
+	eta, ok := history.latest()
+	require.True(t, ok)
+	require.InDelta(t, 50*time.Minute, eta, float64(time.Second))
+
+	// Later estimates are preferred, less the time since they were made.
+	history.addETA(copierETA{estimate: 2 * time.Hour, asOf: time.Now().Add(-time.Hour)})
+	eta, ok = history.latest()
+	require.True(t, ok)
+	require.InDelta(t, time.Hour, eta, float64(time.Second))
+
+	// An estimate that has passed is due.
+	history.addETA(copierETA{estimate: time.Minute, asOf: time.Now().Add(-time.Hour)})
+	eta, ok = history.latest()
+	require.True(t, ok)
+	require.Zero(t, eta)
+}
@@ -440,3 +440,29 @@ func TestRangeOptimizationMustApply(t *testing.T) {
 	err = copier.Run(t.Context())
 	require.NoError(t, err) // works now.
 }
+
+// TestUnbufferedSetConcurrency checks that raising the concurrency wakes
+// a dispatch loop waiting for a free slot.
+func TestUnbufferedSetConcurrency(t *testing.T) {
+	c := &Unbuffered{concurrency: 1, slotFreed: make(chan struct{}, 1)}
+	require.True(t, c.acquireSlot(t.Context()))
+
+	acquired := make(chan bool)
+	go func() { acquired <- c.acquireSlot(t.Context()) }()
+	select {
+	case <-acquired:
+		t.Fatal("acquired a slot beyond the concurrency")
+	case <-time.After(50 * time.Millisecond):
+	}
+	c.SetConcurrency(2)
+	require.True(t, <-acquired)
+
+	// Lowering it again takes effect as chunks complete.
+	c.SetConcurrency(1)
+	c.releaseSlot()
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	require.False(t, c.acquireSlot(ctx))
+	c.releaseSlot()
+	require.True(t, c.acquireSlot(t.Context()))
+}
@@ -32,6 +32,14 @@ type Unbuffered struct {
 	logger           *slog.Logger
 	metricsSink      metrics.Sink
 	copierEtaHistory *copierEtaHistory
+	pause            Pauser
+
+	// inFlight is the number of chunks being copied. It is guarded by the
+	// embedded mutex, like concurrency, which SetConcurrency can change
+	// during Run. slotFreed wakes the dispatch loop in Run when a chunk
+	// completes or the concurrency changes.
+	inFlight  int
+	slotFreed chan struct{}
 }
 
 // Assert that unbuffered implements the Copier interface
@@ -58,11 +66,12 @@ func (c *Unbuffered) CopyChunk(ctx context.Context, chunk *table.Chunk) error {
 	// here on the basis of silent-drop concerns — the checksum is the
 	// agreed safety net.
 	sourceColumns, targetColumns := chunk.ColumnMapping.Columns()
-	query := fmt.Sprintf("INSERT IGNORE INTO %s (%s) SELECT %s FROM %s FORCE INDEX (PRIMARY) WHERE %s",
+	query := fmt.Sprintf("INSERT IGNORE INTO %s (%s) SELECT %s FROM %s FORCE INDEX (%s) WHERE %s",
 		chunk.NewTable.QuotedTableName,
 		targetColumns,
 		sourceColumns,
 		chunk.Table.QuotedTableName,
+		table.QuoteColumns([]string{chunk.Table.KeyName()}),
 		chunk.String(),
 	)
 	c.logger.Debug("running chunk", "chunk", chunk.String(), "query", query)
@@ -82,7 +91,7 @@ func (c *Unbuffered) CopyChunk(ctx context.Context, chunk *table.Chunk) error {
 	c.chunker.Feedback(chunk, chunkProcessingTime, uint64(affectedRows))
 
 	// Send metrics
-	err = c.sendMetrics(ctx, chunkProcessingTime, chunk.ChunkSize, uint64(affectedRows))
+	err = c.sendMetrics(ctx, chunk, chunkProcessingTime, uint64(affectedRows))
 	if err != nil {
 		// we don't want to stop processing if metrics sending fails, log and continue
 		c.logger.Error("error sending metrics from copier", "error", err)
@@ -111,9 +120,14 @@ func (c *Unbuffered) Run(ctx context.Context) error {
 	c.Unlock()
 	go c.estimateRowsPerSecondLoop(ctx) // estimate rows while copying
 	g, errGrpCtx := errgroup.WithContext(ctx)
-	g.SetLimit(c.concurrency)
+	// Not g.SetLimit: the limit can't change while goroutines are running,
+	// and SetConcurrency needs to change it.
 	for !c.chunker.IsRead() && c.isHealthy(errGrpCtx) {
+		if waitWhilePaused(errGrpCtx, c.pause) != nil || !c.acquireSlot(errGrpCtx) {
+			break
+		}
 		g.Go(func() error {
+			defer c.releaseSlot()
 			chunk, err := c.chunker.Next()
 			if err != nil {
 				if errors.Is(err, table.ErrTableIsRead) {
@@ -138,6 +152,57 @@ func (c *Unbuffered) Run(ctx context.Context) error {
 	return nil
 }
 
+// acquireSlot blocks until fewer than concurrency chunks are in flight,
+// and claims a slot. It returns false if ctx is done first.
+func (c *Unbuffered) acquireSlot(ctx context.Context) bool {
+	for {
+		c.Lock()
+		if c.inFlight < c.concurrency {
+			c.inFlight++
+			c.Unlock()
+			return true
+		}
+		c.Unlock()
+		select {
+		case <-ctx.Done():
+			return false
+		case <-c.slotFreed:
+		}
+	}
+}
+
+func (c *Unbuffered) releaseSlot() {
+	c.Lock()
+	c.inFlight--
+	c.Unlock()
+	c.notifySlotFreed()
+}
+
+// notifySlotFreed wakes the dispatch loop without blocking. There is only
+// one waiter, so a single buffered wakeup is never lost.
+func (c *Unbuffered) notifySlotFreed() {
+	select {
+	case c.slotFreed <- struct{}{}:
+	default:
+	}
+}
+
+// SetConcurrency changes the number of chunks copied in parallel. It can
+// be called during Run: a higher value takes effect immediately, and a
+// lower one as the chunks in flight complete.
+func (c *Unbuffered) SetConcurrency(n int) {
+	c.Lock()
+	c.concurrency = max(n, 1)
+	c.Unlock()
+	c.notifySlotFreed()
+}
+
+// SetWriteThreads always fails: the unbuffered copier writes with
+// INSERT .. SELECT from its copy threads and has no write workers.
+func (c *Unbuffered) SetWriteThreads(int) error {
+	return errors.New("write threads are not used by the unbuffered copier")
+}
+
 func (c *Unbuffered) setInvalid(newVal bool) {
 	c.Lock()
 	defer c.Unlock()
@@ -196,6 +261,10 @@ func (c *Unbuffered) GetETA() string {
 	return estimate.String()
 }
 
+func (c *Unbuffered) GetLatestETA() (time.Duration, bool) {
+	return c.copierEtaHistory.latest()
+}
+
 func (c *Unbuffered) estimateRowsPerSecondLoop(ctx context.Context) {
 	// We take >10 second averages because with parallel copy it bounces around a lot.
 	// Get progress from chunker since we no longer track rows locally
@@ -220,23 +289,27 @@ func (c *Unbuffered) estimateRowsPerSecondLoop(ctx context.Context) {
 	}
 }
 
-func (c *Unbuffered) sendMetrics(ctx context.Context, processingTime time.Duration, logicalRowsCount uint64, affectedRowsCount uint64) error {
+func (c *Unbuffered) sendMetrics(ctx context.Context, chunk *table.Chunk, processingTime time.Duration, affectedRowsCount uint64) error {
+	labels := map[string]string{metrics.TableLabel: chunk.Table.TableName}
 	m := &metrics.Metrics{
 		Values: []metrics.MetricValue{
 			{
-				Name:  metrics.ChunkProcessingTimeMetricName,
-				Type:  metrics.GAUGE,
-				Value: float64(processingTime.Milliseconds()), // in milliseconds
+				Name:   metrics.ChunkProcessingTimeMetricName,
+				Type:   metrics.GAUGE,
+				Value:  float64(processingTime.Milliseconds()), // in milliseconds
+				Labels: labels,
 			},
 			{
-				Name:  metrics.ChunkLogicalRowsCountMetricName,
-				Type:  metrics.COUNTER,
-				Value: float64(logicalRowsCount),
+				Name:   metrics.ChunkLogicalRowsCountMetricName,
+				Type:   metrics.COUNTER,
+				Value:  float64(chunk.ChunkSize),
+				Labels: labels,
 			},
 			{
-				Name:  metrics.ChunkAffectedRowsCountMetricName,
-				Type:  metrics.COUNTER,
-				Value: float64(affectedRowsCount),
+				Name:   metrics.ChunkAffectedRowsCountMetricName,
+				Type:   metrics.COUNTER,
+				Value:  float64(affectedRowsCount),
+				Labels: labels,
 			},
 		},
 	}
@@ -15,6 +15,7 @@ import (
 	"github.com/block/spirit/pkg/checksum"
 	"github.com/block/spirit/pkg/copier"
 	"github.com/block/spirit/pkg/dbconn"
+	"github.com/block/spirit/pkg/hooks"
 	"github.com/block/spirit/pkg/metrics"
 	"github.com/block/spirit/pkg/statement"
 	"github.com/block/spirit/pkg/status"
@@ -129,6 +130,19 @@ type Runner struct {
 	firstCleanPassCh          chan struct{}
 	continuousCheckerInitOnce sync.Once
 	firstCleanPassInitOnce    sync.Once
+
+	// metricsDone is closed when the metrics goroutine exits.
+	metricsDone chan struct{}
+
+	metricsSink metrics.Sink
+
+	// httpServer is the optional status/control API (--http-listen).
+	// nil when disabled.
+	httpServer *status.Server
+
+	// hooks are the --hook-exec and --hook-url hooks. They are created
+	// by Run, so that they use the logger from SetLogger.
+	hooks *hooks.Hooks
 }
 
 var _ status.Task = (*Runner)(nil)
@@ -155,9 +169,13 @@ func NewRunner(s *Sync) (*Runner, error) {
 	r := &Runner{
 		sync:              s,
 		logger:            slog.Default(),
+		metricsSink:       &metrics.NoopSink{},
 		continuousReadyCh: make(chan struct{}),
 		firstCleanPassCh:  make(chan struct{}),
 	}
+	if s.MetricsSink == "prometheus" {
+		r.metricsSink = metrics.NewPrometheusSink()
+	}
 	return r, nil
 }
 
@@ -167,10 +185,31 @@ func (r *Runner) SetLogger(logger *slog.Logger) {
 	r.logger = logger
 }
 
+// SetMetricsSink overrides the sink the copier and the periodic metrics
+// loop send to. It must be called before Run.
+func (r *Runner) SetMetricsSink(sink metrics.Sink) {
+	r.metricsSink = sink
+}
+
 // Run performs the initial copy and then streams changes continuously
 // until ctx is cancelled. A clean cancellation returns nil; a fatal
-// source event (e.g. DDL) returns an error.
+// source event (e.g. DDL) returns an error. The success hook runs after a
+// clean cancellation, and the failure hook after an error.
 func (r *Runner) Run(ctx context.Context) error {
+	r.hooks = hooks.New(r.sync.hooksConfig(), r.logger)
+	err := r.run(ctx)
+	// A sync always ends with its context done, but its final hook still
+	// has to run.
+	hookCtx := context.WithoutCancel(ctx)
+	if err != nil {
+		r.runHook(hookCtx, hooks.Failure, err)
+	} else {
+		r.runHook(hookCtx, hooks.Success, nil)
+	}
+	return err
+}
+
+func (r *Runner) run(ctx context.Context) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 	r.progMu.Lock()
@@ -178,6 +217,22 @@ func (r *Runner) Run(ctx context.Context) error {
 	r.startTime = time.Now()
 	r.progMu.Unlock()
 	r.logger.Info("Starting sync", "source_dsn", redactDSN(r.sync.SourceDSN))
+	r.runHook(ctx, hooks.Startup, nil)
+
+	// Start the HTTP status/control API before connecting so operators can
+	// see the setup phases too. It is stopped in Close().
+	if r.sync.HTTPListen != "" {
+		r.httpServer = status.NewServer(r, r.sync.HTTPToken, r.logger)
+		if prom, ok := r.metricsSink.(*metrics.PrometheusSink); ok {
+			r.httpServer.Handle("GET /metrics", prom)
+		}
+		if err := r.httpServer.Start(r.sync.HTTPListen); err != nil {
+			return fmt.Errorf("could not start http server on %s: %w", r.sync.HTTPListen, err)
+		}
+	}
+	r.metricsSink = metrics.NewLabeledSink(r.metricsSink, func() map[string]string {
+		return map[string]string{metrics.PhaseLabel: r.status.Get().String()}
+	})
 
 	r.sourceDBConfig = dbconn.NewDBConfig()
 	// Sync only ever reads the source data (copy SELECTs + the change feed).
@@ -256,6 +311,7 @@ func (r *Runner) Run(ctx context.Context) error {
 		r.logger.Info("No tables to sync; nothing to do")
 		return nil
 	}
+	r.runHook(ctx, hooks.PreflightComplete, nil)
 
 	// Background routines: periodic flush keeps the target caught up; the
 	// status goroutine logs progress.
@@ -305,6 +361,7 @@ func (r *Runner) Run(ctx context.Context) error {
 			return fmt.Errorf("failed to flush after copy: %w", err)
 		}
 	}
+	r.runHook(ctx, hooks.CopyComplete, nil)
 
 	// The initial copy is done. Restore any secondary indexes deferred during
 	// table creation now, before the target is consumed (continuous sync) or
@@ -335,6 +392,32 @@ func (r *Runner) Run(ctx context.Context) error {
 	return r.runContinuous(ctx)
 }
 
+// hookEvent describes the sync to a hook at point. err is the error that
+// the sync failed with, if any.
+func (r *Runner) hookEvent(point hooks.Point, err error) hooks.Event {
+	event := hooks.Event{
+		Point:    point,
+		Command:  "sync",
+		State:    r.status.Get().String(),
+		Progress: r.Progress().Summary,
+	}
+	for _, tbl := range r.sourceTables {
+		event.Tables = append(event.Tables, tbl.TableName)
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	return event
+}
+
+// runHook runs the hooks at point. A sync has no cutover to block, so
+// the errors of its hooks are just logged.
+func (r *Runner) runHook(ctx context.Context, point hooks.Point, err error) {
+	if err := r.hooks.Run(ctx, r.hookEvent(point, err)); err != nil {
+		r.logger.Error("hook failed", "hook", point, "error", err)
+	}
+}
+
 // runCopyOnlyChecksum runs the post-copy continuous checksum without a
 // change feed. With CopyOnly there's no replication to drive, but the
 // checker still verifies source vs. target convergence (and, with a
@@ -1037,7 +1120,7 @@ func (r *Runner) buildCopyPipeline() error {
 		TargetChunkTime: r.sync.TargetChunkTime,
 		Logger:          r.logger,
 		Throttler:       &throttler.Noop{},
-		MetricsSink:     &metrics.NoopSink{},
+		MetricsSink:     r.metricsSink,
 		DBConfig:        r.sourceDBConfig,
 		Applier:         r.applier,
 		Unbuffered:      false, // sync always uses the buffered copier
@@ -1240,6 +1323,24 @@ func (r *Runner) startBackgroundRoutines(ctx context.Context) {
 	go r.watchStatus(ctx)
 	r.checkpointDone = make(chan struct{})
 	go r.dumpCheckpointLoop(ctx, r.checkpointDone)
+	r.metricsDone = make(chan struct{})
+	go r.sendMetricsLoop(ctx, r.metricsDone)
+}
+
+// sendMetricsLoop periodically samples the change feed into the metrics
+// sink until ctx is cancelled.
+func (r *Runner) sendMetricsLoop(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(status.MetricsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.SendMetrics(ctx)
+		}
+	}
 }
 
 // watchStatus logs progress periodically until ctx is cancelled.
@@ -1324,12 +1425,20 @@ func (r *Runner) Close() error {
 	if r.cancelFunc != nil {
 		r.cancelFunc()
 	}
+	if r.httpServer != nil {
+		if err := r.httpServer.Close(); err != nil {
+			r.logger.Error("failed to stop http server", "error", err)
+		}
+	}
 	if r.watchDone != nil {
 		<-r.watchDone
 	}
 	if r.checkpointDone != nil {
 		<-r.checkpointDone
 	}
+	if r.metricsDone != nil {
+		<-r.metricsDone
+	}
 	if r.replClient != nil {
 		r.replClient.StopPeriodicFlush()
 		r.replClient.Close()
@@ -1507,6 +1616,60 @@ func (r *Runner) Cancel() {
 	}
 }
 
+// ThrottlerStatus implements status.ThrottlerStatusProvider. Sync always
+// copies unthrottled today, but the copier's throttler is reported so the
+// HTTP API looks the same as for migrate and move.
+func (r *Runner) ThrottlerStatus() status.ThrottlerStatus {
+	r.progMu.RLock()
+	cp := r.copier
+	r.progMu.RUnlock()
+	if cp == nil {
+		return status.ThrottlerStatus{}
+	}
+	t := cp.GetThrottler()
+	ts := status.ThrottlerStatus{Throttled: t.IsThrottled()}
+	if gradual, ok := t.(throttler.GradualThrottler); ok {
+		ts.Gradual = true
+		ts.Utilization = gradual.Utilization()
+	}
+	return ts
+}
+
+// SendMetrics implements status.MetricsReporter. It samples the change
+// feed; the continuous checksum has no fixed chunk count to report a
+// remainder against, so checksum_chunks_remaining is not sent.
+func (r *Runner) SendMetrics(ctx context.Context) {
+	r.progMu.RLock()
+	sp, ok := r.replClient.(change.StatsProvider)
+	r.progMu.RUnlock()
+	if !ok {
+		return
+	}
+	sendCtx, cancel := context.WithTimeout(ctx, metrics.SinkTimeout)
+	defer cancel()
+	if err := r.metricsSink.Send(sendCtx, &metrics.Metrics{Values: sp.Stats().MetricValues()}); err != nil {
+		r.logger.Debug("failed to send metrics", "error", err)
+	}
+}
+
+// ChecksumStatus implements status.ChecksumStatusProvider from the
+// continuous checker's counters (see ChecksumStats for the full set).
+func (r *Runner) ChecksumStatus() status.ChecksumStatus {
+	r.progMu.RLock()
+	running := r.continuousChecker != nil
+	r.progMu.RUnlock()
+	if !running {
+		return status.ChecksumStatus{}
+	}
+	stats := r.ChecksumStats()
+	return status.ChecksumStatus{
+		Running: true,
+		Progress: fmt.Sprintf("pass=%d chunks-passed=%d/%d passes-completed=%d",
+			stats.CurrentPass, stats.ChunksPassedThisPass, stats.ChunksThisPass, stats.PassesCompleted),
+		DifferencesFound: stats.MismatchesDetected,
+	}
+}
+
 // redactDSN strips credentials from a DSN for safe logging.
 func redactDSN(dsn string) string {
 	if i := strings.LastIndex(dsn, "@"); i >= 0 {
@@ -24,6 +24,7 @@ package datasync
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
@@ -33,6 +34,7 @@ import (
 
 	"github.com/block/spirit/pkg/applier"
 	"github.com/block/spirit/pkg/change"
+	"github.com/block/spirit/pkg/hooks"
 	"github.com/block/spirit/pkg/utils"
 )
 
@@ -80,12 +82,29 @@ type Sync struct {
 	// target-empty guard.
 	Force bool `name:"force" help:"Drop and recreate the target database when the copy cannot resume from a checkpoint." default:"false"`
 
+	// HTTPListen starts the embedded status/control API (see
+	// status.Server) on this address. Control endpoints require HTTPToken.
+	// Sync has no cutover, so only cancel and checkpoint are available.
+	HTTPListen string `name:"http-listen" help:"Address (host:port) to serve the HTTP status and control API on. Disabled when empty" optional:""`
+	HTTPToken  string `name:"http-token" help:"Bearer token required by the HTTP control endpoints (cancel, checkpoint). Control endpoints are disabled when empty" optional:"" env:"SPIRIT_HTTP_TOKEN"`
+
+	// MetricsSink selects a built-in metrics.Sink. "prometheus" serves the
+	// metrics on /metrics of the HTTP API, so it requires HTTPListen.
+	MetricsSink string `name:"metrics-sink" help:"Built-in metrics sink to use: none, or prometheus to serve metrics on /metrics of the HTTP API (requires --http-listen)" enum:"none,prometheus" default:"none"`
+
 	// GTID switches the built-in change source from binlog file+position to
 	// MySQL GTIDs. EXPERIMENTAL — see pkg/change/gtid.go. Ignored when a
 	// pre-constructed Source is injected. Requires gtid_mode=ON and
 	// enforce_gtid_consistency=ON on the source.
 	GTID bool `name:"gtid" help:"EXPERIMENTAL: use GTID-based change source instead of binlog file+position" default:"false"`
 
+	// HookExec and HookURL run a command, or POST a JSON payload to a URL,
+	// at points in the sync's lifecycle (see pkg/hooks). Sync has no
+	// checksum-complete, before-cutover or after-cutover points.
+	HookExec    map[string]string `name:"hook-exec" mapsep:"none" help:"Command to run at a hook point, as point=command. Points: startup, preflight-complete, copy-complete, failure, success" optional:""`
+	HookURL     map[string]string `name:"hook-url" mapsep:"none" help:"URL to POST a JSON payload to at a hook point, as point=url" optional:""`
+	HookTimeout time.Duration     `name:"hook-timeout" help:"How long a hook may run before it fails" optional:"" default:"1m"`
+
 	// Source optionally provides a pre-constructed change.Source to use
 	// for replication instead of constructing a built-in MySQL-binlog
 	// client from SourceDSN. When set, the runner uses this as the change
@@ -128,7 +147,14 @@ func (s *Sync) Validate() error {
 	if s.FlushInterval < 0 {
 		return fmt.Errorf("--flush-interval must be non-negative, got %s", s.FlushInterval)
 	}
-	return nil
+	if s.MetricsSink == "prometheus" && s.HTTPListen == "" {
+		return errors.New("--metrics-sink=prometheus requires --http-listen")
+	}
+	return s.hooksConfig().Validate()
+}
+
+func (s *Sync) hooksConfig() hooks.Config {
+	return hooks.Config{Exec: s.HookExec, URL: s.HookURL, Timeout: s.HookTimeout}
 }
 
 // Run is the kong CLI entry point. It runs the sync until the process
@@ -840,6 +840,8 @@ func TestSyncValidate(t *testing.T) {
 			wantErr: "--target-chunk-time must be non-negative, got -1s"},
 		{name: "negative flush-interval", s: Sync{FlushInterval: -time.Minute},
 			wantErr: "--flush-interval must be non-negative, got -1m0s"},
+		{name: "negative hook-timeout", s: Sync{HookTimeout: -time.Second},
+			wantErr: "--hook-timeout must be non-negative, got -1s"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
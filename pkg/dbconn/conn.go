@@ -290,6 +290,9 @@ func newDSN(dsn string, config *DBConfig) (string, error) {
 	cfg.Params["lock_wait_timeout"] = strconv.Itoa(config.LockWaitTimeout)
 	cfg.Params["range_optimizer_max_mem_size"] = strconv.FormatInt(config.RangeOptimizerMaxMemSize, 10)
 	cfg.Params["transaction_isolation"] = `"read-committed"`
+	if config.DisableForeignKeyChecks {
+		cfg.Params["foreign_key_checks"] = "0"
+	}
 	// go driver charset option, sets:
 	// character_set_client, character_set_connection, character_set_results
 	cfg.Params["charset"] = "utf8mb4"
@@ -32,6 +32,7 @@ func assertDSNConfig(t *testing.T, dsnStr string, user, password, addr, dbName,
 	require.Equal(t, `"NO_AUTO_VALUE_ON_ZERO"`, cfg.Params["sql_mode"])
 	require.Equal(t, `"+00:00"`, cfg.Params["time_zone"])
 	require.Equal(t, `"read-committed"`, cfg.Params["transaction_isolation"])
+	require.NotContains(t, cfg.Params, "foreign_key_checks")
 }
 
 func TestNewDSN(t *testing.T) {
@@ -48,6 +49,15 @@ func TestNewDSN(t *testing.T) {
 	require.NoError(t, err)
 	assertDSNConfig(t, resp, "root", "password", "127.0.0.1:3306", "test", "custom", true)
 
+	// With foreign key checks disabled.
+	config = NewDBConfig()
+	config.DisableForeignKeyChecks = true
+	resp, err = newDSN(dsn, config)
+	require.NoError(t, err)
+	cfg, err := mysql.ParseDSN(resp)
+	require.NoError(t, err)
+	require.Equal(t, "0", cfg.Params["foreign_key_checks"])
+
 	// Also with TLS for non-RDS hosts (now includes tls=custom)
 	dsn = "root:password@tcp(mydbhost.internal:3306)/test"
 	resp, err = newDSN(dsn, NewDBConfig())
@@ -63,6 +63,12 @@ type DBConfig struct {
 	// the replica's read-only responses would loop every source statement to
 	// "driver: bad connection", so the move runner disables it for that case.
 	RejectReadOnly bool
+	// DisableForeignKeyChecks sets foreign_key_checks=0 on every connection.
+	// A migration of a table with foreign keys copies rows into, and applies
+	// changes to, a new table that has its own constraints, in whatever order
+	// they arrive. Referential integrity is verified before the cutover
+	// instead (default: false).
+	DisableForeignKeyChecks bool
 	// TLS Configuration
 	TLSMode            string // TLS connection mode (DISABLED, PREFERRED, REQUIRED, VERIFY_CA, VERIFY_IDENTITY)
 	TLSCertificatePath string // Path to custom TLS certificate file
@@ -152,6 +158,15 @@ func canRetryError(err error) bool {
 // RetryableTransaction retries all statements in a transaction, retrying if a statement
 // errors, or there is a deadlock. It will retry up to maxRetries times.
 func RetryableTransaction(ctx context.Context, db *sql.DB, ignoreDupKeyWarnings bool, config *DBConfig, stmts ...string) (int64, error) {
+	return RetryableTransactionBeforeCommit(ctx, db, ignoreDupKeyWarnings, config, nil, stmts...)
+}
+
+// RetryableTransactionBeforeCommit is RetryableTransaction, but it calls
+// beforeCommit with the transaction and the rows that stmts affected just
+// before each attempt commits, so that it can write in the same
+// transaction, e.g. a checkpoint of the statements' progress. The rows
+// that beforeCommit affects are not counted.
+func RetryableTransactionBeforeCommit(ctx context.Context, db *sql.DB, ignoreDupKeyWarnings bool, config *DBConfig, beforeCommit func(trx *sql.Tx, rowsAffected int64) error, stmts ...string) (int64, error) {
 	var (
 		err          error
 		trx          *sql.Tx
@@ -160,6 +175,8 @@ func RetryableTransaction(ctx context.Context, db *sql.DB, ignoreDupKeyWarnings
 	)
 	for i := range config.MaxRetries {
 		func() {
+			// The rows of an attempt that was rolled back don't count.
+			rowsAffected = 0
 			// Start a transaction
 			if trx, err = db.BeginTx(ctx, nil); err != nil {
 				return
@@ -238,6 +255,14 @@ func RetryableTransaction(ctx context.Context, db *sql.DB, ignoreDupKeyWarnings
 					rowsAffected += count
 				}
 			} // end for each statement
+			if beforeCommit != nil {
+				if err = beforeCommit(trx, rowsAffected); err != nil {
+					if !canRetryError(err) {
+						isFatal = true
+					}
+					return
+				}
+			}
 			// Commit it!
 			if err = trx.Commit(); err != nil {
 				return
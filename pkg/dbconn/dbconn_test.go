@@ -246,6 +246,36 @@ func testRetryableTrxSurvivesKill(t *testing.T, tableName, killStmtFmt string) {
 // TestRetryableTrxRetriesKilledQuery covers ER_QUERY_INTERRUPTED (1317):
 // KILL QUERY aborts the statement but leaves the connection intact. This is
 // what spirit's own force-kill machinery and DBA-issued KILL QUERY produce.
+func TestRetryableTrxBeforeCommit(t *testing.T) {
+	db, err := New(testutils.DSN(), NewDBConfig())
+	require.NoError(t, err)
+	defer utils.CloseAndLog(db)
+	require.NoError(t, Exec(t.Context(), db, "DROP TABLE IF EXISTS test.dbexecbc, test.dbexecbc_log"))
+	require.NoError(t, Exec(t.Context(), db, "CREATE TABLE test.dbexecbc (id INT NOT NULL PRIMARY KEY)"))
+	require.NoError(t, Exec(t.Context(), db, "CREATE TABLE test.dbexecbc_log (id INT NOT NULL PRIMARY KEY, n INT NOT NULL)"))
+
+	// The write of beforeCommit commits with the statements, and its rows
+	// are not counted.
+	n, err := RetryableTransactionBeforeCommit(t.Context(), db, false, NewDBConfig(), func(trx *sql.Tx, rowsAffected int64) error {
+		_, err := trx.ExecContext(t.Context(), "INSERT INTO test.dbexecbc_log (id, n) VALUES (1, ?)", rowsAffected)
+		return err
+	}, "INSERT INTO test.dbexecbc (id) VALUES (1), (2)")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), n)
+	var logged int64
+	require.NoError(t, db.QueryRowContext(t.Context(), "SELECT n FROM test.dbexecbc_log WHERE id = 1").Scan(&logged))
+	require.Equal(t, int64(2), logged)
+
+	// An error from beforeCommit rolls the statements back.
+	_, err = RetryableTransactionBeforeCommit(t.Context(), db, false, NewDBConfig(), func(*sql.Tx, int64) error {
+		return errors.New("checkpoint failed")
+	}, "INSERT INTO test.dbexecbc (id) VALUES (3)")
+	require.ErrorContains(t, err, "checkpoint failed")
+	var count int
+	require.NoError(t, db.QueryRowContext(t.Context(), "SELECT COUNT(*) FROM test.dbexecbc").Scan(&count))
+	require.Equal(t, 2, count)
+}
+
 func TestRetryableTrxRetriesKilledQuery(t *testing.T) {
 	testRetryableTrxSurvivesKill(t, "retry_kill_query", "KILL QUERY %d")
 }
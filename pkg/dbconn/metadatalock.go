@@ -223,6 +223,19 @@ func (m *MetadataLock) CloseDBConnection(logger *slog.Logger) error {
 	return nil
 }
 
+// MetadataLockHolder returns the connection ID of the session that holds
+// the metadata lock of tbl, or 0 if no session holds it. It lets a process
+// other than the one that took the lock, such as `spirit status`, see
+// whether an operation on tbl is running.
+func MetadataLockHolder(ctx context.Context, db *sql.DB, tbl *table.TableInfo) (int64, error) {
+	var holder sql.NullInt64
+	stmt := sqlescape.MustEscapeSQL("SELECT IS_USED_LOCK(%?)", computeLockName(tbl))
+	if err := db.QueryRowContext(ctx, stmt).Scan(&holder); err != nil {
+		return 0, fmt.Errorf("could not check the metadata lock of %s: %w", tbl.TableName, err)
+	}
+	return holder.Int64, nil
+}
+
 func computeLockName(table *table.TableInfo) string {
 	schemaNamePart := table.SchemaName
 	if len(schemaNamePart) > 20 {
@@ -385,3 +385,25 @@ func TestMetadataLockRefreshWithConnIssueSimulation(t *testing.T) {
 	// close the lock
 	require.NoError(t, mdl.Close())
 }
+
+func TestMetadataLockHolder(t *testing.T) {
+	lockTableInfo := table.TableInfo{SchemaName: "test", TableName: "test-holder"}
+	observer, err := New(testutils.DSN(), NewDBConfig())
+	require.NoError(t, err)
+	defer utils.CloseAndLog(observer)
+
+	holder, err := MetadataLockHolder(t.Context(), observer, &lockTableInfo)
+	require.NoError(t, err)
+	require.Zero(t, holder)
+
+	mdl, err := NewMetadataLock(t.Context(), testutils.DSN(), []*table.TableInfo{&lockTableInfo}, NewDBConfig(), slog.Default())
+	require.NoError(t, err)
+	holder, err = MetadataLockHolder(t.Context(), observer, &lockTableInfo)
+	require.NoError(t, err)
+	require.NotZero(t, holder)
+
+	require.NoError(t, mdl.Close())
+	holder, err = MetadataLockHolder(t.Context(), observer, &lockTableInfo)
+	require.NoError(t, err)
+	require.Zero(t, holder)
+}
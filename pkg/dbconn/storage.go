@@ -0,0 +1,77 @@
+package dbconn
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"time"
+)
+
+// BinlogRetention returns how long the server keeps binary logs before it
+// purges them, or 0 if it never purges them automatically. On RDS, the
+// "binlog retention hours" setting takes precedence when it is set.
+func BinlogRetention(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	var rdsHours sql.NullInt64
+	// This table only exists on RDS, so any error just means it's not RDS.
+	if err := db.QueryRowContext(ctx, "SELECT value FROM mysql.rds_configuration WHERE name='binlog retention hours'").Scan(&rdsHours); err == nil && rdsHours.Valid {
+		return time.Duration(rdsHours.Int64) * time.Hour, nil
+	}
+	var seconds int64
+	if err := db.QueryRowContext(ctx, "SELECT @@global.binlog_expire_logs_seconds").Scan(&seconds); err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// TableDiskSize returns the bytes that a table uses on disk. It prefers the
+// allocated size of the table's file-per-table tablespace, which is always
+// current, to the data and index lengths of information_schema.tables,
+// which may be cached for up to information_schema_stats_expiry. It returns
+// 0 if the table doesn't exist.
+func TableDiskSize(ctx context.Context, db *sql.DB, schema, tableName string) (uint64, error) {
+	var size uint64
+	err := db.QueryRowContext(ctx, "SELECT allocated_size FROM information_schema.innodb_tablespaces WHERE name=CONCAT(?, '/', ?)",
+		schema, tableName).Scan(&size)
+	if err == nil {
+		return size, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	err = db.QueryRowContext(ctx, "SELECT IFNULL(data_length,0)+IFNULL(index_length,0) FROM information_schema.tables WHERE table_schema=? AND table_name=?",
+		schema, tableName).Scan(&size)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return size, err
+}
+
+// FreeDiskSpace returns the free bytes on the filesystem of the server's
+// data directory. Aurora reports the space left on its cluster volume as a
+// status variable, which is used when present. MySQL itself doesn't report
+// free space over SQL, so otherwise it can only be measured when Spirit runs
+// on the same host as the server: ok is false when it can't be measured,
+// e.g. on RDS.
+func FreeDiskSpace(ctx context.Context, db *sql.DB) (free uint64, ok bool, err error) {
+	var name string
+	err = db.QueryRowContext(ctx, "SHOW GLOBAL STATUS LIKE 'AuroraVolumeBytesLeftTotal'").Scan(&name, &free)
+	if err == nil {
+		return free, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, false, err
+	}
+	var hostname, datadir string
+	if err := db.QueryRowContext(ctx, "SELECT @@hostname, @@datadir").Scan(&hostname, &datadir); err != nil {
+		return 0, false, err
+	}
+	if local, err := os.Hostname(); err != nil || local != hostname {
+		return 0, false, nil
+	}
+	// The same hostname isn't proof enough, e.g. in containers.
+	if _, err := os.Stat(datadir); err != nil {
+		return 0, false, nil
+	}
+	return statfsFree(datadir)
+}
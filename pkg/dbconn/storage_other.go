@@ -0,0 +1,7 @@
+//go:build !linux && !darwin
+
+package dbconn
+
+func statfsFree(string) (uint64, bool, error) {
+	return 0, false, nil
+}
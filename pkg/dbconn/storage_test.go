@@ -0,0 +1,55 @@
+package dbconn
+
+import (
+	"testing"
+
+	"github.com/block/spirit/pkg/testutils"
+	"github.com/block/spirit/pkg/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableDiskSize(t *testing.T) {
+	tt := testutils.NewTestTable(t, "disksizet1", `CREATE TABLE disksizet1 (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		b varchar(255) NOT NULL
+	)`)
+	tt.SeedRows(t, "INSERT INTO disksizet1 (b) SELECT REPEAT('a', 255) FROM dual", 1000)
+
+	db, err := New(testutils.DSN(), NewDBConfig())
+	require.NoError(t, err)
+	defer utils.CloseAndLog(db)
+
+	size, err := TableDiskSize(t.Context(), db, "test", "disksizet1")
+	require.NoError(t, err)
+	require.Positive(t, size)
+
+	size, err = TableDiskSize(t.Context(), db, "test", "disksizet1_doesnotexist")
+	require.NoError(t, err)
+	require.Zero(t, size)
+}
+
+func TestBinlogRetention(t *testing.T) {
+	db, err := New(testutils.DSN(), NewDBConfig())
+	require.NoError(t, err)
+	defer utils.CloseAndLog(db)
+
+	var seconds int64
+	require.NoError(t, db.QueryRowContext(t.Context(), "SELECT @@global.binlog_expire_logs_seconds").Scan(&seconds))
+	retention, err := BinlogRetention(t.Context(), db)
+	require.NoError(t, err)
+	require.Equal(t, seconds, int64(retention.Seconds()))
+}
+
+func TestFreeDiskSpace(t *testing.T) {
+	db, err := New(testutils.DSN(), NewDBConfig())
+	require.NoError(t, err)
+	defer utils.CloseAndLog(db)
+
+	// The server usually runs on another host (or container), in which case
+	// free space is unknown. Either way it must not be an error.
+	free, ok, err := FreeDiskSpace(t.Context(), db)
+	require.NoError(t, err)
+	if ok {
+		require.Positive(t, free)
+	}
+}
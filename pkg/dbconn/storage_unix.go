@@ -0,0 +1,14 @@
+//go:build linux || darwin
+
+package dbconn
+
+import "syscall"
+
+func statfsFree(path string) (uint64, bool, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, false, err
+	}
+	// Bsize is an int64 on linux and a uint32 on darwin.
+	return uint64(st.Bavail) * uint64(st.Bsize), true, nil
+}
@@ -0,0 +1,352 @@
+package dml
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/block/spirit/pkg/applier"
+	"github.com/block/spirit/pkg/dbconn"
+	"github.com/block/spirit/pkg/migration"
+	"github.com/block/spirit/pkg/table"
+	"github.com/block/spirit/pkg/utils"
+	"github.com/go-sql-driver/mysql"
+)
+
+// ArchiveCmd is the kong CLI entry point of `spirit archive`. It copies the
+// rows of a table that match a condition to an archive table, which may be
+// on another server, and deletes them from the table, one chunk at a time.
+// With --purge, the rows are only deleted.
+type ArchiveCmd struct {
+	Host               string        `name:"host" help:"Hostname" optional:""`
+	Username           string        `name:"username" help:"User" optional:""`
+	Password           *string       `name:"password" help:"Password" optional:""`
+	Database           string        `name:"database" help:"Database" optional:""`
+	ConfFile           string        `name:"conf" help:"MySQL conf file" optional:"" type:"existingfile"`
+	Table              string        `name:"table" help:"The table to archive rows from" required:""`
+	Where              string        `name:"where" help:"The condition of the rows to archive, e.g. created_at < NOW() - INTERVAL 90 DAY" required:""`
+	Key                string        `name:"key" help:"The index to walk the table by, e.g. an index that starts with the columns of --where. Defaults to the key that identifies the rows of the table" optional:""`
+	ArchiveTable       string        `name:"archive-table" help:"The table to copy the rows to before they are deleted. Required unless --purge is set" optional:""`
+	TargetDSN          string        `name:"target-dsn" help:"DSN of the server and database of --archive-table. Defaults to --database on the same server" optional:""`
+	Purge              bool          `name:"purge" help:"Delete the rows without archiving them" optional:"" default:"false"`
+	DryRun             bool          `name:"dry-run" help:"Count the rows that would be archived, without archiving or deleting them" optional:"" default:"false"`
+	TargetChunkTime    time.Duration `name:"target-chunk-time" help:"The target time for each chunk" optional:"" default:"500ms"`
+	ReplicaDSN         string        `name:"replica-dsn" help:"DSN(s) for replica(s) used for lag checking. Multiple replicas can be comma-separated; Spirit throttles on the slowest." optional:""`
+	ReplicaMaxLag      time.Duration `name:"replica-max-lag" help:"The maximum lag allowed on the replica before the archive throttles." optional:"" default:"120s"`
+	MaxCommitLatency   time.Duration `name:"max-commit-latency" help:"Throttle when average commit latency exceeds this threshold (currently only auto-enabled on Aurora)" optional:"" default:"100ms"`
+	TLSMode            string        `name:"tls-mode" help:"TLS connection mode (case insensitive): DISABLED, PREFERRED (default), REQUIRED, VERIFY_CA, VERIFY_IDENTITY" optional:""`
+	TLSCertificatePath string        `name:"tls-ca" help:"Path to custom TLS CA certificate file" optional:""`
+}
+
+// Validate is called by Kong after parsing to check for invalid flag combinations.
+func (a *ArchiveCmd) Validate() error {
+	if a.Purge && a.ArchiveTable != "" {
+		return errors.New("--purge and --archive-table cannot be used together")
+	}
+	if !a.Purge && a.ArchiveTable == "" {
+		return errors.New("one of --archive-table or --purge is required")
+	}
+	if a.TargetDSN != "" && a.ArchiveTable == "" {
+		return errors.New("--target-dsn requires --archive-table")
+	}
+	return nil
+}
+
+// Run archives the rows of a.Table that match a.Where. The table is walked
+// with the composite chunker on a.Key, which defaults to the PRIMARY KEY
+// (or for a table without one, the unique key used in its place), and the
+// rows of each chunk are read with FOR UPDATE, written to the archive
+// table, and only deleted once the write is confirmed, in the same
+// transaction as the read. The rows of a
+// chunk are therefore either archived and deleted, or (if it fails) still
+// in the table. Its progress is checkpointed, so that running it again
+// after it was interrupted resumes it (see chunkedJob). A chunk that is
+// archived again on resume is not duplicated, as long as the archive table
+// has the table's primary key.
+func (a *ArchiveCmd) Run() error {
+	ctx := context.TODO()
+	logger := slog.Default()
+	if err := a.Validate(); err != nil {
+		return err
+	}
+	m := &migration.Migration{
+		Host:               a.Host,
+		Username:           a.Username,
+		Password:           a.Password,
+		Database:           a.Database,
+		ConfFile:           a.ConfFile,
+		ReplicaDSN:         a.ReplicaDSN,
+		ReplicaMaxLag:      a.ReplicaMaxLag,
+		TLSMode:            a.TLSMode,
+		TLSCertificatePath: a.TLSCertificatePath,
+	}
+	dbConfig := dbconn.NewDBConfig()
+	db, dsn, err := m.Connect(dbConfig)
+	if err != nil {
+		return err
+	}
+	defer utils.CloseAndLog(db)
+
+	tbl := table.NewTableInfo(db, m.Database, a.Table)
+	tbl.DisableAnalyze = true
+	if err := tbl.SetInfo(ctx); err != nil {
+		return err
+	}
+	key := cmp.Or(a.Key, tbl.KeyName())
+	var archiveTbl *table.TableInfo
+	if a.ArchiveTable != "" {
+		targetDB, schema := db, m.Database
+		if a.TargetDSN != "" {
+			targetCfg, err := mysql.ParseDSN(a.TargetDSN)
+			if err != nil {
+				return fmt.Errorf("--target-dsn: %w", err)
+			}
+			if targetCfg.DBName == "" {
+				return errors.New("--target-dsn must include the database of the archive table")
+			}
+			if targetDB, err = dbconn.New(a.TargetDSN, dbConfig); err != nil {
+				return fmt.Errorf("failed to connect to target database: %w", err)
+			}
+			defer utils.CloseAndLog(targetDB)
+			schema = targetCfg.DBName
+		}
+		archiveTbl = table.NewTableInfo(targetDB, schema, a.ArchiveTable)
+		archiveTbl.DisableAnalyze = true
+		if err := archiveTbl.SetInfo(ctx); err != nil {
+			return fmt.Errorf("archive table %s: %w", a.ArchiveTable, err)
+		}
+	}
+	chunker, err := table.NewChunker(tbl, table.ChunkerConfig{
+		NewTable:        archiveTbl,
+		TargetChunkTime: a.TargetChunkTime,
+		Logger:          logger,
+		Key:             key,
+		Where:           a.Where,
+	})
+	if err != nil {
+		return err
+	}
+	if archiveTbl != nil {
+		// Columns that the archive table doesn't have would be lost.
+		columns, _ := chunker.ColumnMapping().ColumnsSlice()
+		for _, column := range tbl.NonGeneratedColumns {
+			if !slices.Contains(columns, column) {
+				return fmt.Errorf("archive table %s has no column %s", a.ArchiveTable, column)
+			}
+		}
+	}
+
+	if a.DryRun {
+		return countArchiveRows(ctx, db, tbl, chunker, a.Where, logger)
+	}
+	thr, closeThrottler, err := m.NewStandaloneThrottler(ctx, db, dsn, dbConfig, a.MaxCommitLatency, logger)
+	if err != nil {
+		return err
+	}
+	defer closeThrottler()
+	job := &chunkedJob{
+		db:              db,
+		tbl:             tbl,
+		chunker:         chunker,
+		thr:             thr,
+		checkpointTable: utils.ArchiveCheckpointTableName(tbl.TableName),
+		job:             a.job(archiveTbl),
+		logger:          logger,
+	}
+	if archiveTbl == nil {
+		job.runChunk = func(ctx context.Context, chunk *table.Chunk, checkpoint func(trx *sql.Tx, rowsAffected int64) error) (int64, error) {
+			return dbconn.RetryableTransactionBeforeCommit(ctx, db, false, dbConfig, checkpoint,
+				fmt.Sprintf("DELETE FROM %s WHERE %s", tbl.QuotedTableName, chunk.String()))
+		}
+	} else {
+		appl, err := applier.NewSingleTargetApplier(applier.Target{DB: archiveTbl.DB()}, &applier.ApplierConfig{
+			Logger:   logger,
+			DBConfig: dbConfig,
+		})
+		if err != nil {
+			return err
+		}
+		if err := appl.Start(ctx); err != nil {
+			return err
+		}
+		defer func() { _ = appl.Stop() }()
+		job.runChunk = func(ctx context.Context, chunk *table.Chunk, checkpoint func(trx *sql.Tx, rowsAffected int64) error) (int64, error) {
+			return archiveChunk(ctx, db, appl, chunk, key, checkpoint)
+		}
+	}
+	rows, err := job.run(ctx)
+	if err != nil {
+		return err
+	}
+	if archiveTbl == nil {
+		logger.Info("successfully purged rows", "table", tbl.TableName, "rows", rows)
+	} else {
+		logger.Info("successfully archived rows", "table", tbl.TableName, "archive-table", archiveTbl.TableName, "rows", rows)
+	}
+	return nil
+}
+
+// job describes the archive for its checkpoint.
+func (a *ArchiveCmd) job(archiveTbl *table.TableInfo) string {
+	if archiveTbl == nil {
+		return fmt.Sprintf("purge %s where %s", a.Table, a.Where)
+	}
+	return fmt.Sprintf("archive %s to %s.%s where %s", a.Table, archiveTbl.SchemaName, archiveTbl.TableName, a.Where)
+}
+
+// archiveChunk copies the rows of chunk, which is of the index key, to the
+// archive table with appl, and deletes them from the table once every row
+// is confirmed to be written. The rows are locked by the read, so the
+// delete removes exactly the rows that were copied. checkpoint is called in
+// the same transaction as the delete. It returns the number of rows
+// archived.
+func archiveChunk(ctx context.Context, db *sql.DB, appl applier.Applier, chunk *table.Chunk, key string, checkpoint func(trx *sql.Tx, rowsAffected int64) error) (int64, error) {
+	trx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = trx.Rollback() }()
+	columnList, _ := chunk.ColumnMapping.Columns()
+	rows, err := queryRows(ctx, trx, fmt.Sprintf("SELECT %s FROM %s FORCE INDEX (%s) WHERE %s FOR UPDATE",
+		columnList, chunk.Table.QuotedTableName, table.QuoteColumns([]string{key}), chunk.String()))
+	if err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 {
+		if err := checkpoint(trx, 0); err != nil {
+			return 0, err
+		}
+		return 0, trx.Commit()
+	}
+	type result struct {
+		affected int64
+		err      error
+	}
+	written := make(chan result, 1)
+	if err := appl.Apply(ctx, chunk, rows, func(affected int64, err error) { written <- result{affected, err} }); err != nil {
+		return 0, err
+	}
+	select {
+	case res := <-written:
+		if res.err != nil {
+			return 0, fmt.Errorf("could not write rows to the archive table: %w", res.err)
+		}
+		// The applier writes with INSERT IGNORE, which skips a row whose
+		// key is already in the archive table. That is only safe to delete
+		// if the archive already holds the same row, as it does when a run
+		// stopped between the write and the delete.
+		if res.affected != int64(len(rows)) {
+			archived, err := countArchivedRows(ctx, chunk, rows)
+			if err != nil {
+				return 0, err
+			}
+			if archived != int64(len(rows)) {
+				return 0, fmt.Errorf("chunk %s: read %d rows to archive, but the archive table holds only %d of them; it has other rows with the same keys", chunk.String(), len(rows), archived)
+			}
+		}
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	res, err := trx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s", chunk.Table.QuotedTableName, chunk.String()))
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if deleted != int64(len(rows)) {
+		return 0, fmt.Errorf("chunk %s: read %d rows to archive, but the delete matched %d", chunk.String(), len(rows), deleted)
+	}
+	if err := checkpoint(trx, deleted); err != nil {
+		return 0, err
+	}
+	return deleted, trx.Commit()
+}
+
+// countArchivedRows returns how many of rows, which were read for chunk,
+// are in the archive table with the same value in every column.
+func countArchivedRows(ctx context.Context, chunk *table.Chunk, rows [][]any) (int64, error) {
+	_, columns := chunk.ColumnMapping.ColumnsSlice()
+	conds := make([]string, 0, len(rows))
+	for _, row := range rows {
+		eqs := make([]string, 0, len(row))
+		for i, value := range row {
+			columnType, ok := chunk.ColumnMapping.ValueType(i)
+			if !ok {
+				return 0, fmt.Errorf("column %s not found in source table info", columns[i])
+			}
+			datum, err := table.NewDatumFromValue(value, columnType)
+			if err != nil {
+				return 0, err
+			}
+			eqs = append(eqs, fmt.Sprintf("%s <=> %s", table.QuoteColumns([]string{columns[i]}), datum.String()))
+		}
+		conds = append(conds, "("+strings.Join(eqs, " AND ")+")")
+	}
+	archiveTbl := chunk.ColumnMapping.TargetTable()
+	var n int64
+	err := archiveTbl.DB().QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s",
+		archiveTbl.QuotedTableName, strings.Join(conds, " OR "))).Scan(&n)
+	return n, err
+}
+
+// queryRows returns the rows of query, as the buffered copier reads them.
+func queryRows(ctx context.Context, trx *sql.Tx, query string) ([][]any, error) {
+	rows, err := trx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer utils.CloseAndLog(rows)
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var values [][]any
+	for rows.Next() {
+		row := make([]any, len(columns))
+		ptrs := make([]any, len(columns))
+		for i := range row {
+			ptrs[i] = &row[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		values = append(values, row)
+	}
+	return values, rows.Err()
+}
+
+// countArchiveRows counts the rows that would be archived, one chunk at a
+// time, for --dry-run.
+func countArchiveRows(ctx context.Context, db *sql.DB, tbl *table.TableInfo, chunker table.Chunker, where string, logger *slog.Logger) error {
+	if err := chunker.Open(); err != nil {
+		return err
+	}
+	defer utils.CloseAndLog(chunker)
+	var count int64
+	for !chunker.IsRead() {
+		chunk, err := chunker.Next()
+		if err != nil {
+			if errors.Is(err, table.ErrTableIsRead) {
+				break
+			}
+			return err
+		}
+		startTime := time.Now()
+		var n int64
+		if err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", tbl.QuotedTableName, chunk.String())).Scan(&n); err != nil {
+			return err
+		}
+		chunker.Feedback(chunk, time.Since(startTime), chunk.ChunkSize)
+		count += n
+	}
+	logger.Info("dry run: rows that would be archived", "table", tbl.TableName, "where", where, "rows", count)
+	return nil
+}
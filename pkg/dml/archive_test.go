@@ -0,0 +1,171 @@
+package dml
+
+import (
+	"testing"
+	"time"
+
+	"github.com/block/spirit/pkg/testutils"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveValidate(t *testing.T) {
+	// The options are checked before connecting.
+	for _, a := range []*ArchiveCmd{
+		{Host: "127.0.0.1:1", Table: "t1", Where: "a = 1"},
+		{Host: "127.0.0.1:1", Table: "t1", Where: "a = 1", Purge: true, ArchiveTable: "t1_archive"},
+		{Host: "127.0.0.1:1", Table: "t1", Where: "a = 1", Purge: true, TargetDSN: "root@tcp(127.0.0.1:1)/test"},
+	} {
+		require.Error(t, a.Run())
+	}
+	require.NoError(t, (&ArchiveCmd{Table: "t1", Where: "a = 1", Purge: true}).Validate())
+	require.NoError(t, (&ArchiveCmd{Table: "t1", Where: "a = 1", ArchiveTable: "t1_archive", TargetDSN: "root@tcp(127.0.0.1:1)/test"}).Validate())
+}
+
+func newTestArchive(t *testing.T, tableName, where string) *ArchiveCmd {
+	t.Helper()
+	cfg, err := mysql.ParseDSN(testutils.DSN())
+	require.NoError(t, err)
+	return &ArchiveCmd{
+		Host:            cfg.Addr,
+		Username:        cfg.User,
+		Password:        &cfg.Passwd,
+		Database:        cfg.DBName,
+		Table:           tableName,
+		Where:           where,
+		TargetChunkTime: time.Millisecond,
+	}
+}
+
+func TestArchiveRows(t *testing.T) {
+	t.Parallel()
+	tt := testutils.NewTestTable(t, "archt1", `CREATE TABLE archt1 (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		a int NOT NULL,
+		b varchar(10),
+		KEY a (a)
+	)`)
+	testutils.NewTestTable(t, "archt1_archive", `CREATE TABLE archt1_archive LIKE archt1`)
+	testutils.RunSQL(t, `INSERT INTO archt1 (a, b) WITH RECURSIVE seq (n) AS
+		(SELECT 1 UNION ALL SELECT n + 1 FROM seq WHERE n < 5000) SELECT n, 'x' FROM seq`)
+
+	// A dry run changes nothing.
+	a := newTestArchive(t, "archt1", "a <= 3000")
+	a.ArchiveTable = "archt1_archive"
+	a.DryRun = true
+	require.NoError(t, a.Run())
+	var count int
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), `SELECT COUNT(*) FROM archt1`).Scan(&count))
+	require.Equal(t, 5000, count)
+
+	a.DryRun = false
+	a.Key = "a"
+	require.NoError(t, a.Run())
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), `SELECT COUNT(*) FROM archt1`).Scan(&count))
+	require.Equal(t, 2000, count)
+	var archived, minA, maxA int
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), `SELECT COUNT(*), MIN(a), MAX(a) FROM archt1_archive WHERE b = 'x'`).Scan(&archived, &minA, &maxA))
+	require.Equal(t, 3000, archived)
+	require.Equal(t, 1, minA)
+	require.Equal(t, 3000, maxA)
+
+	// The checkpoint table is dropped when the archive completes.
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), `SELECT COUNT(*) FROM information_schema.tables
+		WHERE table_schema=DATABASE() AND table_name='_archt1_archive_chkpnt'`).Scan(&count))
+	require.Zero(t, count)
+
+	// Purge the rest.
+	p := newTestArchive(t, "archt1", "a > 4000")
+	p.Purge = true
+	require.NoError(t, p.Run())
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), `SELECT COUNT(*) FROM archt1`).Scan(&count))
+	require.Equal(t, 1000, count)
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), `SELECT COUNT(*) FROM archt1_archive`).Scan(&archived))
+	require.Equal(t, 3000, archived)
+}
+
+func TestArchiveTableWithoutPrimaryKey(t *testing.T) {
+	t.Parallel()
+	tt := testutils.NewTestTable(t, "archt4", `CREATE TABLE archt4 (
+		code varchar(10) NOT NULL,
+		a int NOT NULL,
+		UNIQUE KEY code (code)
+	)`)
+	testutils.NewTestTable(t, "archt4_archive", `CREATE TABLE archt4_archive LIKE archt4`)
+	testutils.RunSQL(t, `INSERT INTO archt4 (code, a) WITH RECURSIVE seq (n) AS
+		(SELECT 1 UNION ALL SELECT n + 1 FROM seq WHERE n < 1000) SELECT CONCAT('c', n), n FROM seq`)
+
+	// Without --key, the table is walked by the unique key that identifies
+	// its rows in place of a PRIMARY KEY.
+	a := newTestArchive(t, "archt4", "a <= 400")
+	a.ArchiveTable = "archt4_archive"
+	require.NoError(t, a.Run())
+	var count, archived int
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), `SELECT COUNT(*) FROM archt4`).Scan(&count))
+	require.Equal(t, 600, count)
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), `SELECT COUNT(*) FROM archt4_archive`).Scan(&archived))
+	require.Equal(t, 400, archived)
+}
+
+func TestArchiveKeyAlreadyArchived(t *testing.T) {
+	t.Parallel()
+	tt := testutils.NewTestTable(t, "archt5", `CREATE TABLE archt5 (
+		id int NOT NULL PRIMARY KEY,
+		a int NOT NULL
+	)`)
+	testutils.NewTestTable(t, "archt5_archive", `CREATE TABLE archt5_archive LIKE archt5`)
+	testutils.RunSQL(t, `INSERT INTO archt5 (id, a) WITH RECURSIVE seq (n) AS
+		(SELECT 1 UNION ALL SELECT n + 1 FROM seq WHERE n < 100) SELECT n, n FROM seq`)
+	// The archive table already holds a different row with the key of a
+	// row to archive, so the INSERT IGNORE skips it.
+	testutils.RunSQL(t, `INSERT INTO archt5_archive (id, a) VALUES (50, -1)`)
+
+	a := newTestArchive(t, "archt5", "a <= 100")
+	a.ArchiveTable = "archt5_archive"
+	require.ErrorContains(t, a.Run(), "the archive table holds only")
+	// The row that wasn't archived is still in the table.
+	var count int
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), `SELECT COUNT(*) FROM archt5 WHERE id = 50 AND a = 50`).Scan(&count))
+	require.Equal(t, 1, count)
+
+	// The same row is already archived, as it is when a run stops between
+	// the write and the delete, so the archive completes.
+	testutils.RunSQL(t, `UPDATE archt5_archive SET a = 50 WHERE id = 50`)
+	require.NoError(t, a.Run())
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), `SELECT COUNT(*) FROM archt5`).Scan(&count))
+	require.Zero(t, count)
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), `SELECT COUNT(*) FROM archt5_archive`).Scan(&count))
+	require.Equal(t, 100, count)
+}
+
+func TestArchiveTableMissingColumn(t *testing.T) {
+	t.Parallel()
+	testutils.NewTestTable(t, "archt2", `CREATE TABLE archt2 (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		a int NOT NULL,
+		b int
+	)`)
+	testutils.NewTestTable(t, "archt2_archive", `CREATE TABLE archt2_archive (
+		id int NOT NULL PRIMARY KEY,
+		a int NOT NULL
+	)`)
+	a := newTestArchive(t, "archt2", "a = 1")
+	a.ArchiveTable = "archt2_archive"
+	require.ErrorContains(t, a.Run(), "archive table archt2_archive has no column b")
+}
+
+func TestArchiveResumeOtherJob(t *testing.T) {
+	t.Parallel()
+	testutils.NewTestTable(t, "archt3", `CREATE TABLE archt3 (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		a int NOT NULL
+	)`)
+	testutils.RunSQL(t, "DROP TABLE IF EXISTS _archt3_archive_chkpnt")
+	testutils.RunSQL(t, "CREATE TABLE _archt3_archive_chkpnt "+chunkedCheckpointTableDDL)
+	testutils.RunSQL(t, `INSERT INTO _archt3_archive_chkpnt (id, job, low_watermark, resume_where, rows_affected)
+		VALUES (1, 'purge archt3 where a = 2', '', '1=1', 0)`)
+	a := newTestArchive(t, "archt3", "a = 1")
+	a.Purge = true
+	require.ErrorContains(t, a.Run(), "is for another job")
+	testutils.RunSQL(t, "DROP TABLE _archt3_archive_chkpnt")
+}
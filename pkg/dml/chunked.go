@@ -0,0 +1,181 @@
+package dml
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/block/spirit/pkg/dbconn"
+	"github.com/block/spirit/pkg/status"
+	"github.com/block/spirit/pkg/table"
+	"github.com/block/spirit/pkg/throttler"
+	"github.com/block/spirit/pkg/utils"
+	"github.com/go-sql-driver/mysql"
+)
+
+// errNoSuchTable is the MySQL error number for a table that doesn't exist.
+const errNoSuchTable = 1146
+
+// chunkedCheckpointTableDDL is the structure of the checkpoint table of a
+// chunkedJob, which holds a single row while the job runs.
+const chunkedCheckpointTableDDL = `(
+	id int NOT NULL PRIMARY KEY,
+	job TEXT NOT NULL,
+	low_watermark TEXT NOT NULL,
+	resume_where TEXT NOT NULL,
+	rows_affected bigint unsigned NOT NULL,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+	)`
+
+// chunkedCheckpoint is the row of the checkpoint table of a chunkedJob.
+type chunkedCheckpoint struct {
+	job string
+	// lowWatermark is the chunker's watermark before the last chunk that
+	// ran, or "" if it wasn't ready, and resumeWhere is the condition on
+	// the key of the rows after the last chunk. The chunker resumes at
+	// the watermark, which is behind the last chunk, so resumeWhere skips
+	// the chunks that already ran.
+	lowWatermark string
+	resumeWhere  string
+	rowsAffected int64
+}
+
+// chunkedJob runs a function on every chunk of a table, pausing while the
+// throttler asks it to, for `spirit dml` and `spirit archive`. The
+// checkpoint of each chunk is written in the same transaction as the
+// chunk, so that running the same job again after it was interrupted
+// resumes it exactly after the last chunk that committed.
+type chunkedJob struct {
+	db              *sql.DB
+	tbl             *table.TableInfo
+	chunker         table.Chunker // opened by run
+	thr             throttler.Throttler
+	checkpointTable string
+	// job describes what runChunk does, so that a checkpoint is only
+	// resumed by the same job.
+	job string
+	// runChunk runs the job on chunk in a transaction, and calls
+	// checkpoint with the transaction and the rows that it affected
+	// before it commits.
+	runChunk func(ctx context.Context, chunk *table.Chunk, checkpoint func(trx *sql.Tx, rowsAffected int64) error) (int64, error)
+	logger   *slog.Logger
+}
+
+// run runs the job on every chunk, resuming from the checkpoint table if
+// there is one for the same job, and returns the total of the rows that
+// runChunk affected. The checkpoint table is dropped once every chunk has
+// run.
+func (j *chunkedJob) run(ctx context.Context) (int64, error) {
+	checkpoint, err := j.readCheckpoint(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if checkpoint != nil && checkpoint.job != j.job {
+		return 0, fmt.Errorf("checkpoint table %s is for another job: %s. Run it again to finish it, or drop the checkpoint table", j.checkpointTable, checkpoint.job)
+	}
+	var rowsAffected int64
+	var resumeWhere string
+	if checkpoint != nil {
+		j.logger.Info("resuming from checkpoint", "table", j.tbl.TableName, "low-watermark", checkpoint.lowWatermark, "resume-where", checkpoint.resumeWhere)
+		if checkpoint.lowWatermark == "" {
+			err = j.chunker.Open()
+		} else {
+			err = j.chunker.OpenAtWatermark(checkpoint.lowWatermark)
+		}
+		if err != nil {
+			return 0, err
+		}
+		rowsAffected = checkpoint.rowsAffected
+		resumeWhere = checkpoint.resumeWhere
+	} else {
+		if err := dbconn.Exec(ctx, j.db, "CREATE TABLE IF NOT EXISTS %n.%n "+chunkedCheckpointTableDDL, j.tbl.SchemaName, j.checkpointTable); err != nil {
+			return 0, err
+		}
+		if err := j.chunker.Open(); err != nil {
+			return 0, err
+		}
+	}
+	defer utils.CloseAndLog(j.chunker)
+	lastProgress := time.Now()
+	for !j.chunker.IsRead() {
+		j.thr.BlockWait(ctx)
+		if ctx.Err() != nil {
+			return rowsAffected, ctx.Err()
+		}
+		chunk, err := j.chunker.Next()
+		if err != nil {
+			if errors.Is(err, table.ErrTableIsRead) {
+				break
+			}
+			return rowsAffected, err
+		}
+		if resumeWhere != "" {
+			chunk.AdditionalConditions = joinConditions(chunk.AdditionalConditions, resumeWhere)
+		}
+		startTime := time.Now()
+		affectedRows, err := j.runChunk(ctx, chunk, func(trx *sql.Tx, chunkRows int64) error {
+			return j.writeCheckpoint(ctx, trx, chunk, rowsAffected+chunkRows)
+		})
+		if err != nil {
+			return rowsAffected, err
+		}
+		j.chunker.Feedback(chunk, time.Since(startTime), chunk.ChunkSize)
+		rowsAffected += affectedRows
+		j.logger.Debug("ran chunk", "table", j.tbl.TableName, "chunk", chunk.String(), "rows", affectedRows)
+		if time.Since(lastProgress) >= status.CheckpointDumpInterval {
+			lastProgress = time.Now()
+			rowsRead, _, totalRows := j.chunker.Progress()
+			j.logger.Info("progress", "table", j.tbl.TableName, "rows-read", rowsRead, "rows-total", totalRows, "rows-affected", rowsAffected)
+		}
+	}
+	return rowsAffected, dbconn.Exec(ctx, j.db, "DROP TABLE IF EXISTS %n.%n", j.tbl.SchemaName, j.checkpointTable)
+}
+
+// writeCheckpoint writes in trx that chunk ran, and that rowsAffected rows
+// have been affected in total. It is called before Feedback of chunk, so
+// the low watermark is that of the chunk before it.
+func (j *chunkedJob) writeCheckpoint(ctx context.Context, trx *sql.Tx, chunk *table.Chunk, rowsAffected int64) error {
+	watermark, err := j.chunker.GetLowWatermark()
+	if errors.Is(err, table.ErrWatermarkNotReady) {
+		watermark = ""
+	} else if err != nil {
+		return err
+	}
+	// The rows after chunk are those above its upper bound. The last chunk
+	// has no upper bound, so no rows are after it.
+	resumeWhere := "1=0"
+	if chunk.UpperBound != nil {
+		after := &table.Chunk{Key: chunk.Key, LowerBound: &table.Boundary{Value: chunk.UpperBound.Value, Inclusive: !chunk.UpperBound.Inclusive}}
+		resumeWhere = after.String()
+	}
+	_, err = trx.ExecContext(ctx, fmt.Sprintf("REPLACE INTO `%s`.`%s` (id, job, low_watermark, resume_where, rows_affected) VALUES (1, ?, ?, ?, ?)",
+		j.tbl.SchemaName, j.checkpointTable), j.job, watermark, resumeWhere, rowsAffected)
+	return err
+}
+
+// joinConditions returns the conditions a and b joined with AND, either of
+// which can be "".
+func joinConditions(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return "(" + a + ") AND (" + b + ")"
+}
+
+// readCheckpoint returns the row of the checkpoint table, or nil if there
+// is none.
+func (j *chunkedJob) readCheckpoint(ctx context.Context) (*chunkedCheckpoint, error) {
+	var checkpoint chunkedCheckpoint
+	err := j.db.QueryRowContext(ctx, fmt.Sprintf("SELECT job, low_watermark, resume_where, rows_affected FROM `%s`.`%s` WHERE id=1",
+		j.tbl.SchemaName, j.checkpointTable)).Scan(&checkpoint.job, &checkpoint.lowWatermark, &checkpoint.resumeWhere, &checkpoint.rowsAffected)
+	if err != nil {
+		if mysqlErr, ok := errors.AsType[*mysql.MySQLError](err); (ok && mysqlErr.Number == errNoSuchTable) || errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &checkpoint, nil
+}
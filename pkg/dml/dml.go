@@ -0,0 +1,124 @@
+// Package dml contains the commands that change the rows of a table one
+// chunk at a time, throttled the way the copy of a migration is: spirit dml
+// and spirit archive.
+package dml
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/block/spirit/pkg/dbconn"
+	"github.com/block/spirit/pkg/migration"
+	"github.com/block/spirit/pkg/statement"
+	"github.com/block/spirit/pkg/table"
+	"github.com/block/spirit/pkg/throttler"
+	"github.com/block/spirit/pkg/utils"
+)
+
+// DMLCmd is the kong CLI entry point of `spirit dml`. It runs a single-table
+// UPDATE or DELETE one chunk of the table at a time.
+type DMLCmd struct {
+	Host               string        `name:"host" help:"Hostname" optional:""`
+	Username           string        `name:"username" help:"User" optional:""`
+	Password           *string       `name:"password" help:"Password" optional:""`
+	Database           string        `name:"database" help:"Database" optional:""`
+	ConfFile           string        `name:"conf" help:"MySQL conf file" optional:"" type:"existingfile"`
+	Statement          string        `name:"statement" help:"The UPDATE or DELETE statement to run" required:""`
+	TargetChunkTime    time.Duration `name:"target-chunk-time" help:"The target time for each chunk" optional:"" default:"500ms"`
+	ReplicaDSN         string        `name:"replica-dsn" help:"DSN(s) for replica(s) used for lag checking. Multiple replicas can be comma-separated; Spirit throttles on the slowest." optional:""`
+	ReplicaMaxLag      time.Duration `name:"replica-max-lag" help:"The maximum lag allowed on the replica before the statement throttles." optional:"" default:"120s"`
+	MaxCommitLatency   time.Duration `name:"max-commit-latency" help:"Throttle when average commit latency exceeds this threshold (currently only auto-enabled on Aurora)" optional:"" default:"100ms"`
+	TLSMode            string        `name:"tls-mode" help:"TLS connection mode (case insensitive): DISABLED, PREFERRED (default), REQUIRED, VERIFY_CA, VERIFY_IDENTITY" optional:""`
+	TLSCertificatePath string        `name:"tls-ca" help:"Path to custom TLS CA certificate file" optional:""`
+}
+
+// Run runs d.Statement one chunk of the table's primary key at a time,
+// adapting the size of the chunks to d.TargetChunkTime and pausing while
+// the throttlers ask it to. Its progress is checkpointed with every chunk,
+// so that running the same statement again after it was interrupted
+// resumes it after the last chunk that committed (see chunkedJob).
+func (d *DMLCmd) Run() error {
+	ctx := context.TODO()
+	logger := slog.Default()
+	dml, err := statement.ParseDML(d.Statement)
+	if err != nil {
+		return err
+	}
+	m := &migration.Migration{
+		Host:               d.Host,
+		Username:           d.Username,
+		Password:           d.Password,
+		Database:           d.Database,
+		ConfFile:           d.ConfFile,
+		ReplicaDSN:         d.ReplicaDSN,
+		ReplicaMaxLag:      d.ReplicaMaxLag,
+		TLSMode:            d.TLSMode,
+		TLSCertificatePath: d.TLSCertificatePath,
+	}
+	dbConfig := dbconn.NewDBConfig()
+	db, dsn, err := m.Connect(dbConfig)
+	if err != nil {
+		return err
+	}
+	defer utils.CloseAndLog(db)
+
+	tbl := table.NewTableInfo(db, cmp.Or(dml.Schema, m.Database), dml.Table)
+	tbl.DisableAnalyze = true
+	if err := tbl.SetInfo(ctx); err != nil {
+		return err
+	}
+	// A row whose key an UPDATE changes could move into a chunk that is
+	// yet to run, and be updated twice.
+	for _, column := range dml.SetColumns {
+		if slices.ContainsFunc(tbl.KeyColumns, func(key string) bool { return strings.EqualFold(key, column) }) {
+			return fmt.Errorf("the statement updates column %s of the primary key of table %s, so it can't be split into chunks", column, tbl.TableName)
+		}
+	}
+
+	thr, closeThrottler, err := m.NewStandaloneThrottler(ctx, db, dsn, dbConfig, d.MaxCommitLatency, logger)
+	if err != nil {
+		return err
+	}
+	defer closeThrottler()
+	if err := runDML(ctx, db, dbConfig, tbl, dml, thr, d.TargetChunkTime, logger); err != nil {
+		return err
+	}
+	logger.Info("successfully ran statement", "table", tbl.TableName)
+	return nil
+}
+
+// runDML runs dml on tbl one chunk at a time, resuming from the DML
+// checkpoint table if there is one for the same statement.
+func runDML(ctx context.Context, db *sql.DB, dbConfig *dbconn.DBConfig, tbl *table.TableInfo, dml *statement.DMLStatement, thr throttler.Throttler, targetChunkTime time.Duration, logger *slog.Logger) error {
+	chunker, err := table.NewChunker(tbl, table.ChunkerConfig{
+		TargetChunkTime: targetChunkTime,
+		Logger:          logger,
+	})
+	if err != nil {
+		return err
+	}
+	job := &chunkedJob{
+		db:              db,
+		tbl:             tbl,
+		chunker:         chunker,
+		thr:             thr,
+		checkpointTable: utils.DMLCheckpointTableName(tbl.TableName),
+		job:             dml.String(),
+		runChunk: func(ctx context.Context, chunk *table.Chunk, checkpoint func(trx *sql.Tx, rowsAffected int64) error) (int64, error) {
+			return dbconn.RetryableTransactionBeforeCommit(ctx, db, false, dbConfig, checkpoint, dml.ChunkStatement(chunk.String()))
+		},
+		logger: logger,
+	}
+	rowsAffected, err := job.run(ctx)
+	if err != nil {
+		return err
+	}
+	logger.Info("ran statement on every chunk", "table", tbl.TableName, "rows-affected", rowsAffected)
+	return nil
+}
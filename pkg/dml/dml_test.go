@@ -0,0 +1,99 @@
+package dml
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/block/spirit/pkg/statement"
+	"github.com/block/spirit/pkg/testutils"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDMLRejectsStatements(t *testing.T) {
+	// The statement is parsed before connecting.
+	for _, stmt := range []string{
+		"SELECT * FROM t1",
+		"UPDATE t1 JOIN t2 ON t1.a = t2.a SET t1.b = t2.b",
+		"DELETE FROM t1 WHERE a = 1 LIMIT 100",
+	} {
+		d := &DMLCmd{Host: "127.0.0.1:1", Statement: stmt}
+		require.Error(t, d.Run(), stmt)
+	}
+}
+
+func newTestDML(t *testing.T, stmt string) *DMLCmd {
+	t.Helper()
+	cfg, err := mysql.ParseDSN(testutils.DSN())
+	require.NoError(t, err)
+	return &DMLCmd{
+		Host:            cfg.Addr,
+		Username:        cfg.User,
+		Password:        &cfg.Passwd,
+		Database:        cfg.DBName,
+		Statement:       stmt,
+		TargetChunkTime: time.Millisecond,
+	}
+}
+
+func TestDMLUpdateAndDelete(t *testing.T) {
+	t.Parallel()
+	tt := testutils.NewTestTable(t, "dmlt1", `CREATE TABLE dmlt1 (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		a int NOT NULL,
+		b int
+	)`)
+	testutils.RunSQL(t, `INSERT INTO dmlt1 (a) WITH RECURSIVE seq (n) AS
+		(SELECT 1 UNION ALL SELECT n + 1 FROM seq WHERE n < 5000) SELECT n FROM seq`)
+
+	require.NoError(t, newTestDML(t, "UPDATE dmlt1 SET b = a * 2 WHERE a % 2 = 0").Run())
+	var updated, untouched int
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), `SELECT SUM(b = a * 2), SUM(b IS NULL) FROM dmlt1`).Scan(&updated, &untouched))
+	require.Equal(t, 2500, updated)
+	require.Equal(t, 2500, untouched)
+
+	require.NoError(t, newTestDML(t, "DELETE FROM dmlt1 WHERE b IS NULL").Run())
+	var count int
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), `SELECT COUNT(*) FROM dmlt1`).Scan(&count))
+	require.Equal(t, 2500, count)
+
+	// The checkpoint table is dropped when the statement completes.
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), `SELECT COUNT(*) FROM information_schema.tables
+		WHERE table_schema=DATABASE() AND table_name='_dmlt1_dml_chkpnt'`).Scan(&count))
+	require.Zero(t, count)
+
+	// The primary key can't be updated.
+	require.ErrorContains(t, newTestDML(t, "UPDATE dmlt1 SET id = id + 10000").Run(), "updates column id of the primary key")
+}
+
+func TestDMLResume(t *testing.T) {
+	t.Parallel()
+	tt := testutils.NewTestTable(t, "dmlt2", `CREATE TABLE dmlt2 (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		a int NOT NULL
+	)`)
+	testutils.RunSQL(t, `INSERT INTO dmlt2 (a) WITH RECURSIVE seq (n) AS
+		(SELECT 1 UNION ALL SELECT n + 1 FROM seq WHERE n < 3000) SELECT 0 FROM seq`)
+	testutils.RunSQL(t, "CREATE TABLE _dmlt2_dml_chkpnt "+chunkedCheckpointTableDDL)
+
+	// A checkpoint for another statement is not resumed.
+	testutils.RunSQL(t, `INSERT INTO _dmlt2_dml_chkpnt (id, job, low_watermark, resume_where, rows_affected)
+		VALUES (1, 'DELETE FROM dmlt2', '', '1=1', 0)`)
+	require.ErrorContains(t, newTestDML(t, "UPDATE dmlt2 SET a = a + 1").Run(), "is for another job")
+
+	// The chunks below id 2101 ran before the interruption. The chunker
+	// resumes at the watermark of the chunk before the last one, but the
+	// rows of the last one are not updated again, so a statement that
+	// isn't safe to repeat is applied exactly once.
+	dml, err := statement.ParseDML("UPDATE dmlt2 SET a = a + 1")
+	require.NoError(t, err)
+	testutils.RunSQL(t, `UPDATE dmlt2 SET a = 1 WHERE id < 2101`)
+	testutils.RunSQL(t, fmt.Sprintf(`REPLACE INTO _dmlt2_dml_chkpnt (id, job, low_watermark, resume_where, rows_affected)
+		VALUES (1, '%s', '{"Key":["id"],"ChunkSize":1000,"LowerBound":{"Value":["2001"],"Inclusive":true},"UpperBound":{"Value":["2051"],"Inclusive":false}}', '`+"`id`"+` >= 2101', 2100)`, dml.String()))
+	require.NoError(t, newTestDML(t, "UPDATE dmlt2 SET a = a + 1").Run())
+	var updated, other int
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), `SELECT SUM(a = 1), SUM(a != 1) FROM dmlt2`).Scan(&updated, &other))
+	require.Equal(t, 3000, updated)
+	require.Zero(t, other)
+}
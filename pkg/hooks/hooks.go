@@ -0,0 +1,183 @@
+// Package hooks runs operator-supplied commands and webhooks at points in
+// the lifecycle of a migrate, move or sync, in the spirit of gh-ost's hooks.
+//
+// Each hook point can have a command, which is run with `sh -c` and
+// environment variables describing the task, and/or a URL, which is sent
+// the same description as a JSON POST. A hook fails if its command exits
+// non-zero, or its URL does not return a 2xx status. Whether a failure
+// stops the task depends on the point: only a failing BeforeCutover hook
+// does, the others are logged.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/block/spirit/pkg/utils"
+)
+
+// Point is a point in the lifecycle of a task at which hooks run.
+type Point string
+
+// The hook points, in the order they are reached. Sync never reaches the
+// checksum and cutover points, since it has neither.
+const (
+	Startup           Point = "startup"
+	PreflightComplete Point = "preflight-complete"
+	CopyComplete      Point = "copy-complete"
+	ChecksumComplete  Point = "checksum-complete"
+	BeforeCutover     Point = "before-cutover"
+	AfterCutover      Point = "after-cutover"
+	Failure           Point = "failure"
+	Success           Point = "success"
+)
+
+// Points are all of the hook points.
+var Points = []Point{Startup, PreflightComplete, CopyComplete, ChecksumComplete, BeforeCutover, AfterCutover, Failure, Success}
+
+// DefaultTimeout is how long a hook may run when Config.Timeout is zero.
+const DefaultTimeout = time.Minute
+
+// Config maps hook points (by name) to the commands and URLs to run there.
+type Config struct {
+	Exec    map[string]string
+	URL     map[string]string
+	Timeout time.Duration
+}
+
+// Validate checks that every hook is configured for a known point.
+func (c Config) Validate() error {
+	for _, point := range slices.Sorted(maps.Keys(c.Exec)) {
+		if !slices.Contains(Points, Point(point)) {
+			return fmt.Errorf("--hook-exec: unknown hook point %q", point)
+		}
+	}
+	for _, point := range slices.Sorted(maps.Keys(c.URL)) {
+		if !slices.Contains(Points, Point(point)) {
+			return fmt.Errorf("--hook-url: unknown hook point %q", point)
+		}
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("--hook-timeout must be non-negative, got %s", c.Timeout)
+	}
+	return nil
+}
+
+// Event describes the task to a hook. It is the JSON payload sent to
+// URLs, and the source of the environment variables of commands.
+type Event struct {
+	Point     Point     `json:"hook"`
+	Command   string    `json:"command"` // migrate, move or sync
+	Tables    []string  `json:"tables"`
+	Statement string    `json:"statement,omitempty"`
+	State     string    `json:"state"`
+	Progress  string    `json:"progress,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// environ returns the environment variables that describe e to a command.
+func (e Event) environ() []string {
+	return []string{
+		"SPIRIT_HOOK=" + string(e.Point),
+		"SPIRIT_COMMAND=" + e.Command,
+		"SPIRIT_TABLES=" + strings.Join(e.Tables, ","),
+		"SPIRIT_STATEMENT=" + e.Statement,
+		"SPIRIT_STATE=" + e.State,
+		"SPIRIT_PROGRESS=" + e.Progress,
+		"SPIRIT_ERROR=" + e.Error,
+	}
+}
+
+// Hooks runs the hooks of a Config.
+type Hooks struct {
+	config Config
+	client *http.Client
+	logger *slog.Logger
+}
+
+// New returns the Hooks for config.
+func New(config Config, logger *slog.Logger) *Hooks {
+	if config.Timeout == 0 {
+		config.Timeout = DefaultTimeout
+	}
+	return &Hooks{
+		config: config,
+		client: &http.Client{},
+		logger: logger,
+	}
+}
+
+// Run runs the command and then the URL configured for event.Point, if
+// any, and returns their errors. It does not return until both are done.
+func (h *Hooks) Run(ctx context.Context, event Event) error {
+	command, url := h.config.Exec[string(event.Point)], h.config.URL[string(event.Point)]
+	if command == "" && url == "" {
+		return nil
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	ctx, cancel := context.WithTimeout(ctx, h.config.Timeout)
+	defer cancel()
+	var errs []error
+	if command != "" {
+		if err := h.exec(ctx, command, event); err != nil {
+			errs = append(errs, fmt.Errorf("%s hook command failed: %w", event.Point, err))
+		}
+	}
+	if url != "" {
+		if err := h.post(ctx, url, event); err != nil {
+			errs = append(errs, fmt.Errorf("%s hook URL failed: %w", event.Point, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h *Hooks) exec(ctx context.Context, command string, event Event) error {
+	h.logger.Info("running hook command", "hook", event.Point, "command", command)
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(), event.environ()...)
+	// Don't wait on the output of children that outlive a killed shell.
+	cmd.WaitDelay = time.Second
+	output, err := cmd.CombinedOutput()
+	if len(output) > 0 {
+		h.logger.Info("hook command output", "hook", event.Point, "output", strings.TrimSpace(string(output)))
+	}
+	return err
+}
+
+func (h *Hooks) post(ctx context.Context, url string, event Event) error {
+	h.logger.Info("posting to hook URL", "hook", event.Point)
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer utils.CloseAndLog(resp.Body)
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package hooks
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, Config{}.Validate())
+	require.NoError(t, Config{
+		Exec: map[string]string{"before-cutover": "true", "failure": "true"},
+		URL:  map[string]string{"success": "http://localhost/"},
+	}.Validate())
+	require.ErrorContains(t, Config{Exec: map[string]string{"before-cutvoer": "true"}}.Validate(),
+		`--hook-exec: unknown hook point "before-cutvoer"`)
+	require.ErrorContains(t, Config{URL: map[string]string{"done": "http://localhost/"}}.Validate(),
+		`--hook-url: unknown hook point "done"`)
+	require.ErrorContains(t, Config{Timeout: -time.Second}.Validate(), "--hook-timeout must be non-negative")
+}
+
+func TestExec(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	h := New(Config{Exec: map[string]string{
+		"copy-complete": `echo "$SPIRIT_HOOK $SPIRIT_COMMAND $SPIRIT_TABLES $SPIRIT_STATE $SPIRIT_PROGRESS" > ` + out,
+		"failure":       "exit 3",
+	}}, slog.Default())
+
+	require.NoError(t, h.Run(t.Context(), Event{
+		Point:    CopyComplete,
+		Command:  "migrate",
+		Tables:   []string{"t1", "t2"},
+		State:    "applyChangeset",
+		Progress: "done",
+	}))
+	b, err := os.ReadFile(out)
+	require.NoError(t, err)
+	require.Equal(t, "copy-complete migrate t1,t2 applyChangeset done\n", string(b))
+
+	require.ErrorContains(t, h.Run(t.Context(), Event{Point: Failure}), "failure hook command failed: exit status 3")
+	// Points without hooks do nothing.
+	require.NoError(t, h.Run(t.Context(), Event{Point: Success}))
+}
+
+func TestExecTimeout(t *testing.T) {
+	h := New(Config{Exec: map[string]string{"startup": "sleep 10"}, Timeout: 50 * time.Millisecond}, slog.Default())
+	start := time.Now()
+	require.Error(t, h.Run(t.Context(), Event{Point: Startup}))
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestURL(t *testing.T) {
+	events := make(chan Event, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var event Event
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		events <- event
+		if event.Point == BeforeCutover {
+			w.WriteHeader(http.StatusConflict)
+		}
+	}))
+	defer srv.Close()
+	h := New(Config{URL: map[string]string{"after-cutover": srv.URL, "before-cutover": srv.URL}}, slog.Default())
+
+	require.NoError(t, h.Run(t.Context(), Event{Point: AfterCutover, Command: "move", Tables: []string{"t1"}, Error: ""}))
+	event := <-events
+	require.Equal(t, AfterCutover, event.Point)
+	require.Equal(t, "move", event.Command)
+	require.Equal(t, []string{"t1"}, event.Tables)
+	require.False(t, event.Time.IsZero())
+
+	require.ErrorContains(t, h.Run(t.Context(), Event{Point: BeforeCutover}), "before-cutover hook URL failed: unexpected status 409 Conflict")
+	<-events
+}
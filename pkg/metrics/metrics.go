@@ -4,6 +4,7 @@ package metrics
 
 import (
 	"context"
+	"maps"
 	"time"
 )
 
@@ -24,6 +25,28 @@ const (
 	// continuous load signal (0..>1) the autoscaler controls on.
 	WriteThreadsMetricName         = "write_threads"
 	ThrottlerUtilizationMetricName = "throttler_utilization"
+	// BinlogLagMetricName reports how far (in seconds) the replication
+	// reader is behind the source, based on the timestamp of the last
+	// event read. SubscriptionBufferedChangesMetricName reports the
+	// pending changes held in memory by each subscription (labelled by
+	// table) that have not yet been flushed.
+	BinlogLagMetricName                   = "binlog_reader_lag_seconds"
+	SubscriptionBufferedChangesMetricName = "subscription_buffered_changes"
+	// ChecksumChunksRemainingMetricName is an estimate of the chunks the
+	// current checksum pass still has to compare.
+	ChecksumChunksRemainingMetricName = "checksum_chunks_remaining"
+	// CutoverAttemptsMetricName counts cutover attempts, including the
+	// one that succeeds.
+	CutoverAttemptsMetricName = "cutover_attempts"
+)
+
+// Label names attached to MetricValues. Sinks are free to ignore labels.
+const (
+	TableLabel = "table"
+	PhaseLabel = "phase"
+	// SourceLabel identifies the source database when a task reads from
+	// more than one (e.g. an N:M move).
+	SourceLabel = "source"
 )
 
 // Metrics are collection of MetricValues.
@@ -40,6 +63,10 @@ type MetricValue struct {
 
 	// Type is the metric type: GAUGE, COUNTER, and other const.
 	Type byte
+
+	// Labels are optional dimensions for the value, such as the table
+	// it relates to (see TableLabel).
+	Labels map[string]string
 }
 
 // Sink sends metrics to an external destination.
@@ -56,3 +83,31 @@ func (s *NoopSink) Send(ctx context.Context, m *Metrics) error {
 }
 
 var _ Sink = &NoopSink{}
+
+// labeledSink adds labels to every value before forwarding it.
+type labeledSink struct {
+	sink   Sink
+	labels func() map[string]string
+}
+
+// NewLabeledSink returns a Sink that adds the labels returned by labels
+// to every value sent through it, before forwarding to sink. Labels
+// already set on a value take precedence. labels is called on every
+// Send, so it can report something that changes over time, such as
+// the phase of a migration.
+func NewLabeledSink(sink Sink, labels func() map[string]string) Sink {
+	return &labeledSink{sink: sink, labels: labels}
+}
+
+func (s *labeledSink) Send(ctx context.Context, m *Metrics) error {
+	extra := s.labels()
+	values := make([]MetricValue, len(m.Values))
+	for i, v := range m.Values {
+		merged := make(map[string]string, len(extra)+len(v.Labels))
+		maps.Copy(merged, extra)
+		maps.Copy(merged, v.Labels)
+		v.Labels = merged
+		values[i] = v
+	}
+	return s.sink.Send(ctx, &Metrics{Values: values})
+}
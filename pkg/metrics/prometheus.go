@@ -0,0 +1,254 @@
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// PrometheusNamespace prefixes every metric name exposed by
+	// PrometheusSink, i.e. write_threads is exposed as spirit_write_threads.
+	PrometheusNamespace = "spirit"
+
+	prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// DefaultChunkProcessingTimeBuckets are the histogram buckets (in
+// milliseconds, matching ChunkProcessingTimeMetricName) that
+// PrometheusSink uses for chunk processing time.
+var DefaultChunkProcessingTimeBuckets = []float64{50, 100, 250, 500, 1000, 2000, 5000, 10000, 30000}
+
+// PrometheusSink is a Sink that keeps the values it is sent in memory
+// and serves them in the Prometheus text exposition format. It
+// implements http.Handler, so it can be mounted on /metrics of any
+// server (spirit mounts it on the status API, see status.Server).
+//
+// GAUGE values replace the previous value of the series and COUNTER
+// values are added to it, since the copier sends counters as per-chunk
+// increments. Metrics with histogram buckets configured (by default
+// only ChunkProcessingTimeMetricName) are instead observed into a
+// histogram, which is far more useful than a gauge that only ever holds
+// the time of the last chunk.
+//
+// Each distinct set of Labels is a separate series. Counter and histogram
+// series are never expired; the number of tables and phases of a
+// migration is small and bounded, which keeps cardinality bounded too.
+// A gauge only holds the current value though, so when a value arrives
+// with a new PhaseLabel, the gauge series of the other phases are
+// dropped: otherwise a gauge such as the replication lag would keep
+// exporting its last value under the old phase forever.
+type PrometheusSink struct {
+	mu       sync.Mutex
+	buckets  map[string][]float64
+	families map[string]*promFamily
+	phase    string // the PhaseLabel of the latest value that had one
+}
+
+type promFamily struct {
+	typ    byte // GAUGE, COUNTER or histogramType
+	series map[string]*promSeries
+}
+
+type promSeries struct {
+	labels [][2]string // sorted by name
+	value  float64
+	// histogram only
+	bucketCounts []uint64
+	count        uint64
+}
+
+// histogramType is internal to the sink: callers send observations as
+// GAUGE, and the sink decides to keep a histogram based on the name.
+const histogramType byte = 0xff
+
+var _ Sink = &PrometheusSink{}
+var _ http.Handler = &PrometheusSink{}
+
+// NewPrometheusSink returns an empty PrometheusSink.
+func NewPrometheusSink() *PrometheusSink {
+	return &PrometheusSink{
+		buckets: map[string][]float64{
+			ChunkProcessingTimeMetricName: DefaultChunkProcessingTimeBuckets,
+		},
+		families: make(map[string]*promFamily),
+	}
+}
+
+// SetHistogramBuckets makes the sink observe values named name into a
+// histogram with the given upper bounds, rather than keeping them as a
+// gauge. It must be called before any value named name is sent.
+func (s *PrometheusSink) SetHistogramBuckets(name string, buckets []float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sorted := slices.Clone(buckets)
+	slices.Sort(sorted)
+	s.buckets[name] = sorted
+}
+
+// Send records m. It never blocks on I/O, so the context is not used.
+func (s *PrometheusSink) Send(_ context.Context, m *Metrics) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, v := range m.Values {
+		if phase, ok := v.Labels[PhaseLabel]; ok && phase != s.phase {
+			s.phase = phase
+			s.dropGaugesOfOtherPhases()
+		}
+		typ := v.Type
+		buckets, isHistogram := s.buckets[v.Name]
+		if isHistogram {
+			typ = histogramType
+		} else if typ != GAUGE && typ != COUNTER {
+			return fmt.Errorf("metric %q has unsupported type %d", v.Name, v.Type)
+		}
+		fam, ok := s.families[v.Name]
+		if !ok {
+			fam = &promFamily{typ: typ, series: make(map[string]*promSeries)}
+			s.families[v.Name] = fam
+		}
+		if fam.typ != typ {
+			return fmt.Errorf("metric %q was sent with type %d, but was previously sent with type %d", v.Name, typ, fam.typ)
+		}
+		labels := sortedLabels(v.Labels)
+		key := formatLabels(labels, "", "")
+		ser, ok := fam.series[key]
+		if !ok {
+			ser = &promSeries{labels: labels}
+			if isHistogram {
+				ser.bucketCounts = make([]uint64, len(buckets))
+			}
+			fam.series[key] = ser
+		}
+		switch typ {
+		case GAUGE:
+			ser.value = v.Value
+		case COUNTER:
+			ser.value += v.Value
+		case histogramType:
+			ser.value += v.Value // the sum
+			ser.count++
+			for i, upper := range buckets {
+				if v.Value <= upper {
+					ser.bucketCounts[i]++
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// dropGaugesOfOtherPhases drops the gauge series that are labelled with a
+// phase other than s.phase. Gauges without a phase are kept.
+func (s *PrometheusSink) dropGaugesOfOtherPhases() {
+	for _, fam := range s.families {
+		if fam.typ != GAUGE {
+			continue
+		}
+		maps.DeleteFunc(fam.series, func(_ string, ser *promSeries) bool {
+			i := slices.IndexFunc(ser.labels, func(l [2]string) bool { return l[0] == PhaseLabel })
+			return i >= 0 && ser.labels[i][1] != s.phase
+		})
+	}
+}
+
+// ServeHTTP writes all metrics in the Prometheus text format.
+func (s *PrometheusSink) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", prometheusContentType)
+	bw := bufio.NewWriter(w)
+	s.write(bw)
+	_ = bw.Flush()
+}
+
+func (s *PrometheusSink) write(w *bufio.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := slices.Sorted(maps.Keys(s.families))
+	for _, name := range names {
+		fam := s.families[name]
+		fullName := PrometheusNamespace + "_" + name
+		keys := slices.Sorted(maps.Keys(fam.series))
+		switch fam.typ {
+		case GAUGE:
+			fmt.Fprintf(w, "# TYPE %s gauge\n", fullName)
+			for _, k := range keys {
+				fmt.Fprintf(w, "%s%s %s\n", fullName, k, formatFloat(fam.series[k].value))
+			}
+		case COUNTER:
+			fullName += "_total"
+			fmt.Fprintf(w, "# TYPE %s counter\n", fullName)
+			for _, k := range keys {
+				fmt.Fprintf(w, "%s%s %s\n", fullName, k, formatFloat(fam.series[k].value))
+			}
+		case histogramType:
+			buckets := s.buckets[name]
+			fmt.Fprintf(w, "# TYPE %s histogram\n", fullName)
+			for _, k := range keys {
+				ser := fam.series[k]
+				for i, upper := range buckets {
+					fmt.Fprintf(w, "%s_bucket%s %d\n", fullName, formatLabels(ser.labels, "le", formatFloat(upper)), ser.bucketCounts[i])
+				}
+				fmt.Fprintf(w, "%s_bucket%s %d\n", fullName, formatLabels(ser.labels, "le", "+Inf"), ser.count)
+				fmt.Fprintf(w, "%s_sum%s %s\n", fullName, k, formatFloat(ser.value))
+				fmt.Fprintf(w, "%s_count%s %d\n", fullName, k, ser.count)
+			}
+		}
+	}
+}
+
+func sortedLabels(labels map[string]string) [][2]string {
+	sorted := make([][2]string, 0, len(labels))
+	for k, v := range labels {
+		sorted = append(sorted, [2]string{k, v})
+	}
+	slices.SortFunc(sorted, func(a, b [2]string) int { return strings.Compare(a[0], b[0]) })
+	return sorted
+}
+
+// formatLabels renders labels as {a="1",b="2"}, optionally followed by
+// one extra label (used for the histogram "le" label). It returns ""
+// when there are no labels at all.
+func formatLabels(labels [][2]string, extraName, extraValue string) string {
+	if len(labels) == 0 && extraName == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, l := range labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=\"%s\"", l[0], escapeLabelValue(l[1]))
+	}
+	if extraName != "" {
+		if len(labels) > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=\"%s\"", extraName, escapeLabelValue(extraValue))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeLabelValue(v string) string {
+	return labelValueEscaper.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,131 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func scrape(t *testing.T, sink *PrometheusSink) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	sink.ServeHTTP(rec, httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, prometheusContentType, rec.Header().Get("Content-Type"))
+	return rec.Body.String()
+}
+
+func TestPrometheusSinkGaugesAndCounters(t *testing.T) {
+	sink := NewPrometheusSink()
+	t1 := map[string]string{TableLabel: "t1", PhaseLabel: "copyRows"}
+	for range 2 {
+		require.NoError(t, sink.Send(t.Context(), &Metrics{Values: []MetricValue{
+			{Name: WriteThreadsMetricName, Type: GAUGE, Value: 4},
+			{Name: ChunkLogicalRowsCountMetricName, Type: COUNTER, Value: 1000, Labels: t1},
+		}}))
+	}
+	require.NoError(t, sink.Send(t.Context(), &Metrics{Values: []MetricValue{
+		{Name: WriteThreadsMetricName, Type: GAUGE, Value: 6},
+	}}))
+
+	out := scrape(t, sink)
+	require.Contains(t, out, "# TYPE spirit_write_threads gauge\nspirit_write_threads 6\n")
+	require.Contains(t, out, "# TYPE spirit_chunk_num_logical_rows_total counter\n")
+	// Labels are sorted by name, and counters accumulate.
+	require.Contains(t, out, `spirit_chunk_num_logical_rows_total{phase="copyRows",table="t1"} 2000`+"\n")
+	// Families are sorted by name.
+	require.Less(t, strings.Index(out, "spirit_chunk_num_logical_rows_total"), strings.Index(out, "spirit_write_threads"))
+}
+
+func TestPrometheusSinkHistogram(t *testing.T) {
+	sink := NewPrometheusSink()
+	sink.SetHistogramBuckets(ChunkProcessingTimeMetricName, []float64{1000, 100})
+	labels := map[string]string{TableLabel: "t1"}
+	for _, v := range []float64{50, 500, 5000} {
+		require.NoError(t, sink.Send(t.Context(), &Metrics{Values: []MetricValue{
+			{Name: ChunkProcessingTimeMetricName, Type: GAUGE, Value: v, Labels: labels},
+		}}))
+	}
+	out := scrape(t, sink)
+	require.Contains(t, out, `# TYPE spirit_chunk_processing_time histogram
+spirit_chunk_processing_time_bucket{table="t1",le="100"} 1
+spirit_chunk_processing_time_bucket{table="t1",le="1000"} 2
+spirit_chunk_processing_time_bucket{table="t1",le="+Inf"} 3
+spirit_chunk_processing_time_sum{table="t1"} 5550
+spirit_chunk_processing_time_count{table="t1"} 3
+`)
+}
+
+func TestPrometheusSinkRejectsTypeChange(t *testing.T) {
+	sink := NewPrometheusSink()
+	require.NoError(t, sink.Send(t.Context(), &Metrics{Values: []MetricValue{{Name: "x", Type: GAUGE, Value: 1}}}))
+	require.Error(t, sink.Send(t.Context(), &Metrics{Values: []MetricValue{{Name: "x", Type: COUNTER, Value: 1}}}))
+	require.Error(t, sink.Send(t.Context(), &Metrics{Values: []MetricValue{{Name: "y", Type: UNKNOWN, Value: 1}}}))
+}
+
+func TestPrometheusSinkEscapesLabelValues(t *testing.T) {
+	sink := NewPrometheusSink()
+	require.NoError(t, sink.Send(t.Context(), &Metrics{Values: []MetricValue{
+		{Name: "x", Type: GAUGE, Value: 1, Labels: map[string]string{TableLabel: "a\"b\\c\nd"}},
+	}}))
+	require.Contains(t, scrape(t, sink), `spirit_x{table="a\"b\\c\nd"} 1`)
+}
+
+func TestLabeledSink(t *testing.T) {
+	prom := NewPrometheusSink()
+	phase := "copyRows"
+	sink := NewLabeledSink(prom, func() map[string]string {
+		return map[string]string{PhaseLabel: phase, TableLabel: "default"}
+	})
+	original := map[string]string{TableLabel: "t1"}
+	require.NoError(t, sink.Send(t.Context(), &Metrics{Values: []MetricValue{
+		{Name: "x", Type: GAUGE, Value: 1, Labels: original},
+	}}))
+	phase = "checksum"
+	require.NoError(t, sink.Send(t.Context(), &Metrics{Values: []MetricValue{
+		{Name: "x", Type: GAUGE, Value: 2},
+	}}))
+
+	out := scrape(t, prom)
+	// The value's own labels win over the added ones.
+	require.Contains(t, out, `spirit_x{phase="checksum",table="default"} 2`)
+	// A gauge only exports the value of the current phase.
+	require.NotContains(t, out, `phase="copyRows"`)
+	// The caller's map is not modified.
+	require.Equal(t, map[string]string{TableLabel: "t1"}, original)
+}
+
+func TestPrometheusSinkDropsGaugesOfOtherPhases(t *testing.T) {
+	sink := NewPrometheusSink()
+	send := func(phase string, values ...MetricValue) {
+		for i := range values {
+			values[i].Labels = map[string]string{PhaseLabel: phase}
+		}
+		require.NoError(t, sink.Send(t.Context(), &Metrics{Values: values}))
+	}
+	send("copyRows",
+		MetricValue{Name: "lag", Type: GAUGE, Value: 5},
+		MetricValue{Name: ChunkLogicalRowsCountMetricName, Type: COUNTER, Value: 1000},
+		MetricValue{Name: ChunkProcessingTimeMetricName, Type: GAUGE, Value: 75},
+	)
+	require.NoError(t, sink.Send(t.Context(), &Metrics{Values: []MetricValue{{Name: "unphased", Type: GAUGE, Value: 1}}}))
+	send("checksum", MetricValue{Name: "remaining", Type: GAUGE, Value: 10})
+
+	out := scrape(t, sink)
+	// The gauges of copyRows are dropped when checksum starts, even the
+	// ones that aren't sent again.
+	require.NotContains(t, out, `spirit_lag{`)
+	require.Contains(t, out, `spirit_remaining{phase="checksum"} 10`)
+	require.Contains(t, out, "spirit_unphased 1\n")
+	// Counters and histograms accumulate, so each phase keeps its series.
+	require.Contains(t, out, `spirit_chunk_num_logical_rows_total{phase="copyRows"} 1000`)
+	require.Contains(t, out, `spirit_chunk_processing_time_count{phase="copyRows"} 1`)
+
+	send("cutOver", MetricValue{Name: "remaining", Type: GAUGE, Value: 0})
+	out = scrape(t, sink)
+	require.NotContains(t, out, `spirit_remaining{phase="checksum"}`)
+	require.Contains(t, out, `spirit_remaining{phase="cutOver"} 0`)
+}
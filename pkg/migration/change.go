@@ -1,13 +1,17 @@
 package migration
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/block/spirit/pkg/dbconn"
 	"github.com/block/spirit/pkg/statement"
 	"github.com/block/spirit/pkg/table"
+	"github.com/block/spirit/pkg/throttler"
 	"github.com/block/spirit/pkg/utils"
 )
 
@@ -22,6 +26,22 @@ type tableChange struct {
 	// (not the multi-chunker wrapper stored on the Runner).
 	chunker table.MappedChunker
 
+	// deferredCreateTable is the CREATE TABLE of the new table before
+	// deferSecondaryIndexes dropped its secondary indexes, or empty if it
+	// didn't. restoreSecondaryIndexes adds back the indexes it is missing.
+	// It is recorded in the checkpoint, since the new table no longer has
+	// them when resuming.
+	deferredCreateTable string
+
+	// duplicates checks for duplicates of the unique keys that the ALTER
+	// adds. It is set by setupCopierCheckerAndReplClient().
+	duplicates *duplicateChecker
+
+	// truncatedForeignKeys are the foreign keys, on the table or on tables
+	// that reference it, whose names foreignKeyName truncates. They are
+	// renamed back to their original names after the cutover.
+	truncatedForeignKeys []table.ForeignKey
+
 	// Store a pointer back to the migration runner
 	// (for compatibility, we want to eventually remove this)
 	runner *Runner
@@ -45,7 +65,8 @@ func (c *tableChange) createNewTable(ctx context.Context) error {
 }
 
 // alterNewTable applies the ALTER to the new table.
-// It has been pre-checked it is not a rename, or modifying the PRIMARY KEY.
+// It has been pre-checked it is not a rename. If it changes the PRIMARY KEY,
+// resolveKey finds a key that both tables have to match rows by.
 // We first attempt to do this using ALGORITHM=COPY so we don't burn
 // an INSTANT version. But surprisingly this is not supported for all DDLs (issue #277)
 func (c *tableChange) alterNewTable(ctx context.Context) error {
@@ -58,11 +79,24 @@ func (c *tableChange) alterNewTable(ctx context.Context) error {
 			return err
 		}
 	}
+	// A new table without any key that can identify its rows shares none
+	// with the table. Report it the way resolveKey does, since SetInfo would
+	// fail on it first, with an error about generated invisible primary keys.
+	newKeys, err := c.newTable.UniqueKeys(ctx)
+	if err != nil {
+		return err
+	}
+	if len(newKeys) == 0 {
+		return c.errNoSharedKey(false)
+	}
 	// Call GetInfo on the table again, since the columns
 	// might have changed and this will affect the row copiers intersect func.
 	if err := c.newTable.SetInfo(ctx); err != nil {
 		return err
 	}
+	if err := c.resolveKey(ctx); err != nil {
+		return err
+	}
 
 	// Preserve AUTO_INCREMENT value from the original table AFTER the ALTER.
 	// CREATE TABLE LIKE doesn't copy AUTO_INCREMENT, and ALTER with ALGORITHM=COPY
@@ -71,6 +105,189 @@ func (c *tableChange) alterNewTable(ctx context.Context) error {
 	return c.preserveAutoIncrement(ctx)
 }
 
+// resolveKey makes sure that rows can be matched between the table and the
+// new table by the same key. That's the PRIMARY KEY (or for a table without
+// one, the unique key used in its place), unless the ALTER changes it. Then
+// it's a unique key that both tables have on the same NOT NULL columns,
+// which the copy chunks on, the change subscription keys changes by, and
+// the applier and checksum match rows by. The table's key is preferred,
+// so that the copy still reads the table in its order, then the new
+// table's key, and then the key with the fewest columns.
+func (c *tableChange) resolveKey(ctx context.Context) error {
+	if slices.Equal(c.newTable.KeyColumns, c.table.KeyColumns) {
+		return nil
+	}
+	keys, err := c.table.UniqueKeys(ctx)
+	if err != nil {
+		return err
+	}
+	newKeys, err := c.newTable.UniqueKeys(ctx)
+	if err != nil {
+		return err
+	}
+	type sharedKey struct{ key, newKey table.UniqueKey }
+	var candidates []sharedKey
+	for _, key := range keys {
+		for _, newKey := range newKeys {
+			if slices.Equal(key.Columns, newKey.Columns) {
+				candidates = append(candidates, sharedKey{key, newKey})
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return c.errNoSharedKey(true)
+	}
+	shared := slices.MinFunc(candidates, func(a, b sharedKey) int {
+		return cmp.Or(
+			cmp.Compare(keyRank(c.table, a.key), keyRank(c.table, b.key)),
+			cmp.Compare(keyRank(c.newTable, a.newKey), keyRank(c.newTable, b.newKey)),
+			cmp.Compare(len(a.key.Columns), len(b.key.Columns)),
+		)
+	})
+	c.runner.logger.Info("the ALTER changes the key that identifies rows; matching rows by a key both tables have",
+		"table", c.table.TableName,
+		"key", shared.key.Name,
+		"new-table-key", shared.newKey.Name,
+		"columns", shared.key.Columns,
+	)
+	if err := c.table.SetKey(ctx, shared.key.Name); err != nil {
+		return err
+	}
+	return c.newTable.SetKey(ctx, shared.newKey.Name)
+}
+
+// errNoSharedKey returns the error for an ALTER that leaves the table and
+// the new table without a unique key on the same NOT NULL columns.
+// newTableHasKey is false if the new table has no key that can identify
+// its rows at all.
+func (c *tableChange) errNoSharedKey(newTableHasKey bool) error {
+	keyColumns := strings.Join(c.table.KeyColumns, ", ")
+	newKey, advice := "none", fmt.Sprintf("Keep a unique key on (%s) in the ALTER", keyColumns)
+	if newTableHasKey {
+		newKeyColumns := strings.Join(c.newTable.KeyColumns, ", ")
+		newKey = fmt.Sprintf("%s (%s)", c.newTable.KeyName(), newKeyColumns)
+		advice += fmt.Sprintf(", or add one on (%s) to the table first", newKeyColumns)
+	}
+	return fmt.Errorf("the ALTER changes the key that identifies rows from %s (%s) to %s, but the tables have no unique key on the same NOT NULL columns to match rows by instead. %s",
+		c.table.KeyName(), keyColumns, newKey, advice)
+}
+
+// keyRank ranks key 0 if it is the key that identifies the rows of t,
+// and 1 otherwise.
+func keyRank(t *table.TableInfo, key table.UniqueKey) int {
+	if key.Name == t.KeyName() {
+		return 0
+	}
+	return 1
+}
+
+// columnMapping returns the mapping of the table's columns to the new
+// table's, with the column renames of the ALTER and the --column-expr
+// expressions applied.
+func (c *tableChange) columnMapping() (*table.ColumnMapping, error) {
+	mapping := table.NewColumnMapping(c.table, c.newTable, c.stmt.ColumnRenameMap())
+	if exprs := c.runner.migration.ColumnExpr; len(exprs) > 0 {
+		if err := mapping.SetExpressions(exprs); err != nil {
+			return nil, fmt.Errorf("--column-expr: %w", err)
+		}
+	}
+	return mapping, nil
+}
+
+// checkColumnExprs checks that the --column-expr expressions are valid SQL
+// over the table's columns, so that a typo fails the migration before the
+// copy starts rather than on its first chunk.
+func (c *tableChange) checkColumnExprs(ctx context.Context) error {
+	if len(c.runner.migration.ColumnExpr) == 0 {
+		return nil
+	}
+	mapping, err := c.columnMapping()
+	if err != nil {
+		return err
+	}
+	sourceColumns, _ := mapping.Columns()
+	rows, err := c.runner.db.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %s LIMIT 0", sourceColumns, c.table.QuotedTableName))
+	if err != nil {
+		return fmt.Errorf("--column-expr: %w", err)
+	}
+	return rows.Close()
+}
+
+// deferSecondaryIndexes drops the regular secondary indexes of the new
+// table, so that the copy doesn't have to maintain them. PRIMARY, UNIQUE,
+// FULLTEXT and SPATIAL indexes are kept, like statement.RemoveSecondaryIndexes
+// does for move and sync.
+func (c *tableChange) deferSecondaryIndexes(ctx context.Context) error {
+	createTable, err := c.showCreateNewTable(ctx)
+	if err != nil {
+		return err
+	}
+	ct, err := statement.ParseCreateTable(createTable)
+	if err != nil {
+		return err
+	}
+	var drops []string
+	args := []any{c.newTable.SchemaName, c.newTable.TableName}
+	for _, index := range ct.GetIndexes() {
+		if index.Type == "INDEX" {
+			drops = append(drops, "DROP INDEX %n")
+			args = append(args, index.Name)
+		}
+	}
+	if len(drops) == 0 {
+		return nil
+	}
+	if err := dbconn.Exec(ctx, c.runner.db, "ALTER TABLE %n.%n "+strings.Join(drops, ", "), args...); err != nil {
+		return err
+	}
+	c.deferredCreateTable = createTable
+	c.runner.logger.Info("deferred secondary indexes until after the copy",
+		"table", c.newTable.TableName,
+		"indexes", len(drops),
+	)
+	return c.newTable.SetInfo(ctx)
+}
+
+// restoreSecondaryIndexes adds the indexes that deferSecondaryIndexes
+// dropped back to the new table, with one INPLACE ALTER. It compares the
+// new table with its CREATE TABLE from before they were dropped, so it
+// only adds the ones that are still missing if it is resumed.
+func (c *tableChange) restoreSecondaryIndexes(ctx context.Context) error {
+	if c.deferredCreateTable == "" {
+		return nil
+	}
+	createTable, err := c.showCreateNewTable(ctx)
+	if err != nil {
+		return err
+	}
+	alter, err := statement.GetMissingSecondaryIndexes(c.deferredCreateTable, createTable, c.newTable.TableName)
+	if err != nil {
+		return err
+	}
+	if alter != "" {
+		clauses := strings.TrimPrefix(alter, fmt.Sprintf("ALTER TABLE `%s` ", c.newTable.TableName))
+		c.runner.logger.Info("restoring deferred secondary indexes",
+			"table", c.newTable.TableName,
+			"stmt", clauses,
+		)
+		if err := dbconn.Exec(ctx, c.runner.db, "ALTER TABLE %n.%n "+clauses+", ALGORITHM=INPLACE, LOCK=NONE",
+			c.newTable.SchemaName, c.newTable.TableName); err != nil {
+			return fmt.Errorf("could not restore the deferred secondary indexes of %s: %w", c.newTable.TableName, err)
+		}
+		return c.newTable.SetInfo(ctx)
+	}
+	return nil
+}
+
+func (c *tableChange) showCreateNewTable(ctx context.Context) (string, error) {
+	var name, createTable string
+	if err := c.runner.db.QueryRowContext(ctx, fmt.Sprintf("SHOW CREATE TABLE `%s`.%s",
+		c.newTable.SchemaName, c.newTable.QuotedTableName)).Scan(&name, &createTable); err != nil {
+		return "", err
+	}
+	return createTable, nil
+}
+
 func (c *tableChange) preserveAutoIncrement(ctx context.Context) error {
 	// Get AUTO_INCREMENT from the original table.
 	var originalAutoInc sql.NullInt64
@@ -116,8 +333,25 @@ func (c *tableChange) dropOldTable(ctx context.Context) error {
 	return dbconn.Exec(ctx, c.runner.db, "DROP TABLE IF EXISTS %n.%n", c.table.SchemaName, c.oldTableName())
 }
 
+// dropOldTableGradually drops the old table with dropTableGradually,
+// paced by the migration's throttler and target chunk time.
+func (c *tableChange) dropOldTableGradually(ctx context.Context) error {
+	old := table.NewTableInfo(c.runner.db, c.table.SchemaName, c.oldTableName())
+	old.DisableAnalyze = true
+	if err := old.SetInfo(ctx); err != nil {
+		return err
+	}
+	thr := c.runner.throttler
+	if thr == nil {
+		thr = &throttler.Noop{}
+	}
+	return dropTableGradually(ctx, c.runner.db, c.runner.dbConfig, old, thr, c.runner.Settings().TargetChunkTime, c.runner.logger)
+}
+
 func (c *tableChange) oldTableName() string {
-	if !c.runner.migration.SkipDropAfterCutover {
+	// The old table outlives the migration with a revert window too, so
+	// it needs a name that the next migration of the table won't drop.
+	if !c.runner.migration.SkipDropAfterCutover && c.runner.migration.RevertWindow == 0 {
 		return utils.OldTableName(c.table.TableName)
 	}
 	timestamp := c.runner.startTime.UTC().Format(utils.NameFormatTimestamp)
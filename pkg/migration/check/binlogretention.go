@@ -0,0 +1,48 @@
+package check
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/block/spirit/pkg/dbconn"
+)
+
+func init() {
+	registerCheck("binlogretention", binlogRetentionCheck, ScopePreflight|ScopeCopyRows)
+}
+
+// binlogRetentionCheck warns if the copy is expected to take longer than
+// the server keeps binary logs. Resuming from a checkpoint needs the binary
+// logs since it was written, so the longer the copy runs relative to the
+// retention, the more likely it is that an interrupted migration fails to
+// resume with ErrBinlogNotFound and has to start over. There is no estimate
+// of the copy time before the copy has started, so at preflight this only
+// logs the retention.
+//
+// Like diskSpaceCheck, it never fails the migration.
+func binlogRetentionCheck(ctx context.Context, r Resources, logger *slog.Logger) error {
+	retention, err := dbconn.BinlogRetention(ctx, r.DB)
+	if err != nil {
+		logger.Warn("could not read the binary log retention", "error", err)
+		return nil
+	}
+	if retention == 0 {
+		return nil // binary logs are never purged automatically
+	}
+	if r.CopyETA == 0 {
+		logger.Info("binary logs are purged automatically, the copy will be compared with the retention once it has an ETA",
+			"table", r.Table.TableName,
+			"binlog-retention", retention,
+		)
+		return nil
+	}
+	if expected := r.CopyElapsed + r.CopyETA; expected > retention {
+		logger.Warn("the copy is expected to take longer than the server keeps binary logs. If the migration is interrupted it may not be able to resume; consider increasing binlog_expire_logs_seconds.",
+			"table", r.Table.TableName,
+			"expected-copy-time", expected.Round(time.Second),
+			"binlog-retention", retention,
+		)
+	}
+	return nil
+}
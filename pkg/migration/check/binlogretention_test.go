@@ -0,0 +1,45 @@
+package check
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/block/spirit/pkg/dbconn"
+	"github.com/block/spirit/pkg/table"
+	"github.com/block/spirit/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBinlogRetention(t *testing.T) {
+	tt := testutils.NewTestTable(t, "binlogretentiont1", `CREATE TABLE binlogretentiont1 (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY
+	)`)
+	retention, err := dbconn.BinlogRetention(t.Context(), tt.DB)
+	require.NoError(t, err)
+	if retention == 0 {
+		t.Skip("binary logs are never purged on this server")
+	}
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	r := Resources{
+		DB:    tt.DB,
+		Table: &table.TableInfo{SchemaName: "test", TableName: "binlogretentiont1"},
+	}
+
+	// At preflight there is no ETA yet.
+	require.NoError(t, binlogRetentionCheck(t.Context(), r, logger))
+	require.NotContains(t, logs.String(), "level=WARN")
+
+	// A copy that finishes within the retention is fine.
+	r.CopyElapsed = time.Minute
+	r.CopyETA = time.Minute
+	require.NoError(t, binlogRetentionCheck(t.Context(), r, logger))
+	require.NotContains(t, logs.String(), "level=WARN")
+
+	// A copy that doesn't is warned about, but doesn't fail.
+	r.CopyETA = retention
+	require.NoError(t, binlogRetentionCheck(t.Context(), r, logger))
+	require.Contains(t, logs.String(), "the copy is expected to take longer than the server keeps binary logs")
+}
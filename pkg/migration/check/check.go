@@ -6,6 +6,8 @@ import (
 	"context"
 	"database/sql"
 	"log/slog"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -24,6 +26,8 @@ const (
 	ScopeCutover     ScopeFlag = 1 << 3
 	ScopePostCutover ScopeFlag = 1 << 4
 	ScopeTesting     ScopeFlag = 1 << 5
+	// ScopeCopyRows checks are re-run periodically while rows are copied.
+	ScopeCopyRows ScopeFlag = 1 << 6
 )
 
 type Resources struct {
@@ -47,6 +51,16 @@ type Resources struct {
 	// change source. The configuration check uses this to additionally
 	// validate gtid_mode and enforce_gtid_consistency on the source.
 	GTID bool
+	// ForeignKeys, when true, opts the migration into support for tables
+	// with foreign keys. The foreign key checks then validate that the
+	// constraints can be handled, rather than rejecting them.
+	ForeignKeys bool
+	// CopyElapsed and CopyETA are how long the copy has been running, and
+	// the copier's latest estimate of how much longer it will take. They are
+	// only set for the ScopeCopyRows checks, and CopyETA is zero until the
+	// copier has made an estimate.
+	CopyElapsed time.Duration
+	CopyETA     time.Duration
 }
 
 type check struct {
@@ -82,3 +96,27 @@ func RunChecks(ctx context.Context, r Resources, logger *slog.Logger, scope Scop
 	}
 	return nil
 }
+
+// Result is the outcome of a single check.
+type Result struct {
+	Name string
+	Err  error // nil if the check passed
+}
+
+// RunAllChecks runs all checks that are registered for the given scope.
+// Unlike RunChecks it does not stop at the first failure, so that every
+// problem can be reported at once (e.g. by migrate --plan). The results are
+// sorted by name.
+func RunAllChecks(ctx context.Context, r Resources, logger *slog.Logger, scope ScopeFlag) []Result {
+	var results []Result
+	for name, check := range checks {
+		if check.scope&scope == 0 {
+			continue
+		}
+		results = append(results, Result{Name: name, Err: check.callback(ctx, r, logger)})
+	}
+	slices.SortFunc(results, func(a, b Result) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return results
+}
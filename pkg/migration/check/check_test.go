@@ -2,6 +2,7 @@ package check
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"testing"
 
@@ -31,3 +32,36 @@ func TestCheckAPI(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, "newval", testVal)
 }
+
+func TestRunAllChecks(t *testing.T) {
+	failing := func(_ context.Context, _ Resources, _ *slog.Logger) error {
+		return errors.New("failed")
+	}
+	passing := func(_ context.Context, _ Resources, _ *slog.Logger) error {
+		return nil
+	}
+	registerCheck("zzz-failing", failing, ScopeTesting)
+	registerCheck("aaa-passing", passing, ScopeTesting)
+	t.Cleanup(func() {
+		lock.Lock()
+		defer lock.Unlock()
+		delete(checks, "zzz-failing")
+		delete(checks, "aaa-passing")
+	})
+
+	// RunChecks stops at the first failure, RunAllChecks doesn't.
+	require.Error(t, RunChecks(t.Context(), Resources{}, slog.Default(), ScopeTesting))
+	var names []string
+	for _, res := range RunAllChecks(t.Context(), Resources{}, slog.Default(), ScopeTesting) {
+		names = append(names, res.Name)
+		switch res.Name {
+		case "aaa-passing":
+			require.NoError(t, res.Err)
+		case "zzz-failing":
+			require.EqualError(t, res.Err, "failed")
+		}
+	}
+	require.IsIncreasing(t, names)
+	require.Contains(t, names, "aaa-passing")
+	require.Contains(t, names, "zzz-failing")
+}
@@ -0,0 +1,66 @@
+package check
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/block/spirit/pkg/dbconn"
+	"github.com/block/spirit/pkg/utils"
+)
+
+func init() {
+	registerCheck("diskspace", diskSpacePreflightCheck, ScopePreflight)
+	registerCheck("diskspace_copy_rows", diskSpaceCheck, ScopeCopyRows)
+}
+
+// diskSpaceCheck warns if the server does not appear to have the free disk
+// space to finish copying the table. The _new table ends up about as large
+// as the table itself, so the bytes still needed are the size of the table
+// less what has already been copied. Free space is only known when the
+// server reports it (Aurora) or Spirit runs on the same host as the server.
+//
+// It never fails the migration: the estimate is rough, and the server may
+// be able to reclaim space, so it is up to the operator to act on it.
+func diskSpaceCheck(ctx context.Context, r Resources, logger *slog.Logger) error {
+	return checkDiskSpace(ctx, r, logger, false)
+}
+
+// diskSpacePreflightCheck is diskSpaceCheck, but it also warns when the free
+// space can't be measured, so that the operator knows to check it. The
+// periodic re-checks while copying stay quiet about it.
+func diskSpacePreflightCheck(ctx context.Context, r Resources, logger *slog.Logger) error {
+	return checkDiskSpace(ctx, r, logger, true)
+}
+
+func checkDiskSpace(ctx context.Context, r Resources, logger *slog.Logger, warnUnknown bool) error {
+	tableSize, err := dbconn.TableDiskSize(ctx, r.DB, r.Table.SchemaName, r.Table.TableName)
+	if err != nil {
+		logger.Warn("could not estimate the disk space needed for the copy", "table", r.Table.TableName, "error", err)
+		return nil
+	}
+	newSize, err := dbconn.TableDiskSize(ctx, r.DB, r.Table.SchemaName, utils.NewTableName(r.Table.TableName))
+	if err != nil {
+		logger.Warn("could not estimate the disk space needed for the copy", "table", r.Table.TableName, "error", err)
+		return nil
+	}
+	needed := tableSize - min(newSize, tableSize)
+	free, ok, err := dbconn.FreeDiskSpace(ctx, r.DB)
+	if err != nil || !ok {
+		if warnUnknown {
+			logger.Warn("could not check that the server has enough free disk space to finish copying the table. It can only be measured on Aurora, or when spirit runs on the same host as the server.",
+				"table", r.Table.TableName,
+				"estimated-space-needed", utils.FormatBytes(needed),
+				"error", err,
+			)
+		}
+		return nil
+	}
+	if free < needed {
+		logger.Warn("the server may not have enough free disk space to finish copying the table. Note that the binary logs written by the copy need space too.",
+			"table", r.Table.TableName,
+			"estimated-space-needed", utils.FormatBytes(needed),
+			"free-space", utils.FormatBytes(free),
+		)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package check
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/block/spirit/pkg/dbconn"
+	"github.com/block/spirit/pkg/table"
+	"github.com/block/spirit/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskSpace(t *testing.T) {
+	tt := testutils.NewTestTable(t, "diskspacet1", `CREATE TABLE diskspacet1 (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		b varchar(255) NOT NULL
+	)`)
+	r := Resources{
+		DB:    tt.DB,
+		Table: &table.TableInfo{SchemaName: "test", TableName: "diskspacet1"},
+	}
+	// The check only ever warns.
+	var logs bytes.Buffer
+	require.NoError(t, diskSpaceCheck(t.Context(), r, slog.New(slog.NewTextHandler(&logs, nil))))
+	require.NotContains(t, logs.String(), "could not estimate")
+	require.NotContains(t, logs.String(), "could not check")
+
+	// Only the first check warns when free space is unknown, the periodic
+	// re-checks above stay quiet about it.
+	logs.Reset()
+	require.NoError(t, diskSpacePreflightCheck(t.Context(), r, slog.New(slog.NewTextHandler(&logs, nil))))
+	require.NotContains(t, logs.String(), "could not estimate")
+	free, ok, err := dbconn.FreeDiskSpace(t.Context(), tt.DB)
+	require.NoError(t, err)
+	if !ok {
+		require.Contains(t, logs.String(), "could not check")
+	} else if free > 1<<30 {
+		require.Empty(t, logs.String())
+	}
+}
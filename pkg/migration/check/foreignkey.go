@@ -3,8 +3,11 @@ package check
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"strings"
 
+	"github.com/block/spirit/pkg/table"
 	"github.com/block/spirit/pkg/utils"
 	"github.com/pingcap/tidb/pkg/parser/ast"
 	_ "github.com/pingcap/tidb/pkg/parser/test_driver"
@@ -15,10 +18,16 @@ func init() {
 	registerCheck("hasforeignkeys", hasForeignKeysCheck, ScopePreflight)
 }
 
-// The spirit OSC algorithm does not support foreign key constraints.
-// That's either pre-existing foreign keys, or adding new ones.
+// By default, the spirit OSC algorithm does not support foreign key
+// constraints. That's either pre-existing foreign keys, or adding new ones.
+// With --foreign-keys, the constraints on the table are recreated on the new
+// table, and the constraints that reference it are repointed at the cutover.
+// These checks then only reject what that can't handle.
 
 func hasForeignKeysCheck(ctx context.Context, r Resources, logger *slog.Logger) error {
+	if r.ForeignKeys {
+		return referencingForeignKeysCheck(ctx, r)
+	}
 	sql := `SELECT * FROM information_schema.referential_constraints WHERE 
 	(constraint_schema=? AND table_name=?)
 	or (constraint_schema=? AND referenced_table_name=?)`
@@ -28,7 +37,7 @@ func hasForeignKeysCheck(ctx context.Context, r Resources, logger *slog.Logger)
 	}
 	defer utils.CloseAndLog(rows)
 	if rows.Next() {
-		return errors.New("tables with existing foreign key constraints are not supported")
+		return errors.New("tables with existing foreign key constraints are not supported without --foreign-keys")
 	}
 	if rows.Err() != nil {
 		return rows.Err()
@@ -36,22 +45,62 @@ func hasForeignKeysCheck(ctx context.Context, r Resources, logger *slog.Logger)
 	return nil
 }
 
+// referencingForeignKeysCheck checks that the tables that reference the
+// table are in the same schema, since they have to be locked along with it
+// at the cutover.
+func referencingForeignKeysCheck(ctx context.Context, r Resources) error {
+	fks, err := table.LoadReferencingForeignKeys(ctx, r.DB, r.Table.SchemaName, r.Table.TableName)
+	if err != nil {
+		return err
+	}
+	for _, fk := range fks {
+		if fk.Schema != r.Table.SchemaName {
+			return fmt.Errorf("foreign key %s on %s.%s references the table from another schema, which is not supported",
+				fk.Name, fk.Schema, fk.Table)
+		}
+	}
+	return nil
+}
+
 func addForeignKeyCheck(ctx context.Context, r Resources, logger *slog.Logger) error {
 	alterStmt, ok := (*r.Statement.StmtNode).(*ast.AlterTableStmt)
 	if !ok {
 		return errors.New("not a valid alter table statement")
 	}
 	for _, spec := range alterStmt.Specs {
-		if spec.Constraint != nil && spec.Constraint.Refer != nil {
-			return errors.New("adding foreign key constraints is not supported")
+		constraints := spec.NewConstraints
+		if spec.Constraint != nil {
+			constraints = append(constraints, spec.Constraint)
 		}
-		if spec.NewConstraints != nil {
-			for _, constraint := range spec.NewConstraints {
-				if constraint.Refer != nil {
-					return errors.New("adding foreign key constraints is not supported")
-				}
+		for _, constraint := range constraints {
+			if constraint.Refer == nil {
+				continue
+			}
+			if !r.ForeignKeys {
+				return errors.New("adding foreign key constraints is not supported without --foreign-keys")
 			}
+			// The new table would reference the original table, which is
+			// renamed away at the cutover.
+			if refersTo(constraint.Refer.Table, r) {
+				return errors.New("adding a foreign key that references the table itself is not supported")
+			}
+		}
+		if spec.Tp == ast.AlterTableDropForeignKey && r.ForeignKeys {
+			return errors.New("dropping a foreign key only changes metadata, so it doesn't need to be run by spirit: run it directly with ALTER TABLE")
 		}
 	}
 	return nil // no problems
 }
+
+// refersTo returns true if ref is the table that is being altered. A name
+// without a schema is in the schema of the table.
+func refersTo(ref *ast.TableName, r Resources) bool {
+	if !strings.EqualFold(ref.Name.String(), r.Statement.Table) {
+		return false
+	}
+	schema := r.Statement.Schema
+	if schema == "" && r.Table != nil {
+		schema = r.Table.SchemaName
+	}
+	return ref.Schema.String() == "" || schema == "" || strings.EqualFold(ref.Schema.String(), schema)
+}
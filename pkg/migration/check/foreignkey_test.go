@@ -23,6 +23,29 @@ func TestAddForeignKey(t *testing.T) {
 	r.Statement = statement.MustNew("ALTER TABLE t1 DROP COLUMN foo")[0]
 	err = addForeignKeyCheck(t.Context(), r, slog.Default())
 	require.NoError(t, err) // regular DDL
+
+	r.Statement = statement.MustNew("ALTER TABLE t1 DROP FOREIGN KEY fk_customer")[0]
+	err = addForeignKeyCheck(t.Context(), r, slog.Default())
+	require.NoError(t, err) // fails hasforeignkeys instead
+
+	// With --foreign-keys, adding one is supported unless it references the table itself.
+	r.ForeignKeys = true
+	r.Table = &table.TableInfo{SchemaName: "test", TableName: "t1"}
+	r.Statement = statement.MustNew("ALTER TABLE t1 ADD FOREIGN KEY (customer_id) REFERENCES customers (id)")[0]
+	err = addForeignKeyCheck(t.Context(), r, slog.Default())
+	require.NoError(t, err)
+
+	r.Statement = statement.MustNew("ALTER TABLE t1 ADD COLUMN parent_id INT, ADD CONSTRAINT fk_parent FOREIGN KEY (parent_id) REFERENCES test.t1 (id)")[0]
+	err = addForeignKeyCheck(t.Context(), r, slog.Default())
+	require.ErrorContains(t, err, "references the table itself")
+
+	r.Statement = statement.MustNew("ALTER TABLE t1 ADD COLUMN parent_id INT, ADD CONSTRAINT fk_parent FOREIGN KEY (parent_id) REFERENCES other.t1 (id)")[0]
+	err = addForeignKeyCheck(t.Context(), r, slog.Default())
+	require.NoError(t, err) // references a table with the same name in another schema
+
+	r.Statement = statement.MustNew("ALTER TABLE t1 DROP FOREIGN KEY fk_customer")[0]
+	err = addForeignKeyCheck(t.Context(), r, slog.Default())
+	require.ErrorContains(t, err, "run it directly")
 }
 
 func TestHasForeignKey(t *testing.T) {
@@ -66,6 +89,29 @@ func TestHasForeignKey(t *testing.T) {
 	err = hasForeignKeysCheck(t.Context(), r, slog.Default())
 	require.Error(t, err) // already has foreign keys.
 
+	// Both are supported with --foreign-keys.
+	r.ForeignKeys = true
+	err = hasForeignKeysCheck(t.Context(), r, slog.Default())
+	require.NoError(t, err)
+	r.Table.TableName = "customers"
+	err = hasForeignKeysCheck(t.Context(), r, slog.Default())
+	require.NoError(t, err)
+
+	// Unless customers is referenced from another schema.
+	_, err = db.ExecContext(t.Context(), `CREATE DATABASE IF NOT EXISTS test_fk_other`)
+	require.NoError(t, err)
+	_, err = db.ExecContext(t.Context(), `CREATE TABLE IF NOT EXISTS test_fk_other.customer_notes (
+		id INT NOT NULL PRIMARY KEY,
+		customer_id INT NOT NULL,
+		CONSTRAINT fk_customer_notes FOREIGN KEY (customer_id) REFERENCES test.customers (id)
+	)`)
+	require.NoError(t, err)
+	err = hasForeignKeysCheck(t.Context(), r, slog.Default())
+	require.ErrorContains(t, err, "another schema")
+	_, err = db.ExecContext(t.Context(), `DROP DATABASE test_fk_other`)
+	require.NoError(t, err)
+	r.ForeignKeys = false
+
 	_, err = db.ExecContext(t.Context(), `drop table if exists customer_contacts`)
 	require.NoError(t, err)
 	r.Table.TableName = "customers"
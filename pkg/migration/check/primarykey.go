@@ -3,7 +3,10 @@ package check
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 
 	"github.com/pingcap/tidb/pkg/parser/ast"
 	_ "github.com/pingcap/tidb/pkg/parser/test_driver"
@@ -13,15 +16,63 @@ func init() {
 	registerCheck("primarykey", primaryKeyCheck, ScopePreflight)
 }
 
+// primaryKeyCheck rejects an ALTER that drops the PRIMARY KEY when the
+// table and the new table would share no unique key to match rows by,
+// before the new table is created. A key is shared if the ALTER keeps a
+// unique key of the table, or adds a PRIMARY or unique key on the same
+// columns as one of the table's. Whether the columns stay NOT NULL is left
+// for the migration to find out once the new table exists.
 func primaryKeyCheck(ctx context.Context, r Resources, logger *slog.Logger) error {
 	alterStmt, ok := (*r.Statement.StmtNode).(*ast.AlterTableStmt)
 	if !ok {
 		return errors.New("not a valid alter table statement")
 	}
+	var dropsPrimaryKey bool
+	var added [][]string
+	dropped := make(map[string]bool)
 	for _, spec := range alterStmt.Specs {
-		if spec.Tp == ast.AlterTableDropPrimaryKey {
-			return errors.New("dropping primary key is not supported")
+		switch spec.Tp { //nolint:exhaustive
+		case ast.AlterTableDropPrimaryKey:
+			dropsPrimaryKey = true
+		case ast.AlterTableDropIndex:
+			dropped[strings.ToLower(spec.Name)] = true
+		case ast.AlterTableAddConstraint:
+			switch spec.Constraint.Tp { //nolint:exhaustive
+			case ast.ConstraintPrimaryKey, ast.ConstraintUniq, ast.ConstraintUniqKey, ast.ConstraintUniqIndex:
+				var columns []string
+				for _, key := range spec.Constraint.Keys {
+					if key.Column != nil {
+						columns = append(columns, key.Column.Name.L)
+					}
+				}
+				added = append(added, columns)
+			}
 		}
 	}
-	return nil // no problems
+	if !dropsPrimaryKey || r.Table == nil {
+		return nil
+	}
+	keys, err := r.Table.UniqueKeys(ctx)
+	if err != nil {
+		return err
+	}
+	var primaryKey []string
+	for _, key := range keys {
+		if key.Name == "PRIMARY" {
+			primaryKey = key.Columns
+		} else if !dropped[strings.ToLower(key.Name)] {
+			return nil
+		}
+		columns := make([]string, len(key.Columns))
+		for i, col := range key.Columns {
+			columns[i] = strings.ToLower(col)
+		}
+		if slices.ContainsFunc(added, func(addedColumns []string) bool {
+			return slices.Equal(addedColumns, columns)
+		}) {
+			return nil
+		}
+	}
+	return fmt.Errorf("the ALTER drops the PRIMARY KEY of %s, but the table and the new table would have no unique key on the same NOT NULL columns to match rows by. "+
+		"Keep a unique key on (%s) in the ALTER, or add one to the table first", r.Table.TableName, strings.Join(primaryKey, ", "))
 }
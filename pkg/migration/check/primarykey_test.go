@@ -5,17 +5,38 @@ import (
 	"testing"
 
 	"github.com/block/spirit/pkg/statement"
+	"github.com/block/spirit/pkg/table"
+	"github.com/block/spirit/pkg/testutils"
 	"github.com/stretchr/testify/require"
 )
 
 func TestPrimaryKey(t *testing.T) {
+	tt := testutils.NewTestTable(t, "primarykeyt1", `CREATE TABLE primarykeyt1 (
+		id int NOT NULL,
+		a int NOT NULL,
+		b int,
+		PRIMARY KEY (id),
+		UNIQUE KEY a (a),
+		UNIQUE KEY b (b)
+	)`)
 	r := Resources{
-		Statement: statement.MustNew("ALTER TABLE t1 DROP PRIMARY KEY, ADD PRIMARY KEY (anothercol)")[0],
+		Table: table.NewTableInfo(tt.DB, "test", "primarykeyt1"),
+	}
+	for alter, ok := range map[string]bool{
+		"ADD INDEX (b)":                                         true,
+		"DROP PRIMARY KEY":                                      true,  // a still identifies rows
+		"DROP PRIMARY KEY, DROP INDEX a":                        false, // b is nullable
+		"DROP PRIMARY KEY, DROP INDEX a, ADD UNIQUE (id)":       true,
+		"DROP PRIMARY KEY, DROP INDEX a, ADD PRIMARY KEY (ID)":  true,
+		"DROP PRIMARY KEY, DROP INDEX a, ADD PRIMARY KEY (a)":   true,
+		"DROP PRIMARY KEY, DROP INDEX `A`, ADD PRIMARY KEY (b)": false,
+	} {
+		r.Statement = statement.MustNew("ALTER TABLE primarykeyt1 " + alter)[0]
+		err := primaryKeyCheck(t.Context(), r, slog.Default())
+		if ok {
+			require.NoError(t, err, alter)
+		} else {
+			require.ErrorContains(t, err, "Keep a unique key on (id)", alter)
+		}
 	}
-	err := primaryKeyCheck(t.Context(), r, slog.Default())
-	require.Error(t, err) // drop primary key
-
-	r.Statement = statement.MustNew("ALTER TABLE t1 ADD INDEX (anothercol)")[0]
-	err = primaryKeyCheck(t.Context(), r, slog.Default())
-	require.NoError(t, err) // safe modification
 }
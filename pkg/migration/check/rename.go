@@ -57,7 +57,7 @@ func renameCheck(ctx context.Context, r Resources, logger *slog.Logger) error {
 		if spec.Tp == ast.AlterTableRenameColumn {
 			if spec.OldColumnName != nil {
 				if _, isPK := pkColumns[spec.OldColumnName.Name.L]; isPK {
-					return fmt.Errorf("renaming primary key column %q is not supported", spec.OldColumnName.Name.O)
+					return fmt.Errorf("renaming primary key column %q is not supported: rows are matched between the tables by the names of its columns", spec.OldColumnName.Name.O)
 				}
 				// A case-only rename (foo → FOO) is not a rename for data-mapping
 				// purposes, since identifiers are case-insensitive.
@@ -75,7 +75,7 @@ func renameCheck(ctx context.Context, r Resources, logger *slog.Logger) error {
 				newName := spec.NewColumns[0].Name.Name.L
 				if oldName != newName {
 					if _, isPK := pkColumns[oldName]; isPK {
-						return fmt.Errorf("renaming primary key column %q is not supported", spec.OldColumnName.Name.O)
+						return fmt.Errorf("renaming primary key column %q is not supported: rows are matched between the tables by the names of its columns", spec.OldColumnName.Name.O)
 					}
 					renamedFrom[oldName] = newName
 					renamedTo[newName] = oldName
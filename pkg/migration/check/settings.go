@@ -13,19 +13,26 @@ func init() {
 
 // check the settings used to initialize spirit.
 func settingsCheck(ctx context.Context, r Resources, logger *slog.Logger) error {
+	return ValidateSettings(r.Threads, r.TargetChunkTime, r.ReplicaMaxLag)
+}
+
+// ValidateSettings checks the settings that can also be changed while a
+// migration runs (see migration.Runner.Reconfigure), so that a change
+// can't take them outside the range allowed at startup.
+func ValidateSettings(threads int, targetChunkTime, replicaMaxLag time.Duration) error {
 	// Threads must be in the range of 1-64
-	if r.Threads < 1 || r.Threads > 64 {
+	if threads < 1 || threads > 64 {
 		return errors.New("--threads must be in the range of 1-64")
 	}
 	// TargetChunkTime must be in the range of 100ms-5s
 	// Note to future self: if you increase this, make sure you also extend
 	// the timeouts for locks in dbconn/dbconn.go, otherwise you will encounter problems.
 	// See: https://github.com/block/spirit/issues/96 for an example.
-	if r.TargetChunkTime < 100*time.Millisecond || r.TargetChunkTime > 5*time.Second {
+	if targetChunkTime < 100*time.Millisecond || targetChunkTime > 5*time.Second {
 		return errors.New("--target-chunk-time must be in the range of 100ms-5s")
 	}
 	// ReplicaMaxLag must be in the range of 10s-4hr
-	if r.ReplicaMaxLag < 10*time.Second || r.ReplicaMaxLag > time.Hour*4 {
+	if replicaMaxLag < 10*time.Second || replicaMaxLag > time.Hour*4 {
 		return errors.New("--replica-max-lag must be in the range of 10s-4hr")
 	}
 	return nil
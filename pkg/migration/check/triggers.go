@@ -2,30 +2,143 @@ package check
 
 import (
 	"context"
-	"errors"
+	"database/sql/driver"
+	"fmt"
 	"log/slog"
+	"regexp"
+	"strings"
 
+	"github.com/block/spirit/pkg/dbconn/sqlescape"
+	"github.com/block/spirit/pkg/table"
 	"github.com/block/spirit/pkg/utils"
+	"github.com/pingcap/tidb/pkg/parser/ast"
 )
 
 func init() {
-	registerCheck("hastriggers", hasTriggersCheck, ScopePreflight)
+	registerCheck("triggers", triggersCheck, ScopePreflight)
+	registerCheck("trigger_definers", triggerDefinersCheck, ScopePostSetup)
 }
 
-// hasTriggersCheck check if table has triggers associated with it, which is not supported
-func hasTriggersCheck(ctx context.Context, r Resources, logger *slog.Logger) error {
-	sql := `SELECT * FROM information_schema.triggers WHERE 
-	(event_object_schema=? AND event_object_table=?)`
-	rows, err := r.DB.QueryContext(ctx, sql, r.Table.SchemaName, r.Table.TableName)
+// triggerColumnRegexp matches the columns a trigger body refers to as
+// NEW.col or OLD.col.
+var triggerColumnRegexp = regexp.MustCompile("(?i)\\b(?:NEW|OLD)\\s*\\.\\s*(?:`((?:[^`]|``)+)`|([0-9a-z_$]+))")
+
+// triggersCheck checks that the triggers on the table can be moved to the
+// new table at cutover. The copy doesn't need them: the binary log records
+// the rows as the triggers left them, and their side effects on other
+// tables as changes to those tables. So they are only moved under the
+// cutover lock, once all changes have been applied.
+//
+// A trigger that refers to a column the ALTER drops or renames can't be
+// created on the new table, so that is an error now rather than at
+// cutover.
+func triggersCheck(ctx context.Context, r Resources, logger *slog.Logger) error {
+	triggers, err := table.LoadTriggers(ctx, r.DB, r.Table.SchemaName, r.Table.TableName)
+	if err != nil {
+		return err
+	}
+	if len(triggers) == 0 {
+		return nil
+	}
+	removed := make(map[string]bool)
+	if alterStmt, ok := (*r.Statement.StmtNode).(*ast.AlterTableStmt); ok {
+		for _, spec := range alterStmt.Specs {
+			if spec.Tp == ast.AlterTableDropColumn {
+				removed[spec.OldColumnName.Name.L] = true
+			}
+		}
+	}
+	for from := range r.Statement.ColumnRenameMap() {
+		removed[strings.ToLower(from)] = true
+	}
+	names := make([]string, 0, len(triggers))
+	for _, trg := range triggers {
+		for _, col := range triggerColumns(trg.Statement) {
+			if removed[strings.ToLower(col)] {
+				return fmt.Errorf("trigger %s refers to column %s, which the ALTER drops or renames. Change the trigger first", trg.Name, col)
+			}
+		}
+		names = append(names, trg.Name)
+	}
+	logger.Info("the triggers on the table will be moved to the new table at cutover", "table", r.Table.TableName, "triggers", names)
+	return nil
+}
+
+// triggerDefinersCheck checks that the triggers whose definer isn't the
+// current user can be created on the new table with their definer, which
+// needs SET_USER_ID (or SET_ANY_DEFINER from 8.2). The grant may come from
+// a role, so rather than read the grants it creates each of them on the
+// new table under another name, and drops it again. A trigger that can't
+// be moved would otherwise only fail under the cutover lock.
+func triggerDefinersCheck(ctx context.Context, r Resources, logger *slog.Logger) error {
+	if r.Table == nil {
+		return nil
+	}
+	triggers, err := table.LoadTriggers(ctx, r.DB, r.Table.SchemaName, r.Table.TableName)
 	if err != nil {
 		return err
 	}
-	defer utils.CloseAndLog(rows)
-	if rows.Next() {
-		return errors.New("tables with triggers associated are not supported")
+	if len(triggers) == 0 {
+		return nil
+	}
+	// The session variables are set on one connection, and restored
+	// before it goes back to the pool.
+	conn, err := r.DB.Conn(ctx)
+	if err != nil {
+		return err
 	}
-	if rows.Err() != nil {
-		return rows.Err()
+	defer conn.Close()
+	var currentUser string
+	if err := conn.QueryRowContext(ctx, "SELECT CURRENT_USER()").Scan(&currentUser); err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, "SET @spirit_sql_mode = @@session.sql_mode, @spirit_collation_connection = @@session.collation_connection"); err != nil {
+		return err
+	}
+	defer func() {
+		if _, err := conn.ExecContext(ctx, "SET SESSION sql_mode = @spirit_sql_mode, collation_connection = @spirit_collation_connection"); err != nil {
+			// The connection can't be reused with the trigger's session.
+			_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+	}()
+	newTableName := utils.NewTableName(r.Table.TableName)
+	for _, trg := range triggers {
+		if trg.Definer == currentUser {
+			continue
+		}
+		trial := trg
+		trial.Name = utils.AuxTableName(trg.Name, "_chk")
+		stmts := []string{
+			sqlescape.MustEscapeSQL("DROP TRIGGER IF EXISTS %n", trial.Name),
+			sqlescape.MustEscapeSQL("SET SESSION sql_mode = %?, collation_connection = %?", trg.SQLMode, trg.Collation),
+			trial.CreateStatement(newTableName),
+			sqlescape.MustEscapeSQL("DROP TRIGGER %n", trial.Name),
+		}
+		for _, stmt := range stmts {
+			if _, err := conn.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("trigger %s has the definer %s, and can't be moved to the new table as %s: %w. Grant SET_USER_ID (or SET_ANY_DEFINER from MySQL 8.2), or recreate the trigger with this user as its definer",
+					trg.Name, trg.Definer, currentUser, err)
+			}
+		}
+		logger.Info("trigger has a different definer, and can be moved to the new table with it",
+			"trigger", trg.Name,
+			"definer", trg.Definer,
+			"user", currentUser,
+		)
 	}
 	return nil
 }
+
+// triggerColumns returns the columns that a trigger body refers to as
+// NEW.col or OLD.col.
+func triggerColumns(body string) []string {
+	var cols []string
+	for _, match := range triggerColumnRegexp.FindAllStringSubmatch(body, -1) {
+		if match[1] != "" {
+			cols = append(cols, strings.ReplaceAll(match[1], "``", "`"))
+		} else {
+			cols = append(cols, match[2])
+		}
+	}
+	return cols
+}
@@ -2,16 +2,19 @@ package check
 
 import (
 	"database/sql"
+	"fmt"
 	"log/slog"
 	"testing"
 
 	"github.com/block/spirit/pkg/statement"
 	"github.com/block/spirit/pkg/table"
 	"github.com/block/spirit/pkg/testutils"
+	"github.com/block/spirit/pkg/utils"
+	"github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/require"
 )
 
-func TestHasTriggers(t *testing.T) {
+func TestTriggers(t *testing.T) {
 	db, err := sql.Open("mysql", testutils.DSN())
 	require.NoError(t, err)
 
@@ -36,12 +39,79 @@ func TestHasTriggers(t *testing.T) {
 		Table:     &table.TableInfo{SchemaName: "test", TableName: "account"},
 		Statement: statement.MustNew("ALTER TABLE account Engine=innodb")[0],
 	}
+	// Triggers are moved to the new table at cutover.
+	err = triggersCheck(t.Context(), r, slog.Default())
+	require.NoError(t, err)
 
-	err = hasTriggersCheck(t.Context(), r, slog.Default())
-	require.ErrorContains(t, err, "tables with triggers associated are not supported") // already has a trigger associated.
+	// Unless the ALTER drops or renames a column they refer to.
+	r.Statement = statement.MustNew("ALTER TABLE account DROP COLUMN amount")[0]
+	err = triggersCheck(t.Context(), r, slog.Default())
+	require.ErrorContains(t, err, "trigger ins_sum refers to column amount, which the ALTER drops or renames")
+	r.Statement = statement.MustNew("ALTER TABLE account RENAME COLUMN amount TO amt")[0]
+	err = triggersCheck(t.Context(), r, slog.Default())
+	require.ErrorContains(t, err, "trigger ins_sum refers to column amount, which the ALTER drops or renames")
+	r.Statement = statement.MustNew("ALTER TABLE account ADD COLUMN amount2 int")[0]
+	err = triggersCheck(t.Context(), r, slog.Default())
+	require.NoError(t, err)
 
 	_, err = db.ExecContext(t.Context(), `drop trigger if exists ins_sum`)
 	require.NoError(t, err)
-	err = hasTriggersCheck(t.Context(), r, slog.Default())
-	require.NoError(t, err) // no longer said to have trigger associated.
+	r.Statement = statement.MustNew("ALTER TABLE account DROP COLUMN amount")[0]
+	err = triggersCheck(t.Context(), r, slog.Default())
+	require.NoError(t, err) // no longer has a trigger associated.
+}
+
+func TestTriggerDefiners(t *testing.T) {
+	config, err := mysql.ParseDSN(testutils.DSN())
+	require.NoError(t, err)
+	config.User = "root" // needs grant privilege
+	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s)/%s", config.User, config.Passwd, config.Addr, config.DBName))
+	require.NoError(t, err)
+	defer utils.CloseAndLog(db)
+
+	_, err = db.ExecContext(t.Context(), `drop table if exists trgdefiner, _trgdefiner_new`)
+	require.NoError(t, err)
+	_, err = db.ExecContext(t.Context(), `CREATE TABLE trgdefiner (id INT NOT NULL PRIMARY KEY, a INT, b INT)`)
+	require.NoError(t, err)
+	_, err = db.ExecContext(t.Context(), `CREATE TABLE _trgdefiner_new (id INT NOT NULL PRIMARY KEY, a INT, b INT)`)
+	require.NoError(t, err)
+	_, err = db.ExecContext(t.Context(), "CREATE DEFINER=`root`@`localhost` TRIGGER trgdefiner_bi BEFORE INSERT ON trgdefiner FOR EACH ROW SET NEW.b = NEW.a * 2")
+	require.NoError(t, err)
+	_, err = db.ExecContext(t.Context(), "DROP USER IF EXISTS testtrgdefiner")
+	require.NoError(t, err)
+	_, err = db.ExecContext(t.Context(), "CREATE USER testtrgdefiner")
+	require.NoError(t, err)
+	_, err = db.ExecContext(t.Context(), "GRANT ALL ON test.* TO testtrgdefiner")
+	require.NoError(t, err)
+
+	config.User = "testtrgdefiner"
+	config.Passwd = ""
+	lowPrivDB, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s)/%s", config.User, config.Passwd, config.Addr, config.DBName))
+	require.NoError(t, err)
+	r := Resources{
+		DB:    lowPrivDB,
+		Table: &table.TableInfo{SchemaName: "test", TableName: "trgdefiner"},
+	}
+	// The trigger can't be created with its definer, so it can't be moved.
+	err = triggerDefinersCheck(t.Context(), r, slog.Default())
+	require.ErrorContains(t, err, "trigger trgdefiner_bi has the definer root@localhost, and can't be moved to the new table")
+	require.NoError(t, lowPrivDB.Close())
+
+	// Once the user can, the trial trigger is created and dropped again.
+	_, err = db.ExecContext(t.Context(), "GRANT SET_USER_ID ON *.* TO testtrgdefiner")
+	require.NoError(t, err)
+	lowPrivDB, err = sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s)/%s", config.User, config.Passwd, config.Addr, config.DBName))
+	require.NoError(t, err)
+	defer utils.CloseAndLog(lowPrivDB)
+	r.DB = lowPrivDB
+	require.NoError(t, triggerDefinersCheck(t.Context(), r, slog.Default()))
+	triggers, err := table.LoadTriggers(t.Context(), db, "test", "_trgdefiner_new")
+	require.NoError(t, err)
+	require.Empty(t, triggers)
+}
+
+func TestTriggerColumns(t *testing.T) {
+	require.Equal(t, []string{"amount", "b c", "d`e", "id"},
+		triggerColumns("SET @sum = @sum + NEW.amount + old.`b c` + OLD . `d``e`; INSERT INTO log VALUES (new.id, renew.x)"))
+	require.Empty(t, triggerColumns("INSERT INTO log VALUES (1)"))
 }
@@ -32,6 +32,7 @@ func (m *mockChecker) GetProgress() string           { return "mock" }
 func (m *mockChecker) StartTime() time.Time          { return time.Now() }
 func (m *mockChecker) ExecTime() time.Duration       { return 0 }
 func (m *mockChecker) DifferencesFound() uint64      { return m.differencesFound.Load() }
+func (m *mockChecker) ChunksRemaining() uint64       { return 0 }
 
 // setupRunnerForChecksumTest creates a real table, runs the runner setup as
 // far as creating the checkpoint table on disk, and returns a Runner that can
@@ -0,0 +1,72 @@
+package migration
+
+import (
+	"testing"
+
+	"github.com/block/spirit/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestColumnExprSingleTableOnly(t *testing.T) {
+	pw := "spirit"
+	_, err := NewRunner(&Migration{
+		Host:       "127.0.0.1:1",
+		Username:   "spirit",
+		Password:   &pw,
+		Database:   "test",
+		Statement:  "ALTER TABLE t1 ADD INDEX (a); ALTER TABLE t2 ADD INDEX (a)",
+		ColumnExpr: map[string]string{"a": "b"},
+	})
+	require.ErrorContains(t, err, "--column-expr is only supported for single-table migrations")
+}
+
+func TestMigrateColumnExpr(t *testing.T) {
+	t.Parallel()
+	for _, buffered := range []bool{true, false} {
+		name := "cexprt1"
+		if !buffered {
+			name = "cexprt2"
+		}
+		tt := testutils.NewTestTable(t, name, `CREATE TABLE `+name+` (
+			id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+			first varchar(50) NOT NULL,
+			last varchar(50) NOT NULL,
+			cents int
+		)`)
+		testutils.RunSQL(t, `INSERT INTO `+name+` (first, last, cents) VALUES ('Ada', 'Lovelace', 150), ('Alan', 'Turing', NULL)`)
+
+		// The new columns are backfilled from columns that the ALTER drops.
+		m := NewTestRunner(t, name, "ADD COLUMN full_name varchar(101) NOT NULL, ADD COLUMN dollars decimal(10,2), DROP COLUMN first, DROP COLUMN last, DROP COLUMN cents",
+			WithBuffered(buffered),
+			WithColumnExpr("full_name", "CONCAT(first, ' ', last)"),
+			WithColumnExpr("dollars", "cents / 100"),
+		)
+		require.NoError(t, m.Run(t.Context()))
+		require.NoError(t, m.Close())
+
+		var fullName string
+		var dollars *string
+		require.NoError(t, tt.DB.QueryRowContext(t.Context(), "SELECT full_name, dollars FROM "+name+" WHERE id = 1").Scan(&fullName, &dollars))
+		require.Equal(t, "Ada Lovelace", fullName)
+		require.Equal(t, "1.50", *dollars)
+		require.NoError(t, tt.DB.QueryRowContext(t.Context(), "SELECT full_name, dollars FROM "+name+" WHERE id = 2").Scan(&fullName, &dollars))
+		require.Equal(t, "Alan Turing", fullName)
+		require.Nil(t, dollars)
+	}
+}
+
+func TestMigrateColumnExprInvalid(t *testing.T) {
+	t.Parallel()
+	testutils.NewTestTable(t, "cexprt3", `CREATE TABLE cexprt3 (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		a int NOT NULL
+	)`)
+
+	m := NewTestRunner(t, "cexprt3", "ADD COLUMN b int", WithColumnExpr("c", "a + 1"))
+	require.ErrorContains(t, m.Run(t.Context()), `--column-expr: column "c" not found in table _cexprt3_new`)
+	require.NoError(t, m.Close())
+
+	m = NewTestRunner(t, "cexprt3", "ADD COLUMN b int", WithColumnExpr("b", "nope + 1"))
+	require.ErrorContains(t, m.Run(t.Context()), "--column-expr: Error 1054")
+	require.NoError(t, m.Close())
+}
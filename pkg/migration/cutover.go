@@ -6,11 +6,14 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/block/spirit/pkg/change"
 	"github.com/block/spirit/pkg/dbconn"
+	"github.com/block/spirit/pkg/dbconn/sqlescape"
+	"github.com/block/spirit/pkg/metrics"
 	"github.com/block/spirit/pkg/table"
 	"github.com/block/spirit/pkg/utils"
 )
@@ -31,12 +34,38 @@ const (
 	cutoverUnlockTimeout = 30 * time.Second
 )
 
+// errOutsideCutoverWindow is returned by CutOver.Run when the cutover
+// window is closed before an attempt. Nothing has been renamed, so the
+// caller can wait for the window to open again and call Run again.
+var errOutsideCutoverWindow = errors.New("outside the cutover window")
+
+// errCutoverUndoFailed is returned by an attempt when a change it made
+// under the lock couldn't be undone, so the original table may be missing
+// it, e.g. its triggers. Retrying would run against that table, so the
+// cutover stops for an operator to repair it.
+var errCutoverUndoFailed = errors.New("could not undo the changes of a failed cutover attempt")
+
 type CutOver struct {
 	db       *sql.DB
 	feed     change.Source
 	config   []*cutoverConfig
 	dbConfig *dbconn.DBConfig
 	logger   *slog.Logger
+	// metricsSink is optional; the runner sets it so attempts are counted.
+	metricsSink metrics.Sink
+	// window is optional; when set, the rename is only attempted inside it.
+	window *utils.Window
+	// onLocked is optional; it is called when an attempt has locked the
+	// tables, before anything is changed under the lock. From then on the
+	// attempts are made even if the window has closed, since the caller
+	// can't go back to waiting.
+	onLocked func()
+	// locked is set once an attempt has locked the tables.
+	locked bool
+	// afterRename is optional; it is called after a successful rename,
+	// while the table locks are still held. It can't fail the cutover,
+	// because the rename has already been committed.
+	afterRename func(ctx context.Context)
 	// testInjectRenameError is a test-only seam: when non-nil it is returned
 	// in place of a successful rename's nil result, simulating a connection
 	// that died after the server committed the RENAME TABLE but before the
@@ -49,6 +78,14 @@ type cutoverConfig struct {
 	newTable       *table.TableInfo
 	oldTableName   string
 	useTestCutover bool
+	// triggers are the triggers on table, which are moved to newTable
+	// under the lock. They are read before the first attempt, so that an
+	// attempt that fails part way through can't lose any.
+	triggers       []table.Trigger
+	triggersLoaded bool
+	// repointForeignKeys repoints the foreign keys that reference table at
+	// newTable under the lock, so that the rename carries them over.
+	repointForeignKeys bool
 }
 
 // NewCutOver contains the logic to perform the final cut over. It can cutover multiple tables
@@ -128,6 +165,20 @@ func (c *CutOver) Run(ctx context.Context) error {
 		if renameMayHaveCommitted && c.confirmRenameCompleted(ctx) {
 			return nil
 		}
+		// Only attempt the rename inside the cutover window. If it has
+		// closed while we were retrying, stop rather than exhaust the
+		// retries, so the caller can wait for the next window. An attempt
+		// that may have committed is retried regardless, because it must
+		// be resolved one way or the other, and so are the attempts after
+		// one that locked the tables, because the caller has entered
+		// CutOver.
+		if c.window != nil && !renameMayHaveCommitted && !c.locked && !c.window.Contains(time.Now()) {
+			c.logger.Warn("not attempting cut over outside the cutover window",
+				"cutover-window", c.window.String(),
+				"attempt", i+1,
+			)
+			return errors.Join(append(attemptErrs, errOutsideCutoverWindow)...)
+		}
 		// Try and catch up before we attempt the cutover.
 		// since we will need to catch up again with the lock held
 		// and we want to minimize that.
@@ -140,6 +191,7 @@ func (c *CutOver) Run(ctx context.Context) error {
 			"attempt", i+1,
 			"max_retries", c.dbConfig.MaxRetries,
 		)
+		c.recordAttempt(ctx)
 		// if specified in c.config[0], we will use the test cutover for failure injection.
 		// we don't need to exhaustively check all configs.
 		var err error
@@ -164,6 +216,12 @@ func (c *CutOver) Run(ctx context.Context) error {
 					return nil
 				}
 			}
+			if errors.Is(err, errCutoverUndoFailed) {
+				c.logger.Error("cutover failed, and could not be undone; not retrying",
+					"error", err.Error(),
+				)
+				return errors.Join(attemptErrs...)
+			}
 			c.logger.Warn("cutover failed",
 				"error", err.Error(),
 				"next_backoff", backoff,
@@ -184,6 +242,25 @@ func (c *CutOver) Run(ctx context.Context) error {
 	return errors.Join(attemptErrs...)
 }
 
+// recordAttempt counts a cutover attempt in the metrics sink, if one is
+// set. A failure to send is logged and otherwise ignored; it must never
+// affect the cutover itself.
+func (c *CutOver) recordAttempt(ctx context.Context) {
+	if c.metricsSink == nil {
+		return
+	}
+	sendCtx, cancel := context.WithTimeout(ctx, metrics.SinkTimeout)
+	defer cancel()
+	err := c.metricsSink.Send(sendCtx, &metrics.Metrics{
+		Values: []metrics.MetricValue{
+			{Name: metrics.CutoverAttemptsMetricName, Type: metrics.COUNTER, Value: 1},
+		},
+	})
+	if err != nil {
+		c.logger.Debug("cutover metrics send failed", "error", err)
+	}
+}
+
 // confirmRenameCompleted wraps renameCompleted with logging for use in the
 // retry loop after an ambiguous connection-loss failure. It returns true only
 // if the server-side state proves the cutover rename was committed.
@@ -253,6 +330,8 @@ func (c *CutOver) renameCompleted(ctx context.Context) (bool, error) {
 func (c *CutOver) algorithmRenameUnderLock(ctx context.Context) error {
 	tablesToLock := []*table.TableInfo{}
 	renameFragments := []string{}
+	var steps []cutoverStep
+	var moveTriggers, restoreTriggers []string
 	for _, cfg := range c.config {
 		tablesToLock = append(tablesToLock, cfg.table, cfg.newTable)
 		oldQuotedName := fmt.Sprintf("`%s`", cfg.oldTableName)
@@ -260,17 +339,168 @@ func (c *CutOver) algorithmRenameUnderLock(ctx context.Context) error {
 			fmt.Sprintf("%s TO %s", cfg.table.QuotedTableName, oldQuotedName),
 			fmt.Sprintf("%s TO %s", cfg.newTable.QuotedTableName, cfg.table.QuotedTableName),
 		)
+		if !cfg.triggersLoaded {
+			triggers, err := table.LoadTriggers(ctx, c.db, cfg.table.SchemaName, cfg.table.TableName)
+			if err != nil {
+				return err
+			}
+			cfg.triggers, cfg.triggersLoaded = triggers, true
+		}
+		moveTriggers = append(moveTriggers, triggerStatements(cfg.triggers, cfg.newTable.TableName)...)
+		restoreTriggers = append(restoreTriggers, triggerStatements(cfg.triggers, cfg.table.TableName)...)
+		if cfg.repointForeignKeys {
+			// The foreign keys that reference the table are read on every
+			// attempt, since one that has already been repointed at the new
+			// table by a failed attempt is carried over by its rename.
+			fks, err := table.LoadReferencingForeignKeys(ctx, c.db, cfg.table.SchemaName, cfg.table.TableName)
+			if err != nil {
+				return err
+			}
+			for _, child := range foreignKeysByTable(fks) {
+				// Altering the child table requires a lock on it too.
+				tablesToLock = append(tablesToLock, table.NewTableInfo(c.db, child[0].Schema, child[0].Table))
+				steps = append(steps, repointForeignKeysStep(child, cfg.newTable.TableName))
+			}
+		}
+	}
+	if len(moveTriggers) > 0 {
+		steps = append(steps, cutoverStep{
+			desc: "move the triggers to the new table",
+			do:   withSessionRestored(moveTriggers),
+			undo: withSessionRestored(restoreTriggers),
+		})
+	}
+	return c.executeRenameUnderLock(ctx, tablesToLock, renameFragments, steps)
+}
+
+// cutoverStep is a change made under the lock just before the rename,
+// and the statements that undo it if a later step or the rename fails.
+// The undo statements of a step that fails itself are run too, unless
+// it is atomic, so they have to be safe to run whether or not the
+// step's statements took effect.
+type cutoverStep struct {
+	desc   string
+	do     []string
+	undo   []string
+	atomic bool
+}
+
+// foreignKeysByTable groups fks by the table they are on, in order.
+func foreignKeysByTable(fks []table.ForeignKey) [][]table.ForeignKey {
+	var groups [][]table.ForeignKey
+	for _, fk := range fks {
+		if n := len(groups); n > 0 && groups[n-1][0].Schema == fk.Schema && groups[n-1][0].Table == fk.Table {
+			groups[n-1] = append(groups[n-1], fk)
+			continue
+		}
+		groups = append(groups, []table.ForeignKey{fk})
+	}
+	return groups
+}
+
+// repointForeignKeysStep returns the step that repoints fks, which are all
+// on the same child table, at newTableName. Each constraint is dropped and
+// added again under its toggled name, since the name can't be reused in
+// the same statement. With foreign_key_checks disabled this only changes
+// metadata. The rename of the new table then carries the constraints over
+// to the table's name.
+func repointForeignKeysStep(fks []table.ForeignKey, newTableName string) cutoverStep {
+	repoint := sqlescape.MustEscapeSQL("ALTER TABLE %n.%n", fks[0].Schema, fks[0].Table)
+	restore := repoint
+	for i, fk := range fks {
+		if i > 0 {
+			repoint += ","
+			restore += ","
+		}
+		repointed := fk
+		repointed.Name = foreignKeyName(fk.Name)
+		repointed.ReferencedTable = newTableName
+		repoint += sqlescape.MustEscapeSQL(" DROP FOREIGN KEY %n, ADD ", fk.Name) + repointed.Definition()
+		restore += sqlescape.MustEscapeSQL(" DROP FOREIGN KEY %n, ADD ", repointed.Name) + fk.Definition()
+	}
+	return cutoverStep{
+		desc:   fmt.Sprintf("repoint the foreign keys on %s at the new table", fks[0].Table),
+		do:     []string{repoint},
+		undo:   []string{restore},
+		atomic: true,
+	}
+}
+
+// triggerStatements returns the statements that (re)create triggers on
+// tableName, dropping them from whichever table they are on first.
+// Trigger names are unique per schema, so a trigger can't be on the table
+// and the new table at the same time: it has to be moved, and moving it
+// again is harmless.
+func triggerStatements(triggers []table.Trigger, tableName string) []string {
+	stmts := make([]string, 0, len(triggers)*3)
+	for _, trg := range triggers {
+		stmts = append(stmts,
+			sqlescape.MustEscapeSQL("DROP TRIGGER IF EXISTS %n", trg.Name),
+			sqlescape.MustEscapeSQL("SET SESSION sql_mode = %?, collation_connection = %?", trg.SQLMode, trg.Collation),
+			trg.CreateStatement(tableName),
+		)
 	}
-	return c.executeRenameUnderLock(ctx, tablesToLock, renameFragments)
+	return stmts
+}
+
+// withSessionRestored brackets stmts with statements that save and then
+// restore the session variables that triggerStatements changes, since
+// the lock's connection goes back to the pool afterwards.
+func withSessionRestored(stmts []string) []string {
+	if len(stmts) == 0 {
+		return nil
+	}
+	return slices.Concat(
+		[]string{"SET @spirit_sql_mode = @@session.sql_mode, @spirit_collation_connection = @@session.collation_connection"},
+		stmts,
+		[]string{"SET SESSION sql_mode = @spirit_sql_mode, collation_connection = @spirit_collation_connection"},
+	)
+}
+
+// verifyTriggers checks that the triggers that were moved to the new
+// table are on it, as they were on the original table, now that the
+// cutover has renamed it.
+func (c *CutOver) verifyTriggers(ctx context.Context) error {
+	for _, cfg := range c.config {
+		if !cfg.triggersLoaded || len(cfg.triggers) == 0 {
+			continue
+		}
+		triggers, err := table.LoadTriggers(ctx, c.db, cfg.table.SchemaName, cfg.table.TableName)
+		if err != nil {
+			return err
+		}
+		if !slices.Equal(triggers, cfg.triggers) {
+			return fmt.Errorf("the triggers on table %s don't match those on the original table, now %s: expected %v, found %v",
+				cfg.table.TableName, cfg.oldTableName, triggerNames(cfg.triggers), triggerNames(triggers))
+		}
+		c.logger.Info("triggers moved to the new table", "table", cfg.table.TableName, "triggers", triggerNames(triggers))
+	}
+	return nil
+}
+
+func triggerNames(triggers []table.Trigger) []string {
+	names := make([]string, 0, len(triggers))
+	for _, trg := range triggers {
+		names = append(names, trg.Name)
+	}
+	return names
 }
 
 // executeRenameUnderLock is the shared implementation for performing renames under a table lock.
 // It handles locking, binlog flushing, and executing the rename statement.
-func (c *CutOver) executeRenameUnderLock(ctx context.Context, tablesToLock []*table.TableInfo, renameFragments []string) error {
+// The steps are executed under the lock just before the rename, and undone
+// if they or the rename fail.
+func (c *CutOver) executeRenameUnderLock(ctx context.Context, tablesToLock []*table.TableInfo, renameFragments []string, steps []cutoverStep) error {
 	tableLock, err := dbconn.NewTableLock(ctx, c.db, tablesToLock, c.dbConfig, c.logger)
 	if err != nil {
 		return err
 	}
+	if !c.locked {
+		c.locked = true
+		if c.onLocked != nil {
+			c.onLocked()
+		}
+	}
 	// Run UNLOCK TABLES with a ctx that ignores the parent's cancellation
 	// so a ctx cancel mid-cutover still releases the lock. ExecContext on
 	// a cancelled ctx returns immediately without sending the statement;
@@ -298,10 +528,30 @@ func (c *CutOver) executeRenameUnderLock(ctx context.Context, tablesToLock []*ta
 		return fmt.Errorf("%w, final flush might be broken", change.ErrChangesNotFlushed)
 	}
 
+	// Triggers are moved only now that all changes are flushed, so that
+	// they don't fire again for the changes applied to the new table.
+	for i, step := range steps {
+		if err := tableLock.ExecUnderLock(ctx, step.do...); err != nil {
+			undo := steps[:i+1]
+			if step.atomic {
+				undo = steps[:i]
+			}
+			if undoErr := c.undoSteps(unlockCtx, tableLock, undo); undoErr != nil {
+				return errors.Join(err, undoErr)
+			}
+			return err
+		}
+	}
 	renameStatement := "RENAME TABLE " + strings.Join(renameFragments, ", ")
 	if err := tableLock.ExecUnderLock(ctx, renameStatement); err != nil {
+		if undoErr := c.undoSteps(unlockCtx, tableLock, steps); undoErr != nil {
+			return errors.Join(err, undoErr)
+		}
 		return err
 	}
+	if c.afterRename != nil {
+		c.afterRename(ctx)
+	}
 	if c.testInjectRenameError != nil {
 		// Test-only seam: the rename was committed by the server, but we
 		// pretend the client never read the OK packet.
@@ -310,6 +560,26 @@ func (c *CutOver) executeRenameUnderLock(ctx context.Context, tablesToLock []*ta
 	return nil
 }
 
+// undoSteps undoes steps in reverse order after a failed cutover. A step
+// that can't be undone is logged, and the rest are still undone, but the
+// error wraps errCutoverUndoFailed so that the cutover isn't retried.
+func (c *CutOver) undoSteps(ctx context.Context, tableLock *dbconn.TableLock, steps []cutoverStep) error {
+	var errs []error
+	for _, step := range slices.Backward(steps) {
+		if err := tableLock.ExecUnderLock(ctx, step.undo...); err != nil {
+			c.logger.Error("could not undo a cutover step after a failed cutover; the original table may be missing what it changed",
+				"step", step.desc,
+				"error", err,
+			)
+			errs = append(errs, fmt.Errorf("%s: %w", step.desc, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", errCutoverUndoFailed, errors.Join(errs...))
+	}
+	return nil
+}
+
 // partialRenameForTest performs a partial cutover (only renames original table to _old)
 // This is intended for testing the atomicity/consistency of the cutover.
 func (c *CutOver) partialRenameForTest(ctx context.Context) error {
@@ -328,7 +598,7 @@ func (c *CutOver) partialRenameForTest(ctx context.Context) error {
 		)
 	}
 	// Execute the partial rename using the same code path
-	if err := c.executeRenameUnderLock(ctx, tablesToLock, renameFragments); err != nil {
+	if err := c.executeRenameUnderLock(ctx, tablesToLock, renameFragments, nil); err != nil {
 		return err
 	}
 	// Intentionally return an error to simulate a partial cutover failure
@@ -81,6 +81,136 @@ func TestCutOver(t *testing.T) {
 	require.Equal(t, 2, count)
 }
 
+func TestCutOverMovesTriggers(t *testing.T) {
+	t.Parallel()
+	testutils.NewTestTable(t, "cutovertrgt1", `CREATE TABLE cutovertrgt1 (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		a int NOT NULL,
+		b int NOT NULL DEFAULT 0
+	)`)
+	testutils.RunSQL(t, `CREATE TABLE _cutovertrgt1_new (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		a bigint NOT NULL,
+		b int NOT NULL DEFAULT 0
+	)`)
+	testutils.RunSQL(t, `CREATE TABLE _cutovertrgt1_chkpnt (a int)`) // for binlog advancement
+	testutils.RunSQL(t, `CREATE TRIGGER cutovertrgt1_bi BEFORE INSERT ON cutovertrgt1 FOR EACH ROW SET NEW.b = NEW.a * 2`)
+	testutils.RunSQL(t, `CREATE TRIGGER cutovertrgt1_bu BEFORE UPDATE ON cutovertrgt1 FOR EACH ROW SET NEW.b = NEW.a * 3`)
+
+	cfg, err := mysql.ParseDSN(testutils.DSN())
+	require.NoError(t, err)
+	db, err := dbconn.New(testutils.DSN(), dbconn.NewDBConfig())
+	require.NoError(t, err)
+	defer utils.CloseAndLog(db)
+
+	t1 := table.NewTableInfo(db, cfg.DBName, "cutovertrgt1")
+	require.NoError(t, t1.SetInfo(t.Context()))
+	t1new := table.NewTableInfo(db, cfg.DBName, "_cutovertrgt1_new")
+	feed := change.NewBinlogClient(db, cfg.Addr, cfg.User, cfg.Passwd, applier.NewSingleTargetForTest(t, db), change.NewClientDefaultConfig())
+	defer feed.Close()
+	chunker, err := table.NewChunker(t1, table.ChunkerConfig{NewTable: t1new})
+	require.NoError(t, err)
+	require.NoError(t, feed.AddSubscription(t1, t1new, chunker))
+	require.NoError(t, feed.Start(t.Context()))
+
+	before, err := table.LoadTriggers(t.Context(), db, cfg.DBName, "cutovertrgt1")
+	require.NoError(t, err)
+	require.Len(t, before, 2)
+	cutover, err := NewCutOver(db, []*cutoverConfig{{
+		table:        t1,
+		newTable:     t1new,
+		oldTableName: "_cutovertrgt1_old",
+	}}, feed, dbconn.NewDBConfig(), slog.Default())
+	require.NoError(t, err)
+	require.NoError(t, cutover.Run(t.Context()))
+	require.NoError(t, cutover.verifyTriggers(t.Context()))
+
+	// The triggers are on the new table, and no longer on the old one.
+	after, err := table.LoadTriggers(t.Context(), db, cfg.DBName, "cutovertrgt1")
+	require.NoError(t, err)
+	require.Equal(t, before, after)
+	old, err := table.LoadTriggers(t.Context(), db, cfg.DBName, "_cutovertrgt1_old")
+	require.NoError(t, err)
+	require.Empty(t, old)
+	testutils.RunSQL(t, `INSERT INTO cutovertrgt1 (a) VALUES (5)`)
+	var b int
+	require.NoError(t, db.QueryRowContext(t.Context(), "SELECT b FROM cutovertrgt1 WHERE a = 5").Scan(&b))
+	require.Equal(t, 10, b)
+}
+
+func TestCutOverUndoFailed(t *testing.T) {
+	t.Parallel()
+	testutils.NewTestTable(t, "cutoverundot1", `CREATE TABLE cutoverundot1 (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		a int NOT NULL
+	)`)
+	testutils.RunSQL(t, `CREATE TABLE _cutoverundot1_new (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		a bigint NOT NULL
+	)`)
+	testutils.RunSQL(t, `CREATE TABLE _cutoverundot1_chkpnt (a int)`) // for binlog advancement
+
+	cfg, err := mysql.ParseDSN(testutils.DSN())
+	require.NoError(t, err)
+	db, err := dbconn.New(testutils.DSN(), dbconn.NewDBConfig())
+	require.NoError(t, err)
+	defer utils.CloseAndLog(db)
+
+	t1 := table.NewTableInfo(db, cfg.DBName, "cutoverundot1")
+	require.NoError(t, t1.SetInfo(t.Context()))
+	t1new := table.NewTableInfo(db, cfg.DBName, "_cutoverundot1_new")
+	feed := change.NewBinlogClient(db, cfg.Addr, cfg.User, cfg.Passwd, applier.NewSingleTargetForTest(t, db), change.NewClientDefaultConfig())
+	defer feed.Close()
+	chunker, err := table.NewChunker(t1, table.ChunkerConfig{NewTable: t1new})
+	require.NoError(t, err)
+	require.NoError(t, feed.AddSubscription(t1, t1new, chunker))
+	require.NoError(t, feed.Start(t.Context()))
+	cutover, err := NewCutOver(db, []*cutoverConfig{{
+		table:        t1,
+		newTable:     t1new,
+		oldTableName: "_cutoverundot1_old",
+	}}, feed, dbconn.NewDBConfig(), slog.Default())
+	require.NoError(t, err)
+
+	// A step that fails is undone, and so is the step before it. When an
+	// undo fails too, the error says so, and the cutover isn't retried.
+	steps := []cutoverStep{
+		{desc: "first step", do: []string{"SELECT 1"}, undo: []string{"SELECT no_such_column FROM cutoverundot1"}},
+		{desc: "second step", do: []string{"SELECT no_such_column FROM cutoverundot1"}, atomic: true},
+	}
+	err = cutover.executeRenameUnderLock(t.Context(), []*table.TableInfo{t1, t1new},
+		[]string{"cutoverundot1 TO _cutoverundot1_old", "_cutoverundot1_new TO cutoverundot1"}, steps)
+	require.ErrorIs(t, err, errCutoverUndoFailed)
+	require.ErrorContains(t, err, "first step")
+
+	// When the undo succeeds, the error is the step's own.
+	steps[0].undo = []string{"SELECT 1"}
+	err = cutover.executeRenameUnderLock(t.Context(), []*table.TableInfo{t1, t1new},
+		[]string{"cutoverundot1 TO _cutoverundot1_old", "_cutoverundot1_new TO cutoverundot1"}, steps)
+	require.Error(t, err)
+	require.NotErrorIs(t, err, errCutoverUndoFailed)
+}
+
+func TestTriggerStatements(t *testing.T) {
+	require.Nil(t, withSessionRestored(triggerStatements(nil, "t1")))
+	stmts := withSessionRestored(triggerStatements([]table.Trigger{{
+		Name:      "t1_bi",
+		Timing:    "BEFORE",
+		Event:     "INSERT",
+		Statement: "SET NEW.b = 1",
+		Definer:   "root@localhost",
+		SQLMode:   "STRICT_TRANS_TABLES",
+		Collation: "utf8mb4_0900_ai_ci",
+	}}, "_t1_new"))
+	require.Equal(t, []string{
+		"SET @spirit_sql_mode = @@session.sql_mode, @spirit_collation_connection = @@session.collation_connection",
+		"DROP TRIGGER IF EXISTS `t1_bi`",
+		"SET SESSION sql_mode = 'STRICT_TRANS_TABLES', collation_connection = 'utf8mb4_0900_ai_ci'",
+		"CREATE DEFINER=`root`@`localhost` TRIGGER `t1_bi` BEFORE INSERT ON `_t1_new` FOR EACH ROW SET NEW.b = 1",
+		"SET SESSION sql_mode = @spirit_sql_mode, collation_connection = @spirit_collation_connection",
+	}, stmts)
+}
+
 // TestCutoverRenameCompletedDetection unit-tests the renameCompleted helper
 // against real server state: it must detect a committed cutover rename
 // (original exists, _new gone, _old exists — for every table in the config)
@@ -1096,3 +1226,33 @@ func TestDeferCutOverTwoMigrationsSharedSentinel(t *testing.T) {
 	require.NoError(t, mA.Close())
 	require.NoError(t, mB.Close())
 }
+
+// closedCutoverWindow returns a window that is closed now and for the rest
+// of the test: it only opens the day after tomorrow.
+func closedCutoverWindow(t *testing.T) *utils.Window {
+	t.Helper()
+	day := time.Now().UTC().AddDate(0, 0, 2).Weekday().String()[:3]
+	w, err := utils.ParseWindow(day + " 00:00-01:00 UTC")
+	require.NoError(t, err)
+	return w
+}
+
+// TestCutoverOutsideWindow checks that no attempt is made outside the
+// cutover window. It needs no server: the window is checked before the
+// feed is flushed, so the *sql.DB never connects.
+func TestCutoverOutsideWindow(t *testing.T) {
+	t.Parallel()
+	db, err := sql.Open("mysql", "spirit@tcp(127.0.0.1:1)/test")
+	require.NoError(t, err)
+	defer utils.CloseAndLog(db)
+
+	feed := change.NewBinlogClient(db, "127.0.0.1:1", "spirit", "", nil, change.NewClientDefaultConfig())
+	cutover, err := NewCutOver(db, []*cutoverConfig{{
+		table:        table.NewTableInfo(db, "test", "t1"),
+		newTable:     table.NewTableInfo(db, "test", "_t1_new"),
+		oldTableName: "_t1_old",
+	}}, feed, dbconn.NewDBConfig(), slog.Default())
+	require.NoError(t, err)
+	cutover.window = closedCutoverWindow(t)
+	require.ErrorIs(t, cutover.Run(t.Context()), errOutsideCutoverWindow)
+}
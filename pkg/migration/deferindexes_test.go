@@ -0,0 +1,110 @@
+package migration
+
+import (
+	"testing"
+
+	"github.com/block/spirit/pkg/dbconn"
+	"github.com/block/spirit/pkg/table"
+	"github.com/block/spirit/pkg/testutils"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateDeferSecondaryIndexes(t *testing.T) {
+	t.Parallel()
+	tt := testutils.NewTestTable(t, "deferidxt1", `CREATE TABLE deferidxt1 (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		a int NOT NULL,
+		b varchar(100) NOT NULL,
+		c int,
+		UNIQUE KEY uk_a (a),
+		KEY idx_b (b),
+		KEY idx_cb (c, b),
+		FULLTEXT KEY ft_b (b)
+	)`)
+	testutils.RunSQL(t, `INSERT INTO deferidxt1 (a, b, c) VALUES (1, 'one', 1), (2, 'two', NULL), (3, 'three', 3)`)
+
+	m := NewTestRunner(t, "deferidxt1", "ADD INDEX idx_c (c)", WithDeferSecondaryIndexes())
+	require.NoError(t, m.Run(t.Context()))
+	require.NoError(t, m.Close())
+
+	var name, createTable string
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), "SHOW CREATE TABLE deferidxt1").Scan(&name, &createTable))
+	for _, index := range []string{"UNIQUE KEY `uk_a` (`a`)", "KEY `idx_b` (`b`)", "KEY `idx_cb` (`c`,`b`)", "FULLTEXT KEY `ft_b` (`b`)", "KEY `idx_c` (`c`)"} {
+		require.Contains(t, createTable, index)
+	}
+}
+
+func TestDeferSecondaryIndexesResume(t *testing.T) {
+	t.Parallel()
+	tt := testutils.NewTestTable(t, "deferidxt2", `CREATE TABLE deferidxt2 (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		a int NOT NULL,
+		b int NOT NULL,
+		UNIQUE KEY uk_a (a),
+		KEY idx_b (b)
+	)`)
+	testutils.RunSQL(t, `INSERT INTO deferidxt2 (a, b) VALUES (1, 1), (2, 2), (3, 3)`)
+	cfg, err := mysql.ParseDSN(testutils.DSN())
+	require.NoError(t, err)
+	migration := func() *Migration {
+		return &Migration{
+			Host:                  cfg.Addr,
+			Username:              cfg.User,
+			Password:              &cfg.Passwd,
+			Database:              cfg.DBName,
+			Threads:               2,
+			WriteThreads:          2,
+			Table:                 "deferidxt2",
+			Alter:                 "ADD INDEX idx_ab (a, b)",
+			DeferSecondaryIndexes: true,
+		}
+	}
+
+	r, err := NewRunner(migration())
+	require.NoError(t, err)
+	r.db, err = dbconn.New(testutils.DSN(), dbconn.NewDBConfig())
+	require.NoError(t, err)
+	r.dbConfig = dbconn.NewDBConfig()
+	r.changes[0].table = table.NewTableInfo(r.db, r.migration.Database, r.migration.Table)
+	require.NoError(t, r.changes[0].table.SetInfo(t.Context()))
+	require.NoError(t, r.newMigration(t.Context()))
+
+	// The new table only has its PRIMARY and UNIQUE keys while copying.
+	var name, createTable string
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), "SHOW CREATE TABLE _deferidxt2_new").Scan(&name, &createTable))
+	require.Contains(t, createTable, "UNIQUE KEY `uk_a` (`a`)")
+	require.NotContains(t, createTable, "idx_b")
+	require.NotContains(t, createTable, "idx_ab")
+
+	// Checkpoint before anything is copied, and resume from it: the new
+	// table gets the deferred indexes from the checkpoint.
+	deferredIndexes, err := r.deferredIndexesCheckpoint()
+	require.NoError(t, err)
+	require.Contains(t, deferredIndexes, "_deferidxt2_new")
+	watermark := `{"Key":["id"],"ChunkSize":1000,"LowerBound":{"Value":["1"],"Inclusive":true},"UpperBound":{"Value":["2"],"Inclusive":false}}`
+	require.NoError(t, dbconn.Exec(t.Context(), r.db, `INSERT INTO %n.%n
+	(copier_watermark, checksum_watermark, binlog_position, statement, deferred_indexes)
+	VALUES
+	(%?, %?, %?, %?, %?)`,
+		r.checkpointTable.SchemaName,
+		r.checkpointTable.TableName,
+		watermark,
+		"",
+		r.replClient.Position(),
+		r.migration.Statement,
+		deferredIndexes,
+	))
+	require.NoError(t, r.Close())
+
+	r2, err := NewRunner(migration())
+	require.NoError(t, err)
+	require.NoError(t, r2.Run(t.Context()))
+	require.True(t, r2.usedResumeFromCheckpoint)
+	require.NoError(t, r2.Close())
+
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), "SHOW CREATE TABLE deferidxt2").Scan(&name, &createTable))
+	require.Contains(t, createTable, "UNIQUE KEY `uk_a` (`a`)")
+	require.Contains(t, createTable, "KEY `idx_b` (`b`)")
+	require.Contains(t, createTable, "KEY `idx_ab` (`a`,`b`)")
+}
@@ -0,0 +1,126 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/block/spirit/pkg/dbconn"
+	"github.com/block/spirit/pkg/table"
+	"github.com/block/spirit/pkg/throttler"
+	"github.com/block/spirit/pkg/utils"
+)
+
+// oldTableNamePattern matches the names of the tables that a migration
+// renames the original table to: _<table>_old and _<table>_old_<timestamp>.
+var oldTableNamePattern = regexp.MustCompile(`^_.+_old(_\d{8}_\d{6})?$`)
+
+// Drop is the kong CLI entry point of `spirit drop`. It gradually drops
+// an _old table left behind by --skip-drop-after-cutover.
+type Drop struct {
+	Host               string        `name:"host" help:"Hostname" optional:""`
+	Username           string        `name:"username" help:"User" optional:""`
+	Password           *string       `name:"password" help:"Password" optional:""`
+	Database           string        `name:"database" help:"Database" optional:""`
+	ConfFile           string        `name:"conf" help:"MySQL conf file" optional:"" type:"existingfile"`
+	Table              string        `name:"table" help:"The _old table to drop" required:""`
+	TargetChunkTime    time.Duration `name:"target-chunk-time" help:"The target delete time for each chunk" optional:"" default:"500ms"`
+	ReplicaDSN         string        `name:"replica-dsn" help:"DSN(s) for replica(s) used for lag checking. Multiple replicas can be comma-separated; Spirit throttles on the slowest." optional:""`
+	ReplicaMaxLag      time.Duration `name:"replica-max-lag" help:"The maximum lag allowed on the replica before the drop throttles." optional:"" default:"120s"`
+	MaxCommitLatency   time.Duration `name:"max-commit-latency" help:"Throttle when average commit latency exceeds this threshold (currently only auto-enabled on Aurora)" optional:"" default:"100ms"`
+	TLSMode            string        `name:"tls-mode" help:"TLS connection mode (case insensitive): DISABLED, PREFERRED (default), REQUIRED, VERIFY_CA, VERIFY_IDENTITY" optional:""`
+	TLSCertificatePath string        `name:"tls-ca" help:"Path to custom TLS CA certificate file" optional:""`
+}
+
+// dropTableGradually empties tbl by deleting it one chunk at a time, and
+// then drops it. Dropping a very large table in one statement can stall
+// the server while InnoDB frees its pages; deleting it in chunks spreads
+// that work out and lets thr pause it while replicas catch up. The
+// chunk size adapts to targetChunkTime, as it does for the copy.
+func dropTableGradually(ctx context.Context, db *sql.DB, dbConfig *dbconn.DBConfig, tbl *table.TableInfo, thr throttler.Throttler, targetChunkTime time.Duration, logger *slog.Logger) error {
+	chunker, err := table.NewChunker(tbl, table.ChunkerConfig{
+		TargetChunkTime: targetChunkTime,
+		Logger:          logger,
+	})
+	if err != nil {
+		return err
+	}
+	if err := chunker.Open(); err != nil {
+		return err
+	}
+	defer utils.CloseAndLog(chunker)
+	var deleted int64
+	for !chunker.IsRead() {
+		thr.BlockWait(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		chunk, err := chunker.Next()
+		if err != nil {
+			if errors.Is(err, table.ErrTableIsRead) {
+				break
+			}
+			return err
+		}
+		startTime := time.Now()
+		query := fmt.Sprintf("DELETE FROM %s WHERE %s", tbl.QuotedTableName, chunk.String())
+		affectedRows, err := dbconn.RetryableTransaction(ctx, db, false, dbConfig, query)
+		if err != nil {
+			return err
+		}
+		chunker.Feedback(chunk, time.Since(startTime), uint64(affectedRows))
+		deleted += affectedRows
+		logger.Debug("deleted chunk", "table", tbl.TableName, "chunk", chunk.String(), "rows", affectedRows)
+	}
+	logger.Info("emptied table, dropping it", "table", tbl.TableName, "rows-deleted", deleted)
+	return dbconn.Exec(ctx, db, "DROP TABLE IF EXISTS %n.%n", tbl.SchemaName, tbl.TableName)
+}
+
+// Run empties and drops d.Table. Only the tables that a migration renames
+// the original table to are accepted, since the rows are deleted as it
+// goes: a drop that is interrupted leaves a partially emptied table, and
+// running it again finishes the job.
+func (d *Drop) Run() error {
+	ctx := context.TODO()
+	logger := slog.Default()
+	if !oldTableNamePattern.MatchString(d.Table) {
+		return fmt.Errorf("table %s is not an _old table left behind by a migration", d.Table)
+	}
+	m := &Migration{
+		Host:               d.Host,
+		Username:           d.Username,
+		Password:           d.Password,
+		Database:           d.Database,
+		ConfFile:           d.ConfFile,
+		ReplicaDSN:         d.ReplicaDSN,
+		ReplicaMaxLag:      d.ReplicaMaxLag,
+		TLSMode:            d.TLSMode,
+		TLSCertificatePath: d.TLSCertificatePath,
+	}
+	dbConfig := dbconn.NewDBConfig()
+	db, dsn, err := m.Connect(dbConfig)
+	if err != nil {
+		return err
+	}
+	defer utils.CloseAndLog(db)
+
+	tbl := table.NewTableInfo(db, m.Database, d.Table)
+	tbl.DisableAnalyze = true
+	if err := tbl.SetInfo(ctx); err != nil {
+		return err
+	}
+	thr, closeThrottler, err := m.NewStandaloneThrottler(ctx, db, dsn, dbConfig, d.MaxCommitLatency, logger)
+	if err != nil {
+		return err
+	}
+	defer closeThrottler()
+	if err := dropTableGradually(ctx, db, dbConfig, tbl, thr, d.TargetChunkTime, logger); err != nil {
+		return err
+	}
+	logger.Info("successfully dropped table", "table", d.Table)
+	return nil
+}
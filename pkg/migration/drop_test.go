@@ -0,0 +1,72 @@
+package migration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/block/spirit/pkg/testutils"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDropOnlyOldTables(t *testing.T) {
+	for _, name := range []string{"_t1_old", "_t1_old_20260101_120000", "_my_old_table_old"} {
+		require.True(t, oldTableNamePattern.MatchString(name), name)
+	}
+	for _, name := range []string{"t1", "_t1_new", "_t1_chkpnt", "t1_old", "_t1_old_2026", "_t1_reverted_20260101_120000"} {
+		require.False(t, oldTableNamePattern.MatchString(name), name)
+	}
+	d := &Drop{Host: "127.0.0.1:1", Table: "t1"}
+	require.ErrorContains(t, d.Run(), "table t1 is not an _old table left behind by a migration")
+}
+
+func TestDropGradually(t *testing.T) {
+	t.Parallel()
+	tt := testutils.NewTestTable(t, "dropgrad", `CREATE TABLE dropgrad (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		a int NOT NULL
+	)`)
+	testutils.RunSQL(t, "CREATE TABLE _dropgrad_old LIKE dropgrad")
+	testutils.RunSQL(t, `INSERT INTO _dropgrad_old (a) WITH RECURSIVE seq (n) AS
+		(SELECT 1 UNION ALL SELECT n + 1 FROM seq WHERE n < 5000) SELECT n FROM seq`)
+
+	cfg, err := mysql.ParseDSN(testutils.DSN())
+	require.NoError(t, err)
+	d := &Drop{
+		Host:            cfg.Addr,
+		Username:        cfg.User,
+		Password:        &cfg.Passwd,
+		Database:        cfg.DBName,
+		Table:           "_dropgrad_old",
+		TargetChunkTime: time.Millisecond,
+	}
+	require.NoError(t, d.Run())
+
+	var count int
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(),
+		`SELECT COUNT(*) FROM information_schema.tables
+		WHERE table_schema=DATABASE() AND table_name='_dropgrad_old'`).Scan(&count))
+	require.Zero(t, count)
+
+	// It's an error to drop a table that doesn't exist.
+	require.Error(t, d.Run())
+}
+
+func TestGradualDropAfterCutover(t *testing.T) {
+	t.Parallel()
+	tt := testutils.NewTestTable(t, "gradualdrop", `CREATE TABLE gradualdrop (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		a int NOT NULL
+	)`)
+	testutils.RunSQL(t, "INSERT INTO gradualdrop (a) VALUES (1), (2), (3)")
+	m := NewTestRunner(t, "gradualdrop", "MODIFY a bigint NOT NULL", WithGradualDrop())
+	require.NoError(t, m.Run(t.Context()))
+	require.NoError(t, m.Close())
+	require.False(t, m.usedInstantDDL)
+
+	var count int
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(),
+		`SELECT COUNT(*) FROM information_schema.tables
+		WHERE table_schema=DATABASE() AND table_name LIKE '\_gradualdrop\_%'`).Scan(&count))
+	require.Zero(t, count)
+}
@@ -0,0 +1,629 @@
+package migration
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/block/spirit/pkg/statement"
+	"github.com/block/spirit/pkg/table"
+	"github.com/block/spirit/pkg/utils"
+)
+
+const (
+	// maxDuplicateGroups is how many groups of rows with the same values
+	// a duplicate report lists, and maxDuplicateGroupKeys how many of the
+	// keys of each group's rows.
+	maxDuplicateGroups    = 10
+	maxDuplicateGroupKeys = 5
+
+	// maxPendingDuplicateChecks bounds the changed values that the watch
+	// holds between checks. Values beyond it are not checked by the watch,
+	// but the checksum still finds any duplicates among them.
+	maxPendingDuplicateChecks = 10000
+	duplicateCheckBatchSize   = 1000
+	duplicateCheckInterval    = time.Second
+
+	// maxUnindexedDuplicateHashes bounds the hashes of the values of a key
+	// without an index that the scan holds, some tens of bytes each. The key
+	// of a larger table is left to the checksum.
+	maxUnindexedDuplicateHashes = 5_000_000
+)
+
+// addedUniqueKey is a unique key that the ALTER adds, in terms of the
+// table's columns.
+type addedUniqueKey struct {
+	name     string   // for the report
+	columns  []string // the table's columns, i.e. before any renames
+	ordinals []int    // the positions of the columns in a row image
+}
+
+func (k addedUniqueKey) String() string {
+	return fmt.Sprintf("%s (%s)", k.name, strings.Join(k.columns, ", "))
+}
+
+// duplicateChecker finds rows of the table that have the same values for a
+// unique key that the ALTER adds. Without it, the copy silently skips all
+// but one of them, and the migration only fails at the checksum, possibly
+// hours later. The table is scanned for duplicates before the copy, and
+// while it copies the values written by each change are checked, so that
+// it fails as soon as a change introduces one.
+//
+// Both look up values with an index of the table on the key's columns.
+// The keys without one are scanned by hashing the values of each chunk's
+// rows, and checking the hashes against those of the chunks before it,
+// but are left to the checksum while the table copies. Keys on columns
+// that the ALTER adds are left to the checksum.
+type duplicateChecker struct {
+	db        *sql.DB
+	table     *table.TableInfo
+	keys      []addedUniqueKey
+	unindexed []addedUniqueKey
+	logger    *slog.Logger
+
+	sync.Mutex
+	watching bool
+	pending  []map[string]struct{} // literal value tuples to check, per key
+	npending int
+	dropped  int
+}
+
+// newDuplicateChecker returns a duplicateChecker for the unique keys that
+// stmt adds to tbl.
+func newDuplicateChecker(ctx context.Context, db *sql.DB, tbl *table.TableInfo, stmt *statement.AbstractStatement, logger *slog.Logger) (*duplicateChecker, error) {
+	c := &duplicateChecker{db: db, table: tbl, logger: logger}
+	added := stmt.AddedUniqueKeys()
+	if len(added) == 0 {
+		return c, nil
+	}
+	indexes, err := loadIndexes(ctx, db, tbl)
+	if err != nil {
+		return nil, err
+	}
+	// The key's columns are named as they are after the ALTER.
+	original := make(map[string]string)
+	for oldName, newName := range stmt.ColumnRenameMap() {
+		original[strings.ToLower(newName)] = oldName
+	}
+	for _, key := range added {
+		k := addedUniqueKey{name: cmp.Or(key.Name, "UNIQUE")}
+		for _, col := range key.Columns {
+			if oldName, ok := original[strings.ToLower(col)]; ok {
+				col = oldName
+			}
+			i := slices.IndexFunc(tbl.Columns, func(c string) bool { return strings.EqualFold(c, col) })
+			if i < 0 {
+				k.columns = nil
+				break
+			}
+			k.columns = append(k.columns, tbl.Columns[i])
+			k.ordinals = append(k.ordinals, i)
+		}
+		switch {
+		case k.columns == nil:
+			logger.Info("not checking the table for duplicates of a unique key on a column that the ALTER adds", "key", key.Name)
+		case indexes.unique(k.columns):
+			// The table already has a unique key on some of the columns.
+		case !indexes.leading(k.columns):
+			logger.Warn("no index of the table starts with the columns of a unique key that the ALTER adds, so the scan for its duplicates hashes the values of every row, and the changes made while copying are left to the checksum",
+				"table", tbl.TableName, "key", k.String())
+			c.unindexed = append(c.unindexed, k)
+		default:
+			c.keys = append(c.keys, k)
+		}
+	}
+	return c, nil
+}
+
+type index struct {
+	unique  bool
+	columns []string // lower case, or "" for a prefix or expression part
+}
+
+type indexes []index
+
+// loadIndexes returns the indexes of tbl.
+func loadIndexes(ctx context.Context, db *sql.DB, tbl *table.TableInfo) (indexes, error) {
+	rows, err := db.QueryContext(ctx, `SELECT index_name, non_unique, column_name, sub_part
+		FROM information_schema.statistics
+		WHERE table_schema=? AND table_name=?
+		ORDER BY index_name, seq_in_index`, tbl.SchemaName, tbl.TableName)
+	if err != nil {
+		return nil, err
+	}
+	defer utils.CloseAndLog(rows)
+	var idxs indexes
+	var lastName string
+	for rows.Next() {
+		var name string
+		var nonUnique bool
+		var column sql.NullString
+		var subPart sql.NullInt64
+		if err := rows.Scan(&name, &nonUnique, &column, &subPart); err != nil {
+			return nil, err
+		}
+		if len(idxs) == 0 || name != lastName {
+			idxs = append(idxs, index{unique: !nonUnique})
+			lastName = name
+		}
+		col := strings.ToLower(column.String)
+		if subPart.Valid {
+			col = ""
+		}
+		idxs[len(idxs)-1].columns = append(idxs[len(idxs)-1].columns, col)
+	}
+	return idxs, rows.Err()
+}
+
+// unique returns true if a unique index is on some of columns, so that
+// the table can't have duplicates for columns.
+func (idxs indexes) unique(columns []string) bool {
+	return slices.ContainsFunc(idxs, func(idx index) bool {
+		return idx.unique && !slices.ContainsFunc(idx.columns, func(col string) bool {
+			return !slices.ContainsFunc(columns, func(c string) bool { return strings.ToLower(c) == col })
+		})
+	})
+}
+
+// leading returns true if an index starts with columns, in any order, so
+// that it can look up their values.
+func (idxs indexes) leading(columns []string) bool {
+	return slices.ContainsFunc(idxs, func(idx index) bool {
+		if len(idx.columns) < len(columns) {
+			return false
+		}
+		for _, col := range idx.columns[:len(columns)] {
+			if col == "" || !slices.ContainsFunc(columns, func(c string) bool { return strings.ToLower(c) == col }) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// scan scans the table one chunk at a time for rows that have the same
+// values for one of the keys, and returns a report of the first ones it
+// finds as an error.
+func (c *duplicateChecker) scan(ctx context.Context, targetChunkTime time.Duration) error {
+	if len(c.keys) == 0 && len(c.unindexed) == 0 {
+		return nil
+	}
+	c.logger.Info("scanning the table for duplicates of the unique keys that the ALTER adds", "table", c.table.TableName)
+	c.startWatching()
+	chunker, err := table.NewChunker(c.table, table.ChunkerConfig{
+		TargetChunkTime: targetChunkTime,
+		Logger:          c.logger,
+	})
+	if err != nil {
+		return err
+	}
+	if err := chunker.Open(); err != nil {
+		return err
+	}
+	defer utils.CloseAndLog(chunker)
+	found := make([][][]any, len(c.keys))
+	hashed := make([]*hashedValues, len(c.unindexed))
+	for i := range hashed {
+		hashed[i] = &hashedValues{seen: make(map[uint64]struct{})}
+	}
+	for !chunker.IsRead() {
+		chunk, err := chunker.Next()
+		if err != nil {
+			if errors.Is(err, table.ErrTableIsRead) {
+				break
+			}
+			return err
+		}
+		startTime := time.Now()
+		for i, key := range c.keys {
+			if len(found[i]) >= maxDuplicateGroups {
+				continue
+			}
+			values, err := c.duplicatesInChunk(ctx, key, chunk)
+			if err != nil {
+				return err
+			}
+			for _, v := range values {
+				if !slices.ContainsFunc(found[i], func(f []any) bool { return slices.Equal(f, v) }) && len(found[i]) < maxDuplicateGroups {
+					found[i] = append(found[i], v)
+				}
+			}
+		}
+		for i, key := range c.unindexed {
+			if err := c.hashChunk(ctx, key, chunk, hashed[i]); err != nil {
+				return err
+			}
+		}
+		chunker.Feedback(chunk, time.Since(startTime), chunk.ChunkSize)
+	}
+	for i, key := range c.keys {
+		if len(found[i]) > 0 {
+			return c.report(ctx, key, found[i])
+		}
+	}
+	for i, key := range c.unindexed {
+		values, err := c.confirmDuplicates(ctx, key, hashed[i].candidates)
+		if err != nil {
+			return err
+		}
+		if len(values) > 0 {
+			return c.report(ctx, key, values)
+		}
+	}
+	c.logger.Info("found no duplicates of the unique keys that the ALTER adds", "table", c.table.TableName)
+	return nil
+}
+
+// duplicatesInChunk returns the distinct values of key of the rows in
+// chunk that another row of the table has the same values as.
+func (c *duplicateChecker) duplicatesInChunk(ctx context.Context, key addedUniqueKey, chunk *table.Chunk) ([][]any, error) {
+	conds := make([]string, 0, len(key.columns))
+	matches := make([]string, 0, len(key.columns))
+	for _, col := range key.columns {
+		quoted := table.QuoteColumns([]string{col})
+		conds = append(conds, "c."+quoted+" IS NOT NULL")
+		matches = append(matches, "o."+quoted+" = c."+quoted)
+	}
+	var otherRow []string
+	for _, col := range c.table.KeyColumns {
+		quoted := table.QuoteColumns([]string{col})
+		otherRow = append(otherRow, "o."+quoted+" <> c."+quoted)
+	}
+	query := fmt.Sprintf("SELECT DISTINCT %s FROM %s AS c WHERE %s AND %s AND EXISTS (SELECT 1 FROM %s AS o WHERE %s AND (%s)) LIMIT %d",
+		qualifiedColumns("c", key.columns),
+		c.table.QuotedTableName,
+		chunk.String(),
+		strings.Join(conds, " AND "),
+		c.table.QuotedTableName,
+		strings.Join(matches, " AND "),
+		strings.Join(otherRow, " OR "),
+		maxDuplicateGroups,
+	)
+	return c.queryValues(ctx, query, len(key.columns))
+}
+
+// hashedValues is what the scan keeps of the values of a key without an
+// index: the hashes of those it has read, and the values of the rows whose
+// hash it had already read, which may be duplicates.
+type hashedValues struct {
+	seen       map[uint64]struct{}
+	candidates [][]any
+	overflowed bool
+}
+
+// hashChunk hashes the values of key of the rows in chunk into h. Strings
+// are hashed by their weight in the column's collation, so that values
+// the key treats as the same have the same hash.
+func (c *duplicateChecker) hashChunk(ctx context.Context, key addedUniqueKey, chunk *table.Chunk, h *hashedValues) error {
+	if h.overflowed || len(h.candidates) >= maxDuplicateGroups {
+		return nil
+	}
+	conds := make([]string, 0, len(key.columns))
+	weights := make([]string, 0, len(key.columns))
+	for _, col := range key.columns {
+		quoted := table.QuoteColumns([]string{col})
+		conds = append(conds, quoted+" IS NOT NULL")
+		tp, _ := c.table.GetColumnMySQLType(col)
+		if isCollatedType(tp) {
+			weights = append(weights, "WEIGHT_STRING("+quoted+")")
+		} else {
+			weights = append(weights, quoted)
+		}
+	}
+	query := fmt.Sprintf("SELECT %s, %s FROM %s WHERE %s AND %s",
+		table.QuoteColumns(key.columns),
+		strings.Join(weights, ", "),
+		c.table.QuotedTableName,
+		chunk.String(),
+		strings.Join(conds, " AND "),
+	)
+	rows, err := c.db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	values, err := scanValues(rows, 2*len(key.columns))
+	if err != nil {
+		return err
+	}
+	n := len(key.columns)
+	for _, row := range values {
+		hash := fnv.New64a()
+		for _, v := range row[n:] {
+			s := fmt.Sprint(v)
+			fmt.Fprintf(hash, "%d:%s", len(s), s)
+		}
+		sum := hash.Sum64()
+		if _, ok := h.seen[sum]; !ok {
+			if len(h.seen) >= maxUnindexedDuplicateHashes {
+				c.logger.Warn("the table has too many rows to scan for duplicates of a unique key without an index. The checksum will find any",
+					"table", c.table.TableName, "key", key.String())
+				h.overflowed = true
+				h.seen = nil
+				return nil
+			}
+			h.seen[sum] = struct{}{}
+			continue
+		}
+		if !slices.ContainsFunc(h.candidates, func(f []any) bool { return slices.Equal(f, row[:n]) }) {
+			h.candidates = append(h.candidates, row[:n])
+			if len(h.candidates) >= maxDuplicateGroups {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// confirmDuplicates returns the candidates that more than one row of the
+// table has as the values of key. Two values can have the same hash.
+func (c *duplicateChecker) confirmDuplicates(ctx context.Context, key addedUniqueKey, candidates [][]any) ([][]any, error) {
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(key.columns)), ", ") + ")"
+	tuples := make([]string, 0, len(candidates))
+	args := make([]any, 0, len(candidates)*len(key.columns))
+	for _, values := range candidates {
+		tuples = append(tuples, placeholders)
+		args = append(args, values...)
+	}
+	columns := table.QuoteColumns(key.columns)
+	rows, err := c.db.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE (%s) IN (%s) GROUP BY %s HAVING COUNT(*) > 1 LIMIT %d",
+		columns,
+		c.table.QuotedTableName,
+		columns,
+		strings.Join(tuples, ", "),
+		columns,
+		maxDuplicateGroups,
+	), args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanValues(rows, len(key.columns))
+}
+
+// isCollatedType returns true if values of the MySQL type tp are compared
+// with a collation.
+func isCollatedType(tp string) bool {
+	tp = strings.ToLower(tp)
+	for _, prefix := range []string{"char", "varchar", "tinytext", "text", "mediumtext", "longtext"} {
+		if tp == prefix || strings.HasPrefix(tp, prefix+"(") || strings.HasPrefix(tp, prefix+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// observe queues the values of the keys in a row that a change wrote to
+// the table, for the watch to check.
+func (c *duplicateChecker) observe(row []any) {
+	c.Lock()
+	defer c.Unlock()
+	if !c.watching {
+		return
+	}
+	for i, key := range c.keys {
+		tuple, ok := c.literalTuple(key, row)
+		if !ok {
+			continue
+		}
+		if _, ok := c.pending[i][tuple]; ok {
+			continue
+		}
+		if c.npending >= maxPendingDuplicateChecks {
+			c.dropped++
+			continue
+		}
+		c.pending[i][tuple] = struct{}{}
+		c.npending++
+	}
+}
+
+// literalTuple returns the values of key in row as a tuple of SQL
+// literals, or false if one of them is NULL (NULLs are never duplicates).
+func (c *duplicateChecker) literalTuple(key addedUniqueKey, row []any) (string, bool) {
+	literals := make([]string, len(key.ordinals))
+	for j, ordinal := range key.ordinals {
+		if ordinal >= len(row) || row[ordinal] == nil {
+			return "", false
+		}
+		tp, _ := c.table.GetColumnMySQLType(key.columns[j])
+		datum, err := table.NewDatumFromValue(row[ordinal], tp)
+		if err != nil {
+			c.logger.Warn("could not check a changed row for duplicates", "key", key.String(), "error", err)
+			return "", false
+		}
+		literals[j] = datum.String()
+	}
+	return "(" + strings.Join(literals, ", ") + ")", true
+}
+
+// startWatching starts queuing the values that changes write. The scan
+// starts it, so that the watch also checks the changes made while the
+// table is scanned.
+func (c *duplicateChecker) startWatching() {
+	c.Lock()
+	defer c.Unlock()
+	if c.watching {
+		return
+	}
+	c.watching = true
+	c.pending = make([]map[string]struct{}, len(c.keys))
+	for i := range c.pending {
+		c.pending[i] = make(map[string]struct{})
+	}
+}
+
+// watch checks the values queued by observe every duplicateCheckInterval
+// until ctx is done, and returns a report as an error if a change wrote
+// values to a row that another row of the table already has.
+func (c *duplicateChecker) watch(ctx context.Context) error {
+	if len(c.keys) == 0 {
+		return nil
+	}
+	c.startWatching()
+	defer func() {
+		c.Lock()
+		defer c.Unlock()
+		c.watching = false
+		c.pending, c.npending = nil, 0
+	}()
+	ticker := time.NewTicker(duplicateCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := c.checkPending(ctx); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+}
+
+// checkPending checks the values queued by observe.
+func (c *duplicateChecker) checkPending(ctx context.Context) error {
+	c.Lock()
+	pending := c.pending
+	c.pending = make([]map[string]struct{}, len(c.keys))
+	for i := range c.pending {
+		c.pending[i] = make(map[string]struct{})
+	}
+	c.npending = 0
+	if c.dropped > 0 {
+		c.logger.Warn("too many changed rows to check for duplicates, some were left to the checksum", "table", c.table.TableName, "rows", c.dropped)
+		c.dropped = 0
+	}
+	c.Unlock()
+	for i, key := range c.keys {
+		tuples := make([]string, 0, len(pending[i]))
+		for tuple := range pending[i] {
+			tuples = append(tuples, tuple)
+		}
+		for batch := range slices.Chunk(tuples, duplicateCheckBatchSize) {
+			columns := table.QuoteColumns(key.columns)
+			query := fmt.Sprintf("SELECT %s FROM %s WHERE (%s) IN (%s) GROUP BY %s HAVING COUNT(*) > 1 LIMIT %d",
+				columns,
+				c.table.QuotedTableName,
+				columns,
+				strings.Join(batch, ", "),
+				columns,
+				maxDuplicateGroups,
+			)
+			found, err := c.queryValues(ctx, query, len(key.columns))
+			if err != nil {
+				return err
+			}
+			if len(found) > 0 {
+				return c.report(ctx, key, found)
+			}
+		}
+	}
+	return nil
+}
+
+// report returns an error that describes the rows of each group of
+// duplicate values of key.
+func (c *duplicateChecker) report(ctx context.Context, key addedUniqueKey, groups [][]any) error {
+	var where []string
+	for _, col := range key.columns {
+		where = append(where, table.QuoteColumns([]string{col})+" = ?")
+	}
+	keyColumns := table.QuoteColumns(c.table.KeyColumns)
+	var lines []string
+	for _, values := range groups {
+		var count int
+		if err := c.db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s",
+			c.table.QuotedTableName, strings.Join(where, " AND ")), values...).Scan(&count); err != nil {
+			return err
+		}
+		rows, err := c.db.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY %s LIMIT %d",
+			keyColumns, c.table.QuotedTableName, strings.Join(where, " AND "), keyColumns, maxDuplicateGroupKeys), values...)
+		if err != nil {
+			return err
+		}
+		keys, err := scanValues(rows, len(c.table.KeyColumns))
+		if err != nil {
+			return err
+		}
+		formatted := make([]string, 0, len(keys))
+		for _, k := range keys {
+			formatted = append(formatted, formatValues(k))
+		}
+		if count > len(keys) {
+			formatted = append(formatted, "...")
+		}
+		lines = append(lines, fmt.Sprintf("  %s: %d rows, with %s %s", formatValues(values), count,
+			strings.Join(c.table.KeyColumns, ", "), strings.Join(formatted, ", ")))
+	}
+	return fmt.Errorf("the ALTER adds unique key %s, but table %s has rows with the same values for it. Remove the duplicates and run the migration again. The first found:\n%s",
+		key.String(), c.table.TableName, strings.Join(lines, "\n"))
+}
+
+func (c *duplicateChecker) queryValues(ctx context.Context, query string, n int) ([][]any, error) {
+	rows, err := c.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return scanValues(rows, n)
+}
+
+// scanValues scans the n columns of each of rows, and closes them. Bytes
+// are returned as strings, so that they compare with the column's
+// collation when they are used as arguments.
+func scanValues(rows *sql.Rows, n int) ([][]any, error) {
+	defer utils.CloseAndLog(rows)
+	var result [][]any
+	for rows.Next() {
+		values := make([]any, n)
+		ptrs := make([]any, n)
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		result = append(result, values)
+	}
+	return result, rows.Err()
+}
+
+// formatValues formats values as a tuple, e.g. (1, 'a').
+func formatValues(values []any) string {
+	formatted := make([]string, len(values))
+	for i, v := range values {
+		switch v := v.(type) {
+		case string:
+			formatted[i] = "'" + strings.ReplaceAll(v, "'", "''") + "'"
+		default:
+			formatted[i] = fmt.Sprint(v)
+		}
+	}
+	return "(" + strings.Join(formatted, ", ") + ")"
+}
+
+// qualifiedColumns returns the quoted columns, qualified by alias.
+func qualifiedColumns(alias string, columns []string) string {
+	qualified := make([]string, len(columns))
+	for i, col := range columns {
+		qualified[i] = alias + "." + table.QuoteColumns([]string{col})
+	}
+	return strings.Join(qualified, ", ")
+}
@@ -0,0 +1,106 @@
+package migration
+
+import (
+	"testing"
+
+	"github.com/block/spirit/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexesCover(t *testing.T) {
+	idxs := indexes{
+		{unique: true, columns: []string{"id"}},
+		{unique: false, columns: []string{"b", "a", "c"}},
+		{unique: false, columns: []string{"", "d"}},
+		{unique: true, columns: []string{"e", "f"}},
+	}
+	require.True(t, idxs.leading([]string{"b"}))
+	require.True(t, idxs.leading([]string{"A", "b"}))
+	require.False(t, idxs.leading([]string{"a"}))
+	require.False(t, idxs.leading([]string{"d"}))
+	require.False(t, idxs.leading([]string{"b", "c"}))
+
+	require.True(t, idxs.unique([]string{"id", "b"}))
+	require.True(t, idxs.unique([]string{"f", "E"}))
+	require.False(t, idxs.unique([]string{"e"}))
+	require.False(t, idxs.unique([]string{"a", "b"}))
+}
+
+func TestFormatValues(t *testing.T) {
+	require.Equal(t, "(1, 'it''s', <nil>)", formatValues([]any{int64(1), "it's", nil}))
+}
+
+func TestMigrateReportsDuplicates(t *testing.T) {
+	t.Parallel()
+	testutils.NewTestTable(t, "dupcheckt1", `CREATE TABLE dupcheckt1 (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		email varchar(100),
+		INDEX (email)
+	)`)
+	testutils.RunSQL(t, `INSERT INTO dupcheckt1 (email) VALUES ('a@example.com'), ('b@example.com'), ('a@example.com'), (NULL), (NULL), ('a@example.com')`)
+
+	m := NewTestRunner(t, "dupcheckt1", "ADD UNIQUE INDEX uk_email (email)")
+	err := m.Run(t.Context())
+	require.ErrorContains(t, err, "the ALTER adds unique key uk_email (email), but table dupcheckt1 has rows with the same values for it")
+	require.ErrorContains(t, err, "('a@example.com'): 3 rows, with id (1), (3), (6)")
+	require.NotContains(t, err.Error(), "b@example.com")
+	require.NoError(t, m.Close())
+
+	// With the check skipped, the checksum finds the duplicates instead.
+	m = NewTestRunner(t, "dupcheckt1", "ADD UNIQUE INDEX uk_email (email)", WithSkipDuplicateCheck())
+	require.Error(t, m.Run(t.Context()))
+	require.NoError(t, m.Close())
+}
+
+func TestMigrateReportsDuplicatesWithoutIndex(t *testing.T) {
+	t.Parallel()
+	testutils.NewTestTable(t, "dupcheckt3", `CREATE TABLE dupcheckt3 (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		email varchar(100) COLLATE utf8mb4_0900_ai_ci,
+		n int
+	)`)
+	testutils.RunSQL(t, `INSERT INTO dupcheckt3 (email, n) VALUES ('a@example.com', 1), ('b@example.com', 1), ('A@example.com', 2), (NULL, 3), (NULL, 3)`)
+
+	// No index starts with email, so the scan hashes the values of every
+	// row, by the weight of the column's collation.
+	m := NewTestRunner(t, "dupcheckt3", "ADD UNIQUE INDEX uk_email (email)")
+	err := m.Run(t.Context())
+	require.ErrorContains(t, err, "the ALTER adds unique key uk_email (email), but table dupcheckt3 has rows with the same values for it")
+	require.ErrorContains(t, err, "rows, with id (1), (3)")
+	require.NotContains(t, err.Error(), "b@example.com")
+	require.NoError(t, m.Close())
+
+	m = NewTestRunner(t, "dupcheckt3", "ADD UNIQUE INDEX uk_n (n)")
+	err = m.Run(t.Context())
+	require.ErrorContains(t, err, "(1): 2 rows, with id (1), (2)")
+	require.NoError(t, m.Close())
+
+	testutils.RunSQL(t, `UPDATE dupcheckt3 SET n = id`)
+	m = NewTestRunner(t, "dupcheckt3", "ADD UNIQUE INDEX uk_n (n)")
+	require.NoError(t, m.Run(t.Context()))
+	require.NoError(t, m.Close())
+}
+
+func TestIsCollatedType(t *testing.T) {
+	require.True(t, isCollatedType("varchar(100)"))
+	require.True(t, isCollatedType("TEXT"))
+	require.True(t, isCollatedType("char(2) binary"))
+	require.False(t, isCollatedType("int"))
+	require.False(t, isCollatedType("varbinary(10)"))
+	require.False(t, isCollatedType("datetime(6)"))
+}
+
+func TestMigrateWithoutDuplicates(t *testing.T) {
+	t.Parallel()
+	testutils.NewTestTable(t, "dupcheckt2", `CREATE TABLE dupcheckt2 (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		a int NOT NULL,
+		b int NOT NULL,
+		INDEX (a, b)
+	)`)
+	testutils.RunSQL(t, `INSERT INTO dupcheckt2 (a, b) VALUES (1, 1), (1, 2), (2, 1)`)
+
+	m := NewTestRunner(t, "dupcheckt2", "ADD UNIQUE INDEX (b, a)")
+	require.NoError(t, m.Run(t.Context()))
+	require.NoError(t, m.Close())
+}
@@ -0,0 +1,247 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/block/spirit/pkg/dbconn"
+	"github.com/block/spirit/pkg/table"
+	"github.com/block/spirit/pkg/throttler"
+	"github.com/block/spirit/pkg/utils"
+)
+
+// maxConstraintNameLength is the longest name MySQL allows for a constraint.
+const maxConstraintNameLength = 64
+
+// foreignKeyName returns the name a foreign key is recreated under on the
+// new table, or on a table that references it. Constraint names are unique
+// per schema, so the name can't be reused while the original table still
+// has it. Toggling a leading underscore means that the next migration of
+// the table gets the original name back.
+//
+// A name that is already as long as MySQL allows has no room for the
+// underscore, so it is truncated by a character first. Toggling it back
+// can't restore the last character, so such a constraint is renamed back
+// to its original name by restoreForeignKeyNames once the old table is gone.
+func foreignKeyName(name string) string {
+	if newName, ok := strings.CutPrefix(name, "_"); ok {
+		return newName
+	}
+	if len(name) >= maxConstraintNameLength {
+		name = name[:maxConstraintNameLength-1]
+	}
+	return "_" + name
+}
+
+// isTruncatedByToggle returns true if foreignKeyName truncates name, so
+// that toggling it back doesn't restore it.
+func isTruncatedByToggle(name string) bool {
+	return !strings.HasPrefix(name, "_") && len(name) >= maxConstraintNameLength
+}
+
+// recordTruncatedForeignKeys records the foreign keys on the table, and on
+// the tables that reference it, whose names are truncated when they are
+// toggled. It is called before the cutover, while they are still under
+// their original names.
+func (c *tableChange) recordTruncatedForeignKeys(ctx context.Context) error {
+	fks, err := table.LoadForeignKeys(ctx, c.runner.db, c.table.SchemaName, c.table.TableName)
+	if err != nil {
+		return err
+	}
+	referencing, err := table.LoadReferencingForeignKeys(ctx, c.runner.db, c.table.SchemaName, c.table.TableName)
+	if err != nil {
+		return err
+	}
+	c.truncatedForeignKeys = nil
+	for _, fk := range append(fks, referencing...) {
+		if isTruncatedByToggle(fk.Name) {
+			c.truncatedForeignKeys = append(c.truncatedForeignKeys, fk)
+		}
+	}
+	return nil
+}
+
+// restoreForeignKeyNames renames the foreign keys recorded by
+// recordTruncatedForeignKeys back to their original names. It is called
+// after the cutover, once the old table that held the original names of
+// the table's own foreign keys has been dropped. Each constraint is dropped
+// and added again, which with foreign_key_checks disabled only changes
+// metadata. It keeps the definition that the constraint has now, since the
+// ALTER may have changed it.
+func (c *tableChange) restoreForeignKeyNames(ctx context.Context) error {
+	for _, orig := range c.truncatedForeignKeys {
+		fks, err := table.LoadForeignKeys(ctx, c.runner.db, orig.Schema, orig.Table)
+		if err != nil {
+			return err
+		}
+		for _, fk := range fks {
+			if fk.Name != foreignKeyName(orig.Name) {
+				continue
+			}
+			toggled := fk.Name
+			fk.Name = orig.Name
+			if err := dbconn.Exec(ctx, c.runner.db, "ALTER TABLE %n.%n DROP FOREIGN KEY %n, ADD "+fk.Definition(),
+				fk.Schema, fk.Table, toggled); err != nil {
+				return err
+			}
+			c.runner.logger.Info("restored the name of a foreign key", "table", fk.Table, "foreign-key", fk.Name)
+		}
+	}
+	return nil
+}
+
+// addForeignKeys recreates the foreign keys of the table on the new table,
+// under their toggled names. A foreign key that references the table itself
+// references the new table instead. It is called before the ALTER is
+// applied, so that the ALTER can rename or drop their columns the same way
+// it could on the table.
+func (c *tableChange) addForeignKeys(ctx context.Context) error {
+	fks, err := table.LoadForeignKeys(ctx, c.runner.db, c.table.SchemaName, c.table.TableName)
+	if err != nil {
+		return err
+	}
+	if len(fks) == 0 {
+		return nil
+	}
+	clauses := make([]string, 0, len(fks))
+	for _, fk := range fks {
+		fk.Name = foreignKeyName(fk.Name)
+		if fk.ReferencedSchema == c.table.SchemaName && fk.ReferencedTable == c.table.TableName {
+			fk.ReferencedTable = c.newTable.TableName
+		}
+		clauses = append(clauses, "ADD "+fk.Definition())
+	}
+	c.runner.logger.Info("recreating foreign keys on the new table", "table", c.newTable.TableName, "foreign-keys", len(fks))
+	return dbconn.Exec(ctx, c.runner.db, "ALTER TABLE %n.%n "+strings.Join(clauses, ", "),
+		c.newTable.SchemaName, c.newTable.TableName)
+}
+
+// verifyForeignKeys checks the referential integrity of the new table
+// before the cutover, since the rows were copied into it with
+// foreign_key_checks disabled. The rows of the new table must have a
+// matching row in each table it references, and the rows of each table
+// that references the table must have a matching row in the new table.
+// A constraint that was carried over from the table may have as many
+// rows without a match as it does against the table (they can only get
+// there with foreign_key_checks disabled), but one added by the ALTER
+// may have none.
+func (c *tableChange) verifyForeignKeys(ctx context.Context) error {
+	fks, err := table.LoadForeignKeys(ctx, c.runner.db, c.table.SchemaName, c.table.TableName)
+	if err != nil {
+		return err
+	}
+	original := make(map[string]table.ForeignKey, len(fks))
+	for _, fk := range fks {
+		original[foreignKeyName(fk.Name)] = fk
+	}
+	newFKs, err := table.LoadForeignKeys(ctx, c.runner.db, c.newTable.SchemaName, c.newTable.TableName)
+	if err != nil {
+		return err
+	}
+	for _, fk := range newFKs {
+		var expected int64
+		if orig, ok := original[fk.Name]; ok {
+			if expected, err = c.countOrphanedRows(ctx, orig); err != nil {
+				return err
+			}
+		}
+		if err := c.checkOrphanedRows(ctx, fk, expected); err != nil {
+			return err
+		}
+	}
+	referencing, err := table.LoadReferencingForeignKeys(ctx, c.runner.db, c.table.SchemaName, c.table.TableName)
+	if err != nil {
+		return err
+	}
+	for _, fk := range referencing {
+		expected, err := c.countOrphanedRows(ctx, fk)
+		if err != nil {
+			return err
+		}
+		fk.ReferencedTable = c.newTable.TableName
+		if err := c.checkOrphanedRows(ctx, fk, expected); err != nil {
+			return err
+		}
+	}
+	c.runner.logger.Info("verified foreign keys of the new table", "table", c.table.TableName,
+		"foreign-keys", len(newFKs), "referencing-foreign-keys", len(referencing))
+	return nil
+}
+
+// checkOrphanedRows returns an error if fk has more than expected rows
+// without a matching row in the table it references.
+func (c *tableChange) checkOrphanedRows(ctx context.Context, fk table.ForeignKey, expected int64) error {
+	orphaned, err := c.countOrphanedRows(ctx, fk)
+	if err != nil {
+		return err
+	}
+	if orphaned > expected {
+		return fmt.Errorf("foreign key %s on %s has %d rows without a matching row in %s, but expected at most %d",
+			fk.Name, fk.Table, orphaned, fk.ReferencedTable, expected)
+	}
+	return nil
+}
+
+// countOrphanedRows counts the rows of fk's table without a matching row
+// in the table it references. Like the copy, it reads the table one chunk
+// at a time, paced by the migration's throttler, since the referencing
+// tables can be as large as the table.
+func (c *tableChange) countOrphanedRows(ctx context.Context, fk table.ForeignKey) (int64, error) {
+	tbl, err := c.foreignKeyTable(ctx, fk)
+	if err != nil {
+		return 0, err
+	}
+	chunker, err := table.NewChunker(tbl, table.ChunkerConfig{
+		TargetChunkTime: c.runner.Settings().TargetChunkTime,
+		Logger:          c.runner.logger,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := chunker.Open(); err != nil {
+		return 0, err
+	}
+	defer utils.CloseAndLog(chunker)
+	thr := c.runner.throttler
+	if thr == nil {
+		thr = &throttler.Noop{}
+	}
+	var total int64
+	for !chunker.IsRead() {
+		chunk, err := chunker.Next()
+		if err != nil {
+			if errors.Is(err, table.ErrTableIsRead) {
+				break
+			}
+			return 0, err
+		}
+		thr.BlockWait(ctx)
+		startTime := time.Now()
+		var count int64
+		if err := c.runner.db.QueryRowContext(ctx, fk.OrphanedRowsQuery(chunk.String())).Scan(&count); err != nil {
+			return 0, err
+		}
+		total += count
+		chunker.Feedback(chunk, time.Since(startTime), chunk.ChunkSize)
+	}
+	return total, nil
+}
+
+// foreignKeyTable returns the table that fk is on: the table, the new
+// table, or a table that references the table.
+func (c *tableChange) foreignKeyTable(ctx context.Context, fk table.ForeignKey) (*table.TableInfo, error) {
+	for _, tbl := range []*table.TableInfo{c.table, c.newTable} {
+		if tbl.SchemaName == fk.Schema && tbl.TableName == fk.Table {
+			return tbl, nil
+		}
+	}
+	tbl := table.NewTableInfo(c.runner.db, fk.Schema, fk.Table)
+	tbl.DisableAnalyze = true
+	if err := tbl.SetInfo(ctx); err != nil {
+		return nil, err
+	}
+	return tbl, nil
+}
@@ -0,0 +1,172 @@
+package migration
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/block/spirit/pkg/table"
+	"github.com/block/spirit/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForeignKeyName(t *testing.T) {
+	require.Equal(t, "_fk_customer", foreignKeyName("fk_customer"))
+	require.Equal(t, "fk_customer", foreignKeyName("_fk_customer"))
+	// A name that is already as long as MySQL allows is truncated to make
+	// room for the underscore, so toggling it back doesn't restore it.
+	long := strings.Repeat("a", 63) + "b"
+	require.True(t, isTruncatedByToggle(long))
+	require.Equal(t, "_"+long[:63], foreignKeyName(long))
+	require.Equal(t, long[:63], foreignKeyName(foreignKeyName(long)))
+	require.False(t, isTruncatedByToggle(long[:63]))
+	require.False(t, isTruncatedByToggle(foreignKeyName(long)))
+}
+
+func TestMigrateTableWithLongForeignKeyNames(t *testing.T) {
+	t.Parallel()
+	parentFK := "fklongt1_parent_" + strings.Repeat("x", 48)
+	childFK := "fklongchild_t1_" + strings.Repeat("y", 49)
+	testutils.NewTestTable(t, "fklongparent", `CREATE TABLE fklongparent (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY
+	)`)
+	tt := testutils.NewTestTable(t, "fklongt1", `CREATE TABLE fklongt1 (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		parent_id int NOT NULL,
+		a int NOT NULL,
+		CONSTRAINT `+parentFK+` FOREIGN KEY (parent_id) REFERENCES fklongparent (id)
+	)`)
+	testutils.NewTestTable(t, "fklongchild", `CREATE TABLE fklongchild (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		t1_id int NOT NULL,
+		CONSTRAINT `+childFK+` FOREIGN KEY (t1_id) REFERENCES fklongt1 (id)
+	)`)
+	testutils.RunSQL(t, `INSERT INTO fklongparent VALUES (1)`)
+	testutils.RunSQL(t, `INSERT INTO fklongt1 (parent_id, a) VALUES (1, 1)`)
+	testutils.RunSQL(t, `INSERT INTO fklongchild (t1_id) VALUES (1)`)
+
+	m := NewTestRunner(t, "fklongt1", "MODIFY a bigint NOT NULL", WithForeignKeys())
+	require.NoError(t, m.Run(t.Context()))
+	require.NoError(t, m.Close())
+
+	// The names that had no room for the underscore were restored after
+	// the old table was dropped.
+	fks, err := table.LoadForeignKeys(t.Context(), tt.DB, "test", "fklongt1")
+	require.NoError(t, err)
+	require.Len(t, fks, 1)
+	require.Equal(t, parentFK, fks[0].Name)
+	require.Equal(t, "fklongparent", fks[0].ReferencedTable)
+	fks, err = table.LoadReferencingForeignKeys(t.Context(), tt.DB, "test", "fklongt1")
+	require.NoError(t, err)
+	require.Len(t, fks, 1)
+	require.Equal(t, childFK, fks[0].Name)
+	require.Equal(t, "fklongt1", fks[0].ReferencedTable)
+}
+
+func TestForeignKeysSingleTableOnly(t *testing.T) {
+	pw := "spirit"
+	_, err := NewRunner(&Migration{
+		Host:        "127.0.0.1:1",
+		Username:    "spirit",
+		Password:    &pw,
+		Database:    "test",
+		Statement:   "ALTER TABLE t1 ADD INDEX (a); ALTER TABLE t2 ADD INDEX (a)",
+		ForeignKeys: true,
+	})
+	require.ErrorContains(t, err, "--foreign-keys is only supported for single-table migrations")
+}
+
+func TestRepointForeignKeysStep(t *testing.T) {
+	fks := []table.ForeignKey{{
+		Name:              "fk_a",
+		Schema:            "test",
+		Table:             "child",
+		Columns:           []string{"a_id"},
+		ReferencedSchema:  "test",
+		ReferencedTable:   "parent",
+		ReferencedColumns: []string{"id"},
+		OnUpdate:          "RESTRICT",
+		OnDelete:          "CASCADE",
+	}, {
+		Name:              "_fk_b",
+		Schema:            "test",
+		Table:             "child",
+		Columns:           []string{"b_id"},
+		ReferencedSchema:  "test",
+		ReferencedTable:   "parent",
+		ReferencedColumns: []string{"id"},
+		OnUpdate:          "RESTRICT",
+		OnDelete:          "RESTRICT",
+	}}
+	step := repointForeignKeysStep(fks, "_parent_new")
+	require.True(t, step.atomic)
+	require.Equal(t, []string{"ALTER TABLE `test`.`child`" +
+		" DROP FOREIGN KEY `fk_a`, ADD CONSTRAINT `_fk_a` FOREIGN KEY (`a_id`) REFERENCES `test`.`_parent_new` (`id`) ON DELETE CASCADE ON UPDATE RESTRICT," +
+		" DROP FOREIGN KEY `_fk_b`, ADD CONSTRAINT `fk_b` FOREIGN KEY (`b_id`) REFERENCES `test`.`_parent_new` (`id`) ON DELETE RESTRICT ON UPDATE RESTRICT",
+	}, step.do)
+	require.Equal(t, []string{"ALTER TABLE `test`.`child`" +
+		" DROP FOREIGN KEY `_fk_a`, ADD CONSTRAINT `fk_a` FOREIGN KEY (`a_id`) REFERENCES `test`.`parent` (`id`) ON DELETE CASCADE ON UPDATE RESTRICT," +
+		" DROP FOREIGN KEY `fk_b`, ADD CONSTRAINT `_fk_b` FOREIGN KEY (`b_id`) REFERENCES `test`.`parent` (`id`) ON DELETE RESTRICT ON UPDATE RESTRICT",
+	}, step.undo)
+}
+
+func TestMigrateTableWithForeignKeys(t *testing.T) {
+	t.Parallel()
+	testutils.NewTestTable(t, "fkparent", `CREATE TABLE fkparent (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		name varchar(255) NOT NULL
+	)`)
+	tt := testutils.NewTestTable(t, "fkt1", `CREATE TABLE fkt1 (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		parent_id int NOT NULL,
+		manager_id int,
+		a int NOT NULL,
+		CONSTRAINT fkt1_parent FOREIGN KEY (parent_id) REFERENCES fkparent (id) ON DELETE CASCADE,
+		CONSTRAINT fkt1_manager FOREIGN KEY (manager_id) REFERENCES fkt1 (id)
+	)`)
+	testutils.NewTestTable(t, "fkchild", `CREATE TABLE fkchild (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		t1_id int NOT NULL,
+		CONSTRAINT fkchild_t1 FOREIGN KEY (t1_id) REFERENCES fkt1 (id)
+	)`)
+	testutils.RunSQL(t, `INSERT INTO fkparent (name) VALUES ('a'), ('b')`)
+	testutils.RunSQL(t, `INSERT INTO fkt1 (parent_id, manager_id, a) VALUES (1, NULL, 1), (2, 1, 2), (2, 1, 3)`)
+	testutils.RunSQL(t, `INSERT INTO fkchild (t1_id) VALUES (1), (3)`)
+
+	// Without --foreign-keys the migration is refused.
+	m := NewTestRunner(t, "fkt1", "MODIFY a bigint NOT NULL")
+	require.ErrorContains(t, m.Run(t.Context()), "foreign key constraints are not supported")
+	require.NoError(t, m.Close())
+
+	m = NewTestRunner(t, "fkt1", "MODIFY a bigint NOT NULL", WithForeignKeys())
+	require.NoError(t, m.Run(t.Context()))
+	require.NoError(t, m.Close())
+
+	// The table's constraints were recreated under their toggled names,
+	// and the self-reference references the table itself.
+	fks, err := table.LoadForeignKeys(t.Context(), tt.DB, "test", "fkt1")
+	require.NoError(t, err)
+	require.Len(t, fks, 2)
+	require.Equal(t, "_fkt1_manager", fks[0].Name)
+	require.Equal(t, "fkt1", fks[0].ReferencedTable)
+	require.Equal(t, "_fkt1_parent", fks[1].Name)
+	require.Equal(t, "fkparent", fks[1].ReferencedTable)
+	require.Equal(t, "CASCADE", fks[1].OnDelete)
+
+	// The constraint on fkchild was repointed at the migrated table.
+	fks, err = table.LoadReferencingForeignKeys(t.Context(), tt.DB, "test", "fkt1")
+	require.NoError(t, err)
+	require.Len(t, fks, 1)
+	require.Equal(t, "_fkchild_t1", fks[0].Name)
+	require.Equal(t, "fkchild", fks[0].Table)
+
+	// The constraints are enforced.
+	_, err = tt.DB.ExecContext(t.Context(), `INSERT INTO fkchild (t1_id) VALUES (100)`)
+	require.ErrorContains(t, err, "foreign key constraint fails")
+	_, err = tt.DB.ExecContext(t.Context(), `INSERT INTO fkt1 (parent_id, a) VALUES (100, 1)`)
+	require.ErrorContains(t, err, "foreign key constraint fails")
+	testutils.RunSQL(t, `DELETE FROM fkchild`)
+	testutils.RunSQL(t, `DELETE FROM fkparent WHERE id = 2`)
+	var count int
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), "SELECT COUNT(*) FROM fkt1").Scan(&count))
+	require.Equal(t, 1, count)
+}
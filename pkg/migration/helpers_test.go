@@ -193,6 +193,66 @@ func WithSkipDropAfterCutover() RunnerOption {
 	}
 }
 
+// WithRevertWindow streams changes back to the old table for d after cutover.
+func WithRevertWindow(d time.Duration) RunnerOption {
+	return func(m *Migration) {
+		m.RevertWindow = d
+	}
+}
+
+// WithGradualDrop empties the old table in chunks before dropping it.
+func WithGradualDrop() RunnerOption {
+	return func(m *Migration) {
+		m.GradualDrop = true
+	}
+}
+
+// WithDeferSecondaryIndexes adds the secondary indexes of the new table
+// after the copy instead of before it.
+func WithDeferSecondaryIndexes() RunnerOption {
+	return func(m *Migration) {
+		m.DeferSecondaryIndexes = true
+	}
+}
+
+// WithForeignKeys opts into support for tables with foreign keys.
+func WithForeignKeys() RunnerOption {
+	return func(m *Migration) {
+		m.ForeignKeys = true
+	}
+}
+
+func WithSkipDuplicateCheck() RunnerOption {
+	return func(m *Migration) {
+		m.SkipDuplicateCheck = true
+	}
+}
+
+func WithSkipLossyCheck() RunnerOption {
+	return func(m *Migration) {
+		m.SkipLossyCheck = true
+	}
+}
+
+// WithColumnExpr computes column from expr instead of copying it.
+func WithColumnExpr(column, expr string) RunnerOption {
+	return func(m *Migration) {
+		if m.ColumnExpr == nil {
+			m.ColumnExpr = make(map[string]string)
+		}
+		m.ColumnExpr[column] = expr
+	}
+}
+
+// WithPlanRegression compares the plans of the queries in the file at path
+// before cutover, and either warns or blocks on regressions.
+func WithPlanRegression(path, action string) RunnerOption {
+	return func(m *Migration) {
+		m.PlanRegressionQueries = path
+		m.PlanRegression = action
+	}
+}
+
 // newTestMigration creates a Migration with sensible defaults for integration tests.
 // It parses the test DSN and fills in Host/Username/Password/Database.
 // Callers must set either Table+Alter or Statement before calling Run().
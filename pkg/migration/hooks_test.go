@@ -0,0 +1,44 @@
+package migration
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/block/spirit/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHooks(t *testing.T) {
+	t.Parallel()
+	testutils.NewTestTable(t, "hookst1", `CREATE TABLE hookst1 (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		a int NOT NULL
+	)`)
+	out := filepath.Join(t.TempDir(), "hooks")
+	record := `echo "$SPIRIT_HOOK $SPIRIT_TABLES $SPIRIT_ERROR" >> ` + out
+	m := NewTestRunner(t, "hookst1", "MODIFY a bigint NOT NULL", func(m *Migration) {
+		m.HookExec = map[string]string{
+			"startup":            record,
+			"preflight-complete": record,
+			"copy-complete":      record,
+			"checksum-complete":  record,
+			"before-cutover":     record + "; exit 1",
+			"failure":            record,
+			"success":            record,
+		}
+	})
+	err := m.Run(t.Context())
+	require.ErrorContains(t, err, "cutover blocked: before-cutover hook command failed")
+	require.NoError(t, m.Close())
+
+	b, err := os.ReadFile(out)
+	require.NoError(t, err)
+	require.Equal(t, `startup hookst1
+preflight-complete hookst1
+copy-complete hookst1
+checksum-complete hookst1
+before-cutover hookst1
+failure hookst1 cutover blocked: before-cutover hook command failed: exit status 1
+`, string(b))
+}
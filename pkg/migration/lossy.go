@@ -0,0 +1,319 @@
+package migration
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/block/spirit/pkg/table"
+	"github.com/block/spirit/pkg/utils"
+)
+
+// maxLossySamples is how many of the rows whose values a conversion would
+// lose are listed in the report.
+const maxLossySamples = 5
+
+// columnDef is the definition of a column, from information_schema.
+type columnDef struct {
+	name              string
+	dataType          string // e.g. varchar
+	columnType        string // e.g. varchar(64)
+	charset           string // empty if not a string column
+	charMaxLength     int64
+	octetLength       int64
+	precision         int64
+	scale             int64
+	datetimePrecision int64
+}
+
+// lossyConversion is a change of a column's definition that would change
+// or reject some of its values.
+type lossyConversion struct {
+	column  string // in the table
+	oldType string
+	newType string
+	reason  string // why the rows' values don't survive, e.g. "out of range for int"
+	cond    string // true for the rows whose value doesn't survive
+
+	rows    int64
+	samples [][]any // the key and value of the first rows
+}
+
+// checkLossyConversions scans the table for rows whose values wouldn't
+// survive the column changes of the ALTER, i.e. narrowing integer, decimal,
+// string and temporal types, and character set changes. Spirit copies
+// without strict mode, so those values would otherwise be silently
+// truncated or rounded, and the migration would only fail at the checksum
+// after the whole table is copied. It returns a report as an error if the
+// scan finds any.
+func (c *tableChange) checkLossyConversions(ctx context.Context, targetChunkTime time.Duration) error {
+	conversions, err := c.lossyConversions(ctx)
+	if err != nil || len(conversions) == 0 {
+		return err
+	}
+	logger := c.runner.logger
+	logger.Info("scanning the table for values that the ALTER's column changes would lose", "table", c.table.TableName)
+	chunker, err := table.NewChunker(c.table, table.ChunkerConfig{
+		TargetChunkTime: targetChunkTime,
+		Logger:          logger,
+	})
+	if err != nil {
+		return err
+	}
+	if err := chunker.Open(); err != nil {
+		return err
+	}
+	defer utils.CloseAndLog(chunker)
+	sums := make([]string, len(conversions))
+	for i, conv := range conversions {
+		sums[i] = fmt.Sprintf("IFNULL(SUM(%s), 0)", conv.cond)
+	}
+	keyColumns := table.QuoteColumns(c.table.KeyColumns)
+	for !chunker.IsRead() {
+		chunk, err := chunker.Next()
+		if err != nil {
+			if errors.Is(err, table.ErrTableIsRead) {
+				break
+			}
+			return err
+		}
+		startTime := time.Now()
+		counts := make([]int64, len(conversions))
+		ptrs := make([]any, len(conversions))
+		for i := range counts {
+			ptrs[i] = &counts[i]
+		}
+		if err := c.runner.db.QueryRowContext(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE %s",
+			strings.Join(sums, ", "), c.table.QuotedTableName, chunk.String())).Scan(ptrs...); err != nil {
+			return err
+		}
+		for i, conv := range conversions {
+			conv.rows += counts[i]
+			if counts[i] == 0 || len(conv.samples) >= maxLossySamples {
+				continue
+			}
+			rows, err := c.runner.db.QueryContext(ctx, fmt.Sprintf("SELECT %s, %s FROM %s WHERE %s AND (%s) ORDER BY %s LIMIT %d",
+				keyColumns, table.QuoteColumns([]string{conv.column}), c.table.QuotedTableName, chunk.String(), conv.cond,
+				keyColumns, maxLossySamples-len(conv.samples)))
+			if err != nil {
+				return err
+			}
+			samples, err := scanValues(rows, len(c.table.KeyColumns)+1)
+			if err != nil {
+				return err
+			}
+			conv.samples = append(conv.samples, samples...)
+		}
+		chunker.Feedback(chunk, time.Since(startTime), chunk.ChunkSize)
+	}
+	var lines []string
+	for _, conv := range conversions {
+		if conv.rows > 0 {
+			lines = append(lines, conv.String(c.table.KeyColumns))
+		}
+	}
+	if len(lines) == 0 {
+		logger.Info("found no values that the ALTER's column changes would lose", "table", c.table.TableName)
+		return nil
+	}
+	return fmt.Errorf("the ALTER changes columns of table %s in ways that would lose the values of some rows. Fix the rows or the ALTER, and run the migration again:\n%s",
+		c.table.TableName, strings.Join(lines, "\n"))
+}
+
+func (conv *lossyConversion) String(keyColumns []string) string {
+	samples := make([]string, 0, len(conv.samples))
+	for _, sample := range conv.samples {
+		value := formatValues(sample[len(keyColumns):])
+		value = value[1 : len(value)-1] // a single value, not a tuple
+		if len(value) > 64 {
+			value = value[:61] + "..."
+		}
+		samples = append(samples, fmt.Sprintf("%s %s: %s", strings.Join(keyColumns, ", "),
+			formatValues(sample[:len(keyColumns)]), value))
+	}
+	return fmt.Sprintf("  %s (%s to %s): %d rows %s, e.g. %s", conv.column, conv.oldType, conv.newType,
+		conv.rows, conv.reason, strings.Join(samples, "; "))
+}
+
+// lossyConversions returns the changes that the ALTER makes to the
+// table's columns that might lose values.
+func (c *tableChange) lossyConversions(ctx context.Context) ([]*lossyConversion, error) {
+	redefined, convertsCharset := c.stmt.RedefinedColumns()
+	if len(redefined) == 0 && !convertsCharset {
+		return nil, nil
+	}
+	oldDefs, err := loadColumnDefs(ctx, c.runner.db, c.table)
+	if err != nil {
+		return nil, err
+	}
+	newDefs, err := loadColumnDefs(ctx, c.runner.db, c.newTable)
+	if err != nil {
+		return nil, err
+	}
+	// The new name of each of the table's columns, for the ones that the
+	// ALTER redefines.
+	newNames := make(map[string]string)
+	if convertsCharset {
+		renames := make(map[string]string)
+		for oldName, newName := range c.stmt.ColumnRenameMap() {
+			renames[strings.ToLower(oldName)] = newName
+		}
+		for _, col := range c.table.Columns {
+			newNames[strings.ToLower(col)] = cmp.Or(renames[strings.ToLower(col)], col)
+		}
+	}
+	for oldName, newName := range redefined {
+		newNames[strings.ToLower(oldName)] = newName
+	}
+	var conversions []*lossyConversion
+	for _, col := range c.table.NonGeneratedColumns {
+		newName, ok := newNames[strings.ToLower(col)]
+		if !ok {
+			continue
+		}
+		oldDef, ok := oldDefs[strings.ToLower(col)]
+		if !ok {
+			continue
+		}
+		newDef, ok := newDefs[strings.ToLower(newName)]
+		if !ok {
+			continue
+		}
+		conversions = append(conversions, findLossyConversions(oldDef, newDef)...)
+	}
+	return conversions, nil
+}
+
+// loadColumnDefs returns the definitions of the columns of tbl, by their
+// lower case name.
+func loadColumnDefs(ctx context.Context, db *sql.DB, tbl *table.TableInfo) (map[string]columnDef, error) {
+	rows, err := db.QueryContext(ctx, `SELECT column_name, data_type, column_type, IFNULL(character_set_name, ''),
+		IFNULL(character_maximum_length, 0), IFNULL(character_octet_length, 0),
+		IFNULL(numeric_precision, 0), IFNULL(numeric_scale, 0), IFNULL(datetime_precision, 0)
+		FROM information_schema.columns
+		WHERE table_schema=? AND table_name=?`, tbl.SchemaName, tbl.TableName)
+	if err != nil {
+		return nil, err
+	}
+	defer utils.CloseAndLog(rows)
+	defs := make(map[string]columnDef)
+	for rows.Next() {
+		var def columnDef
+		if err := rows.Scan(&def.name, &def.dataType, &def.columnType, &def.charset, &def.charMaxLength,
+			&def.octetLength, &def.precision, &def.scale, &def.datetimePrecision); err != nil {
+			return nil, err
+		}
+		def.dataType = strings.ToLower(def.dataType)
+		defs[strings.ToLower(def.name)] = def
+	}
+	return defs, rows.Err()
+}
+
+// integerBits is the width of each integer type.
+var integerBits = map[string]uint{
+	"tinyint":   8,
+	"smallint":  16,
+	"mediumint": 24,
+	"int":       32,
+	"bigint":    64,
+}
+
+// integerRange returns the smallest and largest values of an integer
+// column, or false if it is not an integer column.
+func integerRange(def columnDef) (*big.Int, *big.Int, bool) {
+	bits, ok := integerBits[def.dataType]
+	if !ok {
+		return nil, nil, false
+	}
+	one := big.NewInt(1)
+	if strings.Contains(strings.ToLower(def.columnType), "unsigned") {
+		maxValue := new(big.Int).Lsh(one, bits)
+		return big.NewInt(0), maxValue.Sub(maxValue, one), true
+	}
+	half := new(big.Int).Lsh(one, bits-1)
+	return new(big.Int).Neg(half), new(big.Int).Sub(half, one), true
+}
+
+func isCharType(dataType string) bool {
+	return dataType == "char" || dataType == "varchar"
+}
+
+func isByteLengthType(dataType string) bool {
+	switch dataType {
+	case "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob",
+		"tinytext", "text", "mediumtext", "longtext":
+		return true
+	}
+	return false
+}
+
+func isTemporalType(dataType string) bool {
+	return dataType == "datetime" || dataType == "timestamp" || dataType == "time"
+}
+
+// findLossyConversions returns the ways in which changing a column from
+// oldDef to newDef might lose values, with the condition that finds the
+// rows whose values don't survive each of them. Changes between types of
+// different kinds, e.g. from a string to an integer, are not detected.
+func findLossyConversions(oldDef, newDef columnDef) []*lossyConversion {
+	var conversions []*lossyConversion
+	col := table.QuoteColumns([]string{oldDef.name})
+	add := func(reason, cond string) {
+		conversions = append(conversions, &lossyConversion{
+			column:  oldDef.name,
+			oldType: oldDef.columnType,
+			newType: newDef.columnType,
+			reason:  reason,
+			cond:    cond,
+		})
+	}
+	if oldMin, oldMax, ok := integerRange(oldDef); ok {
+		if newMin, newMax, ok := integerRange(newDef); ok && (newMin.Cmp(oldMin) > 0 || newMax.Cmp(oldMax) < 0) {
+			add("out of range for "+newDef.columnType, fmt.Sprintf("%s < %s OR %s > %s", col, newMin, col, newMax))
+		}
+	}
+	if oldDef.dataType == "decimal" && newDef.dataType == "decimal" {
+		if newDef.precision-newDef.scale < oldDef.precision-oldDef.scale {
+			add("out of range for "+newDef.columnType, fmt.Sprintf("ABS(%s) >= 1%s", col, strings.Repeat("0", int(newDef.precision-newDef.scale))))
+		}
+		if newDef.scale < oldDef.scale {
+			add(fmt.Sprintf("that would be rounded to %d decimal places", newDef.scale), fmt.Sprintf("%s <> ROUND(%s, %d)", col, col, newDef.scale))
+		}
+	}
+	oldIsString := isCharType(oldDef.dataType) || isByteLengthType(oldDef.dataType)
+	switch {
+	case !oldIsString:
+	case isCharType(newDef.dataType) && newDef.charMaxLength < oldDef.charMaxLength:
+		add(fmt.Sprintf("longer than %d characters", newDef.charMaxLength), fmt.Sprintf("CHAR_LENGTH(%s) > %d", col, newDef.charMaxLength))
+	case isByteLengthType(newDef.dataType) && newDef.octetLength < oldDef.octetLength:
+		add(fmt.Sprintf("longer than %d bytes", newDef.octetLength), fmt.Sprintf("LENGTH(%s) > %d", col, newDef.octetLength))
+	}
+	if oldDef.charset != "" && newDef.charset != "" && !strings.EqualFold(oldDef.charset, newDef.charset) &&
+		!strings.EqualFold(newDef.charset, "utf8mb4") && !strings.EqualFold(oldDef.charset, "ascii") {
+		// A character that the new character set doesn't have is
+		// converted to '?', so the value doesn't survive a round trip.
+		add("with characters that are not in "+newDef.charset, fmt.Sprintf("CAST(CONVERT(CONVERT(%s USING %s) USING %s) AS BINARY) <> CAST(%s AS BINARY)",
+			col, newDef.charset, oldDef.charset, col))
+	}
+	if isTemporalType(oldDef.dataType) {
+		switch {
+		case isTemporalType(newDef.dataType) && newDef.datetimePrecision < oldDef.datetimePrecision:
+			divisor := 1
+			for range 6 - newDef.datetimePrecision {
+				divisor *= 10
+			}
+			add(fmt.Sprintf("that would be rounded to %d fractional-second digits", newDef.datetimePrecision), fmt.Sprintf("MICROSECOND(%s) %% %d <> 0", col, divisor))
+		case newDef.dataType == "date" && oldDef.dataType != "time":
+			add("with a time of day", fmt.Sprintf("TIME(%s) <> '00:00:00'", col))
+		}
+	}
+	if oldDef.dataType == "datetime" && newDef.dataType == "timestamp" {
+		add("out of range for timestamp", fmt.Sprintf("%s < '1970-01-01 00:00:01' OR %s >= '2038-01-19 03:14:08'", col, col))
+	}
+	return conversions
+}
@@ -0,0 +1,95 @@
+package migration
+
+import (
+	"testing"
+
+	"github.com/block/spirit/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindLossyConversions(t *testing.T) {
+	conds := func(oldDef, newDef columnDef) []string {
+		oldDef.name = "c"
+		newDef.name = "c"
+		var result []string
+		for _, conv := range findLossyConversions(oldDef, newDef) {
+			result = append(result, conv.reason+": "+conv.cond)
+		}
+		return result
+	}
+
+	// Integers
+	require.Equal(t, []string{"out of range for int: `c` < -2147483648 OR `c` > 2147483647"},
+		conds(columnDef{dataType: "bigint", columnType: "bigint"}, columnDef{dataType: "int", columnType: "int"}))
+	require.Equal(t, []string{"out of range for bigint: `c` < -9223372036854775808 OR `c` > 9223372036854775807"},
+		conds(columnDef{dataType: "bigint", columnType: "bigint unsigned"}, columnDef{dataType: "bigint", columnType: "bigint"}))
+	require.Equal(t, []string{"out of range for int unsigned: `c` < 0 OR `c` > 4294967295"},
+		conds(columnDef{dataType: "int", columnType: "int"}, columnDef{dataType: "int", columnType: "int unsigned"}))
+	require.Empty(t, conds(columnDef{dataType: "int", columnType: "int unsigned"}, columnDef{dataType: "bigint", columnType: "bigint"}))
+
+	// Decimals
+	require.Equal(t, []string{"out of range for decimal(6,1): ABS(`c`) >= 100000", "that would be rounded to 1 decimal places: `c` <> ROUND(`c`, 1)"},
+		conds(columnDef{dataType: "decimal", columnType: "decimal(10,2)", precision: 10, scale: 2}, columnDef{dataType: "decimal", columnType: "decimal(6,1)", precision: 6, scale: 1}))
+	require.Empty(t, conds(columnDef{dataType: "decimal", columnType: "decimal(10,2)", precision: 10, scale: 2}, columnDef{dataType: "decimal", columnType: "decimal(12,4)", precision: 12, scale: 4}))
+
+	// Strings
+	require.Equal(t, []string{"longer than 64 characters: CHAR_LENGTH(`c`) > 64"},
+		conds(columnDef{dataType: "varchar", columnType: "varchar(255)", charset: "utf8mb4", charMaxLength: 255, octetLength: 1020},
+			columnDef{dataType: "varchar", columnType: "varchar(64)", charset: "utf8mb4", charMaxLength: 64, octetLength: 256}))
+	require.Equal(t, []string{"longer than 255 bytes: LENGTH(`c`) > 255"},
+		conds(columnDef{dataType: "text", columnType: "text", charset: "utf8mb4", charMaxLength: 65535, octetLength: 65535},
+			columnDef{dataType: "tinytext", columnType: "tinytext", charset: "utf8mb4", charMaxLength: 255, octetLength: 255}))
+	require.Empty(t, conds(columnDef{dataType: "varchar", columnType: "varchar(255)", charset: "utf8mb4", charMaxLength: 255, octetLength: 1020},
+		columnDef{dataType: "text", columnType: "text", charset: "utf8mb4", charMaxLength: 65535, octetLength: 65535}))
+	require.Equal(t, []string{"with characters that are not in latin1: CAST(CONVERT(CONVERT(`c` USING latin1) USING utf8mb4) AS BINARY) <> CAST(`c` AS BINARY)"},
+		conds(columnDef{dataType: "varchar", columnType: "varchar(10)", charset: "utf8mb4", charMaxLength: 10, octetLength: 40},
+			columnDef{dataType: "varchar", columnType: "varchar(10)", charset: "latin1", charMaxLength: 10, octetLength: 10}))
+	require.Empty(t, conds(columnDef{dataType: "varchar", columnType: "varchar(10)", charset: "latin1", charMaxLength: 10, octetLength: 10},
+		columnDef{dataType: "varchar", columnType: "varchar(10)", charset: "utf8mb4", charMaxLength: 10, octetLength: 40}))
+
+	// Temporal types
+	require.Equal(t, []string{"that would be rounded to 0 fractional-second digits: MICROSECOND(`c`) % 1000000 <> 0"},
+		conds(columnDef{dataType: "datetime", columnType: "datetime(6)", datetimePrecision: 6}, columnDef{dataType: "datetime", columnType: "datetime"}))
+	require.Equal(t, []string{"that would be rounded to 3 fractional-second digits: MICROSECOND(`c`) % 1000 <> 0"},
+		conds(columnDef{dataType: "timestamp", columnType: "timestamp(6)", datetimePrecision: 6}, columnDef{dataType: "timestamp", columnType: "timestamp(3)", datetimePrecision: 3}))
+	require.Equal(t, []string{"with a time of day: TIME(`c`) <> '00:00:00'"},
+		conds(columnDef{dataType: "datetime", columnType: "datetime"}, columnDef{dataType: "date", columnType: "date"}))
+	require.Equal(t, []string{"out of range for timestamp: `c` < '1970-01-01 00:00:01' OR `c` >= '2038-01-19 03:14:08'"},
+		conds(columnDef{dataType: "datetime", columnType: "datetime"}, columnDef{dataType: "timestamp", columnType: "timestamp"}))
+
+	// Changes between kinds of types are not detected.
+	require.Empty(t, conds(columnDef{dataType: "varchar", columnType: "varchar(10)", charset: "utf8mb4", charMaxLength: 10}, columnDef{dataType: "int", columnType: "int"}))
+}
+
+func TestMigrateReportsLossyConversions(t *testing.T) {
+	t.Parallel()
+	testutils.NewTestTable(t, "lossyt1", `CREATE TABLE lossyt1 (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		n bigint NOT NULL,
+		s varchar(255) NOT NULL,
+		ts datetime(6) NOT NULL
+	) CHARSET=utf8mb4`)
+	testutils.RunSQL(t, `INSERT INTO lossyt1 (n, s, ts) VALUES
+		(1, 'short', '2024-01-01 00:00:00'),
+		(4294967296, 'short', '2024-01-01 00:00:00.5'),
+		(2, REPEAT('x', 100), '2024-01-01 00:00:00'),
+		(3, '日本語', '2024-01-01 00:00:00')`)
+
+	m := NewTestRunner(t, "lossyt1", "MODIFY n int NOT NULL, MODIFY s varchar(64) NOT NULL, MODIFY ts datetime NOT NULL")
+	err := m.Run(t.Context())
+	require.ErrorContains(t, err, "the ALTER changes columns of table lossyt1 in ways that would lose the values of some rows")
+	require.ErrorContains(t, err, "n (bigint to int): 1 rows out of range for int, e.g. id (2): 4294967296")
+	require.ErrorContains(t, err, "s (varchar(255) to varchar(64)): 1 rows longer than 64 characters, e.g. id (3): 'xxxxxxxx")
+	require.ErrorContains(t, err, "ts (datetime(6) to datetime): 1 rows that would be rounded to 0 fractional-second digits, e.g. id (2): ")
+	require.NoError(t, m.Close())
+
+	m = NewTestRunner(t, "lossyt1", "CONVERT TO CHARACTER SET latin1")
+	require.ErrorContains(t, m.Run(t.Context()), "s (varchar(255) to varchar(255)): 1 rows with characters that are not in latin1, e.g. id (4): ")
+	require.NoError(t, m.Close())
+
+	// Once the rows fit, the migration succeeds.
+	testutils.RunSQL(t, `DELETE FROM lossyt1 WHERE id > 1`)
+	m = NewTestRunner(t, "lossyt1", "MODIFY n int NOT NULL, MODIFY s varchar(64) NOT NULL, MODIFY ts datetime NOT NULL")
+	require.NoError(t, m.Run(t.Context()))
+	require.NoError(t, m.Close())
+}
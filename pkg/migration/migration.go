@@ -5,10 +5,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/block/spirit/pkg/checksum"
+	"github.com/block/spirit/pkg/hooks"
 	"github.com/block/spirit/pkg/migration/check"
 	"github.com/block/spirit/pkg/statement"
 	"github.com/block/spirit/pkg/table"
@@ -46,11 +48,27 @@ type Migration struct {
 	ReplicaMaxLag                 time.Duration `name:"replica-max-lag" help:"The maximum lag allowed on the replica before the migration throttles." optional:"" default:"120s"`
 	LockWaitTimeout               time.Duration `name:"lock-wait-timeout" help:"The DDL lock_wait_timeout required for checksum and cutover" optional:"" default:"30s"`
 	SkipDropAfterCutover          bool          `name:"skip-drop-after-cutover" help:"Keep old table after completing cutover" optional:"" default:"false"`
+	GradualDrop                   bool          `name:"gradual-drop" help:"Empty the old table in throttled chunks before dropping it, instead of dropping it in one statement" optional:"" default:"false"`
+	DeferSecondaryIndexes         bool          `name:"defer-secondary-indexes" help:"Create the new table without its secondary indexes, and add them with one INPLACE ALTER after the copy" optional:"" default:"false"`
 	DeferCutOver                  bool          `name:"defer-cutover" help:"Defer cutover (and checksum) until sentinel table is dropped" optional:"" default:"false"`
+	CutoverWindow                 string        `name:"cutover-window" help:"Only cut over inside this weekly window, e.g. 'Mon-Fri 02:00-04:00 UTC'" optional:""`
+	PlanRegressionQueries         string        `name:"plan-regression-queries" help:"A file of queries, separated by semicolons, whose plans on the new table are compared with their plans on the table before cutover" optional:"" type:"existingfile"`
+	PlanRegressionDigests         int           `name:"plan-regression-digests" help:"Compare the plans of the N statement digests in performance_schema that spend the most time on the table before cutover" optional:"" default:"0"`
+	PlanRegression                string        `name:"plan-regression" help:"What to do when the plan of a query regresses on the new table: warn, or block the cutover" enum:"warn,block" default:"warn"`
+	RevertWindow                  time.Duration `name:"revert-window" help:"After cutover, keep streaming changes back to the old table for this long, so that the migration can be undone with 'spirit revert'" optional:""`
+	ForeignKeys                   bool          `name:"foreign-keys" help:"Support tables with foreign keys: recreate the table's constraints on the new table, and repoint the constraints that reference it at cutover" optional:"" default:"false"`
 	SkipForceKill                 bool          `name:"skip-force-kill" help:"Disable killing long-running transactions in order to acquire metadata lock (MDL) at checksum and cutover time" optional:"" default:"false"`
+	SkipDuplicateCheck            bool          `name:"skip-duplicate-check" help:"Don't scan the table for duplicates of the unique keys that the ALTER adds before copying, or watch for new ones while copying" optional:"" default:"false"`
+	SkipLossyCheck                bool          `name:"skip-lossy-check" help:"Don't scan the table for values that the ALTER's column changes would truncate or round before copying" optional:"" default:"false"`
 	Statement                     string        `name:"statement" help:"The SQL statement to run (replaces --table and --alter)" optional:"" default:""`
 	Lint                          bool          `name:"lint" help:"Run lint checks before running migration" optional:""`
 	LintOnly                      bool          `name:"lint-only" help:"Run lint checks and exit without performing migration" optional:""`
+	Plan                          bool          `name:"plan" help:"Run the preflight checks and report what the migration would do, without doing it" optional:""`
+	PlanFormat                    string        `name:"plan-format" help:"Output format for --plan: text or json" enum:"text,json" default:"text"`
+
+	// ColumnExpr computes columns of the new table from SQL expressions over
+	// the table's columns, instead of copying them (see table.ColumnMapping).
+	ColumnExpr map[string]string `name:"column-expr" mapsep:"none" help:"Compute a column of the new table from an SQL expression over the table's columns instead of copying it, as column=expression, e.g. full_name=CONCAT(first, ' ', last)" optional:""`
 
 	// TLS Configuration
 	TLSMode            string `name:"tls-mode" help:"TLS connection mode (case insensitive): DISABLED, PREFERRED (default), REQUIRED, VERIFY_CA, VERIFY_IDENTITY" optional:""`
@@ -70,6 +88,8 @@ type Migration struct {
 
 	CheckpointMaxAge     time.Duration `name:"checkpoint-max-age" help:"Maximum age of a checkpoint before refusing to resume from it" optional:"" default:"168h"`
 	ChecksumYieldTimeout time.Duration `name:"checksum-yield-timeout" help:"Maximum duration for a single checksum pass before yielding to release long-running REPEATABLE READ transactions (reduces InnoDB HLL growth)" optional:"" default:"24h"`
+	ChecksumDiffFile     string        `name:"checksum-diff-file" help:"Write the rows that the checksum finds differ, with their row images in the table and the new table, to this file before they are recopied" optional:""`
+	ChecksumDiffFormat   string        `name:"checksum-diff-format" help:"The format of --checksum-diff-file: json, or sql statements that make the new table's rows match the table's" enum:"json,sql" default:"json"`
 
 	// MaxCommitLatency throttles when observed commit latency exceeds this
 	// threshold. Currently auto-enabled only on Aurora (auto-detected); the
@@ -77,6 +97,21 @@ type Migration struct {
 	// extreme tail latencies. See issue #468.
 	MaxCommitLatency time.Duration `name:"max-commit-latency" help:"Throttle when average commit latency exceeds this threshold (currently only auto-enabled on Aurora)" optional:"" default:"100ms"`
 
+	// HTTPListen starts the embedded status/control API (see
+	// status.Server) on this address. Control endpoints require HTTPToken.
+	HTTPListen string `name:"http-listen" help:"Address (host:port) to serve the HTTP status and control API on. Disabled when empty" optional:""`
+	HTTPToken  string `name:"http-token" help:"Bearer token required by the HTTP control endpoints (cancel, pause, resume, checkpoint, cutover, config). Control endpoints are disabled when empty" optional:"" env:"SPIRIT_HTTP_TOKEN"`
+
+	// MetricsSink selects a built-in metrics.Sink. "prometheus" serves the
+	// metrics on /metrics of the HTTP API, so it requires HTTPListen.
+	MetricsSink string `name:"metrics-sink" help:"Built-in metrics sink to use: none, or prometheus to serve metrics on /metrics of the HTTP API (requires --http-listen)" enum:"none,prometheus" default:"none"`
+
+	// HookExec and HookURL run a command, or POST a JSON payload to a URL,
+	// at points in the migration's lifecycle (see pkg/hooks).
+	HookExec    map[string]string `name:"hook-exec" mapsep:"none" help:"Command to run at a hook point, as point=command. Points: startup, preflight-complete, copy-complete, checksum-complete, before-cutover, after-cutover, failure, success" optional:""`
+	HookURL     map[string]string `name:"hook-url" mapsep:"none" help:"URL to POST a JSON payload to at a hook point, as point=url" optional:""`
+	HookTimeout time.Duration     `name:"hook-timeout" help:"How long a hook may run before it fails" optional:"" default:"1m"`
+
 	// Hidden options for now (supports more obscure cash/sq usecases)
 	InterpolateParams bool `name:"interpolate-params" help:"Enable interpolate params for DSN" optional:"" default:"false" hidden:""`
 	// Used for tests so we can concurrently execute without issues even though
@@ -97,6 +132,9 @@ func (m *Migration) Validate() error {
 	if m.Lint && m.LintOnly {
 		return errors.New("--lint and --lint-only cannot be used together")
 	}
+	if m.Plan && m.LintOnly {
+		return errors.New("--plan and --lint-only cannot be used together")
+	}
 	if m.Threads < 0 {
 		return fmt.Errorf("--threads must be non-negative, got %d", m.Threads)
 	}
@@ -112,15 +150,50 @@ func (m *Migration) Validate() error {
 	if m.CheckpointMaxAge < 0 {
 		return fmt.Errorf("--checkpoint-max-age must be non-negative, got %s", m.CheckpointMaxAge)
 	}
-	return nil
+	if m.MetricsSink == "prometheus" && m.HTTPListen == "" {
+		return errors.New("--metrics-sink=prometheus requires --http-listen")
+	}
+	if m.RevertWindow < 0 {
+		return fmt.Errorf("--revert-window must be non-negative, got %s", m.RevertWindow)
+	}
+	if m.PlanRegressionDigests < 0 {
+		return fmt.Errorf("--plan-regression-digests must be non-negative, got %d", m.PlanRegressionDigests)
+	}
+	if m.ForeignKeys && m.RevertWindow > 0 {
+		return errors.New("--foreign-keys and --revert-window cannot be used together")
+	}
+	if m.ForeignKeys && m.DeferSecondaryIndexes {
+		// The new table's foreign keys need their indexes while rows are copied.
+		return errors.New("--foreign-keys and --defer-secondary-indexes cannot be used together")
+	}
+	if len(m.ColumnExpr) > 0 && m.RevertWindow > 0 {
+		return errors.New("--column-expr and --revert-window cannot be used together")
+	}
+	if m.CutoverWindow != "" {
+		if _, err := utils.ParseWindow(m.CutoverWindow); err != nil {
+			return fmt.Errorf("--cutover-window: %w", err)
+		}
+	}
+	return m.hooksConfig().Validate()
 }
 
+func (m *Migration) hooksConfig() hooks.Config {
+	return hooks.Config{Exec: m.HookExec, URL: m.HookURL, Timeout: m.HookTimeout}
+}
+
+// Run is the kong CLI entry point. While it runs, SIGUSR1 pauses the
+// migration and SIGUSR2 resumes it on unix (see Runner.Pause).
 func (m *Migration) Run() error {
 	migration, err := NewRunner(m)
 	if err != nil {
 		return err
 	}
 	defer utils.CloseAndLog(migration)
+	if m.Plan {
+		return m.printPlan(migration)
+	}
+	stopSignals := handlePauseSignals(migration)
+	defer stopSignals()
 	if err := migration.runChecks(context.TODO(), check.ScopePreRun); err != nil {
 		return err
 	}
@@ -130,6 +203,28 @@ func (m *Migration) Run() error {
 	return nil
 }
 
+// printPlan prints the runner's plan to stdout in the --plan-format. It
+// returns an error if any check failed, so that --plan can gate a
+// migration in a script.
+func (m *Migration) printPlan(r *Runner) error {
+	plan, err := r.Plan(context.TODO())
+	if err != nil {
+		return err
+	}
+	if m.PlanFormat == "json" {
+		err = plan.WriteJSON(os.Stdout)
+	} else {
+		err = plan.WriteText(os.Stdout)
+	}
+	if err != nil {
+		return err
+	}
+	if failed := plan.FailedChecks(); failed > 0 {
+		return fmt.Errorf("%d preflight check(s) failed", failed)
+	}
+	return nil
+}
+
 // normalizeOptions does some validation and sets defaults.
 // for example, it validates that only --statement or --table and --alter are specified,
 // and when --statement is not specified, it generates it
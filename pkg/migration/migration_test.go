@@ -818,11 +818,12 @@ func TestBadAlter(t *testing.T) {
 	require.NoError(t, err)
 	require.NoError(t, runner.Close())
 
-	// DROP PRIMARY KEY — not supported.
+	// DROP PRIMARY KEY — not supported when nothing else identifies the rows.
 	runner = NewTestRunner(t, "bot2", "DROP PRIMARY KEY")
 	err = runner.Run(t.Context())
 	require.Error(t, err)
-	require.ErrorContains(t, err, "dropping primary key")
+	require.ErrorContains(t, err, "the ALTER drops the PRIMARY KEY of bot2")
+	require.ErrorContains(t, err, "Keep a unique key on (id)")
 	require.NoError(t, runner.Close())
 }
 
@@ -935,6 +936,18 @@ func TestMigrationValidate(t *testing.T) {
 			wantErr: "--replica-max-lag must be non-negative, got -1m0s"},
 		{name: "negative checkpoint-max-age", m: Migration{CheckpointMaxAge: -time.Hour},
 			wantErr: "--checkpoint-max-age must be non-negative, got -1h0m0s"},
+		{name: "negative revert-window", m: Migration{RevertWindow: -time.Hour},
+			wantErr: "--revert-window must be non-negative, got -1h0m0s"},
+		{name: "negative plan-regression-digests", m: Migration{PlanRegressionDigests: -1},
+			wantErr: "--plan-regression-digests must be non-negative, got -1"},
+		{name: "foreign-keys with revert-window", m: Migration{ForeignKeys: true, RevertWindow: time.Hour},
+			wantErr: "--foreign-keys and --revert-window cannot be used together"},
+		{name: "foreign-keys with defer-secondary-indexes", m: Migration{ForeignKeys: true, DeferSecondaryIndexes: true},
+			wantErr: "--foreign-keys and --defer-secondary-indexes cannot be used together"},
+		{name: "column-expr with revert-window", m: Migration{ColumnExpr: map[string]string{"a": "b"}, RevertWindow: time.Hour},
+			wantErr: "--column-expr and --revert-window cannot be used together"},
+		{name: "unknown hook point", m: Migration{HookExec: map[string]string{"cutover": "true"}},
+			wantErr: `--hook-exec: unknown hook point "cutover"`},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,205 @@
+package migration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	"github.com/block/spirit/pkg/migration/check"
+	"github.com/block/spirit/pkg/table"
+	"github.com/block/spirit/pkg/utils"
+)
+
+// The ways in which buffered changes to a table are kept until they are
+// flushed, as reported in TablePlan.ChangeMode.
+const (
+	// changeModeMap keeps the latest row image per primary key in a map.
+	changeModeMap = "map"
+	// changeModeMapThenQueue uses the map during the copy, and a FIFO queue
+	// after it, because the primary key is not memory-comparable (see
+	// pkg/change/subscription_buffered.go).
+	changeModeMapThenQueue = "map-then-queue"
+)
+
+// Plan is what a migration would do, as reported by --plan.
+type Plan struct {
+	Tables []*TablePlan `json:"tables"`
+}
+
+// TablePlan is what a migration would do to one table.
+type TablePlan struct {
+	Schema    string `json:"schema"`
+	Table     string `json:"table"`
+	Statement string `json:"statement"`
+	// Direct is true for statements that are not an ALTER TABLE (such as
+	// CREATE TABLE), which are executed as-is. None of the other fields
+	// are set for them.
+	Direct bool `json:"direct"`
+
+	Checks []PlanCheck `json:"checks,omitempty"`
+
+	// AttemptInstant is true if ALGORITHM=INSTANT is tried first. Whether
+	// it succeeds is up to MySQL; if it is not tried, InstantSkipReason
+	// says why. AttemptInplace is true if INPLACE is tried next, because
+	// every clause only modifies metadata; if not, InplaceSkipReason says
+	// why.
+	AttemptInstant    bool   `json:"attempt_instant"`
+	InstantSkipReason string `json:"instant_skip_reason,omitempty"`
+	AttemptInplace    bool   `json:"attempt_inplace"`
+	InplaceSkipReason string `json:"inplace_skip_reason,omitempty"`
+
+	// The remaining fields describe the copy, used when MySQL can't do
+	// the change itself.
+	Chunker    string `json:"chunker,omitempty"`
+	ChangeMode string `json:"change_mode,omitempty"`
+	// WatermarkExact is false when changes are compared against the copy's
+	// watermarks by a primary key that is not memory-comparable (e.g. a
+	// string with a collation), so some may be applied or skipped when they
+	// didn't need to be. The checksum repairs any differences.
+	WatermarkExact    bool   `json:"watermark_exact"`
+	EstimatedRows     uint64 `json:"estimated_rows"`
+	EstimatedDataSize uint64 `json:"estimated_data_size"`
+}
+
+// PlanCheck is the result of a preflight check.
+type PlanCheck struct {
+	Name  string `json:"name"`
+	Error string `json:"error,omitempty"` // empty if the check passed
+}
+
+// FailedChecks returns the number of checks that failed, across all tables.
+func (p *Plan) FailedChecks() int {
+	var failed int
+	for _, t := range p.Tables {
+		for _, c := range t.Checks {
+			if c.Error != "" {
+				failed++
+			}
+		}
+	}
+	return failed
+}
+
+// WriteJSON writes the plan to w as JSON.
+func (p *Plan) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(p)
+}
+
+// WriteText writes the plan to w in a human-readable form.
+func (p *Plan) WriteText(w io.Writer) error {
+	var sb strings.Builder
+	for i, t := range p.Tables {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "Table %s.%s\n", t.Schema, t.Table)
+		fmt.Fprintf(&sb, "  Statement:       %s\n", t.Statement)
+		if t.Direct {
+			sb.WriteString("  Method:          executed directly\n")
+			continue
+		}
+		if t.AttemptInstant {
+			sb.WriteString("  INSTANT:         will be attempted (MySQL decides if it is possible)\n")
+		} else {
+			fmt.Fprintf(&sb, "  INSTANT:         not attempted (%s)\n", t.InstantSkipReason)
+		}
+		switch {
+		case t.AttemptInplace:
+			sb.WriteString("  INPLACE:         will be attempted if INSTANT is not possible (metadata-only change)\n")
+		case t.InplaceSkipReason != "":
+			fmt.Fprintf(&sb, "  INPLACE:         not attempted (%s)\n", t.InplaceSkipReason)
+		default:
+			sb.WriteString("  INPLACE:         not attempted\n")
+		}
+		fmt.Fprintf(&sb, "  Copy chunker:    %s\n", t.Chunker)
+		if t.ChangeMode == changeModeMap {
+			sb.WriteString("  Change buffer:   map (primary key is memory-comparable)\n")
+		} else {
+			sb.WriteString("  Change buffer:   map during the copy, then queue (primary key is not memory-comparable)\n")
+		}
+		if t.WatermarkExact {
+			sb.WriteString("  Watermark opt.:  applies\n")
+		} else {
+			sb.WriteString("  Watermark opt.:  applies, but is approximate for this primary key (the checksum repairs any differences)\n")
+		}
+		fmt.Fprintf(&sb, "  Estimated rows:  %d\n", t.EstimatedRows)
+		fmt.Fprintf(&sb, "  Estimated size:  %s\n", utils.FormatBytes(t.EstimatedDataSize))
+		sb.WriteString("  Checks:\n")
+		for _, c := range t.Checks {
+			if c.Error == "" {
+				fmt.Fprintf(&sb, "    ok    %s\n", c.Name)
+			} else {
+				fmt.Fprintf(&sb, "    FAIL  %s: %s\n", c.Name, c.Error)
+			}
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// Plan reports what Run would do, without changing anything: it runs the
+// pre-run and preflight checks, and describes how each table would be
+// changed. A failing check does not return an error; it is reported in
+// the plan.
+func (r *Runner) Plan(ctx context.Context) (*Plan, error) {
+	if err := r.connect(); err != nil {
+		return nil, err
+	}
+	plan := &Plan{}
+	for _, change := range r.changes {
+		tp := &TablePlan{
+			Schema:    change.stmt.Schema,
+			Table:     change.stmt.Table,
+			Statement: change.stmt.Statement,
+		}
+		plan.Tables = append(plan.Tables, tp)
+		if !change.stmt.IsAlterTable() {
+			tp.Direct = true
+			continue
+		}
+		change.table = table.NewTableInfo(r.db, change.stmt.Schema, change.stmt.Table)
+		// Don't ANALYZE TABLE: the plan must not change anything, and the
+		// statistics that are already there are good enough for an estimate.
+		change.table.DisableAnalyze = true
+		if err := change.table.SetInfo(ctx); err != nil {
+			return nil, err
+		}
+		for _, res := range check.RunAllChecks(ctx, r.checkResources(change), r.logger, check.ScopePreRun|check.ScopePreflight) {
+			pc := PlanCheck{Name: res.Name}
+			if res.Err != nil {
+				pc.Error = res.Err.Error()
+			}
+			tp.Checks = append(tp.Checks, pc)
+		}
+		tp.InstantSkipReason = r.mysqlDDLSkipReason()
+		tp.AttemptInstant = tp.InstantSkipReason == ""
+		if tp.AttemptInstant {
+			if err := change.stmt.AlgorithmInplaceConsideredSafe(); err != nil {
+				tp.InplaceSkipReason = err.Error()
+			} else {
+				tp.AttemptInplace = true
+			}
+		}
+		// The chunker config only needs the fields that ChunkerType reads;
+		// the copy never sets a key or where clause.
+		tp.Chunker = table.ChunkerType(change.table, table.ChunkerConfig{})
+		tp.ChangeMode = changeModeMap
+		tp.WatermarkExact = true
+		if err := change.table.PrimaryKeyIsMemoryComparable(); err != nil {
+			if !errors.Is(err, table.ErrUnsupportedPKType) {
+				return nil, err
+			}
+			tp.ChangeMode = changeModeMapThenQueue
+			tp.WatermarkExact = false
+		}
+		tp.EstimatedRows = atomic.LoadUint64(&change.table.EstimatedRows)
+		tp.EstimatedDataSize = atomic.LoadUint64(&change.table.EstimatedDataSize)
+	}
+	return plan, nil
+}
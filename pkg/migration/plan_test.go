@@ -0,0 +1,118 @@
+package migration
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/block/spirit/pkg/table"
+	"github.com/block/spirit/pkg/testutils"
+	"github.com/block/spirit/pkg/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanOutput(t *testing.T) {
+	plan := &Plan{Tables: []*TablePlan{
+		{
+			Schema:            "test",
+			Table:             "t1",
+			Statement:         "ALTER TABLE `t1` ADD INDEX (b)",
+			Checks:            []PlanCheck{{Name: "configuration"}, {Name: "privileges", Error: "missing PROCESS"}},
+			AttemptInstant:    true,
+			InplaceSkipReason: "not safe",
+			Chunker:           table.ChunkerTypeOptimistic,
+			ChangeMode:        changeModeMap,
+			WatermarkExact:    true,
+			EstimatedRows:     1000,
+			EstimatedDataSize: 3 << 20,
+		},
+		{
+			Schema:    "test",
+			Table:     "t2",
+			Statement: "CREATE TABLE t2 (id int primary key)",
+			Direct:    true,
+		},
+		{
+			Schema:            "test",
+			Table:             "t3",
+			Statement:         "ALTER TABLE `t3` ADD INDEX (b)",
+			InstantSkipReason: "multi-table migration",
+			Chunker:           table.ChunkerTypeComposite,
+			ChangeMode:        changeModeMapThenQueue,
+		},
+	}}
+	require.Equal(t, 1, plan.FailedChecks())
+
+	var text bytes.Buffer
+	require.NoError(t, plan.WriteText(&text))
+	require.Contains(t, text.String(), "INSTANT:         will be attempted")
+	require.Contains(t, text.String(), "INPLACE:         not attempted (not safe)")
+	require.Contains(t, text.String(), "Estimated size:  3.0 MiB")
+	require.Contains(t, text.String(), "FAIL  privileges: missing PROCESS")
+	require.Contains(t, text.String(), "Method:          executed directly")
+	require.Contains(t, text.String(), "INSTANT:         not attempted (multi-table migration)")
+
+	var js bytes.Buffer
+	require.NoError(t, plan.WriteJSON(&js))
+	var decoded Plan
+	require.NoError(t, json.Unmarshal(js.Bytes(), &decoded))
+	require.Equal(t, plan, &decoded)
+}
+
+func TestMySQLDDLSkipReason(t *testing.T) {
+	r := &Runner{migration: &Migration{}, changes: []*tableChange{{}}}
+	require.Empty(t, r.mysqlDDLSkipReason())
+	r.migration.ColumnExpr = map[string]string{"c": "a + b"}
+	require.Equal(t, "--column-expr requires a copy", r.mysqlDDLSkipReason())
+	r.migration.RevertWindow = time.Hour
+	require.Equal(t, "--revert-window requires a copy", r.mysqlDDLSkipReason())
+	r.changes = append(r.changes, &tableChange{})
+	require.Equal(t, "multi-table migration", r.mysqlDDLSkipReason())
+}
+
+func TestPlan(t *testing.T) {
+	t.Parallel()
+	testutils.NewTestTable(t, "planautoinc", `CREATE TABLE planautoinc (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		b int NOT NULL,
+		KEY b (b)
+	)`)
+	testutils.NewTestTable(t, "planvarchar", `CREATE TABLE planvarchar (
+		id varchar(32) NOT NULL PRIMARY KEY,
+		b int NOT NULL
+	)`)
+
+	r := NewTestRunnerFromStatement(t, "ALTER TABLE planautoinc RENAME INDEX b TO c")
+	defer utils.CloseAndLog(r)
+	plan, err := r.Plan(t.Context())
+	require.NoError(t, err)
+	require.Len(t, plan.Tables, 1)
+	tp := plan.Tables[0]
+	require.True(t, tp.AttemptInstant)
+	require.True(t, tp.AttemptInplace)
+	require.Equal(t, table.ChunkerTypeOptimistic, tp.Chunker)
+	require.Equal(t, changeModeMap, tp.ChangeMode)
+	require.True(t, tp.WatermarkExact)
+	require.NotEmpty(t, tp.Checks)
+	require.Zero(t, plan.FailedChecks())
+
+	// Nothing was changed.
+	var count int
+	require.NoError(t, r.db.QueryRowContext(t.Context(),
+		"SELECT COUNT(*) FROM information_schema.statistics WHERE table_schema=DATABASE() AND table_name='planautoinc' AND index_name='c'").Scan(&count))
+	require.Zero(t, count)
+
+	// A multi-table migration is never done by MySQL itself, and a
+	// string primary key needs the queue after the copy.
+	r2 := NewTestRunnerFromStatement(t, "ALTER TABLE planautoinc ADD INDEX (id, b); ALTER TABLE planvarchar ADD INDEX (b)")
+	defer utils.CloseAndLog(r2)
+	plan, err = r2.Plan(t.Context())
+	require.NoError(t, err)
+	require.Len(t, plan.Tables, 2)
+	require.False(t, plan.Tables[0].AttemptInstant)
+	require.False(t, plan.Tables[1].AttemptInstant)
+	require.Equal(t, table.ChunkerTypeComposite, plan.Tables[1].Chunker)
+	require.Equal(t, changeModeMapThenQueue, plan.Tables[1].ChangeMode)
+	require.False(t, plan.Tables[1].WatermarkExact)
+}
@@ -0,0 +1,311 @@
+package migration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/block/spirit/pkg/statement"
+	"github.com/block/spirit/pkg/utils"
+)
+
+const (
+	// planRowsFactor is how many times more rows a table access in the
+	// new plan has to be estimated to examine before it's a regression,
+	// and planRowsMin the fewest rows that it has to examine, so that
+	// small tables don't flap.
+	planRowsFactor = 10
+	planRowsMin    = 1000
+	// maxPlanQueryLength is how much of a query is shown in a report.
+	maxPlanQueryLength = 120
+)
+
+// planRegression is a query whose plan is worse on the new table.
+type planRegression struct {
+	query   string
+	reasons []string
+}
+
+// loadPlanQueries reads the queries of --plan-regression-queries.
+func loadPlanQueries(path string) ([]string, error) {
+	sql, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	queries, err := statement.SplitQueries(string(sql))
+	if err != nil {
+		return nil, fmt.Errorf("--plan-regression-queries: %w", err)
+	}
+	return queries, nil
+}
+
+// explainPlanQueries explains the queries of --plan-regression-queries
+// that use the table, before anything is copied, so that a query that
+// can't be explained on the table, or rewritten to use the new table,
+// fails the migration now rather than right before the cutover.
+func (c *tableChange) explainPlanQueries(ctx context.Context) error {
+	newTableName := utils.NewTableName(c.table.TableName)
+	for _, query := range c.runner.planQueries {
+		_, ok, err := statement.ReplaceTable(query, c.runner.migration.Database, c.table.SchemaName, c.table.TableName, newTableName)
+		if err != nil {
+			return fmt.Errorf("--plan-regression-queries: could not rewrite query %q to use the new table: %w", query, err)
+		}
+		if !ok {
+			continue // the query doesn't use this table
+		}
+		if _, err := c.explain(ctx, query); err != nil {
+			return fmt.Errorf("--plan-regression-queries: could not explain query %q: %w", query, err)
+		}
+	}
+	return nil
+}
+
+// checkPlans compares the plans of the queries that use the table with
+// their plans when they use the new table instead, and warns about the
+// ones that regress, or, with --plan-regression=block, returns an error.
+// It runs right before the cutover, when the new table's statistics are
+// up to date. A query that can't be explained on the new table, e.g.
+// because it uses a column that the ALTER drops, is also a regression.
+// The queries of --plan-regression-queries were already explained on the
+// table by explainPlanQueries, so a failure to compare a query's plans now
+// is only an error with --plan-regression=block, and is otherwise logged.
+func (c *tableChange) checkPlans(ctx context.Context) error {
+	queries := slices.Clone(c.runner.planQueries)
+	if n := c.runner.migration.PlanRegressionDigests; n > 0 {
+		digests, err := c.digestQueries(ctx, n)
+		if err != nil {
+			if err := c.planCheckFailed(fmt.Errorf("could not read the statement digests: %w", err)); err != nil {
+				return err
+			}
+		}
+		queries = append(queries, digests...)
+	}
+	var regressions []planRegression
+	var compared int
+	for _, query := range queries {
+		newQuery, ok, err := statement.ReplaceTable(query, c.runner.migration.Database, c.table.SchemaName, c.table.TableName, c.newTable.TableName)
+		if err != nil {
+			if err := c.planCheckFailed(fmt.Errorf("could not compare the plans of query %q: %w", query, err)); err != nil {
+				return err
+			}
+			continue
+		}
+		if !ok {
+			continue // the query doesn't use this table
+		}
+		oldPlan, err := c.explain(ctx, query)
+		if err != nil {
+			if err := c.planCheckFailed(fmt.Errorf("could not explain query %q: %w", query, err)); err != nil {
+				return err
+			}
+			continue
+		}
+		var reasons []string
+		if newPlan, err := c.explain(ctx, newQuery); err != nil {
+			reasons = []string{fmt.Sprintf("it can't be explained on the new table: %v", err)}
+		} else if reasons, err = comparePlans(oldPlan, newPlan); err != nil {
+			if err := c.planCheckFailed(fmt.Errorf("could not compare the plans of query %q: %w", query, err)); err != nil {
+				return err
+			}
+			continue
+		}
+		compared++
+		if len(reasons) > 0 {
+			regressions = append(regressions, planRegression{query: query, reasons: reasons})
+		}
+	}
+	c.runner.logger.Info("compared the query plans of the table with the new table",
+		"table", c.table.TableName,
+		"queries", compared,
+		"regressions", len(regressions),
+	)
+	if len(regressions) == 0 {
+		return nil
+	}
+	if c.runner.migration.PlanRegression != "block" {
+		for _, r := range regressions {
+			c.runner.logger.Warn("the plan of a query regresses on the new table",
+				"table", c.table.TableName,
+				"query", r.query,
+				"reasons", r.reasons,
+			)
+		}
+		return nil
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "cutover blocked: the plans of %d queries on table %s regress on the new table. Fix the ALTER, or run the migration again with --plan-regression=warn:", len(regressions), c.table.TableName)
+	for _, r := range regressions {
+		fmt.Fprintf(&sb, "\n  %s: %s", truncateQuery(r.query), strings.Join(r.reasons, "; "))
+	}
+	return errors.New(sb.String())
+}
+
+// planCheckFailed returns err if --plan-regression=block, so that the
+// cutover is blocked, and otherwise logs it and returns nil.
+func (c *tableChange) planCheckFailed(err error) error {
+	if c.runner.migration.PlanRegression == "block" {
+		return err
+	}
+	c.runner.logger.Warn("could not check the plan of a query on the new table",
+		"table", c.table.TableName,
+		"error", err,
+	)
+	return nil
+}
+
+// digestQueries returns samples of the n statement digests that spend the
+// most time on the table, from performance_schema. Only the digests of
+// --database are read, as that is the database the samples are explained
+// in. Spirit's own copy and checksum queries, and samples that were
+// truncated or can't be parsed, are skipped.
+func (c *tableChange) digestQueries(ctx context.Context, n int) ([]string, error) {
+	rows, err := c.runner.db.QueryContext(ctx, `SELECT QUERY_SAMPLE_TEXT
+		FROM performance_schema.events_statements_summary_by_digest
+		WHERE SCHEMA_NAME=? AND DIGEST_TEXT LIKE ?
+		AND DIGEST_TEXT NOT LIKE '%FORCE INDEX%' AND DIGEST_TEXT NOT LIKE '%BIT_XOR%'
+		AND (DIGEST_TEXT LIKE 'SELECT %' OR DIGEST_TEXT LIKE 'UPDATE %' OR DIGEST_TEXT LIKE 'DELETE %')
+		ORDER BY SUM_TIMER_WAIT DESC`,
+		c.runner.migration.Database, "%`"+c.table.TableName+"`%")
+	if err != nil {
+		return nil, err
+	}
+	defer utils.CloseAndLog(rows)
+	var queries []string
+	for len(queries) < n && rows.Next() {
+		var sample string
+		if err := rows.Scan(&sample); err != nil {
+			return nil, err
+		}
+		if strings.Contains(sample, c.newTable.TableName) {
+			continue
+		}
+		if _, ok, err := statement.ReplaceTable(sample, c.runner.migration.Database, c.table.SchemaName, c.table.TableName, c.newTable.TableName); err != nil || !ok {
+			c.runner.logger.Debug("skipping statement digest sample", "query", truncateQuery(sample), "error", err)
+			continue
+		}
+		queries = append(queries, sample)
+	}
+	return queries, rows.Err()
+}
+
+// explain returns the EXPLAIN FORMAT=JSON of query.
+func (c *tableChange) explain(ctx context.Context, query string) ([]byte, error) {
+	var plan []byte
+	err := c.runner.db.QueryRowContext(ctx, "EXPLAIN FORMAT=JSON "+query).Scan(&plan)
+	return plan, err
+}
+
+// tableAccess is how a plan reads one of a query's tables.
+type tableAccess struct {
+	table      string
+	accessType string
+	key        string
+	rows       float64
+}
+
+// fullScan returns true if the access reads the whole table.
+func (a tableAccess) fullScan() bool {
+	return a.accessType == "ALL" || a.accessType == "table"
+}
+
+// comparePlans compares the table accesses of two EXPLAIN FORMAT=JSON
+// plans of a query, and returns how the new plan is worse: a different
+// key, a full scan, or a large jump in the estimated rows examined.
+func comparePlans(oldPlan, newPlan []byte) ([]string, error) {
+	oldAccesses, err := tableAccesses(oldPlan)
+	if err != nil {
+		return nil, err
+	}
+	newAccesses, err := tableAccesses(newPlan)
+	if err != nil {
+		return nil, err
+	}
+	if len(oldAccesses) != len(newAccesses) {
+		return []string{fmt.Sprintf("the plan reads %d tables instead of %d", len(newAccesses), len(oldAccesses))}, nil
+	}
+	var reasons []string
+	for i, o := range oldAccesses {
+		n := newAccesses[i]
+		if n.table != o.table {
+			reasons = append(reasons, fmt.Sprintf("the plan reads table %s where it read %s", n.table, o.table))
+			continue
+		}
+		switch {
+		case n.fullScan() && !o.fullScan():
+			reasons = append(reasons, fmt.Sprintf("a full scan of %s instead of %s", n.table, accessName(o)))
+		case n.key != o.key:
+			reasons = append(reasons, fmt.Sprintf("%s by %s instead of %s", n.table, accessName(n), accessName(o)))
+		}
+		if n.rows >= planRowsMin && n.rows > o.rows*planRowsFactor {
+			reasons = append(reasons, fmt.Sprintf("an estimated %.0f rows of %s examined instead of %.0f", n.rows, n.table, o.rows))
+		}
+	}
+	return reasons, nil
+}
+
+// accessName describes an access for a report.
+func accessName(a tableAccess) string {
+	switch {
+	case a.key != "":
+		return "key " + a.key
+	case a.fullScan():
+		return "a full scan"
+	}
+	return "access type " + a.accessType
+}
+
+// tableAccesses returns the table accesses of an EXPLAIN FORMAT=JSON plan.
+// Both the original format, and the version 2 format of MySQL 8.3 and later
+// are understood. The plan is walked in a fixed order, so that the
+// accesses of two plans of the same shape line up.
+func tableAccesses(plan []byte) ([]tableAccess, error) {
+	var root any
+	if err := json.Unmarshal(plan, &root); err != nil {
+		return nil, err
+	}
+	var accesses []tableAccess
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case []any:
+			for _, e := range v {
+				walk(e)
+			}
+		case map[string]any:
+			if name, ok := v["table_name"].(string); ok {
+				if accessType, ok := v["access_type"].(string); ok {
+					a := tableAccess{table: name, accessType: accessType}
+					a.key, _ = v["key"].(string)
+					if a.key == "" {
+						a.key, _ = v["index_name"].(string)
+					}
+					rows, ok := v["rows_examined_per_scan"].(float64)
+					if !ok {
+						rows, _ = v["estimated_rows"].(float64)
+					}
+					a.rows = rows
+					accesses = append(accesses, a)
+				}
+			}
+			for _, k := range slices.Sorted(maps.Keys(v)) {
+				walk(v[k])
+			}
+		}
+	}
+	walk(root)
+	return accesses, nil
+}
+
+// truncateQuery shortens a query for a report.
+func truncateQuery(query string) string {
+	query = strings.Join(strings.Fields(query), " ")
+	if len(query) > maxPlanQueryLength {
+		return query[:maxPlanQueryLength] + "..."
+	}
+	return query
+}
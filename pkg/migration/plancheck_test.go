@@ -0,0 +1,161 @@
+package migration
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/block/spirit/pkg/table"
+	"github.com/block/spirit/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableAccesses(t *testing.T) {
+	accesses, err := tableAccesses([]byte(`{
+		"query_block": {
+			"select_id": 1,
+			"nested_loop": [
+				{"table": {"table_name": "t1", "access_type": "ref", "key": "a", "rows_examined_per_scan": 4}},
+				{"table": {"table_name": "t2", "access_type": "ALL", "rows_examined_per_scan": 100}}
+			]
+		}
+	}`))
+	require.NoError(t, err)
+	require.Equal(t, []tableAccess{
+		{table: "t1", accessType: "ref", key: "a", rows: 4},
+		{table: "t2", accessType: "ALL", rows: 100},
+	}, accesses)
+
+	// The version 2 format.
+	accesses, err = tableAccesses([]byte(`{
+		"query": "...",
+		"inputs": [{"operation": "Index lookup on t1 using a", "table_name": "t1", "access_type": "index", "index_name": "a", "estimated_rows": 4}]
+	}`))
+	require.NoError(t, err)
+	require.Equal(t, []tableAccess{{table: "t1", accessType: "index", key: "a", rows: 4}}, accesses)
+
+	_, err = tableAccesses([]byte(`not json`))
+	require.Error(t, err)
+}
+
+func TestComparePlans(t *testing.T) {
+	plan := func(access string) []byte {
+		return []byte(`{"query_block": {"table": ` + access + `}}`)
+	}
+	tests := []struct {
+		oldPlan, newPlan []byte
+		expected         []string
+	}{
+		{
+			oldPlan: plan(`{"table_name": "t1", "access_type": "ref", "key": "a", "rows_examined_per_scan": 4}`),
+			newPlan: plan(`{"table_name": "t1", "access_type": "ref", "key": "a", "rows_examined_per_scan": 5}`),
+		},
+		{
+			oldPlan:  plan(`{"table_name": "t1", "access_type": "ref", "key": "a", "rows_examined_per_scan": 4}`),
+			newPlan:  plan(`{"table_name": "t1", "access_type": "ALL", "rows_examined_per_scan": 50000}`),
+			expected: []string{"a full scan of t1 instead of key a", "an estimated 50000 rows of t1 examined instead of 4"},
+		},
+		{
+			oldPlan:  plan(`{"table_name": "t1", "access_type": "ref", "key": "a", "rows_examined_per_scan": 4}`),
+			newPlan:  plan(`{"table_name": "t1", "access_type": "ref", "key": "b", "rows_examined_per_scan": 6}`),
+			expected: []string{"t1 by key b instead of key a"},
+		},
+		{
+			// A full scan that was already a full scan is not a regression,
+			// unless it reads many more rows.
+			oldPlan: plan(`{"table_name": "t1", "access_type": "ALL", "rows_examined_per_scan": 10}`),
+			newPlan: plan(`{"table_name": "t1", "access_type": "ALL", "rows_examined_per_scan": 90}`),
+		},
+		{
+			oldPlan:  plan(`{"table_name": "t1", "access_type": "range", "key": "a", "rows_examined_per_scan": 100}`),
+			newPlan:  plan(`{"table_name": "t1", "access_type": "range", "key": "a", "rows_examined_per_scan": 2000}`),
+			expected: []string{"an estimated 2000 rows of t1 examined instead of 100"},
+		},
+		{
+			oldPlan:  plan(`{"table_name": "t1", "access_type": "ref", "key": "a"}`),
+			newPlan:  []byte(`{"query_block": {"nested_loop": [{"table": {"table_name": "t2", "access_type": "ALL"}}, {"table": {"table_name": "t1", "access_type": "ref", "key": "a"}}]}}`),
+			expected: []string{"the plan reads 2 tables instead of 1"},
+		},
+	}
+	for _, test := range tests {
+		reasons, err := comparePlans(test.oldPlan, test.newPlan)
+		require.NoError(t, err)
+		require.Equal(t, test.expected, reasons, string(test.newPlan))
+	}
+}
+
+func TestLoadPlanQueries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queries.sql")
+	require.NoError(t, os.WriteFile(path, []byte("SELECT * FROM t1 WHERE a = 1;\nSELECT * FROM t1 WHERE b = 'x';\n"), 0o600))
+	queries, err := loadPlanQueries(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{"SELECT * FROM t1 WHERE a = 1;", "SELECT * FROM t1 WHERE b = 'x';"}, queries)
+
+	require.NoError(t, os.WriteFile(path, []byte("SELECT * FROM t1; ALTER TABLE t1 ADD INDEX (b)"), 0o600))
+	_, err = loadPlanQueries(path)
+	require.ErrorContains(t, err, "--plan-regression-queries")
+}
+
+func TestPlanCheckFailed(t *testing.T) {
+	c := &tableChange{
+		table:  &table.TableInfo{TableName: "t1"},
+		runner: &Runner{migration: &Migration{PlanRegression: "warn"}, logger: slog.Default()},
+	}
+	err := errors.New("could not explain query")
+	require.NoError(t, c.planCheckFailed(err)) // only logged
+	c.runner.migration.PlanRegression = "block"
+	require.ErrorIs(t, c.planCheckFailed(err), err)
+}
+
+func TestMigratePlanQueryCantBeExplained(t *testing.T) {
+	t.Parallel()
+	tt := testutils.NewTestTable(t, "plant2", `CREATE TABLE plant2 (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		a int NOT NULL
+	)`)
+	path := filepath.Join(t.TempDir(), "queries.sql")
+	require.NoError(t, os.WriteFile(path, []byte("SELECT * FROM plant2 WHERE no_such_column = 5;\n"), 0o600))
+
+	// The query is explained on the table before anything is copied.
+	m := NewTestRunner(t, "plant2", "MODIFY a bigint NOT NULL", WithPlanRegression(path, "warn"))
+	err := m.Run(t.Context())
+	require.ErrorContains(t, err, "--plan-regression-queries: could not explain query")
+	require.NoError(t, m.Close())
+	var count int
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), `SELECT COUNT(*) FROM information_schema.tables
+		WHERE table_schema=DATABASE() AND table_name='_plant2_new'`).Scan(&count))
+	require.Zero(t, count)
+}
+
+func TestMigratePlanRegression(t *testing.T) {
+	t.Parallel()
+	tt := testutils.NewTestTable(t, "plant1", `CREATE TABLE plant1 (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		a int NOT NULL,
+		b int NOT NULL,
+		KEY a (a)
+	)`)
+	testutils.RunSQL(t, `INSERT INTO plant1 (a, b) SELECT n, n FROM (
+		WITH RECURSIVE seq (n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM seq WHERE n < 2000) SELECT n FROM seq) s`)
+	path := filepath.Join(t.TempDir(), "queries.sql")
+	require.NoError(t, os.WriteFile(path, []byte("SELECT * FROM plant1 WHERE a = 5;\nSELECT * FROM other_table WHERE a = 5;\n"), 0o600))
+
+	// Dropping the index that the query uses blocks the cutover.
+	m := NewTestRunner(t, "plant1", "DROP INDEX a", WithPlanRegression(path, "block"))
+	err := m.Run(t.Context())
+	require.ErrorContains(t, err, "cutover blocked")
+	require.ErrorContains(t, err, "a full scan of plant1 instead of key a")
+	require.NoError(t, m.Close())
+
+	var count int
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), `SELECT COUNT(*) FROM information_schema.statistics
+		WHERE table_schema=DATABASE() AND table_name='plant1' AND index_name='a'`).Scan(&count))
+	require.Equal(t, 1, count)
+
+	// With warn, it only warns.
+	m = NewTestRunner(t, "plant1", "DROP INDEX a", WithPlanRegression(path, "warn"))
+	require.NoError(t, m.Run(t.Context()))
+	require.NoError(t, m.Close())
+}
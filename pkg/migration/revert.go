@@ -0,0 +1,413 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/block/spirit/pkg/applier"
+	"github.com/block/spirit/pkg/change"
+	"github.com/block/spirit/pkg/dbconn"
+	"github.com/block/spirit/pkg/statement"
+	"github.com/block/spirit/pkg/status"
+	"github.com/block/spirit/pkg/table"
+	"github.com/block/spirit/pkg/utils"
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	// revertPositionInterval is how often the revert window flushes the
+	// changes it has streamed back to the old table, and records the
+	// position that `spirit revert` resumes from.
+	revertPositionInterval = 30 * time.Second
+	// revertCatchUpAttempts is how many times `spirit revert` waits (up
+	// to change.DefaultTimeout each) for its feed to catch up with the
+	// binary log before it swaps the tables. Only the changes since the
+	// last recorded position have to be read, so this is generous.
+	revertCatchUpAttempts = 10
+)
+
+// revertTableDDL is the structure of the _<table>_revert table, which
+// holds a single row while a revert window is open.
+const revertTableDDL = `(
+	id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+	original_table_name VARCHAR(64) NOT NULL,
+	old_table_name VARCHAR(64) NOT NULL,
+	statement TEXT NOT NULL,
+	binlog_position TEXT NOT NULL,
+	gtid BOOLEAN NOT NULL,
+	expires_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+	)`
+
+// errRevertWindowInterrupted is returned by runRevertWindow when the
+// migration is cancelled while the revert window is open. The old table
+// and the revert table are left in place for `spirit revert`.
+var errRevertWindowInterrupted = errors.New("revert window interrupted")
+
+// Revert is the kong CLI entry point of `spirit revert`. It swaps a table
+// that was migrated with --revert-window back to the table it was
+// migrated from.
+type Revert struct {
+	Host               string        `name:"host" help:"Hostname" optional:""`
+	Username           string        `name:"username" help:"User" optional:""`
+	Password           *string       `name:"password" help:"Password" optional:""`
+	Database           string        `name:"database" help:"Database" optional:""`
+	ConfFile           string        `name:"conf" help:"MySQL conf file" optional:"" type:"existingfile"`
+	Table              string        `name:"table" help:"The table to revert" required:""`
+	LockWaitTimeout    time.Duration `name:"lock-wait-timeout" help:"The DDL lock_wait_timeout required for the swap" optional:"" default:"30s"`
+	SkipForceKill      bool          `name:"skip-force-kill" help:"Disable killing long-running transactions in order to acquire metadata lock (MDL) for the swap" optional:"" default:"false"`
+	TLSMode            string        `name:"tls-mode" help:"TLS connection mode (case insensitive): DISABLED, PREFERRED (default), REQUIRED, VERIFY_CA, VERIFY_IDENTITY" optional:""`
+	TLSCertificatePath string        `name:"tls-ca" help:"Path to custom TLS CA certificate file" optional:""`
+}
+
+// revertState is the row of the revert table.
+type revertState struct {
+	originalTableName string
+	oldTableName      string
+	statement         string
+	position          string
+	gtid              bool
+	expiresAt         string
+}
+
+// revertFeedConfig configures newRevertFeed.
+type revertFeedConfig struct {
+	db       *sql.DB
+	dbConfig *dbconn.DBConfig
+	host     string
+	username string
+	password string
+	gtid     bool
+	// current is the migrated table, and old the table it was migrated
+	// from. renames are the column renames of the migration (old→new).
+	current *table.TableInfo
+	old     *table.TableInfo
+	renames map[string]string
+	logger  *slog.Logger
+	cancel  func() bool
+}
+
+// newRevertFeed creates a change source that streams the changes made to
+// the migrated table into the old table, starting from position.
+func newRevertFeed(ctx context.Context, cfg *revertFeedConfig, position string) (change.Source, error) {
+	// The feed maps the migrated table's columns to the old table's, so
+	// the renames go the other way.
+	renames := make(map[string]string, len(cfg.renames))
+	for from, to := range cfg.renames {
+		renames[to] = from
+	}
+	// The chunker is never opened. The watermark optimization is off, so
+	// the subscription only uses it for the column mapping.
+	chunker, err := table.NewChunker(cfg.current, table.ChunkerConfig{
+		NewTable:      cfg.old,
+		Logger:        cfg.logger,
+		ColumnMapping: table.NewColumnMapping(cfg.current, cfg.old, renames),
+	})
+	if err != nil {
+		return nil, err
+	}
+	appl, err := applier.NewSingleTargetApplier(
+		applier.Target{DB: cfg.db},
+		&applier.ApplierConfig{
+			Logger:   cfg.logger,
+			DBConfig: cfg.dbConfig,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create applier: %w", err)
+	}
+	replConfig := change.NewClientDefaultConfig()
+	replConfig.Logger = cfg.logger
+	replConfig.CancelFunc = cfg.cancel
+	replConfig.DBConfig = cfg.dbConfig
+	var feed change.Source
+	if cfg.gtid {
+		feed = change.NewGTIDClient(cfg.db, cfg.host, cfg.username, cfg.password, appl, replConfig)
+	} else {
+		feed = change.NewBinlogClient(cfg.db, cfg.host, cfg.username, cfg.password, appl, replConfig)
+	}
+	if err := feed.AddSubscription(cfg.current, cfg.old, chunker); err != nil {
+		return nil, err
+	}
+	if err := feed.StartFromPosition(ctx, position); err != nil {
+		feed.Close()
+		return nil, err
+	}
+	return feed, nil
+}
+
+// currentPosition returns the current position of the change source the
+// migration uses, in the format its StartFromPosition accepts.
+func (r *Runner) currentPosition(ctx context.Context) (string, error) {
+	if r.migration.EnableExperimentalGTID {
+		return change.CurrentGTIDPosition(ctx, r.db)
+	}
+	return change.CurrentBinlogPosition(ctx, r.db)
+}
+
+// recordRevertPosition is called by the cutover after the rename, while it
+// still holds the table locks. Nothing can write to the renamed tables
+// until the locks are released, so a feed that starts from the position
+// it records sees every change to the migrated table, and nothing from
+// before the rename. If the position can't be read, the migration
+// completes but can't be reverted.
+func (r *Runner) recordRevertPosition(ctx context.Context) {
+	pos, err := r.currentPosition(ctx)
+	if err != nil {
+		r.logger.Error("could not read the position at cutover; the migration will not be revertible", "error", err)
+		return
+	}
+	r.revertPosition = pos
+}
+
+// runRevertWindow streams the changes made to the migrated table back into
+// the old table for --revert-window, recording its position in the revert
+// table so that `spirit revert` can take over. The revert table is dropped
+// when it returns, unless the window was interrupted by cancelling the
+// migration, in which case it returns errRevertWindowInterrupted.
+func (r *Runner) runRevertWindow(ctx context.Context) (retErr error) {
+	if r.revertPosition == "" {
+		return errors.New("the position at cutover was not recorded")
+	}
+	// The migration's own feed still reads changes to the table (under
+	// its new name) into a subscription that will never be flushed.
+	r.replClient.Close()
+	chg := r.changes[0]
+	current := table.NewTableInfo(r.db, chg.table.SchemaName, chg.table.TableName)
+	old := table.NewTableInfo(r.db, chg.table.SchemaName, chg.oldTableName())
+	for _, t := range []*table.TableInfo{current, old} {
+		t.DisableAnalyze = true
+		if err := t.SetInfo(ctx); err != nil {
+			return err
+		}
+	}
+	revertTable := utils.RevertTableName(chg.table.TableName)
+	if err := dbconn.Exec(ctx, r.db, "DROP TABLE IF EXISTS %n.%n", chg.table.SchemaName, revertTable); err != nil {
+		return err
+	}
+	if err := dbconn.Exec(ctx, r.db, "CREATE TABLE %n.%n "+revertTableDDL, chg.table.SchemaName, revertTable); err != nil {
+		return err
+	}
+	defer func() {
+		if errors.Is(retErr, errRevertWindowInterrupted) {
+			return
+		}
+		if err := dbconn.Exec(context.WithoutCancel(ctx), r.db, "DROP TABLE IF EXISTS %n.%n", chg.table.SchemaName, revertTable); err != nil {
+			r.logger.Error("could not drop the revert table", "table", revertTable, "error", err)
+		}
+	}()
+	if err := dbconn.Exec(ctx, r.db, `INSERT INTO %n.%n (original_table_name, old_table_name, statement, binlog_position, gtid, expires_at)
+		VALUES (%?, %?, %?, %?, %?, NOW() + INTERVAL %? SECOND)`,
+		chg.table.SchemaName, revertTable, chg.table.TableName, old.TableName, r.migration.Statement,
+		r.revertPosition, r.migration.EnableExperimentalGTID, int64(r.migration.RevertWindow.Seconds())); err != nil {
+		return err
+	}
+
+	// The feed cancels streamCtx when it can't continue, e.g. because of
+	// DDL on one of the tables.
+	streamCtx, cancelStream := context.WithCancel(ctx)
+	defer cancelStream()
+	feed, err := newRevertFeed(streamCtx, &revertFeedConfig{
+		db:       r.db,
+		dbConfig: r.dbConfig,
+		host:     r.migration.Host,
+		username: r.migration.Username,
+		password: *r.migration.Password,
+		gtid:     r.migration.EnableExperimentalGTID,
+		current:  current,
+		old:      old,
+		renames:  chg.stmt.ColumnRenameMap(),
+		logger:   r.logger,
+		cancel: func() bool {
+			cancelStream()
+			return true
+		},
+	}, r.revertPosition)
+	if err != nil {
+		return err
+	}
+	defer feed.Close()
+
+	r.revertWindowEnds = time.Now().Add(r.migration.RevertWindow)
+	r.status.Set(status.RevertWindow)
+	r.logger.Warn("revert window open; changes are streamed back to the old table until it ends",
+		"table", chg.table.TableName,
+		"old-table", old.TableName,
+		"revert-window-ends", r.revertWindowEnds.Format(time.RFC3339),
+	)
+	ticker := time.NewTicker(revertPositionInterval)
+	defer ticker.Stop()
+	expired := time.NewTimer(r.migration.RevertWindow)
+	defer expired.Stop()
+	for {
+		select {
+		case <-streamCtx.Done():
+			if ctx.Err() != nil {
+				// The last recorded position is at or before everything
+				// that was applied, and applying a change again is
+				// harmless, so there is nothing more to save.
+				return errRevertWindowInterrupted
+			}
+			return errors.New("the change feed stopped, e.g. because of DDL on the table")
+		case <-expired.C:
+			r.logger.Info("revert window ended", "table", chg.table.TableName)
+			return nil
+		case <-ticker.C:
+			if err := feed.Flush(streamCtx); err != nil {
+				if ctx.Err() != nil {
+					return errRevertWindowInterrupted
+				}
+				return fmt.Errorf("could not apply changes to the old table: %w", err)
+			}
+			if err := dbconn.Exec(ctx, r.db, "UPDATE %n.%n SET binlog_position = %?",
+				chg.table.SchemaName, revertTable, feed.Position()); err != nil {
+				if ctx.Err() != nil {
+					return errRevertWindowInterrupted
+				}
+				return err
+			}
+		}
+	}
+}
+
+// Run reverts the table. The migration that kept it revertible must have
+// stopped: its revert window has to be interrupted (e.g. with the cancel
+// endpoint of the HTTP API), since the feed that keeps the old table up to
+// date can only be in one process at a time. Run starts its own feed from
+// the last recorded position, catches up, and swaps the tables back with
+// the same lock-and-rename as the cutover. The migrated table is kept as
+// _<table>_reverted_<timestamp>.
+func (rv *Revert) Run() error {
+	ctx := context.TODO()
+	logger := slog.Default()
+	m := &Migration{
+		Host:               rv.Host,
+		Username:           rv.Username,
+		Password:           rv.Password,
+		Database:           rv.Database,
+		ConfFile:           rv.ConfFile,
+		TLSMode:            rv.TLSMode,
+		TLSCertificatePath: rv.TLSCertificatePath,
+	}
+	dbConfig := dbconn.NewDBConfig()
+	if rv.LockWaitTimeout > 0 {
+		dbConfig.LockWaitTimeout = int(rv.LockWaitTimeout.Seconds())
+	}
+	dbConfig.ForceKill = !rv.SkipForceKill
+	db, dsn, err := m.Connect(dbConfig)
+	if err != nil {
+		return err
+	}
+	defer utils.CloseAndLog(db)
+
+	revertTable := utils.RevertTableName(rv.Table)
+	var state revertState
+	err = db.QueryRowContext(ctx, fmt.Sprintf("SELECT original_table_name, old_table_name, statement, binlog_position, gtid, expires_at FROM `%s`.`%s` ORDER BY id DESC LIMIT 1",
+		m.Database, revertTable)).Scan(&state.originalTableName, &state.oldTableName, &state.statement, &state.position, &state.gtid, &state.expiresAt)
+	if err != nil {
+		if mysqlErr, ok := errors.AsType[*mysql.MySQLError](err); (ok && mysqlErr.Number == errNoSuchTable) || errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("table %s can't be reverted: it was not migrated with --revert-window, or its revert window has ended", rv.Table)
+		}
+		return err
+	}
+	if state.originalTableName != rv.Table {
+		return fmt.Errorf("revert table %s belongs to table %s, not %s", revertTable, state.originalTableName, rv.Table)
+	}
+	current := table.NewTableInfo(db, m.Database, rv.Table)
+	old := table.NewTableInfo(db, m.Database, state.oldTableName)
+	for _, t := range []*table.TableInfo{current, old} {
+		t.DisableAnalyze = true
+		if err := t.SetInfo(ctx); err != nil {
+			return err
+		}
+	}
+	// The migration holds this lock for as long as its revert window
+	// is open, so taking it also proves that its feed has stopped.
+	lock, err := dbconn.NewMetadataLock(ctx, dsn, []*table.TableInfo{current}, dbConfig, logger)
+	if err != nil {
+		return fmt.Errorf("could not lock table %s; is its migration still running? Cancel it before reverting: %w", rv.Table, err)
+	}
+	defer utils.CloseAndLog(lock)
+
+	stmts, err := statement.New(state.statement)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	feed, err := newRevertFeed(ctx, &revertFeedConfig{
+		db:       db,
+		dbConfig: dbConfig,
+		host:     m.Host,
+		username: m.Username,
+		password: *m.Password,
+		gtid:     state.gtid,
+		current:  current,
+		old:      old,
+		renames:  stmts[0].ColumnRenameMap(),
+		logger:   logger,
+		cancel: func() bool {
+			cancel()
+			return true
+		},
+	}, state.position)
+	if err != nil {
+		return err
+	}
+	defer feed.Close()
+	logger.Info("catching up on changes since the last recorded position",
+		"table", rv.Table,
+		"old-table", old.TableName,
+		"position", state.position,
+		"revert-window-expired-at", state.expiresAt,
+	)
+	if err := catchUp(ctx, feed, logger); err != nil {
+		return err
+	}
+	swappedOut := utils.RevertedTableNameWithTimestamp(rv.Table, time.Now().UTC().Format(utils.NameFormatTimestamp))
+	cutover, err := NewCutOver(db, []*cutoverConfig{{
+		table:        current,
+		newTable:     old,
+		oldTableName: swappedOut,
+	}}, feed, dbConfig, logger)
+	if err != nil {
+		return err
+	}
+	if err := cutover.Run(ctx); err != nil {
+		return fmt.Errorf("revert failed: %w", err)
+	}
+	if err := cutover.verifyTriggers(ctx); err != nil {
+		return fmt.Errorf("revert complete, but %w", err)
+	}
+	if err := dbconn.Exec(ctx, db, "DROP TABLE IF EXISTS %n.%n", m.Database, revertTable); err != nil {
+		logger.Error("revert successful but failed to drop the revert table", "table", revertTable, "error", err)
+	}
+	logger.Info("revert complete; the migrated table has been kept",
+		"table", rv.Table,
+		"migrated-table", swappedOut,
+	)
+	return nil
+}
+
+// catchUp waits until feed has read up to the current position of the
+// binary log, and flushes what it has read.
+func catchUp(ctx context.Context, feed change.Source, logger *slog.Logger) error {
+	var err error
+	for attempt := range revertCatchUpAttempts {
+		if err = feed.BlockWait(ctx); err == nil {
+			return feed.Flush(ctx)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		logger.Info("still catching up", "attempt", attempt+1, "error", err)
+		if err := feed.Flush(ctx); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("could not catch up on changes: %w", err)
+}
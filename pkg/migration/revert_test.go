@@ -0,0 +1,104 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/block/spirit/pkg/status"
+	"github.com/block/spirit/pkg/testutils"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevertWindowSingleTableOnly(t *testing.T) {
+	pw := "spirit"
+	_, err := NewRunner(&Migration{
+		Host:         "127.0.0.1:1",
+		Username:     "spirit",
+		Password:     &pw,
+		Database:     "test",
+		Statement:    "ALTER TABLE t1 ADD INDEX (a); ALTER TABLE t2 ADD INDEX (a)",
+		RevertWindow: time.Hour,
+	})
+	require.ErrorContains(t, err, "--revert-window is only supported for single-table migrations")
+}
+
+func TestRevertWindow(t *testing.T) {
+	t.Parallel()
+	tt := testutils.NewTestTable(t, "revertwin", `CREATE TABLE revertwin (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		a int NOT NULL,
+		b int NOT NULL DEFAULT 0
+	)`)
+	testutils.RunSQL(t, "INSERT INTO revertwin (a, b) VALUES (1, 1), (2, 2), (3, 3)")
+
+	m := NewTestRunner(t, "revertwin", "DROP COLUMN b, RENAME COLUMN a TO c", WithRevertWindow(time.Hour))
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- m.Run(ctx) }()
+	waitForStatus(t, m, status.RevertWindow)
+	require.False(t, m.usedInstantDDL)
+
+	// Changes to the migrated table are streamed back to the old one.
+	testutils.RunSQL(t, "INSERT INTO revertwin (c) VALUES (4)")
+	testutils.RunSQL(t, "UPDATE revertwin SET c = 10 WHERE id = 1")
+	testutils.RunSQL(t, "DELETE FROM revertwin WHERE id = 2")
+
+	// Interrupting the window keeps the old table for spirit revert.
+	cancel()
+	require.NoError(t, <-done)
+	require.NoError(t, m.Close())
+
+	cfg, err := mysql.ParseDSN(testutils.DSN())
+	require.NoError(t, err)
+	rv := &Revert{
+		Host:     cfg.Addr,
+		Username: cfg.User,
+		Password: &cfg.Passwd,
+		Database: cfg.DBName,
+		Table:    "revertwin",
+	}
+	require.NoError(t, rv.Run())
+
+	var columns string
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(),
+		`SELECT GROUP_CONCAT(column_name ORDER BY ordinal_position) FROM information_schema.columns
+		WHERE table_schema=DATABASE() AND table_name='revertwin'`).Scan(&columns))
+	require.Equal(t, "id,a,b", columns)
+	var rows string
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(),
+		"SELECT GROUP_CONCAT(CONCAT(id, ':', a, ':', b) ORDER BY id) FROM revertwin").Scan(&rows))
+	require.Equal(t, "1:10:1,3:3:3,4:4:0", rows)
+
+	// The migrated table is kept, the old and revert tables are gone.
+	var reverted string
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(),
+		`SELECT GROUP_CONCAT(table_name) FROM information_schema.tables
+		WHERE table_schema=DATABASE() AND table_name LIKE '\_revertwin\_%'`).Scan(&reverted))
+	require.Regexp(t, `^_revertwin_reverted_\d{8}_\d{6}$`, reverted)
+	testutils.RunSQL(t, fmt.Sprintf("DROP TABLE `%s`", reverted))
+
+	// There is nothing left to revert.
+	require.ErrorContains(t, rv.Run(), "can't be reverted")
+}
+
+func TestRevertWindowEnds(t *testing.T) {
+	t.Parallel()
+	tt := testutils.NewTestTable(t, "revertwinend", `CREATE TABLE revertwinend (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		a int NOT NULL
+	)`)
+	m := NewTestRunner(t, "revertwinend", "ADD INDEX (a)", WithRevertWindow(time.Second))
+	require.NoError(t, m.Run(t.Context()))
+	require.NoError(t, m.Close())
+
+	// The old table and the revert table are dropped when the window ends.
+	var count int
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(),
+		`SELECT COUNT(*) FROM information_schema.tables
+		WHERE table_schema=DATABASE() AND table_name LIKE '\_revertwinend\_%'`).Scan(&count))
+	require.Zero(t, count)
+}
@@ -3,9 +3,11 @@ package migration
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"sync"
 	"time"
 
@@ -17,6 +19,7 @@ import (
 	"github.com/block/spirit/pkg/checksum"
 	"github.com/block/spirit/pkg/copier"
 	"github.com/block/spirit/pkg/dbconn"
+	"github.com/block/spirit/pkg/hooks"
 	"github.com/block/spirit/pkg/metrics"
 	"github.com/block/spirit/pkg/migration/check"
 	"github.com/block/spirit/pkg/status"
@@ -28,6 +31,7 @@ import (
 // These are really consts, but set to var for testing.
 var (
 	tableStatUpdateInterval = 5 * time.Minute
+	copyRecheckInterval     = 5 * time.Minute
 	sentinelCheckInterval   = 1 * time.Second
 	sentinelWaitLimit       = 48 * time.Hour
 	sentinelTableName       = "_spirit_sentinel"   // this is now a const.
@@ -124,9 +128,60 @@ type Runner struct {
 
 	// MetricsSink
 	metricsSink metrics.Sink
+
+	// httpServer is the optional status/control API (--http-listen).
+	// nil when disabled.
+	httpServer *status.Server
+
+	// settingsMu guards the fields of r.migration that Reconfigure can
+	// change while the migration runs: Threads, WriteThreads,
+	// TargetChunkTime and ReplicaMaxLag. Code that reads them once the
+	// copy has started must go through Settings().
+	settingsMu sync.Mutex
+
+	// poolMu guards poolSize, the connection limit last set on r.db by
+	// growPool.
+	poolMu   sync.Mutex
+	poolSize int
+
+	// continuousChunker is the chunker of continuousChecker, kept so that
+	// Reconfigure can change its target chunk time. Guarded by
+	// checkpointMu, like continuousChecker.
+	continuousChunker table.Chunker
+
+	// pause is passed to the copier and the replication client, which
+	// stop copying and flushing while it is paused. The runner itself
+	// holds at the points where it would otherwise flush or cut over.
+	// pauseMu makes refusing a pause from cutover on atomic with the
+	// start of the cutover, so a pause can't slip in after the runner
+	// has checked it. cuttingOver is set while the cutover attempts run,
+	// before the first one that locks the tables enters CutOver.
+	pause       status.Pause
+	pauseMu     sync.Mutex
+	cuttingOver bool
+
+	// cutoverWindow restricts cutover to --cutover-window. nil when unset.
+	cutoverWindow *utils.Window
+
+	// planQueries are the queries of --plan-regression-queries, whose
+	// plans are compared on the new tables before cutover.
+	planQueries []string
+
+	// revertPosition is the position recorded under the cutover's table
+	// locks, from which the revert window streams changes back to the
+	// old table. Empty unless --revert-window is set and it was recorded.
+	// revertWindowEnds is set before the state changes to RevertWindow.
+	revertPosition   string
+	revertWindowEnds time.Time
+
+	// hooks are the --hook-exec and --hook-url hooks. They are created
+	// by Run, so that they use the logger from SetLogger.
+	hooks *hooks.Hooks
 }
 
 var _ status.Task = (*Runner)(nil)
+var _ status.Reconfigurable = (*Runner)(nil)
+var _ status.Pausable = (*Runner)(nil)
 
 func NewRunner(m *Migration) (*Runner, error) {
 	stmts, err := m.normalizeOptions()
@@ -145,6 +200,33 @@ func NewRunner(m *Migration) (*Runner, error) {
 		metricsSink: &metrics.NoopSink{},
 		changes:     changes,
 	}
+	if m.MetricsSink == "prometheus" {
+		runner.metricsSink = metrics.NewPrometheusSink()
+	}
+	if m.CutoverWindow != "" {
+		if runner.cutoverWindow, err = utils.ParseWindow(m.CutoverWindow); err != nil {
+			return nil, fmt.Errorf("--cutover-window: %w", err)
+		}
+	}
+	// Reverting swaps the tables back under one lock-and-rename, and
+	// `spirit revert` finds the state by table name, so it is limited to
+	// a single table.
+	if m.RevertWindow > 0 && len(stmts) > 1 {
+		return nil, errors.New("--revert-window is only supported for single-table migrations")
+	}
+	// The tables that reference a table are locked with it at the cutover,
+	// and could also be one of the other tables.
+	if m.ForeignKeys && len(stmts) > 1 {
+		return nil, errors.New("--foreign-keys is only supported for single-table migrations")
+	}
+	if len(m.ColumnExpr) > 0 && len(stmts) > 1 {
+		return nil, errors.New("--column-expr is only supported for single-table migrations")
+	}
+	if m.PlanRegressionQueries != "" {
+		if runner.planQueries, err = loadPlanQueries(m.PlanRegressionQueries); err != nil {
+			return nil, err
+		}
+	}
 	for _, change := range changes {
 		change.runner = runner // link back.
 	}
@@ -189,30 +271,32 @@ func (r *Runner) attemptMySQLDDL(ctx context.Context) error {
 	return r.changes[0].attemptMySQLDDL(ctx)
 }
 
-func (r *Runner) Run(ctx context.Context) error {
-	ctx, r.cancelFunc = context.WithCancel(ctx)
-	defer r.cancelFunc()
-	r.startTime = time.Now()
-	bi := buildinfo.Get()
-	r.logger.Info("Starting spirit migration",
-		"version", bi.Version,
-		"commit", bi.Commit,
-		"build-date", bi.Date,
-		"go", bi.GoVer,
-		"dirty", bi.Modified,
-		"concurrency", r.migration.Threads,
-		"target-chunk-size", r.migration.TargetChunkTime,
-	)
+// mysqlDDLSkipReason returns why attemptMySQLDDL is not tried, or "" if
+// it is. It only supports single-table changes, and the table is always
+// copied when the old table is needed for a revert window, or when a
+// column is computed by an expression.
+func (r *Runner) mysqlDDLSkipReason() string {
+	switch {
+	case len(r.changes) > 1:
+		return "multi-table migration"
+	case r.migration.RevertWindow > 0:
+		return "--revert-window requires a copy"
+	case len(r.migration.ColumnExpr) > 0:
+		return "--column-expr requires a copy"
+	}
+	return ""
+}
 
-	// Create a database connection
-	// It will be closed in r.Close()
-	var err error
+// connect creates the main database connection (r.db). It is called from
+// Run and Plan, and closed in Close.
+func (r *Runner) connect() error {
 	r.dbConfig = dbconn.NewDBConfig()
 	if r.migration.LockWaitTimeout > 0 {
 		r.dbConfig.LockWaitTimeout = int(r.migration.LockWaitTimeout.Seconds())
 	}
 	r.dbConfig.InterpolateParams = r.migration.InterpolateParams
 	r.dbConfig.ForceKill = !r.migration.SkipForceKill
+	r.dbConfig.DisableForeignKeyChecks = r.migration.ForeignKeys
 	// Map TLS configuration from migration to dbConfig
 	r.dbConfig.TLSMode = r.migration.TLSMode
 	r.dbConfig.TLSCertificatePath = r.migration.TLSCertificatePath
@@ -236,10 +320,69 @@ func (r *Runner) Run(ctx context.Context) error {
 	// resolving WriteThreads. The pool only ever grows (via SetMaxOpenConns);
 	// later phases (checksum, cutover) ratchet it further but never shrink it.
 	r.dbConfig.MaxOpenConnections = r.migration.Threads + r.migration.WriteThreads + r.controlPlaneConns()
+	var err error
 	r.db, err = dbconn.New(r.dsn(), r.dbConfig)
 	if err != nil {
 		return fmt.Errorf("failed to connect to main database (DSN: %s): %w", maskPasswordInDSN(r.dsn()), err)
 	}
+	r.poolSize = r.dbConfig.MaxOpenConnections
+	return nil
+}
+
+// Run runs the migration, and then its failure or success hook.
+func (r *Runner) Run(ctx context.Context) error {
+	r.hooks = hooks.New(r.migration.hooksConfig(), r.logger)
+	err := r.run(ctx)
+	// A failed migration's context is often done (e.g. it was cancelled),
+	// but its failure hook still has to run.
+	hookCtx := context.WithoutCancel(ctx)
+	if err != nil {
+		r.runHook(hookCtx, hooks.Failure, err)
+	} else {
+		r.runHook(hookCtx, hooks.Success, nil)
+	}
+	return err
+}
+
+func (r *Runner) run(ctx context.Context) error {
+	ctx, r.cancelFunc = context.WithCancel(ctx)
+	defer r.cancelFunc()
+	r.startTime = time.Now()
+	bi := buildinfo.Get()
+	r.logger.Info("Starting spirit migration",
+		"version", bi.Version,
+		"commit", bi.Commit,
+		"build-date", bi.Date,
+		"go", bi.GoVer,
+		"dirty", bi.Modified,
+		"concurrency", r.migration.Threads,
+		"target-chunk-size", r.migration.TargetChunkTime,
+	)
+	r.runHook(ctx, hooks.Startup, nil)
+
+	// Create a database connection
+	// It will be closed in r.Close()
+	if err := r.connect(); err != nil {
+		return err
+	}
+
+	// Start the HTTP status/control API as early as possible so operators
+	// can see the preflight phases too. It is stopped in Close().
+	if r.migration.HTTPListen != "" {
+		r.httpServer = status.NewServer(r, r.migration.HTTPToken, r.logger)
+		if prom, ok := r.metricsSink.(*metrics.PrometheusSink); ok {
+			r.httpServer.Handle("GET /metrics", prom)
+		}
+		if err := r.httpServer.Start(r.migration.HTTPListen); err != nil {
+			return fmt.Errorf("could not start http server on %s: %w", r.migration.HTTPListen, err)
+		}
+	}
+	// Label everything sent to the sink with the current phase, so chunk
+	// timings from the copy can be told apart from those of a recopy
+	// during checksum.
+	r.metricsSink = metrics.NewLabeledSink(r.metricsSink, func() map[string]string {
+		return map[string]string{metrics.PhaseLabel: r.status.Get().String()}
+	})
 
 	// Run linting if --lint or --lint-only is specified.
 	// --lint-only implies lint.
@@ -293,9 +436,9 @@ func (r *Runner) Run(ctx context.Context) error {
 	// use MySQL's built-in DDL. This is because it's usually faster
 	// when it is compatible. If it returns no error, that means it
 	// has been successful and the DDL is complete.
-	// Note: this function returns an error when in multi-table mode.
-	err = r.attemptMySQLDDL(ctx)
-	if err == nil {
+	if reason := r.mysqlDDLSkipReason(); reason != "" {
+		r.logger.Info("not attempting INSTANT or INPLACE DDL", "reason", reason)
+	} else if err := r.attemptMySQLDDL(ctx); err == nil {
 		r.logger.Info("apply complete",
 			"instant-ddl", r.usedInstantDDL,
 			"inplace-ddl", r.usedInplaceDDL,
@@ -307,6 +450,12 @@ func (r *Runner) Run(ctx context.Context) error {
 	if err := r.runChecks(ctx, check.ScopePreflight); err != nil {
 		return err
 	}
+	for _, change := range r.changes {
+		if err := change.explainPlanQueries(ctx); err != nil {
+			return err
+		}
+	}
+	r.runHook(ctx, hooks.PreflightComplete, nil)
 
 	// Perform setup steps, including resuming from a checkpoint (if available)
 	// and creating the new and checkpoint tables.
@@ -325,11 +474,18 @@ func (r *Runner) Run(ctx context.Context) error {
 	// but we always recopy the last-bit, even if we are resuming
 	// partially through the checksum.
 	r.status.Set(status.CopyRows)
-	if err := r.copier.Run(ctx); err != nil {
+	if err := r.copyRows(ctx); err != nil {
 		return err
 	}
 	r.logger.Info("copy rows complete")
 	r.copyDuration = time.Since(r.copier.StartTime())
+	r.runHook(ctx, hooks.CopyComplete, nil)
+
+	// Everything from here on flushes changes, so a pause that was
+	// requested during the copy holds here.
+	if err := r.waitWhilePaused(ctx); err != nil {
+		return err
+	}
 
 	// Disable both watermark optimizations so that all changes can be flushed.
 	// For non-memory-comparable PKs this also drains the buffered map and
@@ -344,6 +500,7 @@ func (r *Runner) Run(ctx context.Context) error {
 	if err := r.postCopyPhase(ctx); err != nil {
 		return err
 	}
+	r.runHook(ctx, hooks.ChecksumComplete, nil)
 
 	// Block on the sentinel table (if defer-cutover is in use). While we
 	// wait, waitOnSentinelTable also runs a "continuous checksum" loop in
@@ -362,53 +519,97 @@ func (r *Runner) Run(ctx context.Context) error {
 			return err
 		}
 	}
-	// Run any checks that need to be done pre-cutover.
-	if err := r.runChecks(ctx, check.ScopeCutover); err != nil {
-		return err
+	// The rows were copied with foreign_key_checks disabled, so verify
+	// that the new table's constraints hold before it replaces the table.
+	if r.migration.ForeignKeys {
+		for _, change := range r.changes {
+			if err := change.verifyForeignKeys(ctx); err != nil {
+				return err
+			}
+			if err := change.recordTruncatedForeignKeys(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	// Compare the plans of the queries that use the tables with their
+	// plans on the new tables, now that their statistics are up to date.
+	if len(r.planQueries) > 0 || r.migration.PlanRegressionDigests > 0 {
+		for _, change := range r.changes {
+			if err := change.checkPlans(ctx); err != nil {
+				return err
+			}
+		}
 	}
-	// It's time for the final cut-over, where
-	// the tables are swapped under a lock.
-	r.status.Set(status.CutOver)
 	cutoverCfg := []*cutoverConfig{}
 	for _, change := range r.changes {
 		cutoverCfg = append(cutoverCfg, &cutoverConfig{
-			table:          change.table,
-			newTable:       change.newTable,
-			oldTableName:   change.oldTableName(),
-			useTestCutover: r.migration.useTestCutover, // indicates we want the test cutover
+			table:              change.table,
+			newTable:           change.newTable,
+			oldTableName:       change.oldTableName(),
+			useTestCutover:     r.migration.useTestCutover, // indicates we want the test cutover
+			repointForeignKeys: r.migration.ForeignKeys,
 		})
 	}
 	cutover, err := NewCutOver(r.db, cutoverCfg, r.replClient, r.dbConfig, r.logger)
 	if err != nil {
 		return err
 	}
-	// Drop the _old table if it exists. This ensures
-	// that the rename will succeed (although there is a brief race)
-	for _, change := range r.changes {
-		if err := change.dropOldTable(ctx); err != nil {
+	cutover.metricsSink = r.metricsSink
+	cutover.window = r.cutoverWindow
+	cutover.onLocked = r.enterCutOver
+	if r.migration.RevertWindow > 0 {
+		cutover.afterRename = r.recordRevertPosition
+	}
+	for {
+		// With a --cutover-window, wait for it to open. The continuous
+		// checksum runs in the meantime, as it does during the sentinel wait.
+		if err := r.waitOnCutoverWindow(ctx); err != nil {
 			return err
 		}
-	}
-	if err := cutover.Run(ctx); err != nil {
-		return fmt.Errorf("cutover failed: %w", err)
-	}
-	if !r.migration.SkipDropAfterCutover {
+		// Run any checks that need to be done pre-cutover.
+		if err := r.runChecks(ctx, check.ScopeCutover); err != nil {
+			return err
+		}
+		// A failing before-cutover hook blocks the cutover. The
+		// checkpoint is still in place, so the migration can resume.
+		if err := r.hooks.Run(ctx, r.hookEvent(hooks.BeforeCutover, nil)); err != nil {
+			return fmt.Errorf("cutover blocked: %w", err)
+		}
+		if err := r.startCutover(ctx); err != nil {
+			return err
+		}
+		// Drop the _old table if it exists. This ensures
+		// that the rename will succeed (although there is a brief race)
 		for _, change := range r.changes {
 			if err := change.dropOldTable(ctx); err != nil {
-				// Don't return the error because our automation
-				// will retry the migration (but it's already happened)
-				r.logger.Error("migration successful but failed to drop old table",
-					"table", change.oldTableName(),
-					"error", err,
-				)
-			} else {
-				r.logger.Info("successfully dropped old table",
-					"table", change.oldTableName(),
-				)
+				return err
 			}
 		}
-	} else {
-		r.logger.Info("skipped dropping old table")
+		// It's time for the final cut-over, where the tables are swapped
+		// under a lock. The window is checked before each attempt, and
+		// the state only becomes CutOver once an attempt has locked the
+		// tables.
+		err := cutover.Run(ctx)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, errOutsideCutoverWindow) {
+			return fmt.Errorf("cutover failed: %w", err)
+		}
+		// No attempt got as far as locking the tables, so we are still
+		// before CutOver and checkpointing: wait for the next window.
+		r.stopCutover()
+		r.logger.Warn("cutover window closed before cutover succeeded; waiting for the next window", "error", err)
+	}
+	// The old table is kept if the triggers didn't move as expected, so
+	// that they can be compared.
+	if err := cutover.verifyTriggers(ctx); err != nil {
+		return fmt.Errorf("cutover complete, but the old table has been kept: %w", err)
+	}
+	r.runHook(ctx, hooks.AfterCutover, nil)
+	// With a revert window, the old table is needed until it ends.
+	if r.migration.RevertWindow == 0 {
+		r.dropOldTables(ctx)
 	}
 	_, copiedChunks, _ := r.copyChunker.Progress()
 	r.logger.Info("apply complete",
@@ -432,12 +633,100 @@ func (r *Runner) Run(ctx context.Context) error {
 			return err
 		}
 	}
+	if r.migration.RevertWindow > 0 {
+		err := r.runRevertWindow(ctx)
+		if errors.Is(err, errRevertWindowInterrupted) {
+			r.logger.Warn("migration cancelled during its revert window; the old table has been kept. Run spirit revert to revert the migration, or drop the old and revert tables to keep it",
+				"old-table", r.changes[0].oldTableName(),
+				"revert-table", utils.RevertTableName(r.changes[0].table.TableName),
+			)
+			return nil
+		}
+		if err != nil {
+			// Don't return the error, the migration itself has
+			// succeeded (see dropOldTables).
+			r.logger.Error("migration successful but it can no longer be reverted", "error", err)
+		}
+		r.dropOldTables(ctx)
+	}
 	return nil
 }
 
+// hookEvent describes the migration to a hook at point. err is the error
+// that the migration failed with, if any.
+func (r *Runner) hookEvent(point hooks.Point, err error) hooks.Event {
+	event := hooks.Event{
+		Point:    point,
+		Command:  "migrate",
+		State:    r.status.Get().String(),
+		Progress: r.Progress().Summary,
+	}
+	statements := make([]string, 0, len(r.changes))
+	for _, change := range r.changes {
+		event.Tables = append(event.Tables, change.stmt.Table)
+		statements = append(statements, change.stmt.Statement)
+	}
+	event.Statement = strings.Join(statements, "; ")
+	if err != nil {
+		event.Error = err.Error()
+	}
+	return event
+}
+
+// runHook runs the hooks at point. Only a failing before-cutover hook
+// stops the migration, so the errors of the others are just logged.
+func (r *Runner) runHook(ctx context.Context, point hooks.Point, err error) {
+	if err := r.hooks.Run(ctx, r.hookEvent(point, err)); err != nil {
+		r.logger.Error("hook failed", "hook", point, "error", err)
+	}
+}
+
+// dropOldTables drops the old tables after a successful cutover, unless
+// --skip-drop-after-cutover is set. With --gradual-drop they are emptied
+// in chunks first.
+func (r *Runner) dropOldTables(ctx context.Context) {
+	if r.migration.SkipDropAfterCutover {
+		r.logger.Info("skipped dropping old table")
+		for _, change := range r.changes {
+			for _, fk := range change.truncatedForeignKeys {
+				r.logger.Warn("the foreign key keeps its truncated name, since it is only restored once the old table is dropped",
+					"table", fk.Table,
+					"foreign-key", fk.Name,
+					"truncated-name", foreignKeyName(fk.Name),
+				)
+			}
+		}
+		return
+	}
+	for _, change := range r.changes {
+		drop := change.dropOldTable
+		if r.migration.GradualDrop {
+			drop = change.dropOldTableGradually
+		}
+		if err := drop(ctx); err != nil {
+			// Don't return the error because our automation
+			// will retry the migration (but it's already happened)
+			r.logger.Error("migration successful but failed to drop old table",
+				"table", change.oldTableName(),
+				"error", err,
+			)
+			continue
+		}
+		r.logger.Info("successfully dropped old table",
+			"table", change.oldTableName(),
+		)
+		if err := change.restoreForeignKeyNames(ctx); err != nil {
+			r.logger.Error("migration successful but failed to restore the names of its foreign keys",
+				"table", change.table.TableName,
+				"error", err,
+			)
+		}
+	}
+}
+
 // postCopyPhase runs the work that happens between copy-rows and the
-// sentinel wait: drain the binlog backlog, run ANALYZE TABLE, and
-// perform the initial checksum. When defer-cutover is not in use this
+// sentinel wait: drain the binlog backlog, restore secondary indexes
+// (if deferred), run ANALYZE TABLE, and perform the initial checksum. When defer-cutover is not in use this
 // is also the last phase before cutover.
 func (r *Runner) postCopyPhase(ctx context.Context) error {
 	r.status.Set(status.ApplyChangeset)
@@ -450,16 +739,34 @@ func (r *Runner) postCopyPhase(ctx context.Context) error {
 		return err
 	}
 
+	// Restore secondary indexes if they were deferred when the new table
+	// was created. This also covers resuming from a checkpoint of a
+	// migration that deferred them.
+	r.status.Set(status.RestoreSecondaryIndexes)
+	for _, change := range r.changes {
+		if err := change.restoreSecondaryIndexes(ctx); err != nil {
+			return err
+		}
+	}
+
 	// Run ANALYZE TABLE to update the statistics on the new table.
 	// This is required so on cutover plans don't go sideways, which
 	// is at elevated risk because the batch loading can cause statistics
 	// to be out of date.
+	// The statistics options and column histograms of the table are
+	// carried over too, so that they are the same after the cutover.
 	r.status.Set(status.AnalyzeTable)
 	r.logger.Info("Running ANALYZE TABLE")
 	for _, change := range r.changes {
+		if err := change.carryOverStatsOptions(ctx); err != nil {
+			return err
+		}
 		if err := dbconn.Exec(ctx, r.db, "ANALYZE TABLE %n.%n", change.newTable.SchemaName, change.newTable.TableName); err != nil {
 			return err
 		}
+		if err := change.carryOverHistograms(ctx); err != nil {
+			return err
+		}
 
 		// Disable the auto-update statistics go routine. This is because the
 		// checksum uses a consistent read and doesn't see any of the new rows in the
@@ -480,41 +787,107 @@ func (r *Runner) postCopyPhase(ctx context.Context) error {
 		change.table.DisableAutoUpdateStatistics.Store(true)
 	}
 
+	// The checksum starts by flushing under a lock, so hold it if the
+	// migration was paused since the copy finished.
+	if err := r.waitWhilePaused(ctx); err != nil {
+		return err
+	}
+
 	// The checksum is ONLINE after an initial lock
 	// for consistency. It is the main way that we determine that
 	// this program is safe to use even when immature.
 	return r.checksum(ctx)
 }
 
+// copyRows runs the copier, re-running the ScopeCopyRows checks every
+// copyRecheckInterval until it is done. Unless --skip-duplicate-check, it
+// also watches the changes for duplicates of the unique keys that the
+// ALTER adds, and stops the copy if one is found.
+func (r *Runner) copyRows(ctx context.Context) error {
+	copyCtx, cancelCopy := context.WithCancel(ctx)
+	defer cancelCopy()
+	recheckCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	var dupErr error
+	var dupOnce sync.Once
+	if !r.migration.SkipDuplicateCheck {
+		for _, change := range r.changes {
+			if change.duplicates == nil {
+				continue
+			}
+			wg.Go(func() {
+				if err := change.duplicates.watch(recheckCtx); err != nil {
+					dupOnce.Do(func() { dupErr = err })
+					cancelCopy()
+				}
+			})
+		}
+	}
+	wg.Go(func() {
+		ticker := time.NewTicker(copyRecheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-recheckCtx.Done():
+				return
+			case <-ticker.C:
+				// The copy rows checks only warn, so an error doesn't stop the copy.
+				if err := r.runChecks(recheckCtx, check.ScopeCopyRows); err != nil {
+					r.logger.Warn("copy rows checks failed", "error", err)
+				}
+			}
+		}
+	})
+	err := r.copier.Run(copyCtx)
+	cancel()
+	wg.Wait()
+	if dupErr != nil {
+		return dupErr
+	}
+	return err
+}
+
 // runChecks wraps around check.RunChecks and adds the context of this migration
 // We redundantly run checks, once per change.
 func (r *Runner) runChecks(ctx context.Context, scope check.ScopeFlag) error {
 	for _, change := range r.changes {
-		if err := check.RunChecks(ctx, check.Resources{
-			DB:              r.db,
-			Replicas:        r.replicas,
-			Table:           change.table,
-			Statement:       change.stmt,
-			TargetChunkTime: r.migration.TargetChunkTime,
-			Threads:         r.migration.Threads,
-			ReplicaMaxLag:   r.migration.ReplicaMaxLag,
-			ForceKill:       !r.migration.SkipForceKill,
-			// For the pre-run checks we don't have a DB connection yet.
-			// Instead we check the credentials provided.
-			Host:                 r.migration.Host,
-			Username:             r.migration.Username,
-			Password:             *r.migration.Password,
-			TLSMode:              r.migration.TLSMode,
-			TLSCertificatePath:   r.migration.TLSCertificatePath,
-			SkipDropAfterCutover: r.migration.SkipDropAfterCutover,
-			GTID:                 r.migration.EnableExperimentalGTID,
-		}, r.logger, scope); err != nil {
+		if err := check.RunChecks(ctx, r.checkResources(change), r.logger, scope); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// checkResources returns the resources the checks for change are run with.
+func (r *Runner) checkResources(change *tableChange) check.Resources {
+	settings := r.Settings() // the cutover checks run after Reconfigure is possible
+	res := check.Resources{
+		DB:              r.db,
+		Replicas:        r.replicas,
+		Table:           change.table,
+		Statement:       change.stmt,
+		TargetChunkTime: settings.TargetChunkTime,
+		Threads:         settings.Threads,
+		ReplicaMaxLag:   settings.ReplicaMaxLag,
+		ForceKill:       !r.migration.SkipForceKill,
+		// For the pre-run checks we don't have a DB connection yet.
+		// Instead we check the credentials provided.
+		Host:                 r.migration.Host,
+		Username:             r.migration.Username,
+		Password:             *r.migration.Password,
+		TLSMode:              r.migration.TLSMode,
+		TLSCertificatePath:   r.migration.TLSCertificatePath,
+		SkipDropAfterCutover: r.migration.SkipDropAfterCutover,
+		GTID:                 r.migration.EnableExperimentalGTID,
+		ForeignKeys:          r.migration.ForeignKeys,
+	}
+	if r.status.Get() == status.CopyRows {
+		res.CopyElapsed = time.Since(r.copier.StartTime())
+		res.CopyETA, _ = r.copier.GetLatestETA()
+	}
+	return res
+}
+
 func (r *Runner) dsn() string {
 	cfg := mysql.NewConfig()
 	cfg.User = r.migration.Username
@@ -542,10 +915,13 @@ func (r *Runner) setupCopierCheckerAndReplClient(ctx context.Context) error {
 	// instance vCPU count; on non-Aurora there is no reliable vCPU signal to
 	// size from, so it falls back to the default. Idempotent: a resolved
 	// (non-zero) value passes through unchanged if this runs again.
-	r.migration.WriteThreads, err = throttler.ResolveWriteThreads(ctx, r.db, r.migration.WriteThreads, r.logger)
+	writeThreads, err := throttler.ResolveWriteThreads(ctx, r.db, r.migration.WriteThreads, r.logger)
 	if err != nil {
 		return err
 	}
+	r.settingsMu.Lock()
+	r.migration.WriteThreads = writeThreads
+	r.settingsMu.Unlock()
 	// Autoscaling drives the buffered copier's applier worker pool; the legacy
 	// unbuffered copier has no such pool, so the combination downgrades to a
 	// fixed thread count with a warning rather than silently doing nothing.
@@ -587,7 +963,7 @@ func (r *Runner) setupCopierCheckerAndReplClient(ctx context.Context) error {
 	// or autoscaling raised the ceiling; the pool only ever grows.
 	if poolSize := r.migration.Threads + maxWrite + r.controlPlaneConns(); poolSize > r.dbConfig.MaxOpenConnections {
 		r.dbConfig.MaxOpenConnections = poolSize
-		r.db.SetMaxOpenConns(poolSize)
+		r.growPool(poolSize)
 	}
 
 	r.checkpointTable = table.NewTableInfo(r.db, r.changes[0].table.SchemaName, r.checkpointTableName())
@@ -630,6 +1006,7 @@ func (r *Runner) setupCopierCheckerAndReplClient(ctx context.Context) error {
 			StartThreads: r.migration.WriteThreads,
 			MaxThreads:   maxWrite,
 		},
+		Pause: &r.pause,
 	})
 	if err != nil {
 		return err
@@ -641,6 +1018,13 @@ func (r *Runner) setupCopierCheckerAndReplClient(ctx context.Context) error {
 	replConfig.Logger = r.logger
 	replConfig.CancelFunc = r.fatalError
 	replConfig.DBConfig = r.dbConfig
+	replConfig.Pause = &r.pause
+	for _, change := range r.changes {
+		if change.duplicates, err = newDuplicateChecker(ctx, r.db, change.table, change.stmt, r.logger); err != nil {
+			return err
+		}
+	}
+	replConfig.OnRowChange = r.rowChanged
 	if r.migration.EnableExperimentalGTID {
 		r.logger.Info("EXPERIMENTAL: using GTID-based change source")
 		r.replClient = change.NewGTIDClient(r.db, r.migration.Host, r.migration.Username, *r.migration.Password, appl, replConfig)
@@ -666,6 +1050,8 @@ func (r *Runner) setupCopierCheckerAndReplClient(ctx context.Context) error {
 		FixDifferences:  true,
 		MaxRetries:      3,
 		YieldTimeout:    r.migration.ChecksumYieldTimeout,
+		DiffFile:        r.migration.ChecksumDiffFile,
+		DiffFormat:      r.migration.ChecksumDiffFormat,
 	})
 
 	return err
@@ -680,9 +1066,22 @@ func (r *Runner) newMigration(ctx context.Context) error {
 		if err := change.createNewTable(ctx); err != nil {
 			return err
 		}
+		if r.migration.ForeignKeys {
+			if err := change.addForeignKeys(ctx); err != nil {
+				return err
+			}
+		}
 		if err := change.alterNewTable(ctx); err != nil {
 			return err
 		}
+		if err := change.checkColumnExprs(ctx); err != nil {
+			return err
+		}
+		if r.migration.DeferSecondaryIndexes {
+			if err := change.deferSecondaryIndexes(ctx); err != nil {
+				return err
+			}
+		}
 	}
 	if err := r.createCheckpointTable(ctx); err != nil {
 		return err
@@ -717,9 +1116,40 @@ func (r *Runner) newMigration(ctx context.Context) error {
 	if err := r.replClient.Start(ctx); err != nil {
 		return err
 	}
+
+	// Scan for values that the ALTER's column changes would lose, and for
+	// duplicates of any unique keys that it adds, so that they are reported
+	// now rather than by the checksum after the copy.
+	if !r.migration.SkipLossyCheck {
+		for _, change := range r.changes {
+			if err := change.checkLossyConversions(ctx, r.migration.TargetChunkTime); err != nil {
+				return err
+			}
+		}
+	}
+	if !r.migration.SkipDuplicateCheck {
+		for _, change := range r.changes {
+			if change.duplicates == nil {
+				continue
+			}
+			if err := change.duplicates.scan(ctx, r.migration.TargetChunkTime); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
+// rowChanged is called by the change source with the row image of each
+// insert and update, for the duplicate check to watch.
+func (r *Runner) rowChanged(tbl *table.TableInfo, row []any) {
+	for _, change := range r.changes {
+		if change.table == tbl && change.duplicates != nil {
+			change.duplicates.observe(row)
+		}
+	}
+}
+
 // closeReplicas closes all open replica database connections, aggregating
 // errors with errors.Join so a failure on one replica doesn't leak the
 // handles of the rest. Matches the cleanup discipline in Close().
@@ -994,6 +1424,8 @@ func (r *Runner) createCheckpointTable(ctx context.Context) error {
 	// original_table_name records the full untruncated table name (single-table
 	// migrations only) so resume can detect the rare case where two long table
 	// names truncate to the same checkpoint table name. Empty for multi-table.
+	// deferred_indexes records the CREATE TABLE of each new table whose
+	// secondary indexes were deferred (see deferredIndexesCheckpoint).
 	const checkpointTableDDL = `(
 	id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
 	copier_watermark TEXT,
@@ -1001,6 +1433,7 @@ func (r *Runner) createCheckpointTable(ctx context.Context) error {
 	binlog_position TEXT,
 	statement TEXT,
 	original_table_name VARCHAR(64) NOT NULL DEFAULT '',
+	deferred_indexes TEXT,
 	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
 	)`
 	if len(r.changes) > 1 {
@@ -1047,7 +1480,11 @@ func (r *Runner) Progress() status.Progress {
 		)
 	case status.WaitingOnSentinelTable:
 		summary = "Waiting on Sentinel Table"
-	case status.ApplyChangeset, status.PostChecksum:
+	case status.WaitingOnCutoverWindow:
+		summary = "Waiting on Cutover Window"
+	case status.RevertWindow:
+		summary = "Revert Window ends " + r.revertWindowEnds.Format(time.RFC3339)
+	case status.ApplyChangeset, status.RestoreSecondaryIndexes, status.PostChecksum:
 		summary = fmt.Sprintf("Applying Changeset Deltas=%v", r.replClient.GetDeltaLen())
 	case status.Checksum:
 		summary = "Checksum Progress=" + r.checker.GetProgress()
@@ -1085,9 +1522,11 @@ func (r *Runner) Progress() status.Progress {
 		})
 	}
 
+	paused, _ := r.pause.Paused()
 	return status.Progress{
 		CurrentState: r.status.Get(),
 		Summary:      summary,
+		Paused:       paused,
 		Tables:       tables,
 	}
 }
@@ -1132,6 +1571,13 @@ func (r *Runner) Close() error {
 	// individual close calls are independent enough that running them all
 	// out of order does no harm.
 	var errs []error
+	// Stop the HTTP API first so no request is served against a runner
+	// whose connections are being torn down.
+	if r.httpServer != nil {
+		if err := r.httpServer.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
 	for _, change := range r.changes {
 		if err := change.Close(); err != nil {
 			errs = append(errs, err)
@@ -1193,9 +1639,10 @@ func (r *Runner) resumeFromCheckpoint(ctx context.Context) error {
 		queryArgs = append(queryArgs, r.migration.Statement)
 	}
 	var copierWatermark, binlogPosition, statement, checksumWatermark, originalTableName string
+	var deferredIndexes sql.NullString
 	var id int
 	var createdAtStr string
-	err := r.db.QueryRowContext(ctx, query, queryArgs...).Scan(&id, &copierWatermark, &checksumWatermark, &binlogPosition, &statement, &originalTableName, &createdAtStr)
+	err := r.db.QueryRowContext(ctx, query, queryArgs...).Scan(&id, &copierWatermark, &checksumWatermark, &binlogPosition, &statement, &originalTableName, &deferredIndexes, &createdAtStr)
 	if err != nil {
 		// Distinguish "no checkpoint to resume from" — a normal state — from a
 		// real read failure (permission denied, server gone, etc.) so an
@@ -1253,6 +1700,18 @@ func (r *Runner) resumeFromCheckpoint(ctx context.Context) error {
 		if err := change.newTable.SetInfo(ctx); err != nil {
 			return err
 		}
+		if err := change.resolveKey(ctx); err != nil {
+			return err
+		}
+	}
+	if deferredIndexes.String != "" {
+		deferred := make(map[string]string)
+		if err := json.Unmarshal([]byte(deferredIndexes.String), &deferred); err != nil {
+			return fmt.Errorf("could not parse the deferred indexes of the checkpoint: %w", err)
+		}
+		for _, change := range r.changes {
+			change.deferredCreateTable = deferred[change.newTable.TableName]
+		}
 	}
 
 	// Initialize the chunker now that we have the new table info
@@ -1320,14 +1779,16 @@ func (r *Runner) initChunkers() error {
 				"renames", columnRenames,
 			)
 		}
-		columnMapping := table.NewColumnMapping(change.table, change.newTable, columnRenames)
+		columnMapping, err := change.columnMapping()
+		if err != nil {
+			return err
+		}
 		chunkerCfg := table.ChunkerConfig{
 			NewTable:        change.newTable,
 			TargetChunkTime: r.migration.TargetChunkTime,
 			Logger:          r.logger,
 			ColumnMapping:   columnMapping,
 		}
-		var err error
 		change.chunker, err = table.NewChunker(change.table, chunkerCfg)
 		if err != nil {
 			return err
@@ -1364,7 +1825,7 @@ func (r *Runner) checksum(ctx context.Context) error {
 	// applies, and the only thing left is cutover, which itself wants at
 	// least 5 connections. Pool size grows monotonically; see the
 	// MaxOpenConnections doc in (*Runner).Run.
-	r.db.SetMaxOpenConns(r.dbConfig.MaxOpenConnections + 2)
+	r.growPool(r.dbConfig.MaxOpenConnections + 2)
 
 	// Run the checksum with internal retry logic.
 	//
@@ -1488,7 +1949,11 @@ func (r *Runner) DumpCheckpoint(ctx context.Context) error {
 	if len(r.changes) == 1 {
 		originalTableName = r.changes[0].table.TableName
 	}
-	err = dbconn.Exec(ctx, r.db, "INSERT INTO %n.%n (copier_watermark, checksum_watermark, binlog_position, statement, original_table_name) VALUES (%?, %?, %?, %?, %?)",
+	deferredIndexes, err := r.deferredIndexesCheckpoint()
+	if err != nil {
+		return err
+	}
+	err = dbconn.Exec(ctx, r.db, "INSERT INTO %n.%n (copier_watermark, checksum_watermark, binlog_position, statement, original_table_name, deferred_indexes) VALUES (%?, %?, %?, %?, %?, %?)",
 		r.checkpointTable.SchemaName,
 		r.checkpointTable.TableName,
 		copierWatermark,
@@ -1496,6 +1961,7 @@ func (r *Runner) DumpCheckpoint(ctx context.Context) error {
 		binlogPosition,
 		r.migration.Statement,
 		originalTableName,
+		deferredIndexes,
 	)
 	if err != nil {
 		return status.ErrCouldNotWriteCheckpoint
@@ -1503,9 +1969,34 @@ func (r *Runner) DumpCheckpoint(ctx context.Context) error {
 	return nil
 }
 
+// deferredIndexesCheckpoint returns the CREATE TABLE of each new table
+// whose secondary indexes were deferred, by table name, as JSON. It is
+// empty if none were.
+func (r *Runner) deferredIndexesCheckpoint() (string, error) {
+	deferred := make(map[string]string)
+	for _, change := range r.changes {
+		if change.deferredCreateTable != "" {
+			deferred[change.newTable.TableName] = change.deferredCreateTable
+		}
+	}
+	if len(deferred) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(deferred)
+	return string(b), err
+}
+
 func (r *Runner) Status() string {
+	line := r.stateStatus()
+	if paused, since := r.pause.Paused(); paused && line != "" {
+		line += " paused-for=" + time.Since(since).Round(time.Second).String()
+	}
+	return line
+}
+
+func (r *Runner) stateStatus() string {
 	state := r.status.Get()
-	if state > status.CutOver {
+	if state > status.RevertWindow {
 		return ""
 	}
 	switch state { //nolint: exhaustive
@@ -1531,7 +2022,23 @@ func (r *Runner) Status() string {
 			sentinelWaitLimit,
 			r.db.Stats().InUse,
 		)
-	case status.ApplyChangeset, status.PostChecksum:
+	case status.WaitingOnCutoverWindow:
+		return fmt.Sprintf("migration status: state=%s cutover-window=%q cutover-window-opens=%s total-time=%s conns-in-use=%d",
+			r.status.Get().String(),
+			r.cutoverWindow.String(),
+			r.cutoverWindow.NextOpen(time.Now()).Format(time.RFC3339),
+			time.Since(r.startTime).Round(time.Second),
+			r.db.Stats().InUse,
+		)
+	case status.RevertWindow:
+		return fmt.Sprintf("migration status: state=%s old-table=%s revert-window-ends=%s total-time=%s conns-in-use=%d",
+			r.status.Get().String(),
+			r.changes[0].oldTableName(),
+			r.revertWindowEnds.Format(time.RFC3339),
+			time.Since(r.startTime).Round(time.Second),
+			r.db.Stats().InUse,
+		)
+	case status.ApplyChangeset, status.RestoreSecondaryIndexes, status.PostChecksum:
 		// We've finished copying rows, and we are now trying to reduce the number of binlog deltas before
 		// proceeding to the checksum and then the final cutover.
 		return fmt.Sprintf("migration status: state=%s binlog-deltas=%v total-time=%s conns-in-use=%d",
@@ -1564,15 +2071,9 @@ func (r *Runner) sentinelTableExists(ctx context.Context) (bool, error) {
 }
 
 // Check every sentinelCheckInterval up to sentinelWaitLimit to see if sentinelTable has been dropped.
-// While we wait, run a "continuous checksum" loop in the background as a
-// best-effort consistency re-check. The continuous checksum is purely
-// opportunistic — the initial checksum (already run in postCopyPhase) is
-// the correctness gate. The continuous loop is cancelled when the sentinel
-// drops; any in-flight chunk recopy runs under context.WithoutCancel up to
-// fixChunkTimeout so the DELETE + re-insert pair stays atomic, then the
-// goroutine exits. A real "checksum found differences" surfaced from that
-// in-flight repair is promoted into retErr and aborts cutover.
-func (r *Runner) waitOnSentinelTable(ctx context.Context) (retErr error) {
+// While we wait, run a "continuous checksum" loop in the background (see
+// waitWithContinuousChecksum).
+func (r *Runner) waitOnSentinelTable(ctx context.Context) error {
 	if sentinelExists, err := r.sentinelTableExists(ctx); err != nil {
 		return err
 	} else if !sentinelExists {
@@ -1584,9 +2085,57 @@ func (r *Runner) waitOnSentinelTable(ctx context.Context) (retErr error) {
 		"sentinel-table", sentinelTableName,
 		"wait-limit", sentinelWaitLimit.String(),
 	)
+	return r.waitWithContinuousChecksum(ctx, sentinelWaitLimit,
+		errors.New("timed out waiting for sentinel table to be dropped"),
+		func(ctx context.Context) (bool, error) {
+			sentinelExists, err := r.sentinelTableExists(ctx)
+			if err != nil {
+				return false, err
+			}
+			if !sentinelExists {
+				r.logger.Info("sentinel table dropped",
+					"time", time.Now(),
+				)
+			}
+			return !sentinelExists, nil
+		})
+}
+
+// waitOnCutoverWindow waits until the --cutover-window is open, running
+// the continuous checksum in the meantime. There is no limit on how long
+// it waits: the window is at most a week away.
+func (r *Runner) waitOnCutoverWindow(ctx context.Context) error {
+	if r.cutoverWindow == nil || r.cutoverWindow.Contains(time.Now()) {
+		return nil
+	}
+	r.status.Set(status.WaitingOnCutoverWindow)
+	r.logger.Warn("outside the cutover window; will wait",
+		"cutover-window", r.cutoverWindow.String(),
+		"opens", r.cutoverWindow.NextOpen(time.Now()).Format(time.RFC3339),
+	)
+	return r.waitWithContinuousChecksum(ctx, 0, nil, func(_ context.Context) (bool, error) {
+		if !r.cutoverWindow.Contains(time.Now()) {
+			return false, nil
+		}
+		r.logger.Info("cutover window opened", "cutover-window", r.cutoverWindow.String())
+		return true, nil
+	})
+}
 
+// waitWithContinuousChecksum polls ready every sentinelCheckInterval until
+// it returns true, or until limit (if non-zero) has passed, in which case
+// it returns limitErr. While we wait, run a "continuous checksum" loop in
+// the background as a best-effort consistency re-check. The continuous
+// checksum is purely opportunistic — the initial checksum (already run in
+// postCopyPhase) is the correctness gate. The continuous loop is cancelled
+// when the wait ends; any in-flight chunk recopy runs under
+// context.WithoutCancel up to fixChunkTimeout so the DELETE + re-insert
+// pair stays atomic, then the goroutine exits. A real "checksum found
+// differences" surfaced from that in-flight repair is promoted into retErr
+// and aborts cutover.
+func (r *Runner) waitWithContinuousChecksum(ctx context.Context, limit time.Duration, limitErr error, ready func(context.Context) (bool, error)) (retErr error) {
 	// Spawn the continuous checksum. It uses its own checker + chunker and is
-	// not wired into the checkpoint — so a crash during sentinel wait does
+	// not wired into the checkpoint — so a crash during the wait does
 	// not add mandatory checksum time on resume. The checker manages its own
 	// periodic-flush lifecycle per iteration; runContinuousChecksum drives
 	// flushes during the inter-iteration wait so binlog deltas don't pile up
@@ -1599,7 +2148,7 @@ func (r *Runner) waitOnSentinelTable(ctx context.Context) (retErr error) {
 		continuousErr = r.runContinuousChecksum(continuousCtx)
 	}()
 
-	// runContinuousChecksum already filters harmless sentinel cancellations
+	// runContinuousChecksum already filters harmless end-of-wait cancellations
 	// to nil, so any non-nil continuousErr is one it intentionally chose to
 	// propagate — surface it as retErr whenever the parent ctx itself has
 	// not been cancelled (parent cancellation is its own error path).
@@ -1633,32 +2182,33 @@ func (r *Runner) waitOnSentinelTable(ctx context.Context) (retErr error) {
 		}
 	}()
 
-	timer := time.NewTimer(sentinelWaitLimit)
-	defer timer.Stop() // Ensure timer is always stopped to prevent goroutine leak
+	var limitC <-chan time.Time // nil, and never ready, without a limit
+	if limit > 0 {
+		timer := time.NewTimer(limit)
+		defer timer.Stop() // Ensure timer is always stopped to prevent goroutine leak
+		limitC = timer.C
+	}
 
 	ticker := time.NewTicker(sentinelCheckInterval)
 	defer ticker.Stop()
 	for {
 		select {
-		case t := <-ticker.C:
-			sentinelExists, err := r.sentinelTableExists(ctx)
+		case <-ticker.C:
+			isReady, err := ready(ctx)
 			if err != nil {
 				return err
 			}
-			if !sentinelExists {
-				// Sentinel table has been dropped, we can proceed with cutover.
-				// The defer above still observes continuousErr — if a continuous
-				// pass was mid-recopy and surfaces a real drift error, that
+			if isReady {
+				// We can proceed with cutover. The defer above still
+				// observes continuousErr — if a continuous pass was
+				// mid-recopy and surfaces a real drift error, that
 				// overrides this nil return.
-				r.logger.Info("sentinel table dropped",
-					"time", t,
-				)
 				return nil
 			}
-		case <-timer.C:
-			return errors.New("timed out waiting for sentinel table to be dropped")
+		case <-limitC:
+			return limitErr
 		case <-continuousDone:
-			// Continuous goroutine exited before the sentinel was dropped.
+			// Continuous goroutine exited before the wait was over.
 			// If our parent ctx is cancelled, the goroutine just propagated
 			// that cancellation — surface the parent's error directly.
 			if err := ctx.Err(); err != nil {
@@ -1707,7 +2257,7 @@ func (r *Runner) invalidateChecksumWatermark(ctx context.Context) error {
 // runContinuousChecksum loops calling a fresh checker over the source/new
 // tables for as long as ctx is alive. It is the "continuous" half of the
 // two-checksum model (see docs/migrate.md) and is only called while the
-// migration is blocked in WaitingOnSentinelTable.
+// migration is blocked in WaitingOnSentinelTable or WaitingOnCutoverWindow.
 //
 // The checker used here is separate from r.checker and uses a fresh chunker
 // so checkpoint state is unaffected. Single-threaded by design — checksum
@@ -1730,7 +2280,7 @@ func (r *Runner) runContinuousChecksum(ctx context.Context) error {
 			// TODO(#831): once the throttler can size threads dynamically,
 			// replace the hard-coded 1 with the migration's thread count.
 			Concurrency:     1,
-			TargetChunkTime: r.migration.TargetChunkTime,
+			TargetChunkTime: r.Settings().TargetChunkTime,
 			DBConfig:        r.dbConfig,
 			Logger:          r.logger,
 			FixDifferences:  true,
@@ -1752,6 +2302,7 @@ func (r *Runner) runContinuousChecksum(ctx context.Context) error {
 	// the checker increments its counter atomically *before* repairing.
 	r.checkpointMu.Lock()
 	r.continuousChecker = checker
+	r.continuousChunker = chunker
 	r.checkpointMu.Unlock()
 
 	iteration := 0
@@ -1819,11 +2370,13 @@ func (r *Runner) runContinuousChecksum(ctx context.Context) error {
 func (r *Runner) buildContinuousChunker() (table.Chunker, error) {
 	chunkers := make([]table.Chunker, 0, len(r.changes))
 	for _, change := range r.changes {
-		columnRenames := change.stmt.ColumnRenameMap()
-		columnMapping := table.NewColumnMapping(change.table, change.newTable, columnRenames)
+		columnMapping, err := change.columnMapping()
+		if err != nil {
+			return nil, err
+		}
 		c, err := table.NewChunker(change.table, table.ChunkerConfig{
 			NewTable:        change.newTable,
-			TargetChunkTime: r.migration.TargetChunkTime,
+			TargetChunkTime: r.Settings().TargetChunkTime,
 			Logger:          r.logger,
 			ColumnMapping:   columnMapping,
 		})
@@ -1840,3 +2393,251 @@ func (r *Runner) Cancel() {
 		r.cancelFunc()
 	}
 }
+
+// setupComplete reports whether setup has finished assigning the copier,
+// checker and replication client, so they can be read from other
+// goroutines (the HTTP API). The atomic status store that moves past
+// Initial happens after those assignments.
+func (r *Runner) setupComplete() bool {
+	state := r.status.Get()
+	return state >= status.CopyRows && state <= status.CutOver
+}
+
+// ThrottlerStatus implements status.ThrottlerStatusProvider.
+func (r *Runner) ThrottlerStatus() status.ThrottlerStatus {
+	if !r.setupComplete() {
+		return status.ThrottlerStatus{}
+	}
+	t := r.copier.GetThrottler()
+	ts := status.ThrottlerStatus{Throttled: t.IsThrottled()}
+	if gradual, ok := t.(throttler.GradualThrottler); ok {
+		ts.Gradual = true
+		ts.Utilization = gradual.Utilization()
+	}
+	return ts
+}
+
+// ChecksumStatus implements status.ChecksumStatusProvider. It reports the
+// initial checksum; the continuous checksum run during the sentinel wait
+// only contributes to DifferencesFound.
+func (r *Runner) ChecksumStatus() status.ChecksumStatus {
+	if !r.setupComplete() {
+		return status.ChecksumStatus{}
+	}
+	state := r.status.Get()
+	cs := status.ChecksumStatus{
+		Running:          state == status.Checksum,
+		DifferencesFound: r.checker.DifferencesFound(),
+		ExecTime:         r.checker.ExecTime(),
+	}
+	if state >= status.Checksum {
+		cs.Progress = r.checker.GetProgress()
+	}
+	r.checkpointMu.Lock()
+	if r.continuousChecker != nil {
+		cs.DifferencesFound += r.continuousChecker.DifferencesFound()
+	}
+	r.checkpointMu.Unlock()
+	return cs
+}
+
+// SendMetrics implements status.MetricsReporter. It samples the
+// replication reader and, while the checksum runs, its remaining chunks.
+func (r *Runner) SendMetrics(ctx context.Context) {
+	if !r.setupComplete() {
+		return
+	}
+	var values []metrics.MetricValue
+	if sp, ok := r.replClient.(change.StatsProvider); ok {
+		values = append(values, sp.Stats().MetricValues()...)
+	}
+	if r.status.Get() == status.Checksum {
+		values = append(values, metrics.MetricValue{
+			Name:  metrics.ChecksumChunksRemainingMetricName,
+			Type:  metrics.GAUGE,
+			Value: float64(r.checker.ChunksRemaining()),
+		})
+	}
+	sendCtx, cancel := context.WithTimeout(ctx, metrics.SinkTimeout)
+	defer cancel()
+	if err := r.metricsSink.Send(sendCtx, &metrics.Metrics{Values: values}); err != nil {
+		r.logger.Debug("failed to send metrics", "error", err)
+	}
+}
+
+// ReleaseCutover implements status.CutoverReleaser by dropping the
+// sentinel table, exactly as an operator would by hand. The sentinel is
+// shared by every migration in the schema, so this releases them all.
+func (r *Runner) ReleaseCutover(ctx context.Context) error {
+	if !r.setupComplete() {
+		return errors.New("migration has not finished setup yet; nothing to release")
+	}
+	r.logger.Warn("dropping sentinel table to release deferred cutover",
+		"sentinel-table", sentinelTableName,
+	)
+	return dbconn.Exec(ctx, r.db, "DROP TABLE IF EXISTS %n.%n", r.changes[0].table.SchemaName, sentinelTableName)
+}
+
+// growPool raises the connection limit of r.db to n, unless it is already
+// at least n. The pool only ever grows; see the MaxOpenConnections doc in
+// (*Runner).Run.
+func (r *Runner) growPool(n int) {
+	r.poolMu.Lock()
+	defer r.poolMu.Unlock()
+	if n > r.poolSize {
+		r.poolSize = n
+		r.db.SetMaxOpenConns(n)
+	}
+}
+
+// Settings implements status.Reconfigurable.
+func (r *Runner) Settings() status.Settings {
+	r.settingsMu.Lock()
+	defer r.settingsMu.Unlock()
+	return status.Settings{
+		Threads:         r.migration.Threads,
+		WriteThreads:    r.migration.WriteThreads,
+		TargetChunkTime: r.migration.TargetChunkTime,
+		ReplicaMaxLag:   r.migration.ReplicaMaxLag,
+	}
+}
+
+// Reconfigure implements status.Reconfigurable. It is only possible from
+// the start of the copy until cutover begins: before that the copier and
+// chunkers don't exist yet, and setup reads the settings without a lock.
+//
+// Threads changes the number of copy threads immediately. The initial
+// checksum opens its transaction pool when it starts, so it uses the
+// value in effect at that point. WriteThreads changes the applier's write
+// workers, which only write during the copy; it is refused when the
+// autoscaler is managing them. TargetChunkTime applies to the copy, the
+// checksum and the continuous checksum, and ReplicaMaxLag to every
+// replica throttler. The connection pool grows to fit new thread counts
+// but does not shrink.
+func (r *Runner) Reconfigure(_ context.Context, settings status.Settings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	if err := check.ValidateSettings(settings.Threads, settings.TargetChunkTime, settings.ReplicaMaxLag); err != nil {
+		return err
+	}
+	r.settingsMu.Lock()
+	defer r.settingsMu.Unlock()
+	if state := r.status.Get(); state < status.CopyRows || state >= status.CutOver {
+		return fmt.Errorf("settings can only be changed from the start of the copy until cutover, the migration is in state %s", state)
+	}
+	// The write threads change is the only one that can fail, so it goes
+	// first and nothing has been applied if it does.
+	if settings.WriteThreads != r.migration.WriteThreads {
+		if err := r.copier.SetWriteThreads(settings.WriteThreads); err != nil {
+			return err
+		}
+	}
+	r.growPool(settings.Threads + settings.WriteThreads + r.controlPlaneConns())
+	if settings.Threads != r.migration.Threads {
+		r.copier.SetConcurrency(settings.Threads)
+	}
+	if settings.TargetChunkTime != r.migration.TargetChunkTime {
+		r.chunkerMu.RLock()
+		chunkers := []table.Chunker{r.copyChunker, r.checksumChunker}
+		r.chunkerMu.RUnlock()
+		r.checkpointMu.Lock()
+		chunkers = append(chunkers, r.continuousChunker)
+		r.checkpointMu.Unlock()
+		for _, c := range chunkers {
+			if setter, ok := c.(table.TargetChunkTimeSetter); ok {
+				setter.SetTargetChunkTime(settings.TargetChunkTime)
+			}
+		}
+	}
+	if settings.ReplicaMaxLag != r.migration.ReplicaMaxLag {
+		if setter, ok := r.copier.GetThrottler().(throttler.LagToleranceSetter); ok {
+			setter.SetLagTolerance(settings.ReplicaMaxLag)
+		}
+	}
+	r.logger.Info("settings changed",
+		"threads", settings.Threads,
+		"write-threads", settings.WriteThreads,
+		"target-chunk-time", settings.TargetChunkTime,
+		"replica-max-lag", settings.ReplicaMaxLag,
+	)
+	r.migration.Threads = settings.Threads
+	r.migration.WriteThreads = settings.WriteThreads
+	r.migration.TargetChunkTime = settings.TargetChunkTime
+	r.migration.ReplicaMaxLag = settings.ReplicaMaxLag
+	return nil
+}
+
+// Pause implements status.Pausable. The copier stops starting new chunks
+// and buffered changes are no longer flushed, while the replication
+// client keeps reading the binary log into its subscriptions. A checksum
+// that is already running continues, but the migration does not move on
+// to the next phase until it is resumed. Cutover can't be paused.
+func (r *Runner) Pause(_ context.Context) error {
+	r.pauseMu.Lock()
+	defer r.pauseMu.Unlock()
+	if state := r.status.Get(); state >= status.CutOver || r.cuttingOver {
+		return fmt.Errorf("the migration can't be paused in state %s: it is cutting over", state)
+	}
+	if r.pause.Pause() {
+		r.logger.Warn("migration paused; copying and flushing changes will stop until it is resumed",
+			"state", r.status.Get().String())
+	}
+	return nil
+}
+
+// Resume implements status.Pausable.
+func (r *Runner) Resume(_ context.Context) error {
+	_, since := r.pause.Paused()
+	if r.pause.Resume() {
+		r.logger.Warn("migration resumed",
+			"state", r.status.Get().String(),
+			"paused-duration", time.Since(since).Round(time.Second).String())
+	}
+	return nil
+}
+
+// waitWhilePaused blocks while the migration is paused, logging once if
+// it has to wait.
+func (r *Runner) waitWhilePaused(ctx context.Context) error {
+	if paused, _ := r.pause.Paused(); paused {
+		r.logger.Info("waiting for the migration to be resumed", "state", r.status.Get().String())
+	}
+	return r.pause.Wait(ctx)
+}
+
+// startCutover waits until the migration is not paused, and then refuses
+// pauses until stopCutover, under pauseMu so that Pause can't succeed in
+// between.
+func (r *Runner) startCutover(ctx context.Context) error {
+	for {
+		if err := r.waitWhilePaused(ctx); err != nil {
+			return err
+		}
+		r.pauseMu.Lock()
+		if paused, _ := r.pause.Paused(); !paused {
+			r.cuttingOver = true
+			r.pauseMu.Unlock()
+			return nil
+		}
+		r.pauseMu.Unlock() // paused again before we got the lock
+	}
+}
+
+// stopCutover allows pauses again after the cutover window closed before
+// an attempt locked the tables.
+func (r *Runner) stopCutover() {
+	r.pauseMu.Lock()
+	defer r.pauseMu.Unlock()
+	r.cuttingOver = false
+}
+
+// enterCutOver sets the state to CutOver once a cutover attempt holds the
+// table locks. The state never moves back from CutOver, and the periodic
+// checkpoint stops there, so it is under checkpointMu: no checkpoint is
+// written after the state is set.
+func (r *Runner) enterCutOver() {
+	r.checkpointMu.Lock()
+	defer r.checkpointMu.Unlock()
+	r.status.Set(status.CutOver)
+}
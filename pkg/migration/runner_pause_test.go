@@ -0,0 +1,45 @@
+package migration
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/block/spirit/pkg/status"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPauseHoldsCutover(t *testing.T) {
+	r := &Runner{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	r.status.Set(status.WaitingOnSentinelTable)
+	require.NoError(t, r.Pause(t.Context()))
+	require.NoError(t, r.Pause(t.Context())) // idempotent
+	paused, _ := r.pause.Paused()
+	require.True(t, paused)
+
+	started := make(chan error, 1)
+	go func() { started <- r.startCutover(t.Context()) }()
+	time.Sleep(50 * time.Millisecond)
+	require.False(t, r.cuttingOver)
+
+	require.NoError(t, r.Resume(t.Context()))
+	require.NoError(t, <-started)
+	// The state only becomes CutOver once an attempt locks the tables,
+	// but the cutover can't be paused from its start.
+	require.Equal(t, status.WaitingOnSentinelTable, r.status.Get())
+	require.Error(t, r.Pause(t.Context()))
+	paused, _ = r.pause.Paused()
+	require.False(t, paused)
+
+	// The window closed before an attempt locked the tables, so the
+	// migration can be paused again while it waits for the next one.
+	r.stopCutover()
+	require.NoError(t, r.Pause(t.Context()))
+	require.NoError(t, r.Resume(t.Context()))
+
+	require.NoError(t, r.startCutover(t.Context()))
+	r.enterCutOver()
+	require.Equal(t, status.CutOver, r.status.Get())
+	require.Error(t, r.Pause(t.Context()))
+}
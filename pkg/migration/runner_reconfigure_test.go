@@ -0,0 +1,132 @@
+package migration
+
+import (
+	"database/sql"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/block/spirit/pkg/applier"
+	"github.com/block/spirit/pkg/copier"
+	"github.com/block/spirit/pkg/dbconn"
+	"github.com/block/spirit/pkg/status"
+	"github.com/block/spirit/pkg/table"
+	"github.com/block/spirit/pkg/throttler"
+	"github.com/block/spirit/pkg/utils"
+	"github.com/stretchr/testify/require"
+)
+
+// targetRecordingChunker records the target chunk time it is given.
+type targetRecordingChunker struct {
+	table.Chunker
+	target time.Duration
+}
+
+func (c *targetRecordingChunker) SetTargetChunkTime(target time.Duration) {
+	c.target = target
+}
+
+// lagRecordingThrottler records the lag tolerance it is given.
+type lagRecordingThrottler struct {
+	throttler.Noop
+	tolerance time.Duration
+}
+
+func (l *lagRecordingThrottler) SetLagTolerance(lagTolerance time.Duration) {
+	l.tolerance = lagTolerance
+}
+
+// newReconfigureTestRunner returns a runner that has "finished setup" as
+// far as Reconfigure is concerned. It needs no server: the *sql.DB never
+// connects.
+func newReconfigureTestRunner(t *testing.T, autoscale bool) (*Runner, *targetRecordingChunker, *lagRecordingThrottler) {
+	t.Helper()
+	db, err := sql.Open("mysql", "spirit@tcp(127.0.0.1:1)/test")
+	require.NoError(t, err)
+	t.Cleanup(func() { utils.CloseAndLog(db) })
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	appl, err := applier.NewSingleTargetApplier(applier.Target{DB: db}, &applier.ApplierConfig{
+		Logger:   logger,
+		DBConfig: dbconn.NewDBConfig(),
+		Threads:  4,
+	})
+	require.NoError(t, err)
+	chunker := &targetRecordingChunker{Chunker: table.NewMockChunker("t1", 100)}
+	thr := &lagRecordingThrottler{}
+	c, err := copier.NewCopier(db, chunker, &copier.CopierConfig{
+		Concurrency: 4,
+		Throttler:   thr,
+		Logger:      logger,
+		DBConfig:    dbconn.NewDBConfig(),
+		Applier:     appl,
+		Autoscale:   copier.AutoscaleConfig{Enabled: autoscale, StartThreads: 4, MaxThreads: 8},
+	})
+	require.NoError(t, err)
+
+	r := &Runner{
+		migration: &Migration{
+			Threads:         4,
+			WriteThreads:    4,
+			TargetChunkTime: 500 * time.Millisecond,
+			ReplicaMaxLag:   2 * time.Minute,
+		},
+		changes:     make([]*tableChange, 1),
+		db:          db,
+		poolSize:    11,
+		copier:      c,
+		copyChunker: chunker,
+		logger:      logger,
+	}
+	db.SetMaxOpenConns(r.poolSize)
+	r.status.Set(status.CopyRows)
+	return r, chunker, thr
+}
+
+func TestReconfigure(t *testing.T) {
+	r, chunker, thr := newReconfigureTestRunner(t, false)
+	updated := status.Settings{Threads: 8, WriteThreads: 6, TargetChunkTime: 200 * time.Millisecond, ReplicaMaxLag: 30 * time.Second}
+	require.NoError(t, r.Reconfigure(t.Context(), updated))
+	require.Equal(t, updated, r.Settings())
+	require.Equal(t, 200*time.Millisecond, chunker.target)
+	require.Equal(t, 30*time.Second, thr.tolerance)
+	// threads + write-threads + controlPlaneConns()
+	require.Equal(t, 17, r.db.Stats().MaxOpenConnections)
+
+	// Fewer threads don't shrink the pool.
+	updated.Threads = 1
+	require.NoError(t, r.Reconfigure(t.Context(), updated))
+	require.Equal(t, 17, r.db.Stats().MaxOpenConnections)
+
+	// Values outside the range allowed at startup are refused.
+	outOfRange := updated
+	outOfRange.TargetChunkTime = 10 * time.Second
+	require.ErrorContains(t, r.Reconfigure(t.Context(), outOfRange), "--target-chunk-time")
+	require.Equal(t, updated, r.Settings())
+
+	// Only from the start of the copy until cutover.
+	r.status.Set(status.CutOver)
+	require.Error(t, r.Reconfigure(t.Context(), updated))
+	r.status.Set(status.Initial)
+	require.Error(t, r.Reconfigure(t.Context(), updated))
+}
+
+func TestReconfigureWriteThreadsWithAutoscaling(t *testing.T) {
+	r, chunker, _ := newReconfigureTestRunner(t, true)
+	original := r.Settings()
+
+	// The autoscaler owns the write threads, and nothing else is applied
+	// when the change is refused.
+	updated := original
+	updated.WriteThreads = 2
+	updated.TargetChunkTime = time.Second
+	require.ErrorContains(t, r.Reconfigure(t.Context(), updated), "autoscaler")
+	require.Equal(t, original, r.Settings())
+	require.Zero(t, chunker.target)
+
+	// Other settings can still be changed.
+	updated.WriteThreads = original.WriteThreads
+	require.NoError(t, r.Reconfigure(t.Context(), updated))
+	require.Equal(t, time.Second, chunker.target)
+}
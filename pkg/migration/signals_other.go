@@ -0,0 +1,9 @@
+//go:build !unix
+
+package migration
+
+// handlePauseSignals does nothing, since there are no SIGUSR1 and SIGUSR2
+// to pause and resume the runner with.
+func handlePauseSignals(*Runner) (stop func()) {
+	return func() {}
+}
@@ -0,0 +1,40 @@
+//go:build unix
+
+package migration
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// handlePauseSignals pauses the runner on SIGUSR1 and resumes it on
+// SIGUSR2, until the returned function is called.
+func handlePauseSignals(r *Runner) (stop func()) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGUSR1, syscall.SIGUSR2)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case sig := <-sigCh:
+				var err error
+				if sig == syscall.SIGUSR1 {
+					err = r.Pause(context.Background())
+				} else {
+					err = r.Resume(context.Background())
+				}
+				if err != nil {
+					r.logger.Error("could not handle signal", "signal", sig.String(), "error", err)
+				}
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(sigCh)
+		close(done)
+	}
+}
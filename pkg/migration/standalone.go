@@ -0,0 +1,90 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/block/spirit/pkg/dbconn"
+	"github.com/block/spirit/pkg/throttler"
+	"github.com/block/spirit/pkg/utils"
+	"github.com/go-sql-driver/mysql"
+)
+
+// Connect fills in the connection options of m that aren't set from its
+// conf file and the defaults, and connects to the server with dbConfig. It
+// is for the commands that run outside of a migration but take the same
+// connection options, such as spirit drop and spirit dml. It returns the
+// connection and its DSN.
+func (m *Migration) Connect(dbConfig *dbconn.DBConfig) (*sql.DB, string, error) {
+	if err := m.normalizeConnectionOptions(); err != nil {
+		return nil, "", err
+	}
+	dbConfig.TLSMode = m.TLSMode
+	dbConfig.TLSCertificatePath = m.TLSCertificatePath
+	cfg := mysql.NewConfig()
+	cfg.User = m.Username
+	cfg.Passwd = *m.Password
+	cfg.Net = "tcp"
+	cfg.Addr = m.Host
+	cfg.DBName = m.Database
+	dsn := cfg.FormatDSN()
+	db, err := dbconn.New(dsn, dbConfig)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to connect to main database (DSN: %s): %w", maskPasswordInDSN(dsn), err)
+	}
+	return db, dsn, nil
+}
+
+// NewStandaloneThrottler returns the throttler of a command that runs
+// outside of a migration: the replica throttlers of m.ReplicaDSN, and the
+// Aurora throttlers if db is Aurora. db and dbConfig are those returned
+// by Connect. The returned close function closes the throttler and its
+// connections.
+func (m *Migration) NewStandaloneThrottler(ctx context.Context, db *sql.DB, dsn string, dbConfig *dbconn.DBConfig, maxCommitLatency time.Duration, logger *slog.Logger) (throttler.Throttler, func(), error) {
+	// The runner is only used for its replica connection handling.
+	r := &Runner{migration: m, dbConfig: dbConfig, logger: logger}
+	var throttlers []throttler.Throttler
+	if m.ReplicaDSN != "" {
+		replicaThrottlers, err := r.buildReplicaThrottlers()
+		if err != nil {
+			return nil, nil, err
+		}
+		throttlers = replicaThrottlers
+	}
+	auroraRes, err := throttler.AuroraSetup{
+		Source: db,
+		OpenMonitor: func() (*sql.DB, error) {
+			monitorCfg := *dbConfig
+			monitorCfg.MaxOpenConnections = 2
+			return dbconn.NewWithConnectionType(dsn, &monitorCfg, "monitor database")
+		},
+		CommitLatencyThreshold: maxCommitLatency,
+		Logger:                 logger,
+	}.Build(ctx)
+	if err != nil {
+		_ = r.closeReplicas()
+		return nil, nil, err
+	}
+	closeConns := func() {
+		if auroraRes.MonitorDB != nil {
+			utils.CloseAndLog(auroraRes.MonitorDB)
+		}
+		_ = r.closeReplicas()
+	}
+	throttlers = append(throttlers, auroraRes.Throttlers...)
+	if len(throttlers) == 0 {
+		return &throttler.Noop{}, closeConns, nil
+	}
+	thr := throttler.NewMultiThrottler(throttlers...)
+	if err := thr.Open(ctx); err != nil {
+		closeConns()
+		return nil, nil, fmt.Errorf("opening throttlers: %w", err)
+	}
+	return thr, func() {
+		utils.CloseAndLog(thr)
+		closeConns()
+	}, nil
+}
@@ -0,0 +1,178 @@
+package migration
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/block/spirit/pkg/dbconn"
+	"github.com/block/spirit/pkg/table"
+	"github.com/block/spirit/pkg/utils"
+)
+
+// statsOptions are the table options of InnoDB persistent statistics, as
+// named in the CREATE_OPTIONS of information_schema.tables.
+var statsOptions = []string{"stats_persistent", "stats_sample_pages", "stats_auto_recalc"}
+
+// carryOverStatsOptions sets the persistent statistics options of the
+// table on the new table, unless the ALTER sets them. It runs before the
+// new table is analyzed, so that its statistics are sampled the same way.
+func (c *tableChange) carryOverStatsOptions(ctx context.Context) error {
+	oldOptions, err := createOptions(ctx, c.runner.db, c.table)
+	if err != nil {
+		return err
+	}
+	newOptions, err := createOptions(ctx, c.runner.db, c.newTable)
+	if err != nil {
+		return err
+	}
+	set := c.stmt.SetsStatsOptions()
+	var clauses []string
+	for _, option := range statsOptions {
+		value, ok := oldOptions[option]
+		if !ok || set[option] || newOptions[option] == value {
+			continue
+		}
+		clauses = append(clauses, fmt.Sprintf("%s=%s", strings.ToUpper(option), value))
+	}
+	if len(clauses) == 0 {
+		return nil
+	}
+	c.runner.logger.Info("carrying over the statistics options of the table",
+		"table", c.newTable.TableName,
+		"options", strings.Join(clauses, " "),
+	)
+	return dbconn.Exec(ctx, c.runner.db, "ALTER TABLE %n.%n "+strings.Join(clauses, ", "),
+		c.newTable.SchemaName, c.newTable.TableName)
+}
+
+// createOptions returns the CREATE_OPTIONS of tbl, e.g. stats_persistent=0,
+// by their name.
+func createOptions(ctx context.Context, db *sql.DB, tbl *table.TableInfo) (map[string]string, error) {
+	var createOptions sql.NullString
+	if err := db.QueryRowContext(ctx, "SELECT CREATE_OPTIONS FROM information_schema.tables WHERE table_schema=? AND table_name=?",
+		tbl.SchemaName, tbl.TableName).Scan(&createOptions); err != nil {
+		return nil, err
+	}
+	options := make(map[string]string)
+	for option := range strings.FieldsSeq(createOptions.String) {
+		if name, value, ok := strings.Cut(option, "="); ok {
+			options[strings.ToLower(name)] = value
+		}
+	}
+	return options, nil
+}
+
+// histogram is a column histogram of a table, from
+// information_schema.COLUMN_STATISTICS.
+type histogram struct {
+	column     string
+	buckets    int
+	autoUpdate bool
+}
+
+// carryOverHistograms creates the histograms of the table's columns on
+// the new table, with the same number of buckets, so that the query plans
+// that use them don't change after the cutover. Columns that the ALTER
+// drops are skipped. A histogram that can't be created on the new table,
+// e.g. because its column is now unique, is logged rather than failing
+// the migration.
+func (c *tableChange) carryOverHistograms(ctx context.Context) error {
+	histograms, err := c.histograms(ctx)
+	if err != nil || len(histograms) == 0 {
+		return err
+	}
+	// ANALYZE TABLE takes one number of buckets for all of its columns.
+	type analyzeOptions struct {
+		buckets    int
+		autoUpdate bool
+	}
+	groups := make(map[analyzeOptions][]string)
+	for _, h := range histograms {
+		options := analyzeOptions{h.buckets, h.autoUpdate}
+		groups[options] = append(groups[options], h.column)
+	}
+	for _, options := range slices.SortedFunc(maps.Keys(groups), func(a, b analyzeOptions) int {
+		return cmp.Or(cmp.Compare(a.buckets, b.buckets), cmp.Compare(fmt.Sprint(a.autoUpdate), fmt.Sprint(b.autoUpdate)))
+	}) {
+		columns := groups[options]
+		query := fmt.Sprintf("ANALYZE TABLE `%s`.%s UPDATE HISTOGRAM ON %s WITH %d BUCKETS",
+			c.newTable.SchemaName, c.newTable.QuotedTableName, table.QuoteColumns(columns), options.buckets)
+		if options.autoUpdate {
+			query += " AUTO UPDATE"
+		}
+		c.runner.logger.Info("carrying over column histograms",
+			"table", c.newTable.TableName,
+			"columns", columns,
+			"buckets", options.buckets,
+		)
+		if err := c.analyzeHistograms(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// histograms returns the histograms of the table's columns, with the
+// names that the columns have in the new table.
+func (c *tableChange) histograms(ctx context.Context) ([]histogram, error) {
+	rows, err := c.runner.db.QueryContext(ctx, `SELECT COLUMN_NAME,
+		IFNULL(JSON_EXTRACT(HISTOGRAM, '$."number-of-buckets-specified"'), 0),
+		IFNULL(JSON_UNQUOTE(JSON_EXTRACT(HISTOGRAM, '$."auto-update"')), 'false') = 'true'
+		FROM information_schema.COLUMN_STATISTICS
+		WHERE SCHEMA_NAME=? AND TABLE_NAME=?`, c.table.SchemaName, c.table.TableName)
+	if err != nil {
+		return nil, err
+	}
+	defer utils.CloseAndLog(rows)
+	renames := make(map[string]string)
+	for oldName, newName := range c.stmt.ColumnRenameMap() {
+		renames[strings.ToLower(oldName)] = newName
+	}
+	var histograms []histogram
+	for rows.Next() {
+		var h histogram
+		if err := rows.Scan(&h.column, &h.buckets, &h.autoUpdate); err != nil {
+			return nil, err
+		}
+		name := cmp.Or(renames[strings.ToLower(h.column)], h.column)
+		i := slices.IndexFunc(c.newTable.Columns, func(col string) bool { return strings.EqualFold(col, name) })
+		if i < 0 {
+			continue // the ALTER drops the column
+		}
+		if h.buckets <= 0 {
+			continue
+		}
+		h.column = c.newTable.Columns[i]
+		histograms = append(histograms, h)
+	}
+	return histograms, rows.Err()
+}
+
+// analyzeHistograms runs an ANALYZE TABLE ... UPDATE HISTOGRAM, which
+// reports a histogram that it can't create as a row of its result rather
+// than as an error.
+func (c *tableChange) analyzeHistograms(ctx context.Context, query string) error {
+	rows, err := c.runner.db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer utils.CloseAndLog(rows)
+	for rows.Next() {
+		var tbl, op, msgType, msgText string
+		if err := rows.Scan(&tbl, &op, &msgType, &msgText); err != nil {
+			return err
+		}
+		if !strings.EqualFold(msgType, "status") {
+			c.runner.logger.Warn("could not carry over a column histogram",
+				"table", c.newTable.TableName,
+				"message", msgText,
+			)
+		}
+	}
+	return rows.Err()
+}
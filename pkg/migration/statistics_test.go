@@ -0,0 +1,48 @@
+package migration
+
+import (
+	"testing"
+
+	"github.com/block/spirit/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateCarriesOverStatistics(t *testing.T) {
+	t.Parallel()
+	tt := testutils.NewTestTable(t, "statst1", `CREATE TABLE statst1 (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		a int NOT NULL,
+		b int NOT NULL,
+		c int NOT NULL
+	) STATS_PERSISTENT=1 STATS_SAMPLE_PAGES=50`)
+	testutils.RunSQL(t, `INSERT INTO statst1 (a, b, c) VALUES (1, 1, 1), (2, 2, 2), (3, 3, 3)`)
+	testutils.RunSQL(t, `ANALYZE TABLE statst1 UPDATE HISTOGRAM ON a, b WITH 16 BUCKETS`)
+	testutils.RunSQL(t, `ANALYZE TABLE statst1 UPDATE HISTOGRAM ON c WITH 32 BUCKETS`)
+
+	// b is renamed, and c is dropped.
+	m := NewTestRunner(t, "statst1", "RENAME COLUMN b TO d, DROP COLUMN c, STATS_PERSISTENT=0")
+	require.NoError(t, m.Run(t.Context()))
+	require.NoError(t, m.Close())
+
+	rows, err := tt.DB.QueryContext(t.Context(), `SELECT COLUMN_NAME, JSON_EXTRACT(HISTOGRAM, '$."number-of-buckets-specified"')
+		FROM information_schema.COLUMN_STATISTICS WHERE SCHEMA_NAME=DATABASE() AND TABLE_NAME='statst1' ORDER BY COLUMN_NAME`)
+	require.NoError(t, err)
+	histograms := make(map[string]int)
+	for rows.Next() {
+		var column string
+		var buckets int
+		require.NoError(t, rows.Scan(&column, &buckets))
+		histograms[column] = buckets
+	}
+	require.NoError(t, rows.Err())
+	require.NoError(t, rows.Close())
+	require.Equal(t, map[string]int{"a": 16, "d": 16}, histograms)
+
+	// STATS_SAMPLE_PAGES is carried over, but not STATS_PERSISTENT, which
+	// the ALTER sets.
+	var createOptions string
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), `SELECT CREATE_OPTIONS FROM information_schema.tables
+		WHERE table_schema=DATABASE() AND table_name='statst1'`).Scan(&createOptions))
+	require.Contains(t, createOptions, "stats_sample_pages=50")
+	require.Contains(t, createOptions, "stats_persistent=0")
+}
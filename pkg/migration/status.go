@@ -0,0 +1,423 @@
+package migration
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/block/spirit/pkg/dbconn"
+	"github.com/block/spirit/pkg/table"
+	"github.com/block/spirit/pkg/utils"
+)
+
+// Status is the kong CLI entry point of `spirit status`. It lists the
+// tables that spirit operations leave in a server's schemas while they run
+// (checkpoints, _new and _old tables, sentinels), so that an operation
+// that someone else started can be inspected without guessing.
+type Status struct {
+	DSN string `name:"dsn" help:"The server to inspect, e.g. spirit:spirit@tcp(127.0.0.1:3306)/" required:""`
+}
+
+// artifactKind is the kind of a table that spirit creates.
+type artifactKind string
+
+const (
+	artifactCheckpoint        artifactKind = "checkpoint"
+	artifactDMLCheckpoint     artifactKind = "dml checkpoint"
+	artifactArchiveCheckpoint artifactKind = "archive checkpoint"
+	artifactNew               artifactKind = "new table"
+	artifactOld               artifactKind = "old table"
+	artifactRevert            artifactKind = "revert state"
+	artifactReverted          artifactKind = "reverted table"
+	artifactSentinel          artifactKind = "sentinel"
+)
+
+// artifactPatterns are the names of the tables that belong to a single
+// table, in the order they are tried: a DML checkpoint also ends in
+// _chkpnt, so the more specific suffixes come first. The submatch is the
+// name of the table, which is truncated if it is long (see
+// utils.AuxTableName).
+var artifactPatterns = []struct {
+	kind    artifactKind
+	pattern *regexp.Regexp
+}{
+	{artifactDMLCheckpoint, regexp.MustCompile(`^_(.+)_dml_chkpnt$`)},
+	{artifactArchiveCheckpoint, regexp.MustCompile(`^_(.+)_archive_chkpnt$`)},
+	{artifactCheckpoint, regexp.MustCompile(`^_(.+)_chkpnt$`)},
+	{artifactNew, regexp.MustCompile(`^_(.+)_new$`)},
+	{artifactOld, regexp.MustCompile(`^_(.+)_old(?:_\d{8}_\d{6})?$`)},
+	{artifactRevert, regexp.MustCompile(`^_(.+)_revert$`)},
+	{artifactReverted, regexp.MustCompile(`^_(.+)_reverted_\d{8}_\d{6}$`)},
+}
+
+// classifyArtifact returns the kind of the table name, and the table that
+// it belongs to, which is "" for the tables that are shared by the
+// operations in a schema. ok is false if spirit doesn't create tables of
+// this name.
+func classifyArtifact(name string) (kind artifactKind, tableName string, ok bool) {
+	switch name {
+	case checkpointTableName:
+		return artifactCheckpoint, "", true
+	case sentinelTableName:
+		return artifactSentinel, "", true
+	}
+	for _, p := range artifactPatterns {
+		if m := p.pattern.FindStringSubmatch(name); m != nil {
+			return p.kind, m[1], true
+		}
+	}
+	return "", "", false
+}
+
+// artifact is a table that spirit created.
+type artifact struct {
+	name      string
+	kind      artifactKind
+	createdAt string
+	// checkpoints are the latest rows of each statement or job of a
+	// checkpoint table, by column name.
+	checkpoints []map[string]string
+}
+
+// isChunkedCheckpoint returns whether a is the checkpoint of spirit dml or
+// spirit archive, which don't hold the table's metadata lock.
+func (a *artifact) isChunkedCheckpoint() bool {
+	return a.kind == artifactDMLCheckpoint || a.kind == artifactArchiveCheckpoint
+}
+
+// tableStatus is the artifacts that belong to a table.
+type tableStatus struct {
+	name string
+	// lockHolder is the connection that holds the table's metadata lock,
+	// i.e. that runs a migration, move or revert of it, or 0.
+	lockHolder int64
+	artifacts  []*artifact
+}
+
+// schemaStatus is the artifacts in a schema.
+type schemaStatus struct {
+	name   string
+	tables []*tableStatus
+	shared []*artifact
+}
+
+// Run prints the artifacts in every schema of the server.
+func (s *Status) Run() error {
+	ctx := context.TODO()
+	db, err := dbconn.New(s.DSN, dbconn.NewDBConfig())
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer utils.CloseAndLog(db)
+	schemas, err := inspectServer(ctx, db)
+	if err != nil {
+		return err
+	}
+	writeServerStatus(os.Stdout, schemas, time.Now())
+	return nil
+}
+
+// inspectServer finds the artifacts in every schema of the server, reads
+// their checkpoints, and checks whether the metadata lock of each table
+// that they belong to is held.
+func inspectServer(ctx context.Context, db *sql.DB) ([]*schemaStatus, error) {
+	// All tables are listed, not only the artifacts, so that the artifacts
+	// whose names are truncated can be matched to their table.
+	rows, err := db.QueryContext(ctx, `SELECT table_schema, table_name, IFNULL(create_time, '')
+		FROM information_schema.tables
+		WHERE table_schema NOT IN ('mysql', 'information_schema', 'performance_schema', 'sys')
+		ORDER BY table_schema, table_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer utils.CloseAndLog(rows)
+	var schemaNames []string
+	tables := map[string][]schemaTable{}
+	for rows.Next() {
+		var schemaName string
+		var t schemaTable
+		if err := rows.Scan(&schemaName, &t.name, &t.createdAt); err != nil {
+			return nil, err
+		}
+		if _, ok := tables[schemaName]; !ok {
+			schemaNames = append(schemaNames, schemaName)
+		}
+		tables[schemaName] = append(tables[schemaName], t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	var schemas []*schemaStatus
+	for _, schemaName := range schemaNames {
+		if schema := newSchemaStatus(schemaName, tables[schemaName]); schema != nil {
+			schemas = append(schemas, schema)
+		}
+	}
+	for _, schema := range schemas {
+		for _, a := range schema.shared {
+			if a.kind == artifactCheckpoint {
+				if a.checkpoints, err = readCheckpoints(ctx, db, schema.name, a.name); err != nil {
+					return nil, err
+				}
+			}
+		}
+		for _, t := range schema.tables {
+			if t.lockHolder, err = dbconn.MetadataLockHolder(ctx, db, table.NewTableInfo(db, schema.name, t.name)); err != nil {
+				return nil, err
+			}
+			for _, a := range t.artifacts {
+				switch a.kind { //nolint: exhaustive
+				case artifactCheckpoint, artifactDMLCheckpoint, artifactArchiveCheckpoint:
+					if a.checkpoints, err = readCheckpoints(ctx, db, schema.name, a.name); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+	return schemas, nil
+}
+
+// schemaTable is a table in a schema.
+type schemaTable struct {
+	name, createdAt string
+}
+
+// newSchemaStatus groups the artifacts among the tables of a schema by the
+// table that they belong to, or returns nil if there are none.
+//
+// The name of a table is truncated in the names of its artifacts if it is
+// long, and by how much depends on the suffix: it keeps 56 characters in
+// _chkpnt, but 43 in _old_<timestamp>. An artifact whose name is as long
+// as a table name can be is matched to the only other table in the schema
+// whose name starts with the truncated one, so that all the artifacts of
+// the table are grouped under its full name, from which the name of its
+// metadata lock is computed. If there is no such table, e.g. because it
+// was dropped, the truncated name is kept.
+func newSchemaStatus(name string, tables []schemaTable) *schemaStatus {
+	schema := &schemaStatus{name: name}
+	for _, t := range tables {
+		kind, tableName, ok := classifyArtifact(t.name)
+		if !ok {
+			continue
+		}
+		a := &artifact{name: t.name, kind: kind, createdAt: t.createdAt}
+		if tableName == "" {
+			schema.shared = append(schema.shared, a)
+			continue
+		}
+		if len(t.name) == utils.MaxTableNameLength {
+			tableName = resolveTableName(tableName, tables)
+		}
+		i := slices.IndexFunc(schema.tables, func(t *tableStatus) bool { return t.name == tableName })
+		if i < 0 {
+			schema.tables = append(schema.tables, &tableStatus{name: tableName})
+			i = len(schema.tables) - 1
+		}
+		schema.tables[i].artifacts = append(schema.tables[i].artifacts, a)
+	}
+	if len(schema.tables) == 0 && len(schema.shared) == 0 {
+		return nil
+	}
+	return schema
+}
+
+// resolveTableName returns the name of the only table that starts with the
+// truncated name prefix and isn't an artifact, or prefix if there isn't
+// exactly one.
+func resolveTableName(prefix string, tables []schemaTable) string {
+	var found []string
+	for _, t := range tables {
+		if _, _, ok := classifyArtifact(t.name); !ok && strings.HasPrefix(t.name, prefix) {
+			found = append(found, t.name)
+		}
+	}
+	if len(found) != 1 {
+		return prefix
+	}
+	return found[0]
+}
+
+// readCheckpoints reads the rows of a checkpoint table by column name, and
+// returns the latest row of each statement or job. The columns are not
+// hardcoded, since they differ between migrate, move and the chunked jobs,
+// and between spirit versions.
+func readCheckpoints(ctx context.Context, db *sql.DB, schemaName, name string) ([]map[string]string, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT * FROM `%s`.`%s` ORDER BY id", schemaName, name))
+	if err != nil {
+		return nil, fmt.Errorf("could not read checkpoint table %s.%s: %w", schemaName, name, err)
+	}
+	defer utils.CloseAndLog(rows)
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var checkpoints []map[string]string
+	for rows.Next() {
+		values := make([]sql.NullString, len(cols))
+		ptrs := make([]any, len(cols))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]string, len(cols))
+		for i, col := range cols {
+			row[col] = values[i].String
+		}
+		// The rows are in the order they were written, so a later row of
+		// the same statement replaces an earlier one.
+		i := slices.IndexFunc(checkpoints, func(c map[string]string) bool {
+			return c["statement"] == row["statement"] && c["job"] == row["job"]
+		})
+		if i < 0 {
+			checkpoints = append(checkpoints, row)
+		} else {
+			checkpoints[i] = row
+		}
+	}
+	return checkpoints, rows.Err()
+}
+
+// checkpointFields are the columns of checkpoint tables that are printed,
+// in order.
+var checkpointFields = []struct {
+	col, label string
+	watermark  bool
+}{
+	{"statement", "statement", false},
+	{"job", "job", false},
+	{"copier_watermark", "copier watermark", true},
+	{"checksum_watermark", "checksum watermark", true},
+	{"low_watermark", "watermark", true},
+	{"resume_where", "resumes at", false},
+	{"rows_affected", "rows affected", false},
+	{"binlog_position", "binlog position", false},
+	{"binlog_positions", "binlog positions", false},
+}
+
+// writeServerStatus prints schemas to w.
+func writeServerStatus(w io.Writer, schemas []*schemaStatus, now time.Time) {
+	if len(schemas) == 0 {
+		fmt.Fprintln(w, "No spirit tables found.")
+		return
+	}
+	for _, schema := range schemas {
+		fmt.Fprintf(w, "Schema %s\n", schema.name)
+		var running []string
+		for _, t := range schema.tables {
+			switch {
+			case t.lockHolder != 0:
+				running = append(running, t.name)
+				fmt.Fprintf(w, "  Table %s: running, its metadata lock is held by connection %d\n", t.name, t.lockHolder)
+			case slices.ContainsFunc(t.artifacts, func(a *artifact) bool { return !a.isChunkedCheckpoint() }):
+				fmt.Fprintf(w, "  Table %s: not running, no connection holds its metadata lock\n", t.name)
+			default:
+				fmt.Fprintf(w, "  Table %s: no migrate, move or revert is running\n", t.name)
+			}
+			for _, a := range t.artifacts {
+				writeArtifact(w, "    ", a, now)
+				if a.isChunkedCheckpoint() {
+					// Only migrate, move and revert hold the metadata lock.
+					fmt.Fprintln(w, "      unknown whether running, spirit dml and spirit archive don't hold a metadata lock")
+				}
+			}
+		}
+		for _, a := range schema.shared {
+			writeArtifact(w, "  ", a, now)
+			if a.kind != artifactSentinel {
+				continue
+			}
+			// Only the migrations that were started with --defer-cutover
+			// wait for the sentinel to be dropped before their cutover, and
+			// the checkpoint doesn't record which ones were.
+			if len(running) == 0 {
+				fmt.Fprintln(w, "    not blocking a running migration")
+			} else {
+				fmt.Fprintf(w, "    may block the cutover of: %s (if started with --defer-cutover)\n", strings.Join(running, ", "))
+			}
+		}
+	}
+}
+
+// writeArtifact prints a, and its checkpoints if it has any.
+func writeArtifact(w io.Writer, indent string, a *artifact, now time.Time) {
+	fmt.Fprintf(w, "%s%s (%s%s)\n", indent, a.name, a.kind, describeAge(", created ", a.createdAt, now))
+	for _, checkpoint := range a.checkpoints {
+		first := true
+		for _, f := range checkpointFields {
+			value, ok := checkpoint[f.col]
+			if !ok {
+				continue
+			}
+			if f.watermark {
+				value = describeWatermark(value)
+			}
+			prefix := "  "
+			if first {
+				prefix = "- "
+				first = false
+			}
+			fmt.Fprintf(w, "%s  %s%s: %s\n", indent, prefix, f.label, value)
+		}
+		if age := describeAge("", cmp.Or(checkpoint["created_at"], checkpoint["updated_at"]), now); age != "" {
+			fmt.Fprintf(w, "%s    written: %s\n", indent, age)
+		}
+	}
+}
+
+// describeAge returns how long ago the DATETIME ts was, after prefix, or
+// "" if ts isn't a DATETIME. The connection's time zone is UTC, so ts is
+// in UTC.
+func describeAge(prefix, ts string, now time.Time) string {
+	t, err := time.Parse(time.DateTime, ts)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%s%s ago", prefix, now.Sub(t).Round(time.Second))
+}
+
+// describeWatermark returns a copier or checksum watermark as the range of
+// keys that is done, e.g. "id < 1001", or the watermark itself if it isn't
+// in a format that table.WatermarkPerTable reads.
+func describeWatermark(wm string) string {
+	if wm == "" {
+		return "none"
+	}
+	// The multi-chunker's watermark is a map of each table's watermark.
+	multi := map[string]string{}
+	if err := json.Unmarshal([]byte(wm), &multi); err == nil {
+		parts := make([]string, 0, len(multi))
+		for _, tbl := range slices.Sorted(maps.Keys(multi)) {
+			parts = append(parts, fmt.Sprintf("%s: %s", tbl, describeWatermark(multi[tbl])))
+		}
+		return strings.Join(parts, "; ")
+	}
+	// The composite chunker's watermark is an envelope of the chunk.
+	var envelope struct{ ChunkJSON string }
+	if err := json.Unmarshal([]byte(wm), &envelope); err == nil && envelope.ChunkJSON != "" {
+		return describeWatermark(envelope.ChunkJSON)
+	}
+	var chunk table.JSONChunk
+	if err := json.Unmarshal([]byte(wm), &chunk); err != nil || len(chunk.Key) == 0 || len(chunk.UpperBound.Value) != len(chunk.Key) {
+		return wm
+	}
+	op := "<"
+	if chunk.UpperBound.Inclusive {
+		op = "<="
+	}
+	if len(chunk.Key) == 1 {
+		return fmt.Sprintf("%s %s %s", chunk.Key[0], op, chunk.UpperBound.Value[0])
+	}
+	return fmt.Sprintf("(%s) %s (%s)", strings.Join(chunk.Key, ", "), op, strings.Join(chunk.UpperBound.Value, ", "))
+}
@@ -0,0 +1,174 @@
+package migration
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/block/spirit/pkg/dbconn"
+	"github.com/block/spirit/pkg/table"
+	"github.com/block/spirit/pkg/testutils"
+	"github.com/block/spirit/pkg/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyArtifact(t *testing.T) {
+	for name, want := range map[string]struct {
+		kind  artifactKind
+		table string
+	}{
+		"_spirit_checkpoint":           {artifactCheckpoint, ""},
+		"_spirit_sentinel":             {artifactSentinel, ""},
+		"_t1_chkpnt":                   {artifactCheckpoint, "t1"},
+		"_t1_dml_chkpnt":               {artifactDMLCheckpoint, "t1"},
+		"_t1_archive_chkpnt":           {artifactArchiveCheckpoint, "t1"},
+		"_my_t1_new":                   {artifactNew, "my_t1"},
+		"_t1_old":                      {artifactOld, "t1"},
+		"_t1_old_20261017_093000":      {artifactOld, "t1"},
+		"_t1_revert":                   {artifactRevert, "t1"},
+		"_t1_reverted_20261017_0930":   {"", ""},
+		"_t1_reverted_20261017_093000": {artifactReverted, "t1"},
+		"t1_new":                       {"", ""},
+	} {
+		kind, tableName, ok := classifyArtifact(name)
+		require.Equal(t, want.kind != "", ok, name)
+		require.Equal(t, want.kind, kind, name)
+		require.Equal(t, want.table, tableName, name)
+	}
+}
+
+func TestDescribeWatermark(t *testing.T) {
+	require.Equal(t, "none", describeWatermark(""))
+	require.Equal(t, "id < 1001", describeWatermark(`{"Key":["id"],"ChunkSize":1000,"LowerBound":{"Value":["1"],"Inclusive":true},"UpperBound":{"Value":["1001"],"Inclusive":false}}`))
+	require.Equal(t, "(a, b) <= (1, 'x')", describeWatermark(`{"Key":["a","b"],"ChunkSize":1000,"LowerBound":{"Value":["1","'a'"],"Inclusive":true},"UpperBound":{"Value":["1","'x'"],"Inclusive":true}}`))
+	require.Equal(t, "id < 5", describeWatermark(`{"ChunkJSON":"{\"Key\":[\"id\"],\"ChunkSize\":1,\"LowerBound\":{\"Value\":[\"4\"],\"Inclusive\":true},\"UpperBound\":{\"Value\":[\"5\"],\"Inclusive\":false}}","RowsCopied":4}`))
+	require.Equal(t, "test.t1: id < 5; test.t2: none", describeWatermark(`{"test.t2":"","test.t1":"{\"Key\":[\"id\"],\"ChunkSize\":1,\"LowerBound\":{\"Value\":[\"4\"],\"Inclusive\":true},\"UpperBound\":{\"Value\":[\"5\"],\"Inclusive\":false}}"}`))
+	require.Equal(t, "not a watermark", describeWatermark("not a watermark"))
+}
+
+func TestWriteServerStatus(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	writeServerStatus(&buf, nil, now)
+	require.Equal(t, "No spirit tables found.\n", buf.String())
+
+	buf.Reset()
+	writeServerStatus(&buf, []*schemaStatus{{
+		name: "test",
+		tables: []*tableStatus{
+			{name: "t1", lockHolder: 42, artifacts: []*artifact{
+				{name: "_t1_chkpnt", kind: artifactCheckpoint, createdAt: "2026-10-17 11:00:00", checkpoints: []map[string]string{{
+					"id":                 "7",
+					"statement":          "ALTER TABLE t1 ADD COLUMN c INT",
+					"copier_watermark":   `{"Key":["id"],"ChunkSize":1000,"LowerBound":{"Value":["1"],"Inclusive":true},"UpperBound":{"Value":["1001"],"Inclusive":false}}`,
+					"checksum_watermark": "",
+					"binlog_position":    "binlog.000003:1234",
+					"created_at":         "2026-10-17 11:59:40",
+				}}},
+				{name: "_t1_new", kind: artifactNew, createdAt: "2026-10-17 11:00:00"},
+			}},
+			{name: "t2", artifacts: []*artifact{
+				{name: "_t2_old", kind: artifactOld},
+			}},
+			{name: "t3", artifacts: []*artifact{
+				{name: "_t3_dml_chkpnt", kind: artifactDMLCheckpoint},
+			}},
+		},
+		shared: []*artifact{{name: "_spirit_sentinel", kind: artifactSentinel, createdAt: "2026-10-17 10:00:00"}},
+	}}, now)
+	require.Equal(t, `Schema test
+  Table t1: running, its metadata lock is held by connection 42
+    _t1_chkpnt (checkpoint, created 1h0m0s ago)
+      - statement: ALTER TABLE t1 ADD COLUMN c INT
+        copier watermark: id < 1001
+        checksum watermark: none
+        binlog position: binlog.000003:1234
+        written: 20s ago
+    _t1_new (new table, created 1h0m0s ago)
+  Table t2: not running, no connection holds its metadata lock
+    _t2_old (old table)
+  Table t3: no migrate, move or revert is running
+    _t3_dml_chkpnt (dml checkpoint)
+      unknown whether running, spirit dml and spirit archive don't hold a metadata lock
+  _spirit_sentinel (sentinel, created 2h0m0s ago)
+    may block the cutover of: t1 (if started with --defer-cutover)
+`, buf.String())
+}
+
+func TestNewSchemaStatus(t *testing.T) {
+	require.Nil(t, newSchemaStatus("test", []schemaTable{{name: "t1"}}))
+
+	// The name of a long table is truncated by more in _old_<timestamp>
+	// than in _chkpnt, but both are grouped under its full name.
+	long := strings.Repeat("a", 60)
+	other := strings.Repeat("b", 60)
+	tables := []schemaTable{
+		{name: utils.AuxTableName(long, "_chkpnt")},
+		{name: utils.AuxTableName(long, "_old_20261017_093000")},
+		{name: utils.AuxTableName(other, "_old_20261017_093000")},
+		{name: "_spirit_sentinel"},
+		{name: long},
+		{name: "t1"},
+	}
+	schema := newSchemaStatus("test", tables)
+	require.NotNil(t, schema)
+	require.Len(t, schema.shared, 1)
+	require.Len(t, schema.tables, 2)
+	require.Equal(t, long, schema.tables[0].name)
+	require.Len(t, schema.tables[0].artifacts, 2)
+	// The table of other was dropped, so its truncated name is kept.
+	require.Equal(t, other[:43], schema.tables[1].name)
+}
+
+func TestInspectServer(t *testing.T) {
+	t.Parallel()
+	testutils.NewTestTable(t, "statt1", `CREATE TABLE statt1 (id int NOT NULL PRIMARY KEY)`)
+	testutils.NewTestTable(t, "_statt1_new", `CREATE TABLE _statt1_new (id int NOT NULL PRIMARY KEY)`)
+	testutils.RunSQL(t, "DROP TABLE IF EXISTS _statt1_dml_chkpnt")
+	testutils.RunSQL(t, `CREATE TABLE _statt1_dml_chkpnt (
+		id int NOT NULL PRIMARY KEY,
+		job TEXT NOT NULL,
+		low_watermark TEXT NOT NULL,
+		resume_where TEXT NOT NULL,
+		rows_affected bigint unsigned NOT NULL,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+	)`)
+	defer testutils.RunSQL(t, "DROP TABLE IF EXISTS _statt1_dml_chkpnt")
+	testutils.RunSQL(t, `INSERT INTO _statt1_dml_chkpnt (id, job, low_watermark, resume_where, rows_affected)
+		VALUES (1, 'DELETE FROM statt1 WHERE id > 10', '{"Key":["id"],"ChunkSize":1000,"LowerBound":{"Value":["1"],"Inclusive":true},"UpperBound":{"Value":["1001"],"Inclusive":false}}', '`+"`id`"+` >= 2001', 990)`)
+
+	db, err := dbconn.New(testutils.DSN(), dbconn.NewDBConfig())
+	require.NoError(t, err)
+	defer utils.CloseAndLog(db)
+	find := func() *tableStatus {
+		schemas, err := inspectServer(t.Context(), db)
+		require.NoError(t, err)
+		for _, schema := range schemas {
+			for _, ts := range schema.tables {
+				if schema.name == "test" && ts.name == "statt1" {
+					return ts
+				}
+			}
+		}
+		return nil
+	}
+
+	ts := find()
+	require.NotNil(t, ts)
+	require.Zero(t, ts.lockHolder)
+	require.Len(t, ts.artifacts, 2)
+	require.Equal(t, artifactDMLCheckpoint, ts.artifacts[0].kind)
+	require.Len(t, ts.artifacts[0].checkpoints, 1)
+	require.Equal(t, "990", ts.artifacts[0].checkpoints[0]["rows_affected"])
+	require.Equal(t, artifactNew, ts.artifacts[1].kind)
+
+	// The metadata lock of a running migration is found.
+	lock, err := dbconn.NewMetadataLock(t.Context(), testutils.DSN(), []*table.TableInfo{table.NewTableInfo(db, "test", "statt1")}, dbconn.NewDBConfig(), slog.Default())
+	require.NoError(t, err)
+	defer utils.CloseAndLog(lock)
+	ts = find()
+	require.NotNil(t, ts)
+	require.NotZero(t, ts.lockHolder)
+}
@@ -0,0 +1,46 @@
+package migration
+
+import (
+	"testing"
+
+	"github.com/block/spirit/pkg/table"
+	"github.com/block/spirit/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateTableWithTriggers(t *testing.T) {
+	t.Parallel()
+	tt := testutils.NewTestTable(t, "triggerst1", `CREATE TABLE triggerst1 (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		a int NOT NULL,
+		b int NOT NULL DEFAULT 0
+	)`)
+	testutils.NewTestTable(t, "triggerst1_audit", `CREATE TABLE triggerst1_audit (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		row_id int NOT NULL
+	)`)
+	testutils.RunSQL(t, `CREATE TRIGGER triggerst1_bi BEFORE INSERT ON triggerst1 FOR EACH ROW SET NEW.b = NEW.a * 2`)
+	testutils.RunSQL(t, `CREATE TRIGGER triggerst1_ai AFTER INSERT ON triggerst1 FOR EACH ROW INSERT INTO triggerst1_audit (row_id) VALUES (NEW.id)`)
+	testutils.RunSQL(t, `INSERT INTO triggerst1 (a) VALUES (1), (2), (3)`)
+	before, err := table.LoadTriggers(t.Context(), tt.DB, "test", "triggerst1")
+	require.NoError(t, err)
+
+	m := NewTestRunner(t, "triggerst1", "MODIFY a bigint NOT NULL")
+	require.NoError(t, m.Run(t.Context()))
+	require.NoError(t, m.Close())
+
+	after, err := table.LoadTriggers(t.Context(), tt.DB, "test", "triggerst1")
+	require.NoError(t, err)
+	require.Equal(t, before, after)
+
+	// The copy didn't fire the triggers again, and they fire on the
+	// migrated table.
+	var b, audits int
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), "SELECT COUNT(*) FROM triggerst1_audit").Scan(&audits))
+	require.Equal(t, 3, audits)
+	testutils.RunSQL(t, `INSERT INTO triggerst1 (a) VALUES (10)`)
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), "SELECT b FROM triggerst1 WHERE a = 10").Scan(&b))
+	require.Equal(t, 20, b)
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), "SELECT COUNT(*) FROM triggerst1_audit").Scan(&audits))
+	require.Equal(t, 4, audits)
+}
@@ -0,0 +1,109 @@
+package migration
+
+import (
+	"testing"
+
+	"github.com/block/spirit/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateTableWithoutPrimaryKey(t *testing.T) {
+	t.Parallel()
+	tt := testutils.NewTestTable(t, "nopkt1", `CREATE TABLE nopkt1 (
+		a int NOT NULL,
+		b varchar(255) NOT NULL,
+		c int,
+		UNIQUE KEY c (c),
+		UNIQUE KEY a (a)
+	)`)
+	testutils.RunSQL(t, `INSERT INTO nopkt1 (a, b, c) VALUES (1, 'x', NULL), (2, 'y', 2), (3, 'z', NULL)`)
+
+	// The ALTER can't drop the only unique key that can identify the rows.
+	m := NewTestRunner(t, "nopkt1", "DROP INDEX a, ADD COLUMN e int")
+	require.ErrorContains(t, m.Run(t.Context()), "no primary key found on table _nopkt1_new")
+	require.NoError(t, m.Close())
+
+	m = NewTestRunner(t, "nopkt1", "ADD COLUMN d int NOT NULL DEFAULT 0")
+	require.NoError(t, m.Run(t.Context()))
+	require.NoError(t, m.Close())
+	var count int
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), "SELECT COUNT(*) FROM nopkt1 WHERE d = 0").Scan(&count))
+	require.Equal(t, 3, count)
+}
+
+func TestMigrateTableWithInvisiblePrimaryKey(t *testing.T) {
+	t.Parallel()
+	// This is the primary key that sql_generate_invisible_primary_key adds.
+	tt := testutils.NewTestTable(t, "gipkt1", `CREATE TABLE gipkt1 (
+		my_row_id bigint unsigned NOT NULL AUTO_INCREMENT /*!80023 INVISIBLE */,
+		a int NOT NULL,
+		PRIMARY KEY (my_row_id)
+	)`)
+	testutils.RunSQL(t, `INSERT INTO gipkt1 (a) VALUES (1), (2), (3)`)
+	testutils.RunSQL(t, `DELETE FROM gipkt1 WHERE a = 2`)
+
+	m := NewTestRunner(t, "gipkt1", "ADD COLUMN b int NOT NULL DEFAULT 0")
+	require.NoError(t, m.Run(t.Context()))
+	require.NoError(t, m.Close())
+	// The invisible column was copied, rather than generated again.
+	var ids string
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), "SELECT GROUP_CONCAT(my_row_id ORDER BY a) FROM gipkt1").Scan(&ids))
+	require.Equal(t, "1,3", ids)
+}
+
+func TestMigrateChangePrimaryKey(t *testing.T) {
+	t.Parallel()
+	tt := testutils.NewTestTable(t, "pkchgt1", `CREATE TABLE pkchgt1 (
+		id int NOT NULL AUTO_INCREMENT,
+		a int NOT NULL,
+		b int NOT NULL,
+		PRIMARY KEY (id)
+	)`)
+	testutils.RunSQL(t, `INSERT INTO pkchgt1 (a, b) VALUES (1, 1), (1, 2), (2, 1)`)
+
+	// The old PRIMARY KEY stays unique in the new table, so rows are
+	// still matched by it.
+	m := NewTestRunner(t, "pkchgt1", "DROP PRIMARY KEY, ADD PRIMARY KEY (a, b), ADD UNIQUE KEY id (id)")
+	require.NoError(t, m.Run(t.Context()))
+	require.NoError(t, m.Close())
+	var createTable string
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), "SHOW CREATE TABLE pkchgt1").Scan(new(string), &createTable))
+	require.Contains(t, createTable, "PRIMARY KEY (`a`,`b`)")
+	var count int
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), "SELECT COUNT(*) FROM pkchgt1").Scan(&count))
+	require.Equal(t, 3, count)
+}
+
+func TestMigrateReplacePrimaryKey(t *testing.T) {
+	t.Parallel()
+	tt := testutils.NewTestTable(t, "pkchgt2", `CREATE TABLE pkchgt2 (
+		id int NOT NULL AUTO_INCREMENT,
+		a int NOT NULL,
+		b int NOT NULL,
+		c int,
+		PRIMARY KEY (id),
+		UNIQUE KEY ab (a, b)
+	)`)
+	testutils.RunSQL(t, `INSERT INTO pkchgt2 (a, b, c) VALUES (1, 1, 1), (1, 2, 2), (2, 1, 3)`)
+
+	// Without a unique key on (a, b) in the table, rows can't be matched.
+	m := NewTestRunner(t, "pkchgt2", "DROP PRIMARY KEY, ADD PRIMARY KEY (b, c), MODIFY id int NOT NULL, DROP INDEX ab")
+	require.ErrorContains(t, m.Run(t.Context()), "no unique key on the same NOT NULL columns to match rows by")
+	require.NoError(t, m.Close())
+
+	// The unique key on (a, b) is kept, but it can no longer identify rows
+	// once a column is nullable, which is only known once the new table has
+	// been altered.
+	m = NewTestRunner(t, "pkchgt2", "DROP PRIMARY KEY, MODIFY a int NULL")
+	require.ErrorContains(t, m.Run(t.Context()), "from PRIMARY (id) to none")
+	require.NoError(t, m.Close())
+
+	// The surrogate key is replaced with the natural key, which the table
+	// already has a unique key on.
+	m = NewTestRunner(t, "pkchgt2", "DROP PRIMARY KEY, DROP COLUMN id, ADD PRIMARY KEY (a, b)")
+	require.NoError(t, m.Run(t.Context()))
+	require.NoError(t, m.Close())
+	var sum int
+	require.NoError(t, tt.DB.QueryRowContext(t.Context(), "SELECT SUM(c) FROM pkchgt2").Scan(&sum))
+	require.Equal(t, 6, sum)
+}
@@ -0,0 +1,51 @@
+package check
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/block/spirit/pkg/dbconn"
+)
+
+func init() {
+	registerCheck("binlog_retention", binlogRetentionCheck, ScopePreflight)
+	registerCheck("binlog_retention_copy_rows", binlogRetentionCheck, ScopeCopyRows)
+}
+
+// binlogRetentionCheck warns if the copy is expected to take longer than a
+// source keeps binary logs. Resuming from a checkpoint needs the binary
+// logs of every source since it was written, so the longer the copy runs
+// relative to the retention, the more likely it is that an interrupted move
+// fails to resume with ErrBinlogNotFound and has to start over. There is no
+// estimate of the copy time before the copy has started, so at preflight
+// this only logs the retention.
+//
+// Like diskSpaceCheck, it never fails the move.
+func binlogRetentionCheck(ctx context.Context, r Resources, logger *slog.Logger) error {
+	for _, source := range r.Sources {
+		retention, err := dbconn.BinlogRetention(ctx, source.DB)
+		if err != nil {
+			logger.Warn("could not read the binary log retention", "source", source.Config.Addr, "error", err)
+			continue
+		}
+		if retention == 0 {
+			continue // binary logs are never purged automatically
+		}
+		if r.CopyETA == 0 {
+			logger.Info("binary logs are purged automatically, the copy will be compared with the retention once it has an ETA",
+				"source", source.Config.Addr,
+				"binlog-retention", retention,
+			)
+			continue
+		}
+		if expected := r.CopyElapsed + r.CopyETA; expected > retention {
+			logger.Warn("the copy is expected to take longer than the source keeps binary logs. If the move is interrupted it may not be able to resume; consider increasing binlog_expire_logs_seconds.",
+				"source", source.Config.Addr,
+				"expected-copy-time", expected.Round(time.Second),
+				"binlog-retention", retention,
+			)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package check
+
+import (
+	"bytes"
+	"database/sql"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/block/spirit/pkg/dbconn"
+	"github.com/block/spirit/pkg/testutils"
+	"github.com/block/spirit/pkg/utils"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBinlogRetentionCheck(t *testing.T) {
+	db, err := sql.Open("mysql", testutils.DSN())
+	require.NoError(t, err)
+	defer utils.CloseAndLog(db)
+	retention, err := dbconn.BinlogRetention(t.Context(), db)
+	require.NoError(t, err)
+	if retention == 0 {
+		t.Skip("binary logs are never purged on this server")
+	}
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	r := Resources{
+		Sources: []SourceResource{{DB: db, Config: &mysql.Config{Addr: "source1"}}},
+	}
+
+	require.NoError(t, binlogRetentionCheck(t.Context(), r, logger))
+	require.NotContains(t, logs.String(), "level=WARN")
+
+	r.CopyElapsed = retention
+	r.CopyETA = time.Hour
+	require.NoError(t, binlogRetentionCheck(t.Context(), r, logger))
+	require.Contains(t, logs.String(), "the copy is expected to take longer than the source keeps binary logs")
+	require.Contains(t, logs.String(), "source=source1")
+}
@@ -7,6 +7,7 @@ import (
 	"database/sql"
 	"log/slog"
 	"sync"
+	"time"
 
 	"github.com/block/spirit/pkg/applier"
 	"github.com/block/spirit/pkg/table"
@@ -22,6 +23,8 @@ const (
 	ScopePreflight
 	ScopePostSetup
 	ScopeResume
+	// ScopeCopyRows checks are re-run periodically while rows are copied.
+	ScopeCopyRows
 )
 
 // SourceResource holds per-source connection state for checks.
@@ -48,6 +51,12 @@ type Resources struct {
 	// moving everything, an extra/missing table on one shard is a drift error;
 	// when only a named subset is moved, tables outside that subset are ignored.
 	MoveEverything bool
+	// CopyElapsed and CopyETA are how long the copy has been running, and
+	// the copier's latest estimate of how much longer it will take. They are
+	// only set for the ScopeCopyRows checks, and CopyETA is zero until the
+	// copier has made an estimate.
+	CopyElapsed time.Duration
+	CopyETA     time.Duration
 }
 
 type check struct {
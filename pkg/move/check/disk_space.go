@@ -0,0 +1,94 @@
+package check
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/block/spirit/pkg/applier"
+	"github.com/block/spirit/pkg/dbconn"
+	"github.com/block/spirit/pkg/utils"
+)
+
+func init() {
+	registerCheck("disk_space", diskSpacePostSetupCheck, ScopePostSetup)
+	registerCheck("disk_space_copy_rows", diskSpaceCheck, ScopeCopyRows)
+}
+
+// diskSpaceCheck warns if a target host does not appear to have the free
+// disk space to finish the copy. The rows of the source tables are assumed
+// to be spread evenly over the targets, so each target needs its share of
+// their combined size, less the size of the tables already copied to it.
+// Free space is only known when the target reports it (Aurora) or Spirit
+// runs on the same host as the target.
+//
+// It runs at ScopePostSetup, because it needs the source tables, and never
+// fails the move: the estimate is rough, and it is up to the operator to
+// act on it.
+func diskSpaceCheck(ctx context.Context, r Resources, logger *slog.Logger) error {
+	return checkDiskSpace(ctx, r, logger, false)
+}
+
+// diskSpacePostSetupCheck is diskSpaceCheck, but it also warns when the free
+// space of a target can't be measured, so that the operator knows to check
+// it. The periodic re-checks while copying stay quiet about it.
+func diskSpacePostSetupCheck(ctx context.Context, r Resources, logger *slog.Logger) error {
+	return checkDiskSpace(ctx, r, logger, true)
+}
+
+func checkDiskSpace(ctx context.Context, r Resources, logger *slog.Logger, warnUnknown bool) error {
+	if len(r.SourceTables) == 0 || len(r.Targets) == 0 {
+		return nil
+	}
+	var sourceSize uint64
+	for _, source := range r.Sources {
+		for _, tbl := range r.SourceTables {
+			size, err := dbconn.TableDiskSize(ctx, source.DB, source.Config.DBName, tbl.TableName)
+			if err != nil {
+				logger.Warn("could not estimate the disk space needed for the copy", "table", tbl.TableName, "error", err)
+				return nil
+			}
+			sourceSize += size
+		}
+	}
+	share := sourceSize / uint64(len(r.Targets))
+	// Several targets may share a host, and so its free space.
+	var hosts []applier.Target
+	needed := make(map[string]uint64)
+	for _, target := range r.Targets {
+		var copied uint64
+		for _, tbl := range r.SourceTables {
+			size, err := dbconn.TableDiskSize(ctx, target.DB, target.Config.DBName, tbl.TableName)
+			if err != nil {
+				logger.Warn("could not estimate the disk space needed for the copy", "table", tbl.TableName, "error", err)
+				return nil
+			}
+			copied += size
+		}
+		if _, ok := needed[target.Config.Addr]; !ok {
+			hosts = append(hosts, target)
+		}
+		needed[target.Config.Addr] += share - min(copied, share)
+	}
+	for _, target := range hosts {
+		host := target.Config.Addr
+		free, ok, err := dbconn.FreeDiskSpace(ctx, target.DB)
+		if err != nil || !ok {
+			if warnUnknown {
+				logger.Warn("could not check that the target has enough free disk space to finish the copy. It can only be measured on Aurora, or when spirit runs on the same host as the target.",
+					"target", host,
+					"estimated-space-needed", utils.FormatBytes(needed[host]),
+					"error", err,
+				)
+			}
+			continue
+		}
+		if free < needed[host] {
+			logger.Warn("the target may not have enough free disk space to finish the copy. Note that the binary logs written by the copy need space too.",
+				"target", host,
+				"estimated-space-needed", utils.FormatBytes(needed[host]),
+				"free-space", utils.FormatBytes(free),
+			)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package check
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/block/spirit/pkg/applier"
+	"github.com/block/spirit/pkg/dbconn"
+	"github.com/block/spirit/pkg/table"
+	"github.com/block/spirit/pkg/testutils"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskSpaceCheck(t *testing.T) {
+	tt := testutils.NewTestTable(t, "movediskspacet1", `CREATE TABLE movediskspacet1 (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY
+	)`)
+	cfg := &mysql.Config{Addr: "host1", DBName: "test"}
+	r := Resources{
+		Sources:      []SourceResource{{DB: tt.DB, Config: cfg}},
+		Targets:      []applier.Target{{DB: tt.DB, Config: cfg}, {DB: tt.DB, Config: cfg}},
+		SourceTables: []*table.TableInfo{{SchemaName: "test", TableName: "movediskspacet1"}},
+	}
+	// The check only ever warns.
+	var logs bytes.Buffer
+	require.NoError(t, diskSpaceCheck(t.Context(), r, slog.New(slog.NewTextHandler(&logs, nil))))
+	require.NotContains(t, logs.String(), "could not estimate")
+	require.NotContains(t, logs.String(), "could not check")
+
+	// Only the first check warns when free space is unknown, the periodic
+	// re-checks above stay quiet about it.
+	logs.Reset()
+	require.NoError(t, diskSpacePostSetupCheck(t.Context(), r, slog.New(slog.NewTextHandler(&logs, nil))))
+	require.NotContains(t, logs.String(), "could not estimate")
+	free, ok, err := dbconn.FreeDiskSpace(t.Context(), tt.DB)
+	require.NoError(t, err)
+	if !ok {
+		require.Contains(t, logs.String(), "could not check")
+	} else if free > 1<<30 {
+		require.Empty(t, logs.String())
+	}
+
+	// Without source tables there is nothing to check.
+	require.NoError(t, diskSpaceCheck(t.Context(), Resources{}, slog.Default()))
+}
@@ -12,7 +12,8 @@ func init() {
 
 // tableCompatibilityCheck verifies that all source tables are compatible
 // with move operations. The only remaining requirement is that every table
-// has a primary key, which is required for replication tracking.
+// has a primary key, or a unique key with only NOT NULL columns to use in
+// its place, which is required for replication tracking.
 //
 // Non-memory-comparable PKs (e.g. VARCHAR with a CI collation) are now
 // supported: bufferedMap routes those subscriptions through its FIFO queue
@@ -22,7 +23,7 @@ func init() {
 func tableCompatibilityCheck(ctx context.Context, r Resources, logger *slog.Logger) error {
 	for _, tbl := range r.SourceTables {
 		if len(tbl.KeyColumns) == 0 {
-			return fmt.Errorf("table '%s' does not have a primary key, which is required for move operations", tbl.TableName)
+			return fmt.Errorf("table '%s' does not have a primary key or a NOT NULL unique key, which is required for move operations", tbl.TableName)
 		}
 	}
 	return nil
@@ -31,6 +31,7 @@ func (m *mockChecker) GetProgress() string           { return "mock" }
 func (m *mockChecker) StartTime() time.Time          { return time.Now() }
 func (m *mockChecker) ExecTime() time.Duration       { return 0 }
 func (m *mockChecker) DifferencesFound() uint64      { return m.differencesFound.Load() }
+func (m *mockChecker) ChunksRemaining() uint64       { return 0 }
 
 // setupRunnerForChecksumTest builds a move.Runner up to the point where the
 // checkpoint table exists on the first target, the copier has produced a watermark,
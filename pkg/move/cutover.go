@@ -11,6 +11,7 @@ import (
 
 	"github.com/block/spirit/pkg/change"
 	"github.com/block/spirit/pkg/dbconn"
+	"github.com/block/spirit/pkg/metrics"
 	"github.com/block/spirit/pkg/move/check"
 	"github.com/block/spirit/pkg/table"
 	"github.com/block/spirit/pkg/utils"
@@ -26,6 +27,12 @@ var renameRetryWait = 1 * time.Second
 // that state cannot converge, so the retry loop aborts immediately.
 var errRenameRollbackFailed = errors.New("rename rollback failed")
 
+// errOutsideCutoverWindow is returned by CutOver.Run when the cutover
+// window is closed before an attempt. Neither the cutover function nor the
+// rename has run, so the caller can wait for the window to open again and
+// call Run again.
+var errOutsideCutoverWindow = errors.New("outside the cutover window")
+
 // CutOverSource holds per-source state needed for the cutover.
 type CutOverSource struct {
 	DB         *sql.DB
@@ -38,6 +45,17 @@ type CutOver struct {
 	cutoverFunc func(ctx context.Context) error
 	dbConfig    *dbconn.DBConfig
 	logger      *slog.Logger
+	// metricsSink is optional; the runner sets it so attempts are counted.
+	metricsSink metrics.Sink
+	// window is optional; when set, attempts are only started inside it.
+	window *utils.Window
+	// onLocked is optional; it is called when an attempt has locked the
+	// source tables, before anything is changed under the lock. From then
+	// on the attempts are made even if the window has closed, since the
+	// caller can't go back to waiting.
+	onLocked func()
+	// locked is set once an attempt has locked the source tables.
+	locked bool
 	// cutoverFuncSucceeded tracks whether cutoverFunc has been invoked and
 	// returned nil. The cutover function is a caller-supplied traffic switch
 	// (e.g. a Vitess routing change) and is not assumed to be idempotent:
@@ -91,6 +109,19 @@ func (c *CutOver) Run(ctx context.Context) error {
 		if ctx.Err() != nil {
 			return ctx.Err()
 		}
+		// Only start an attempt inside the cutover window. If it has
+		// closed while we were retrying, stop rather than exhaust the
+		// retries, so the caller can wait for the next window. Once an
+		// attempt has locked the tables the caller has entered CutOver,
+		// so the remaining attempts are made regardless. Once the
+		// cutover function has succeeded we never get here again (see
+		// below), so this can't strand traffic on the target.
+		if c.window != nil && !c.locked && !c.window.Contains(time.Now()) {
+			c.logger.Warn("not attempting cut over outside the cutover window",
+				"cutover-window", c.window.String(),
+				"attempt", attempt+1)
+			return errors.Join(err, errOutsideCutoverWindow)
+		}
 		// Flush all sources before attempting the cutover.
 		for i, src := range c.sources {
 			if err := src.ReplClient.Flush(ctx); err != nil {
@@ -100,6 +131,7 @@ func (c *CutOver) Run(ctx context.Context) error {
 		c.logger.Warn("Attempting final cut over operation",
 			"attempt", attempt+1,
 			"max-retries", c.dbConfig.MaxRetries)
+		c.recordAttempt(ctx)
 		err = c.algorithmCutover(ctx)
 		if err != nil {
 			if c.cutoverFuncSucceeded {
@@ -145,6 +177,12 @@ func (c *CutOver) algorithmCutover(ctx context.Context) error {
 			utils.CloseAndLogWithContext(ctx, l)
 		}
 	}()
+	if !c.locked {
+		c.locked = true
+		if c.onLocked != nil {
+			c.onLocked()
+		}
+	}
 
 	// Flush ALL repl clients. No new changes will arrive because all sources are locked.
 	for i, src := range c.sources {
@@ -247,3 +285,22 @@ func (c *CutOver) renameAllSources(ctx context.Context, sourceLocks []*dbconn.Ta
 	}
 	return nil
 }
+
+// recordAttempt counts a cutover attempt in the metrics sink, if one is
+// set. A failure to send is logged and otherwise ignored; it must never
+// affect the cutover itself.
+func (c *CutOver) recordAttempt(ctx context.Context) {
+	if c.metricsSink == nil {
+		return
+	}
+	sendCtx, cancel := context.WithTimeout(ctx, metrics.SinkTimeout)
+	defer cancel()
+	err := c.metricsSink.Send(sendCtx, &metrics.Metrics{
+		Values: []metrics.MetricValue{
+			{Name: metrics.CutoverAttemptsMetricName, Type: metrics.COUNTER, Value: 1},
+		},
+	})
+	if err != nil {
+		c.logger.Debug("cutover metrics send failed", "error", err)
+	}
+}
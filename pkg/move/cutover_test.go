@@ -2,6 +2,7 @@ package move
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"log/slog"
 	"testing"
@@ -281,3 +282,28 @@ func TestCutOverFuncCalledOnceAcrossRenameRetry(t *testing.T) {
 	_, err = srcDB.ExecContext(ctx, "SELECT 1 FROM t1")
 	require.Error(t, err, "t1 should not exist after rename")
 }
+
+// TestCutOverOutsideWindow checks that neither the sources are flushed nor
+// the cutover function is called outside the cutover window. It needs no
+// server: the *sql.DB never connects.
+func TestCutOverOutsideWindow(t *testing.T) {
+	db, err := sql.Open("mysql", "spirit@tcp(127.0.0.1:1)/test")
+	require.NoError(t, err)
+	defer utils.CloseAndLog(db)
+
+	replClient := change.NewBinlogClient(db, "127.0.0.1:1", "spirit", "", nil, change.NewClientDefaultConfig())
+	cutoverFunc := func(ctx context.Context) error {
+		t.Fatal("cutover function called outside the cutover window")
+		return nil
+	}
+	cutover, err := NewCutOver([]CutOverSource{{
+		DB:         db,
+		ReplClient: replClient,
+		Tables:     []*table.TableInfo{table.NewTableInfo(db, "test", "t1")},
+	}}, cutoverFunc, dbconn.NewDBConfig(), slog.Default())
+	require.NoError(t, err)
+	day := time.Now().UTC().AddDate(0, 0, 2).Weekday().String()[:3]
+	cutover.window, err = utils.ParseWindow(day + " 00:00-01:00 UTC")
+	require.NoError(t, err)
+	require.ErrorIs(t, cutover.Run(t.Context()), errOutsideCutoverWindow)
+}
@@ -2,10 +2,12 @@ package move
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/block/spirit/pkg/applier"
+	"github.com/block/spirit/pkg/hooks"
 	"github.com/block/spirit/pkg/table"
 	"github.com/block/spirit/pkg/utils"
 )
@@ -19,12 +21,30 @@ type Move struct {
 	CreateSentinel        bool          `name:"create-sentinel" help:"Create a sentinel table on the source database to block after table copy" default:"false"`
 	DeferSecondaryIndexes bool          `name:"defer-secondary-indexes" help:"Create target tables without secondary indexes, add them before cutover" default:"false"`
 	CheckpointMaxAge      time.Duration `name:"checkpoint-max-age" help:"Maximum age of a checkpoint before refusing to resume from it" optional:"" default:"168h"`
+	CutoverWindow         string        `name:"cutover-window" help:"Only cut over inside this weekly window, e.g. 'Mon-Fri 02:00-04:00 UTC'" optional:""`
+	ChecksumDiffFile      string        `name:"checksum-diff-file" help:"Write the rows that the checksum finds differ, with their row images on the source and the target, to this file before they are recopied" optional:""`
+	ChecksumDiffFormat    string        `name:"checksum-diff-format" help:"The format of --checksum-diff-file: json, or sql statements that make the target's rows match the source's" enum:"json,sql" default:"json"`
+
+	// HTTPListen starts the embedded status/control API (see
+	// status.Server) on this address. Control endpoints require HTTPToken.
+	HTTPListen string `name:"http-listen" help:"Address (host:port) to serve the HTTP status and control API on. Disabled when empty" optional:""`
+	HTTPToken  string `name:"http-token" help:"Bearer token required by the HTTP control endpoints (cancel, checkpoint, cutover). Control endpoints are disabled when empty" optional:"" env:"SPIRIT_HTTP_TOKEN"`
+
+	// MetricsSink selects a built-in metrics.Sink. "prometheus" serves the
+	// metrics on /metrics of the HTTP API, so it requires HTTPListen.
+	MetricsSink string `name:"metrics-sink" help:"Built-in metrics sink to use: none, or prometheus to serve metrics on /metrics of the HTTP API (requires --http-listen)" enum:"none,prometheus" default:"none"`
 
 	// EnableExperimentalGTID switches the change source from binlog file+position to MySQL GTIDs.
 	// EXPERIMENTAL — see pkg/change/gtid.go. Requires gtid_mode=ON and
 	// enforce_gtid_consistency=ON on every source.
 	EnableExperimentalGTID bool `name:"enable-experimental-gtid" help:"EXPERIMENTAL: use GTID-based change source instead of binlog file+position" default:"false"`
 
+	// HookExec and HookURL run a command, or POST a JSON payload to a URL,
+	// at points in the move's lifecycle (see pkg/hooks).
+	HookExec    map[string]string `name:"hook-exec" mapsep:"none" help:"Command to run at a hook point, as point=command. Points: startup, preflight-complete, copy-complete, checksum-complete, before-cutover, after-cutover, failure, success" optional:""`
+	HookURL     map[string]string `name:"hook-url" mapsep:"none" help:"URL to POST a JSON payload to at a hook point, as point=url" optional:""`
+	HookTimeout time.Duration     `name:"hook-timeout" help:"How long a hook may run before it fails" optional:"" default:"1m"`
+
 	// SourceTables optionally specifies a list of tables to move.
 	// If empty, all tables in the source database will be moved.
 	// This is useful for Vitess MoveTables operations where only specific tables should be moved.
@@ -58,7 +78,19 @@ func (m *Move) Validate() error {
 	if m.TargetChunkTime < 0 {
 		return fmt.Errorf("--target-chunk-time must be non-negative, got %s", m.TargetChunkTime)
 	}
-	return nil
+	if m.MetricsSink == "prometheus" && m.HTTPListen == "" {
+		return errors.New("--metrics-sink=prometheus requires --http-listen")
+	}
+	if m.CutoverWindow != "" {
+		if _, err := utils.ParseWindow(m.CutoverWindow); err != nil {
+			return fmt.Errorf("--cutover-window: %w", err)
+		}
+	}
+	return m.hooksConfig().Validate()
+}
+
+func (m *Move) hooksConfig() hooks.Config {
+	return hooks.Config{Exec: m.HookExec, URL: m.HookURL, Timeout: m.HookTimeout}
 }
 
 func (m *Move) Run() error {
@@ -459,6 +459,8 @@ func TestMoveValidate(t *testing.T) {
 			wantErr: "--write-threads must be non-negative, got -1"},
 		{name: "negative target-chunk-time", m: Move{TargetChunkTime: -time.Second},
 			wantErr: "--target-chunk-time must be non-negative, got -1s"},
+		{name: "unknown hook point", m: Move{HookURL: map[string]string{"done": "http://localhost/"}},
+			wantErr: `--hook-url: unknown hook point "done"`},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
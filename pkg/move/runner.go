@@ -19,6 +19,7 @@ import (
 	"github.com/block/spirit/pkg/copier"
 	"github.com/block/spirit/pkg/dbconn"
 	"github.com/block/spirit/pkg/dbconn/sqlescape"
+	"github.com/block/spirit/pkg/hooks"
 	"github.com/block/spirit/pkg/metrics"
 	"github.com/block/spirit/pkg/move/check"
 	"github.com/block/spirit/pkg/statement"
@@ -33,6 +34,7 @@ import (
 var (
 	sentinelCheckInterval   = 1 * time.Second
 	tableStatUpdateInterval = 5 * time.Minute
+	copyRecheckInterval     = 5 * time.Minute
 	sentinelWaitLimit       = 48 * time.Hour
 	sentinelTableName       = "_spirit_sentinel" // this is now a const.
 	checkpointTableName     = "_spirit_checkpoint"
@@ -130,6 +132,19 @@ type Runner struct {
 	// Set in startBackgroundRoutines and invoked from Close() so that
 	// late status/checkpoint goroutine activity cannot race with teardown.
 	watchTaskWait func()
+
+	metricsSink metrics.Sink
+
+	// httpServer is the optional status/control API (--http-listen).
+	// nil when disabled.
+	httpServer *status.Server
+
+	// cutoverWindow restricts cutover to --cutover-window. nil when unset.
+	cutoverWindow *utils.Window
+
+	// hooks are the --hook-exec and --hook-url hooks. They are created
+	// by Run, so that they use the logger from SetLogger.
+	hooks *hooks.Hooks
 }
 
 var _ status.Task = (*Runner)(nil)
@@ -146,8 +161,18 @@ func NewRunner(m *Move) (*Runner, error) {
 		m.CheckpointMaxAge = 7 * 24 * time.Hour // 7 days, same as migrate
 	}
 	r := &Runner{
-		move:   m,
-		logger: slog.Default(),
+		move:        m,
+		logger:      slog.Default(),
+		metricsSink: &metrics.NoopSink{},
+	}
+	if m.MetricsSink == "prometheus" {
+		r.metricsSink = metrics.NewPrometheusSink()
+	}
+	if m.CutoverWindow != "" {
+		var err error
+		if r.cutoverWindow, err = utils.ParseWindow(m.CutoverWindow); err != nil {
+			return nil, fmt.Errorf("--cutover-window: %w", err)
+		}
 	}
 	return r, nil
 }
@@ -169,6 +194,13 @@ func (r *Runner) Close() error {
 	// rest, leaking the remaining repl clients' binlog reader goroutines
 	// and the target DB handles.
 	var errs []error
+	// Stop the HTTP API first so no request is served against a runner
+	// whose connections are being torn down.
+	if r.httpServer != nil {
+		if err := r.httpServer.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
 	if r.copyChunker != nil {
 		if err := r.copyChunker.Close(); err != nil {
 			errs = append(errs, err)
@@ -368,7 +400,7 @@ func (r *Runner) resumeFromCheckpoint(ctx context.Context) error {
 		TargetChunkTime: r.move.TargetChunkTime,
 		Logger:          r.logger,
 		Throttler:       &throttler.Noop{},
-		MetricsSink:     &metrics.NoopSink{},
+		MetricsSink:     r.metricsSink,
 		DBConfig:        r.dbConfig,
 		Applier:         r.applier, // Use the shared applier
 		Unbuffered:      false,     // move always uses the buffered copier
@@ -484,6 +516,7 @@ func (r *Runner) setup(ctx context.Context) error {
 	if err := r.runChecks(ctx, check.ScopePreflight); err != nil {
 		return err
 	}
+	r.runHook(ctx, hooks.PreflightComplete, nil)
 
 	// Fetch the canonical table list from sources[0].
 	// All sources have identical schemas (validated by source_schema_consistency check).
@@ -630,7 +663,7 @@ func (r *Runner) newCopy(ctx context.Context) error {
 		TargetChunkTime: r.move.TargetChunkTime,
 		Logger:          r.logger,
 		Throttler:       &throttler.Noop{},
-		MetricsSink:     &metrics.NoopSink{},
+		MetricsSink:     r.metricsSink,
 		DBConfig:        r.dbConfig,
 		Applier:         r.applier, // Use the shared applier
 		Unbuffered:      false,     // move always uses the buffered copier
@@ -677,7 +710,22 @@ func (r *Runner) createCheckpointTable(ctx context.Context) error {
 	return nil
 }
 
+// Run runs the move, and then its failure or success hook.
 func (r *Runner) Run(ctx context.Context) error {
+	r.hooks = hooks.New(r.move.hooksConfig(), r.logger)
+	err := r.run(ctx)
+	// A failed move's context is often done (e.g. it was cancelled), but
+	// its failure hook still has to run.
+	hookCtx := context.WithoutCancel(ctx)
+	if err != nil {
+		r.runHook(hookCtx, hooks.Failure, err)
+	} else {
+		r.runHook(hookCtx, hooks.Success, nil)
+	}
+	return err
+}
+
+func (r *Runner) run(ctx context.Context) error {
 	ctx, r.cancelFunc = context.WithCancel(ctx)
 	defer r.cancelFunc()
 	r.startTime = time.Now()
@@ -689,6 +737,7 @@ func (r *Runner) Run(ctx context.Context) error {
 		"go", bi.GoVer,
 		"dirty", bi.Modified,
 	)
+	r.runHook(ctx, hooks.Startup, nil)
 
 	var err error
 	r.dbConfig = dbconn.NewDBConfig()
@@ -696,6 +745,21 @@ func (r *Runner) Run(ctx context.Context) error {
 	// Buffered copier needs more connections due to parallel read/write workers
 	r.dbConfig.MaxOpenConnections = r.move.Threads + r.move.WriteThreads + 2
 
+	// Start the HTTP status/control API before connecting so operators can
+	// see the setup phases too. It is stopped in Close().
+	if r.move.HTTPListen != "" {
+		r.httpServer = status.NewServer(r, r.move.HTTPToken, r.logger)
+		if prom, ok := r.metricsSink.(*metrics.PrometheusSink); ok {
+			r.httpServer.Handle("GET /metrics", prom)
+		}
+		if err := r.httpServer.Start(r.move.HTTPListen); err != nil {
+			return fmt.Errorf("could not start http server on %s: %w", r.move.HTTPListen, err)
+		}
+	}
+	r.metricsSink = metrics.NewLabeledSink(r.metricsSink, func() map[string]string {
+		return map[string]string{metrics.PhaseLabel: r.status.Get().String()}
+	})
+
 	// Build the list of source DSNs. If SourceDSNs is set (N:M), use it.
 	// Otherwise, use SourceDSN as the single source (backward compat).
 	sourceDSNs := r.move.SourceDSNs
@@ -777,12 +841,16 @@ func (r *Runner) Run(ctx context.Context) error {
 		// But the caller will still want their cutoverFunc called. So we do that
 		// and then exit.
 		r.logger.Info("No tables to copy, proceeding directly to cutover")
+		if err := r.hooks.Run(ctx, r.hookEvent(hooks.BeforeCutover, nil)); err != nil {
+			return fmt.Errorf("cutover blocked: %w", err)
+		}
 		r.status.Set(status.CutOver)
 		if r.cutoverFunc != nil {
 			if err := r.cutoverFunc(ctx); err != nil {
 				return err
 			}
 		}
+		r.runHook(ctx, hooks.AfterCutover, nil)
 		r.logger.Info("Move operation complete.")
 		return nil
 	}
@@ -815,7 +883,7 @@ func (r *Runner) Run(ctx context.Context) error {
 	}
 
 	r.status.Set(status.CopyRows)
-	if err := r.copier.Run(ctx); err != nil {
+	if err := r.copyRows(ctx); err != nil {
 		return err
 	}
 
@@ -831,6 +899,7 @@ func (r *Runner) Run(ctx context.Context) error {
 	}
 
 	r.logger.Info("All tables copied successfully")
+	r.runHook(ctx, hooks.CopyComplete, nil)
 
 	// Post-copy phase: drain the binlog, restore secondary indexes,
 	// ANALYZE TABLE, run the initial checksum. While the sentinel blocks
@@ -840,6 +909,7 @@ func (r *Runner) Run(ctx context.Context) error {
 		return err
 	}
 	r.logger.Info("Initial checksum completed successfully")
+	r.runHook(ctx, hooks.ChecksumComplete, nil)
 
 	r.sentinelWaitStartTime = time.Now()
 	r.status.Set(status.WaitingOnSentinelTable)
@@ -849,7 +919,6 @@ func (r *Runner) Run(ctx context.Context) error {
 
 	r.logger.Info("Sentinel released, starting cutover")
 	// Create a cutover.
-	r.status.Set(status.CutOver)
 	cutoverSources := make([]CutOverSource, len(r.sources))
 	for i := range r.sources {
 		cutoverSources[i] = CutOverSource{
@@ -862,9 +931,35 @@ func (r *Runner) Run(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	if err = cutover.Run(ctx); err != nil {
-		return err
+	cutover.metricsSink = r.metricsSink
+	cutover.window = r.cutoverWindow
+	cutover.onLocked = r.enterCutOver
+	for {
+		// With a --cutover-window, wait for it to open. The continuous
+		// checksum runs in the meantime, as it does during the sentinel wait.
+		if err := r.waitOnCutoverWindow(ctx); err != nil {
+			return err
+		}
+		// A failing before-cutover hook blocks the cutover. The
+		// checkpoint is still in place, so the move can resume.
+		if err := r.hooks.Run(ctx, r.hookEvent(hooks.BeforeCutover, nil)); err != nil {
+			return fmt.Errorf("cutover blocked: %w", err)
+		}
+		// The window is checked before each attempt, and the state only
+		// becomes CutOver once an attempt has locked the source tables.
+		err = cutover.Run(ctx)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, errOutsideCutoverWindow) {
+			return err
+		}
+		// No attempt got as far as locking the tables, so neither the
+		// cutover function nor the rename ran, and we are still before
+		// CutOver and checkpointing: wait for the next window.
+		r.logger.Warn("cutover window closed before cutover succeeded; waiting for the next window", "error", err)
 	}
+	r.runHook(ctx, hooks.AfterCutover, nil)
 	// Delete checkpoint table from targets[0].
 	tgt0 := &r.targets[0]
 	if err := dbconn.Exec(ctx, tgt0.DB, "DROP TABLE IF EXISTS %n.%n", tgt0.Config.DBName, checkpointTableName); err != nil {
@@ -874,6 +969,36 @@ func (r *Runner) Run(ctx context.Context) error {
 	return nil
 }
 
+// hookEvent describes the move to a hook at point. err is the error that
+// the move failed with, if any.
+func (r *Runner) hookEvent(point hooks.Point, err error) hooks.Event {
+	event := hooks.Event{
+		Point:    point,
+		Command:  "move",
+		Tables:   r.move.SourceTables,
+		State:    r.status.Get().String(),
+		Progress: r.Progress().Summary,
+	}
+	if len(r.sourceTables) > 0 {
+		event.Tables = make([]string, 0, len(r.sourceTables))
+		for _, tbl := range r.sourceTables {
+			event.Tables = append(event.Tables, tbl.TableName)
+		}
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	return event
+}
+
+// runHook runs the hooks at point. Only a failing before-cutover hook
+// stops the move, so the errors of the others are just logged.
+func (r *Runner) runHook(ctx context.Context, point hooks.Point, err error) {
+	if err := r.hooks.Run(ctx, r.hookEvent(point, err)); err != nil {
+		r.logger.Error("hook failed", "hook", point, "error", err)
+	}
+}
+
 // startBackgroundRoutines starts the background routines needed for monitoring.
 // This includes table statistics updates and periodic binlog flushing.
 func (r *Runner) startBackgroundRoutines(ctx context.Context) {
@@ -966,6 +1091,13 @@ func (r *Runner) Status() string {
 			time.Since(r.sentinelWaitStartTime).Round(time.Second),
 			sentinelWaitLimit,
 		)
+	case status.WaitingOnCutoverWindow:
+		return fmt.Sprintf("migration status: state=%s cutover-window=%q cutover-window-opens=%s total-time=%s",
+			r.status.Get().String(),
+			r.cutoverWindow.String(),
+			r.cutoverWindow.NextOpen(time.Now()).Format(time.RFC3339),
+			time.Since(r.startTime).Round(time.Second),
+		)
 	case status.ApplyChangeset, status.PostChecksum:
 		// We've finished copying rows, and we are now trying to reduce the number of binlog deltas before
 		// proceeding to the checksum and then the final cutover.
@@ -997,6 +1129,36 @@ func (r *Runner) SetLogger(logger *slog.Logger) {
 	r.logger = logger
 }
 
+func (r *Runner) SetMetricsSink(sink metrics.Sink) {
+	r.metricsSink = sink
+}
+
+// copyRows runs the copier, re-running the ScopeCopyRows checks every
+// copyRecheckInterval until it is done.
+func (r *Runner) copyRows(ctx context.Context) error {
+	recheckCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		ticker := time.NewTicker(copyRecheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-recheckCtx.Done():
+				return
+			case <-ticker.C:
+				// The copy rows checks only warn, so an error doesn't stop the copy.
+				if err := r.runChecks(recheckCtx, check.ScopeCopyRows); err != nil {
+					r.logger.Warn("copy rows checks failed", "error", err)
+				}
+			}
+		}
+	})
+	err := r.copier.Run(ctx)
+	cancel()
+	wg.Wait()
+	return err
+}
+
 // runChecks wraps around check.RunChecks and adds the context of this move operation
 func (r *Runner) runChecks(ctx context.Context, scope check.ScopeFlag) error {
 	sources := make([]check.SourceResource, len(r.sources))
@@ -1007,14 +1169,19 @@ func (r *Runner) runChecks(ctx context.Context, scope check.ScopeFlag) error {
 			DSN:    r.sources[i].dsn,
 		}
 	}
-	return check.RunChecks(ctx, check.Resources{
+	res := check.Resources{
 		Sources:        sources,
 		Targets:        r.targets,
 		SourceTables:   r.sourceTables,
 		CreateSentinel: r.move.CreateSentinel,
 		GTID:           r.move.EnableExperimentalGTID,
 		MoveEverything: len(r.move.SourceTables) == 0,
-	}, r.logger, scope)
+	}
+	if scope == check.ScopeCopyRows {
+		res.CopyElapsed = time.Since(r.copier.StartTime())
+		res.CopyETA, _ = r.copier.GetLatestETA()
+	}
+	return check.RunChecks(ctx, res, r.logger, scope)
 }
 
 // restoreSecondaryIndexes restores any secondary indexes that were deferred during table creation.
@@ -1194,6 +1361,8 @@ func (r *Runner) postCopyPhase(ctx context.Context) error {
 		Logger:          r.logger,
 		Applier:         r.applier,
 		FixDifferences:  true,
+		DiffFile:        r.move.ChecksumDiffFile,
+		DiffFormat:      r.move.ChecksumDiffFormat,
 	})
 	if err != nil {
 		return err
@@ -1269,6 +1438,8 @@ func (r *Runner) Progress() status.Progress {
 			"sentinel-wait-time", time.Since(r.sentinelWaitStartTime).Round(time.Second).String(),
 			"sentinel-max-wait-time", sentinelWaitLimit.String(),
 		)
+	case status.WaitingOnCutoverWindow:
+		summary = "Waiting on Cutover Window"
 	case status.ApplyChangeset, status.PostChecksum:
 		summary = fmt.Sprintf("Applying Changeset Deltas=%v", r.getDeltaLenAll())
 	case status.Checksum:
@@ -1308,16 +1479,9 @@ func (r *Runner) sentinelTableExists(ctx context.Context) (bool, error) {
 }
 
 // Check every sentinelCheckInterval up to sentinelWaitLimit to see if sentinelTable has been dropped.
-// While we wait, run a "continuous checksum" loop in the background as a
-// best-effort consistency re-check. The continuous checksum is purely
-// opportunistic — the initial checksum (already run in postCopyPhase) is
-// the correctness gate. The continuous loop is cancelled when the sentinel
-// drops; any in-flight chunk recopy runs under context.WithoutCancel up to
-// fixChunkTimeout so the DELETE-from-targets + re-apply-from-sources pair
-// stays atomic, then the goroutine exits. A real "checksum found
-// differences" surfaced from that in-flight repair is promoted into retErr
-// and aborts cutover.
-func (r *Runner) waitOnSentinelTable(ctx context.Context) (retErr error) {
+// While we wait, run a "continuous checksum" loop in the background (see
+// waitWithContinuousChecksum).
+func (r *Runner) waitOnSentinelTable(ctx context.Context) error {
 	if sentinelExists, err := r.sentinelTableExists(ctx); err != nil {
 		return err
 	} else if !sentinelExists {
@@ -1328,9 +1492,64 @@ func (r *Runner) waitOnSentinelTable(ctx context.Context) (retErr error) {
 	r.logger.Warn("cutover deferred while sentinel table exists; will wait",
 		"sentinel-table", sentinelTableName,
 		"wait-limit", sentinelWaitLimit.String())
+	return r.waitWithContinuousChecksum(ctx, sentinelWaitLimit,
+		errors.New("timed out waiting for sentinel table to be dropped"),
+		func(ctx context.Context) (bool, error) {
+			sentinelExists, err := r.sentinelTableExists(ctx)
+			if err != nil {
+				return false, err
+			}
+			if !sentinelExists {
+				r.logger.Info("sentinel table dropped", "time", time.Now())
+			}
+			return !sentinelExists, nil
+		})
+}
 
+// enterCutOver sets the state to CutOver once a cutover attempt holds the
+// source table locks. The state never moves back from CutOver, and the
+// periodic checkpoint stops there, so it is under checkpointMu: no
+// checkpoint is written after the state is set.
+func (r *Runner) enterCutOver() {
+	r.checkpointMu.Lock()
+	defer r.checkpointMu.Unlock()
+	r.status.Set(status.CutOver)
+}
+
+// waitOnCutoverWindow waits until the --cutover-window is open, running
+// the continuous checksum in the meantime. There is no limit on how long
+// it waits: the window is at most a week away.
+func (r *Runner) waitOnCutoverWindow(ctx context.Context) error {
+	if r.cutoverWindow == nil || r.cutoverWindow.Contains(time.Now()) {
+		return nil
+	}
+	r.status.Set(status.WaitingOnCutoverWindow)
+	r.logger.Warn("outside the cutover window; will wait",
+		"cutover-window", r.cutoverWindow.String(),
+		"opens", r.cutoverWindow.NextOpen(time.Now()).Format(time.RFC3339))
+	return r.waitWithContinuousChecksum(ctx, 0, nil, func(_ context.Context) (bool, error) {
+		if !r.cutoverWindow.Contains(time.Now()) {
+			return false, nil
+		}
+		r.logger.Info("cutover window opened", "cutover-window", r.cutoverWindow.String())
+		return true, nil
+	})
+}
+
+// waitWithContinuousChecksum polls ready every sentinelCheckInterval until
+// it returns true, or until limit (if non-zero) has passed, in which case
+// it returns limitErr. While we wait, run a "continuous checksum" loop in
+// the background as a best-effort consistency re-check. The continuous
+// checksum is purely opportunistic — the initial checksum (already run in
+// postCopyPhase) is the correctness gate. The continuous loop is cancelled
+// when the wait ends; any in-flight chunk recopy runs under
+// context.WithoutCancel up to fixChunkTimeout so the DELETE-from-targets +
+// re-apply-from-sources pair stays atomic, then the goroutine exits. A real
+// "checksum found differences" surfaced from that in-flight repair is
+// promoted into retErr and aborts cutover.
+func (r *Runner) waitWithContinuousChecksum(ctx context.Context, limit time.Duration, limitErr error, ready func(context.Context) (bool, error)) (retErr error) {
 	// Spawn the continuous checksum. It uses its own checker + chunker and is
-	// not wired into the checkpoint — so a crash during sentinel wait does
+	// not wired into the checkpoint — so a crash during the wait does
 	// not add mandatory checksum time on resume. The checker manages its own
 	// periodic-flush lifecycle per iteration; runContinuousChecksum drives
 	// flushes during the inter-iteration wait so binlog deltas don't pile up
@@ -1343,7 +1562,7 @@ func (r *Runner) waitOnSentinelTable(ctx context.Context) (retErr error) {
 		continuousErr = r.runContinuousChecksum(continuousCtx)
 	}()
 
-	// runContinuousChecksum already filters harmless sentinel cancellations
+	// runContinuousChecksum already filters harmless end-of-wait cancellations
 	// to nil, so any non-nil continuousErr is one it intentionally chose to
 	// propagate — surface it as retErr whenever the parent ctx itself has
 	// not been cancelled (parent cancellation is its own error path).
@@ -1377,30 +1596,33 @@ func (r *Runner) waitOnSentinelTable(ctx context.Context) (retErr error) {
 		}
 	}()
 
-	timer := time.NewTimer(sentinelWaitLimit)
-	defer timer.Stop() // Ensure timer is always stopped to prevent goroutine leak
+	var limitC <-chan time.Time // nil, and never ready, without a limit
+	if limit > 0 {
+		timer := time.NewTimer(limit)
+		defer timer.Stop() // Ensure timer is always stopped to prevent goroutine leak
+		limitC = timer.C
+	}
 
 	ticker := time.NewTicker(sentinelCheckInterval)
 	defer ticker.Stop()
 	for {
 		select {
-		case t := <-ticker.C:
-			sentinelExists, err := r.sentinelTableExists(ctx)
+		case <-ticker.C:
+			isReady, err := ready(ctx)
 			if err != nil {
 				return err
 			}
-			if !sentinelExists {
-				// Sentinel table has been dropped, we can proceed with cutover.
-				// The defer above still observes continuousErr — if a continuous
-				// pass was mid-recopy and surfaces a real drift error, that
+			if isReady {
+				// We can proceed with cutover. The defer above still
+				// observes continuousErr — if a continuous pass was
+				// mid-recopy and surfaces a real drift error, that
 				// overrides this nil return.
-				r.logger.Info("sentinel table dropped", "time", t)
 				return nil
 			}
-		case <-timer.C:
-			return errors.New("timed out waiting for sentinel table to be dropped")
+		case <-limitC:
+			return limitErr
 		case <-continuousDone:
-			// Continuous goroutine exited before the sentinel was dropped.
+			// Continuous goroutine exited before the wait was over.
 			// If our parent ctx is cancelled, the goroutine just propagated
 			// that cancellation — surface the parent's error directly.
 			if err := ctx.Err(); err != nil {
@@ -1446,7 +1668,7 @@ func (r *Runner) invalidateChecksumWatermark(ctx context.Context) error {
 // runContinuousChecksum loops calling a fresh distributed checker over the
 // source/target tables for as long as ctx is alive. It is the "continuous"
 // half of the two-checksum model (see docs/move.md) and is only called while
-// the move is blocked in WaitingOnSentinelTable.
+// the move is blocked in WaitingOnSentinelTable or WaitingOnCutoverWindow.
 //
 // The checker used here is separate from r.checker and uses a fresh chunker
 // so checkpoint state is unaffected. Single-threaded by design — checksum
@@ -1664,6 +1886,97 @@ func (r *Runner) Cancel() {
 	r.cancelFunc()
 }
 
+// setupComplete reports whether setup has finished assigning the copier
+// and the per-source replication clients, so they can be read from other
+// goroutines (the HTTP API). The atomic status store that moves past
+// Initial happens after those assignments.
+func (r *Runner) setupComplete() bool {
+	state := r.status.Get()
+	return state >= status.CopyRows && state <= status.CutOver
+}
+
+// ThrottlerStatus implements status.ThrottlerStatusProvider.
+func (r *Runner) ThrottlerStatus() status.ThrottlerStatus {
+	// copier is nil when there were no tables to move (straight to cutover).
+	if !r.setupComplete() || r.copier == nil {
+		return status.ThrottlerStatus{}
+	}
+	t := r.copier.GetThrottler()
+	ts := status.ThrottlerStatus{Throttled: t.IsThrottled()}
+	if gradual, ok := t.(throttler.GradualThrottler); ok {
+		ts.Gradual = true
+		ts.Utilization = gradual.Utilization()
+	}
+	return ts
+}
+
+// ChecksumStatus implements status.ChecksumStatusProvider. The checker is
+// only built in postCopyPhase, so nothing is reported before then.
+func (r *Runner) ChecksumStatus() status.ChecksumStatus {
+	state := r.status.Get()
+	if state < status.Checksum || state > status.CutOver || r.checker == nil {
+		return status.ChecksumStatus{}
+	}
+	cs := status.ChecksumStatus{
+		Running:          state == status.Checksum,
+		Progress:         r.checker.GetProgress(),
+		DifferencesFound: r.checker.DifferencesFound(),
+		ExecTime:         r.checker.ExecTime(),
+	}
+	r.checkpointMu.Lock()
+	if r.continuousChecker != nil {
+		cs.DifferencesFound += r.continuousChecker.DifferencesFound()
+	}
+	r.checkpointMu.Unlock()
+	return cs
+}
+
+// SendMetrics implements status.MetricsReporter. Replication metrics are
+// labelled by source, since an N:M move reads from several.
+func (r *Runner) SendMetrics(ctx context.Context) {
+	if !r.setupComplete() {
+		return
+	}
+	var values []metrics.MetricValue
+	for _, src := range r.sources {
+		sp, ok := src.replClient.(change.StatsProvider)
+		if !ok {
+			continue
+		}
+		for _, v := range sp.Stats().MetricValues() {
+			if v.Labels == nil {
+				v.Labels = make(map[string]string, 1)
+			}
+			v.Labels[metrics.SourceLabel] = src.sourceKey()
+			values = append(values, v)
+		}
+	}
+	if r.status.Get() == status.Checksum && r.checker != nil {
+		values = append(values, metrics.MetricValue{
+			Name:  metrics.ChecksumChunksRemainingMetricName,
+			Type:  metrics.GAUGE,
+			Value: float64(r.checker.ChunksRemaining()),
+		})
+	}
+	sendCtx, cancel := context.WithTimeout(ctx, metrics.SinkTimeout)
+	defer cancel()
+	if err := r.metricsSink.Send(sendCtx, &metrics.Metrics{Values: values}); err != nil {
+		r.logger.Debug("failed to send metrics", "error", err)
+	}
+}
+
+// ReleaseCutover implements status.CutoverReleaser by dropping the
+// sentinel table on the source, exactly as an operator would by hand.
+func (r *Runner) ReleaseCutover(ctx context.Context) error {
+	if !r.setupComplete() {
+		return errors.New("move has not finished setup yet; nothing to release")
+	}
+	r.logger.Warn("dropping sentinel table to release deferred cutover",
+		"sentinel-table", sentinelTableName,
+	)
+	return dbconn.Exec(ctx, r.sources[0].db, "DROP TABLE IF EXISTS %n.%n", r.sources[0].config.DBName, sentinelTableName)
+}
+
 // createApplier creates the appropriate applier based on the number of targets.
 // Note: The applier is NOT started here. The copier will start it when it begins copying.
 func (r *Runner) createApplier() (applier.Applier, error) {
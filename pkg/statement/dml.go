@@ -0,0 +1,116 @@
+package statement
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser"
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/format"
+)
+
+var (
+	ErrNotSingleTableDML = errors.New("not a single-table UPDATE or DELETE statement")
+	ErrDMLOrderOrLimit   = errors.New("UPDATE and DELETE statements with ORDER BY or LIMIT can't be split into chunks")
+)
+
+// DMLStatement is a single-table UPDATE or DELETE, split so that it can be
+// run one chunk of the table at a time: Base is the statement without its
+// WHERE clause, and Where is the condition of the WHERE clause, if any.
+type DMLStatement struct {
+	Type   StatementType // StatementUpdate or StatementDelete
+	Schema string        // empty unless the table name is fully qualified
+	Table  string
+	Base   string
+	Where  string
+	// SetColumns are the columns that an UPDATE assigns to.
+	SetColumns []string
+}
+
+// ParseDML parses sql, which must be a single UPDATE or DELETE of one
+// table, without ORDER BY or LIMIT.
+func ParseDML(sql string) (*DMLStatement, error) {
+	p := parser.New()
+	node, err := p.ParseOneStmt(sql, "", "")
+	if err != nil {
+		return nil, err
+	}
+	dml := &DMLStatement{}
+	var refs *ast.TableRefsClause
+	var where ast.ExprNode
+	switch stmt := node.(type) {
+	case *ast.UpdateStmt:
+		if stmt.MultipleTable || stmt.With != nil {
+			return nil, ErrNotSingleTableDML
+		}
+		if stmt.Order != nil || stmt.Limit != nil {
+			return nil, ErrDMLOrderOrLimit
+		}
+		dml.Type = StatementUpdate
+		refs, where = stmt.TableRefs, stmt.Where
+		for _, assignment := range stmt.List {
+			dml.SetColumns = append(dml.SetColumns, assignment.Column.Name.O)
+		}
+		stmt.Where = nil
+	case *ast.DeleteStmt:
+		if stmt.IsMultiTable || stmt.With != nil {
+			return nil, ErrNotSingleTableDML
+		}
+		if stmt.Order != nil || stmt.Limit != nil {
+			return nil, ErrDMLOrderOrLimit
+		}
+		dml.Type = StatementDelete
+		refs, where = stmt.TableRefs, stmt.Where
+		stmt.Where = nil
+	default:
+		return nil, ErrNotSingleTableDML
+	}
+	if refs == nil || refs.TableRefs == nil || refs.TableRefs.Right != nil {
+		return nil, ErrNotSingleTableDML
+	}
+	src, ok := refs.TableRefs.Left.(*ast.TableSource)
+	if !ok {
+		return nil, ErrNotSingleTableDML
+	}
+	tn, ok := src.Source.(*ast.TableName)
+	if !ok {
+		return nil, ErrNotSingleTableDML
+	}
+	dml.Schema, dml.Table = tn.Schema.O, tn.Name.O
+	if dml.Base, err = restore(node); err != nil {
+		return nil, fmt.Errorf("could not restore statement: %w", err)
+	}
+	if where != nil {
+		if dml.Where, err = restore(where); err != nil {
+			return nil, fmt.Errorf("could not restore WHERE clause: %w", err)
+		}
+	}
+	return dml, nil
+}
+
+// String returns the normalized statement.
+func (d *DMLStatement) String() string {
+	if d.Where == "" {
+		return d.Base
+	}
+	return d.Base + " WHERE " + d.Where
+}
+
+// ChunkStatement returns the statement restricted to the rows of the
+// table that match cond, e.g. the condition of a chunk.
+func (d *DMLStatement) ChunkStatement(cond string) string {
+	if d.Where == "" {
+		return d.Base + " WHERE " + cond
+	}
+	return d.Base + " WHERE " + cond + " AND (" + d.Where + ")"
+}
+
+// restore returns the SQL text of node.
+func restore(node ast.Node) (string, error) {
+	var sb strings.Builder
+	if err := node.Restore(format.NewRestoreCtx(format.DefaultRestoreFlags, &sb)); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,50 @@
+package statement
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDML(t *testing.T) {
+	dml, err := ParseDML("UPDATE t1 SET a = b + 1, c = NULL WHERE b > 10")
+	require.NoError(t, err)
+	require.Equal(t, &DMLStatement{
+		Type:       StatementUpdate,
+		Table:      "t1",
+		Base:       "UPDATE `t1` SET `a`=`b`+1, `c`=NULL",
+		Where:      "`b`>10",
+		SetColumns: []string{"a", "c"},
+	}, dml)
+	require.Equal(t, "UPDATE `t1` SET `a`=`b`+1, `c`=NULL WHERE `b`>10", dml.String())
+	require.Equal(t, "UPDATE `t1` SET `a`=`b`+1, `c`=NULL WHERE `id`>=1 AND `id`<100 AND (`b`>10)", dml.ChunkStatement("`id`>=1 AND `id`<100"))
+
+	dml, err = ParseDML("DELETE FROM test.t1")
+	require.NoError(t, err)
+	require.Equal(t, &DMLStatement{
+		Type:   StatementDelete,
+		Schema: "test",
+		Table:  "t1",
+		Base:   "DELETE FROM `test`.`t1`",
+	}, dml)
+	require.Equal(t, "DELETE FROM `test`.`t1` WHERE `id`>=1", dml.ChunkStatement("`id`>=1"))
+
+	for _, sql := range []string{
+		"SELECT * FROM t1",
+		"INSERT INTO t1 VALUES (1)",
+		"UPDATE t1 JOIN t2 ON t1.a = t2.a SET t1.b = t2.b",
+		"UPDATE t1, t2 SET t1.b = t2.b",
+		"DELETE t1 FROM t1 JOIN t2 ON t1.a = t2.a",
+		"DELETE FROM t1 WHERE a = 1; DELETE FROM t2",
+	} {
+		_, err := ParseDML(sql)
+		require.Error(t, err, sql)
+	}
+	for _, sql := range []string{
+		"UPDATE t1 SET a = 1 ORDER BY b",
+		"DELETE FROM t1 WHERE a = 1 LIMIT 10",
+	} {
+		_, err := ParseDML(sql)
+		require.ErrorIs(t, err, ErrDMLOrderOrLimit, sql)
+	}
+}
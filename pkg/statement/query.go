@@ -0,0 +1,106 @@
+package statement
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser"
+	"github.com/pingcap/tidb/pkg/parser/ast"
+)
+
+// ErrNotQuery is returned for a statement that EXPLAIN can't show the plan
+// of, i.e. one that is not a SELECT, UPDATE or DELETE.
+var ErrNotQuery = errors.New("not a SELECT, UPDATE or DELETE statement")
+
+// SplitQueries parses the semicolon-separated queries in sql, and returns
+// the text of each.
+func SplitQueries(sql string) ([]string, error) {
+	p := parser.New()
+	stmtNodes, _, err := p.Parse(sql, "", "")
+	if err != nil {
+		return nil, err
+	}
+	queries := make([]string, 0, len(stmtNodes))
+	for _, node := range stmtNodes {
+		if !isQuery(node) {
+			return nil, fmt.Errorf("%w: %s", ErrNotQuery, node.Text())
+		}
+		queries = append(queries, strings.TrimSpace(node.Text()))
+	}
+	return queries, nil
+}
+
+// ReplaceTable returns query with each reference to the table schema.name
+// replaced by a reference to newName in the same schema. Unqualified
+// references are to defaultSchema. A replaced reference without an alias is
+// aliased to the table's name, so that the columns qualified with it still
+// resolve, and so that EXPLAIN shows the same table name for both versions
+// of the query. It returns false if query doesn't reference the table.
+func ReplaceTable(query, defaultSchema, schema, name, newName string) (string, bool, error) {
+	p := parser.New()
+	node, err := p.ParseOneStmt(query, "", "")
+	if err != nil {
+		return "", false, err
+	}
+	if !isQuery(node) {
+		return "", false, ErrNotQuery
+	}
+	r := &tableReplacer{defaultSchema: defaultSchema, schema: schema, name: name, newName: newName}
+	node.Accept(r)
+	if !r.replaced {
+		return query, false, nil
+	}
+	restored, err := restore(node)
+	if err != nil {
+		return "", false, fmt.Errorf("could not restore query: %w", err)
+	}
+	return restored, true, nil
+}
+
+// isQuery returns true if node is a statement that EXPLAIN can show the
+// plan of.
+func isQuery(node ast.StmtNode) bool {
+	switch node.(type) {
+	case *ast.SelectStmt, *ast.SetOprStmt, *ast.UpdateStmt, *ast.DeleteStmt:
+		return true
+	}
+	return false
+}
+
+// tableReplacer is an ast.Visitor that replaces the table sources that
+// reference a table. The table names outside of table sources, e.g. the
+// targets of a multi-table DELETE, refer to the sources' aliases and so
+// are left as they are.
+type tableReplacer struct {
+	defaultSchema, schema, name, newName string
+	replaced                             bool
+}
+
+func (r *tableReplacer) Enter(in ast.Node) (ast.Node, bool) {
+	src, ok := in.(*ast.TableSource)
+	if !ok {
+		return in, false
+	}
+	tn, ok := src.Source.(*ast.TableName)
+	if !ok {
+		return in, false
+	}
+	schema := tn.Schema.O
+	if schema == "" {
+		schema = r.defaultSchema
+	}
+	if !strings.EqualFold(schema, r.schema) || !strings.EqualFold(tn.Name.O, r.name) {
+		return in, false
+	}
+	if src.AsName.O == "" {
+		src.AsName = tn.Name
+	}
+	tn.Name = ast.NewCIStr(r.newName)
+	r.replaced = true
+	return in, false
+}
+
+func (r *tableReplacer) Leave(in ast.Node) (ast.Node, bool) {
+	return in, true
+}
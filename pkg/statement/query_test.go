@@ -0,0 +1,75 @@
+package statement
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitQueries(t *testing.T) {
+	queries, err := SplitQueries("SELECT * FROM t1 WHERE b = 'a;b';\n\nUPDATE t1 SET a = 1 WHERE b = 2;\nDELETE FROM t1 WHERE a > 3")
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"SELECT * FROM t1 WHERE b = 'a;b';",
+		"UPDATE t1 SET a = 1 WHERE b = 2;",
+		"DELETE FROM t1 WHERE a > 3",
+	}, queries)
+
+	_, err = SplitQueries("SELECT 1; INSERT INTO t1 VALUES (1)")
+	require.ErrorIs(t, err, ErrNotQuery)
+
+	_, err = SplitQueries("SELECT * FROM")
+	require.Error(t, err)
+}
+
+func TestReplaceTable(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+		replaced bool
+	}{
+		{
+			query:    "SELECT * FROM t1 WHERE a = 1",
+			expected: "SELECT * FROM `_t1_new` AS `t1` WHERE `a`=1",
+			replaced: true,
+		},
+		{
+			query:    "SELECT t1.a FROM test.t1 JOIN t2 ON t1.a = t2.a",
+			expected: "SELECT `t1`.`a` FROM `test`.`_t1_new` AS `t1` JOIN `t2` ON `t1`.`a`=`t2`.`a`",
+			replaced: true,
+		},
+		{
+			query:    "SELECT x.a FROM t1 x WHERE x.b IN (SELECT b FROM t1 WHERE c = 'y')",
+			expected: "SELECT `x`.`a` FROM `_t1_new` AS `x` WHERE `x`.`b` IN (SELECT `b` FROM `_t1_new` AS `t1` WHERE `c`=_UTF8MB4'y')",
+			replaced: true,
+		},
+		{
+			query:    "UPDATE t1 SET a = a + 1 WHERE b = 2",
+			expected: "UPDATE `_t1_new` AS `t1` SET `a`=`a`+1 WHERE `b`=2",
+			replaced: true,
+		},
+		{
+			query:    "DELETE t1 FROM t1 JOIN t2 ON t1.a = t2.a",
+			expected: "DELETE `t1` FROM `_t1_new` AS `t1` JOIN `t2` ON `t1`.`a`=`t2`.`a`",
+			replaced: true,
+		},
+		{
+			// A table of the same name in another schema.
+			query:    "SELECT * FROM other.t1",
+			expected: "SELECT * FROM other.t1",
+		},
+		{
+			query:    "SELECT * FROM t2",
+			expected: "SELECT * FROM t2",
+		},
+	}
+	for _, test := range tests {
+		query, replaced, err := ReplaceTable(test.query, "test", "test", "t1", "_t1_new")
+		require.NoError(t, err, test.query)
+		require.Equal(t, test.expected, query, test.query)
+		require.Equal(t, test.replaced, replaced, test.query)
+	}
+
+	_, _, err := ReplaceTable("INSERT INTO t1 VALUES (1)", "test", "test", "t1", "_t1_new")
+	require.ErrorIs(t, err, ErrNotQuery)
+}
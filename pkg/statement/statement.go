@@ -291,6 +291,60 @@ func (a *AbstractStatement) AlterContainsAddUnique() error {
 	return nil
 }
 
+// UniqueKey is a PRIMARY KEY or UNIQUE key that an ALTER adds.
+type UniqueKey struct {
+	Name    string   // PRIMARY, or empty if MySQL names it
+	Columns []string // the columns of the altered table, as typed in the ALTER
+}
+
+// AddedUniqueKeys returns the PRIMARY KEY and UNIQUE keys that an ALTER
+// adds, either as a constraint or as a column option. Keys with a prefix
+// or an expression part are not returned, since they aren't on the values
+// of the columns. Returns nil if this is not an ALTER TABLE statement.
+func (a *AbstractStatement) AddedUniqueKeys() []UniqueKey {
+	alterStmt, ok := (*a.StmtNode).(*ast.AlterTableStmt)
+	if !ok {
+		return nil
+	}
+	var keys []UniqueKey
+	for _, spec := range alterStmt.Specs {
+		switch spec.Tp { //nolint:exhaustive
+		case ast.AlterTableAddConstraint:
+			name := spec.Constraint.Name
+			switch spec.Constraint.Tp { //nolint:exhaustive
+			case ast.ConstraintPrimaryKey:
+				name = "PRIMARY"
+			case ast.ConstraintUniq, ast.ConstraintUniqKey, ast.ConstraintUniqIndex:
+			default:
+				continue
+			}
+			key := UniqueKey{Name: name}
+			for _, part := range spec.Constraint.Keys {
+				if part.Column == nil || part.Length > 0 {
+					key.Columns = nil
+					break
+				}
+				key.Columns = append(key.Columns, part.Column.Name.O)
+			}
+			if len(key.Columns) > 0 {
+				keys = append(keys, key)
+			}
+		case ast.AlterTableAddColumns, ast.AlterTableModifyColumn, ast.AlterTableChangeColumn:
+			for _, col := range spec.NewColumns {
+				for _, opt := range col.Options {
+					switch opt.Tp { //nolint:exhaustive
+					case ast.ColumnOptionPrimaryKey:
+						keys = append(keys, UniqueKey{Name: "PRIMARY", Columns: []string{col.Name.Name.O}})
+					case ast.ColumnOptionUniqKey:
+						keys = append(keys, UniqueKey{Columns: []string{col.Name.Name.O}})
+					}
+				}
+			}
+		}
+	}
+	return keys
+}
+
 // ColumnRenameMap returns a mapping of old column name → new column name
 // for any RENAME COLUMN or CHANGE COLUMN (with a different name) specs
 // in this ALTER TABLE statement. Returns nil if there are no renames
@@ -334,6 +388,70 @@ func (a *AbstractStatement) ColumnRenameMap() map[string]string {
 	return renames
 }
 
+// RedefinedColumns returns the columns whose definition an ALTER changes,
+// mapped from their name in the table to their name after the ALTER: the
+// columns of MODIFY COLUMN and CHANGE COLUMN specs. If the ALTER also
+// converts the table to another character set (CONVERT TO CHARACTER SET),
+// all of its string columns are redefined, and convertsCharset is true.
+// Like ColumnRenameMap, the names keep the case as typed in the ALTER.
+func (a *AbstractStatement) RedefinedColumns() (columns map[string]string, convertsCharset bool) {
+	alterStmt, ok := (*a.StmtNode).(*ast.AlterTableStmt)
+	if !ok {
+		return nil, false
+	}
+	for _, spec := range alterStmt.Specs {
+		switch spec.Tp { //nolint:exhaustive
+		case ast.AlterTableModifyColumn, ast.AlterTableChangeColumn:
+			if len(spec.NewColumns) == 0 {
+				continue
+			}
+			name := spec.NewColumns[0].Name.Name.O
+			oldName := name
+			if spec.OldColumnName != nil {
+				oldName = spec.OldColumnName.Name.O
+			}
+			if columns == nil {
+				columns = make(map[string]string)
+			}
+			columns[oldName] = name
+		case ast.AlterTableOption:
+			for _, opt := range spec.Options {
+				if opt.Tp == ast.TableOptionCharset && opt.UintValue == ast.TableOptionCharsetWithConvertTo {
+					convertsCharset = true
+				}
+			}
+		}
+	}
+	return columns, convertsCharset
+}
+
+// SetsStatsOptions returns the InnoDB persistent statistics options that
+// an ALTER sets, by their lower case name as in the CREATE_OPTIONS of
+// information_schema.tables, e.g. stats_persistent.
+func (a *AbstractStatement) SetsStatsOptions() map[string]bool {
+	alterStmt, ok := (*a.StmtNode).(*ast.AlterTableStmt)
+	if !ok {
+		return nil
+	}
+	options := make(map[string]bool)
+	for _, spec := range alterStmt.Specs {
+		if spec.Tp != ast.AlterTableOption {
+			continue
+		}
+		for _, opt := range spec.Options {
+			switch opt.Tp { //nolint:exhaustive
+			case ast.TableOptionStatsPersistent:
+				options["stats_persistent"] = true
+			case ast.TableOptionStatsSamplePages:
+				options["stats_sample_pages"] = true
+			case ast.TableOptionStatsAutoRecalc:
+				options["stats_auto_recalc"] = true
+			}
+		}
+	}
+	return options
+}
+
 func (a *AbstractStatement) TrimAlter() string {
 	return strings.TrimSuffix(strings.TrimSpace(a.Alter), ";")
 }
@@ -229,6 +229,21 @@ func TestAlterIsAddUnique(t *testing.T) {
 	require.ErrorIs(t, test("add unique(b)"), ErrAlterContainsUnique) // this is potentially lossy.
 }
 
+func TestAddedUniqueKeys(t *testing.T) {
+	var test = func(stmt string) []UniqueKey {
+		return MustNew("ALTER TABLE `t1` " + stmt)[0].AddedUniqueKeys()
+	}
+	require.Empty(t, test("ADD INDEX (a)"))
+	require.Empty(t, test("DROP INDEX a, ADD COLUMN b INT"))
+	require.Equal(t, []UniqueKey{{Columns: []string{"b"}}}, test("add unique(b)"))
+	require.Equal(t, []UniqueKey{{Name: "ab", Columns: []string{"a", "B"}}}, test("ADD UNIQUE KEY ab (a, B)"))
+	require.Equal(t, []UniqueKey{{Name: "PRIMARY", Columns: []string{"a", "b"}}}, test("DROP PRIMARY KEY, ADD PRIMARY KEY (a, b)"))
+	require.Equal(t, []UniqueKey{{Columns: []string{"c"}}, {Name: "PRIMARY", Columns: []string{"d"}}},
+		test("MODIFY c INT UNIQUE, CHANGE e d INT NOT NULL PRIMARY KEY"))
+	require.Empty(t, test("ADD UNIQUE INDEX (name(10))"))
+	require.Empty(t, test("ADD UNIQUE INDEX ((lower(name)))"))
+}
+
 func TestAlterContainsUnsupportedClause(t *testing.T) {
 	var test = func(stmt string) error {
 		return MustNew("ALTER TABLE `t1` " + stmt)[0].AlterContainsUnsupportedClause()
@@ -419,3 +434,30 @@ func TestColumnRenameMap(t *testing.T) {
 	renames = stmts[0].ColumnRenameMap()
 	require.Equal(t, map[string]string{"Foo": "Bar"}, renames)
 }
+
+func TestRedefinedColumns(t *testing.T) {
+	columns, convertsCharset := MustNew("ALTER TABLE t1 MODIFY a INT, CHANGE COLUMN b c VARCHAR(10), ADD COLUMN d INT")[0].RedefinedColumns()
+	require.Equal(t, map[string]string{"a": "a", "b": "c"}, columns)
+	require.False(t, convertsCharset)
+
+	columns, convertsCharset = MustNew("ALTER TABLE t1 CONVERT TO CHARACTER SET latin1")[0].RedefinedColumns()
+	require.Nil(t, columns)
+	require.True(t, convertsCharset)
+
+	// Changing the default character set doesn't change the columns.
+	columns, convertsCharset = MustNew("ALTER TABLE t1 DEFAULT CHARACTER SET latin1, RENAME COLUMN a TO b")[0].RedefinedColumns()
+	require.Nil(t, columns)
+	require.False(t, convertsCharset)
+
+	columns, convertsCharset = MustNew("CREATE TABLE t1 (a INT PRIMARY KEY)")[0].RedefinedColumns()
+	require.Nil(t, columns)
+	require.False(t, convertsCharset)
+}
+
+func TestSetsStatsOptions(t *testing.T) {
+	require.Equal(t, map[string]bool{"stats_persistent": true, "stats_sample_pages": true},
+		MustNew("ALTER TABLE t1 STATS_PERSISTENT=0, STATS_SAMPLE_PAGES=50, ADD INDEX (a)")[0].SetsStatsOptions())
+	require.Equal(t, map[string]bool{"stats_auto_recalc": true},
+		MustNew("ALTER TABLE t1 STATS_AUTO_RECALC=DEFAULT")[0].SetsStatsOptions())
+	require.Empty(t, MustNew("ALTER TABLE t1 ENGINE=InnoDB")[0].SetsStatsOptions())
+}
@@ -0,0 +1,82 @@
+package status
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Pause is an operator-requested hold on a task: while it is paused, the
+// copier stops starting new chunks and buffered changes are no longer
+// flushed, but the change source keeps reading into its subscriptions so
+// that no position is lost. Unlike being throttled, a pause only ends when
+// Resume is called.
+//
+// It is kept separate from State because a task can be paused in several
+// states (e.g. CopyRows or WaitingOnSentinelTable), and code that compares
+// states (`state >= CutOver`) must keep seeing how far the task has got.
+//
+// The zero value is not paused. A nil *Pause is never paused, so components
+// that accept one work unchanged for callers that don't support pausing.
+type Pause struct {
+	mu      sync.Mutex
+	resumed chan struct{} // non-nil while paused, closed by Resume
+	since   time.Time
+}
+
+// Pause pauses p. It returns false if p was already paused.
+func (p *Pause) Pause() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.resumed != nil {
+		return false
+	}
+	p.resumed = make(chan struct{})
+	p.since = time.Now()
+	return true
+}
+
+// Resume releases everything waiting on p. It returns false if p was not
+// paused.
+func (p *Pause) Resume() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.resumed == nil {
+		return false
+	}
+	close(p.resumed)
+	p.resumed = nil
+	return true
+}
+
+// Paused reports whether p is paused, and if so since when.
+func (p *Pause) Paused() (paused bool, since time.Time) {
+	if p == nil {
+		return false, time.Time{}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.resumed != nil, p.since
+}
+
+// Wait blocks while p is paused. It returns ctx.Err() if ctx is cancelled
+// first.
+func (p *Pause) Wait(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	for {
+		p.mu.Lock()
+		resumed := p.resumed
+		p.mu.Unlock()
+		if resumed == nil {
+			return nil
+		}
+		select {
+		case <-resumed:
+			// Loop, in case it was paused again before we woke up.
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package status
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPause(t *testing.T) {
+	var p Pause
+	paused, _ := p.Paused()
+	require.False(t, paused)
+	require.NoError(t, p.Wait(t.Context())) // doesn't block
+	require.False(t, p.Resume())
+
+	require.True(t, p.Pause())
+	require.False(t, p.Pause()) // already paused
+	paused, since := p.Paused()
+	require.True(t, paused)
+	require.WithinDuration(t, time.Now(), since, time.Minute)
+
+	waited := make(chan error)
+	go func() { waited <- p.Wait(t.Context()) }()
+	select {
+	case <-waited:
+		t.Fatal("Wait returned while paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+	require.True(t, p.Resume())
+	require.NoError(t, <-waited)
+
+	// Cancelling the context ends the wait.
+	require.True(t, p.Pause())
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	require.ErrorIs(t, p.Wait(ctx), context.Canceled)
+	require.True(t, p.Resume())
+}
+
+func TestNilPause(t *testing.T) {
+	var p *Pause
+	paused, _ := p.Paused()
+	require.False(t, paused)
+	require.NoError(t, p.Wait(t.Context()))
+}
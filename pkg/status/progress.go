@@ -6,6 +6,7 @@ package status
 type Progress struct {
 	CurrentState State  // current state, i.e. CopyRows
 	Summary      string // text based representation, i.e. "12.5% copyRows ETA 1h 30m"
+	Paused       bool   // true while an operator has paused the task, see Pause
 
 	// Tables contains per-table progress for multi-table migrations.
 	// For single-table migrations, this will have one entry.
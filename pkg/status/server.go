@@ -0,0 +1,424 @@
+package status
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// serverShutdownTimeout bounds how long Close waits for in-flight
+// requests to finish before the listener is torn down.
+var serverShutdownTimeout = 5 * time.Second
+
+// ErrNotSupported is returned by control handlers when the task does not
+// implement the optional capability the request needs (e.g. releasing a
+// deferred cutover on a sync, which has no cutover).
+var ErrNotSupported = errors.New("operation not supported by this task")
+
+// ThrottlerStatus is a point-in-time view of the throttler pacing a task.
+type ThrottlerStatus struct {
+	Throttled bool `json:"throttled"`
+	// Utilization is only meaningful when Gradual is true (the throttler
+	// provides a continuous load signal, e.g. on Aurora).
+	Gradual     bool    `json:"gradual"`
+	Utilization float64 `json:"utilization,omitempty"`
+}
+
+// ChecksumStatus is a point-in-time view of a task's checksum.
+type ChecksumStatus struct {
+	Running          bool          `json:"running"`
+	Progress         string        `json:"progress,omitempty"`
+	DifferencesFound uint64        `json:"differences_found"`
+	ExecTime         time.Duration `json:"exec_time_ns,omitempty"`
+}
+
+// ThrottlerStatusProvider is implemented by tasks that can report the
+// state of their throttler. It is optional: the server omits the field
+// for tasks that don't implement it.
+type ThrottlerStatusProvider interface {
+	ThrottlerStatus() ThrottlerStatus
+}
+
+// ChecksumStatusProvider is implemented by tasks that run a checksum.
+type ChecksumStatusProvider interface {
+	ChecksumStatus() ChecksumStatus
+}
+
+// CutoverReleaser is implemented by tasks that support a deferred cutover.
+// ReleaseCutover has the same effect as an operator dropping the sentinel
+// table by hand.
+type CutoverReleaser interface {
+	ReleaseCutover(ctx context.Context) error
+}
+
+// Pausable is implemented by tasks that an operator can pause (see Pause).
+// Both methods are idempotent. Pause returns an error if the task can't be
+// paused now, e.g. because it is already cutting over.
+type Pausable interface {
+	Pause(ctx context.Context) error
+	Resume(ctx context.Context) error
+}
+
+// Settings are the settings of a running task that can be changed
+// without restarting it. They mirror the flags of the same name.
+type Settings struct {
+	Threads         int
+	WriteThreads    int
+	TargetChunkTime time.Duration
+	ReplicaMaxLag   time.Duration
+}
+
+// Validate checks that every setting is positive. Zero is not accepted as
+// "use the default" the way it is on the command line, because on a
+// running task it is more likely a mistake than a request for the default.
+func (s Settings) Validate() error {
+	switch {
+	case s.Threads < 1:
+		return fmt.Errorf("threads must be at least 1, got %d", s.Threads)
+	case s.WriteThreads < 1:
+		return fmt.Errorf("write-threads must be at least 1, got %d", s.WriteThreads)
+	case s.TargetChunkTime <= 0:
+		return fmt.Errorf("target-chunk-time must be positive, got %s", s.TargetChunkTime)
+	case s.ReplicaMaxLag <= 0:
+		return fmt.Errorf("replica-max-lag must be positive, got %s", s.ReplicaMaxLag)
+	}
+	return nil
+}
+
+// Reconfigurable is implemented by tasks whose Settings can be changed
+// while they run. Reconfigure is always passed a complete, valid set of
+// Settings and applies those that differ from the current ones. It
+// returns an error without applying anything if the task can't apply
+// them now (e.g. it has not finished setup yet).
+type Reconfigurable interface {
+	Settings() Settings
+	Reconfigure(ctx context.Context, settings Settings) error
+}
+
+// Server exposes a running Task over HTTP: read-only progress as JSON,
+// plus authenticated POST endpoints to cancel, pause or resume the task,
+// force a checkpoint and release a deferred cutover. It is intended for
+// operators and wrappers that would otherwise have to parse the log
+// lines written by WatchTask.
+//
+// The control (POST) endpoints require "Authorization: Bearer <token>".
+// When no token is configured they are disabled and always return 403;
+// the read-only endpoints never require a token.
+type Server struct {
+	task   Task
+	token  string
+	logger *slog.Logger
+
+	mux      *http.ServeMux
+	srv      *http.Server
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	// configMu makes the read-modify-write of POST /v1/config atomic.
+	configMu sync.Mutex
+}
+
+// statusResponse is the JSON body returned by GET /v1/status.
+type statusResponse struct {
+	State     string           `json:"state"`
+	Paused    bool             `json:"paused"`
+	Summary   string           `json:"summary"`
+	Tables    []tableResponse  `json:"tables"`
+	Throttler *ThrottlerStatus `json:"throttler,omitempty"`
+	Checksum  *ChecksumStatus  `json:"checksum,omitempty"`
+}
+
+// settingsJSON is the JSON form of Settings used by /v1/config. Durations
+// are strings in time.ParseDuration format, e.g. "500ms". In a POST body
+// every field is optional, and omitted fields keep their current value.
+type settingsJSON struct {
+	Threads         *int    `json:"threads,omitempty"`
+	WriteThreads    *int    `json:"write_threads,omitempty"`
+	TargetChunkTime *string `json:"target_chunk_time,omitempty"`
+	ReplicaMaxLag   *string `json:"replica_max_lag,omitempty"`
+}
+
+func toSettingsJSON(s Settings) settingsJSON {
+	targetChunkTime, replicaMaxLag := s.TargetChunkTime.String(), s.ReplicaMaxLag.String()
+	return settingsJSON{
+		Threads:         &s.Threads,
+		WriteThreads:    &s.WriteThreads,
+		TargetChunkTime: &targetChunkTime,
+		ReplicaMaxLag:   &replicaMaxLag,
+	}
+}
+
+// applyTo returns current with the fields set in j replaced.
+func (j settingsJSON) applyTo(current Settings) (Settings, error) {
+	var err error
+	if j.Threads != nil {
+		current.Threads = *j.Threads
+	}
+	if j.WriteThreads != nil {
+		current.WriteThreads = *j.WriteThreads
+	}
+	if j.TargetChunkTime != nil {
+		if current.TargetChunkTime, err = time.ParseDuration(*j.TargetChunkTime); err != nil {
+			return current, fmt.Errorf("target_chunk_time: %w", err)
+		}
+	}
+	if j.ReplicaMaxLag != nil {
+		if current.ReplicaMaxLag, err = time.ParseDuration(*j.ReplicaMaxLag); err != nil {
+			return current, fmt.Errorf("replica_max_lag: %w", err)
+		}
+	}
+	return current, nil
+}
+
+type tableResponse struct {
+	TableName  string `json:"table_name"`
+	RowsCopied uint64 `json:"rows_copied"`
+	RowsTotal  uint64 `json:"rows_total"`
+	IsComplete bool   `json:"is_complete"`
+}
+
+// NewServer returns a server for task. It does not listen until Start is
+// called. An empty token disables the control endpoints.
+func NewServer(task Task, token string, logger *slog.Logger) *Server {
+	s := &Server{
+		task:   task,
+		token:  token,
+		logger: logger,
+		mux:    http.NewServeMux(),
+	}
+	s.mux.HandleFunc("GET /v1/status", s.handleStatus)
+	s.mux.HandleFunc("GET /v1/tables", s.handleTables)
+	s.mux.HandleFunc("GET /v1/config", s.handleGetConfig)
+	s.HandleControl("POST /v1/cancel", s.handleCancel)
+	s.HandleControl("POST /v1/pause", s.handlePause)
+	s.HandleControl("POST /v1/resume", s.handleResume)
+	s.HandleControl("POST /v1/checkpoint", s.handleCheckpoint)
+	s.HandleControl("POST /v1/cutover", s.handleCutover)
+	s.HandleControl("POST /v1/config", s.handleSetConfig)
+	return s
+}
+
+// Handle registers an unauthenticated (read-only) handler. It must be
+// called before Start.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// HandleControl registers a handler that requires the bearer token. It
+// must be called before Start.
+func (s *Server) HandleControl(pattern string, handler http.HandlerFunc) {
+	s.mux.Handle(pattern, s.authenticated(handler))
+}
+
+// Start listens on addr (host:port; port 0 picks a free port) and serves
+// in a background goroutine until Close is called.
+func (s *Server) Start(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	s.listener = ln
+	s.srv = &http.Server{
+		Handler:           s.mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if s.token == "" {
+		s.logger.Warn("http control endpoints are disabled because no token was configured; only read-only endpoints are available")
+	}
+	s.logger.Info("serving http status api", "addr", ln.Addr().String())
+	s.wg.Go(func() {
+		if err := s.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("http status api stopped", "error", err)
+		}
+	})
+	return nil
+}
+
+// Addr returns the address the server is listening on, or "" before Start.
+func (s *Server) Addr() string {
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Close gracefully shuts the server down. It is safe to call on a server
+// that was never started, and more than once.
+func (s *Server) Close() error {
+	if s.srv == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+	defer cancel()
+	err := s.srv.Shutdown(ctx)
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) authenticated(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if s.token == "" {
+			writeError(w, http.StatusForbidden, errors.New("control endpoints are disabled: no token configured"))
+			return
+		}
+		provided, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(s.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+			return
+		}
+		next(w, req)
+	})
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, _ *http.Request) {
+	progress := s.task.Progress()
+	resp := statusResponse{
+		State:   progress.CurrentState.String(),
+		Paused:  progress.Paused,
+		Summary: progress.Summary,
+		Tables:  tablesResponse(progress.Tables),
+	}
+	if p, ok := s.task.(ThrottlerStatusProvider); ok {
+		ts := p.ThrottlerStatus()
+		resp.Throttler = &ts
+	}
+	if p, ok := s.task.(ChecksumStatusProvider); ok {
+		cs := p.ChecksumStatus()
+		resp.Checksum = &cs
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleTables(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, tablesResponse(s.task.Progress().Tables))
+}
+
+func (s *Server) handleCancel(w http.ResponseWriter, req *http.Request) {
+	s.logger.Warn("cancel requested over http", "remote", req.RemoteAddr)
+	s.task.Cancel()
+	writeJSON(w, http.StatusAccepted, map[string]string{"result": "cancelling"})
+}
+
+func (s *Server) handlePause(w http.ResponseWriter, req *http.Request) {
+	p, ok := s.task.(Pausable)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, ErrNotSupported)
+		return
+	}
+	s.logger.Warn("pause requested over http", "remote", req.RemoteAddr)
+	if err := p.Pause(req.Context()); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"result": "paused"})
+}
+
+func (s *Server) handleResume(w http.ResponseWriter, req *http.Request) {
+	p, ok := s.task.(Pausable)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, ErrNotSupported)
+		return
+	}
+	s.logger.Warn("resume requested over http", "remote", req.RemoteAddr)
+	if err := p.Resume(req.Context()); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"result": "resumed"})
+}
+
+func (s *Server) handleCheckpoint(w http.ResponseWriter, req *http.Request) {
+	s.logger.Info("checkpoint requested over http", "remote", req.RemoteAddr)
+	if err := s.task.DumpCheckpoint(req.Context()); err != nil {
+		if errors.Is(err, ErrWatermarkNotReady) {
+			writeError(w, http.StatusConflict, err)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"result": "checkpoint written"})
+}
+
+func (s *Server) handleCutover(w http.ResponseWriter, req *http.Request) {
+	releaser, ok := s.task.(CutoverReleaser)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, ErrNotSupported)
+		return
+	}
+	s.logger.Warn("cutover release requested over http", "remote", req.RemoteAddr)
+	if err := releaser.ReleaseCutover(req.Context()); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"result": "cutover released"})
+}
+
+func (s *Server) handleGetConfig(w http.ResponseWriter, _ *http.Request) {
+	r, ok := s.task.(Reconfigurable)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, ErrNotSupported)
+		return
+	}
+	writeJSON(w, http.StatusOK, toSettingsJSON(r.Settings()))
+}
+
+func (s *Server) handleSetConfig(w http.ResponseWriter, req *http.Request) {
+	r, ok := s.task.(Reconfigurable)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, ErrNotSupported)
+		return
+	}
+	var body settingsJSON
+	dec := json.NewDecoder(req.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+	old := r.Settings()
+	updated, err := body.applyTo(old)
+	if err == nil {
+		err = updated.Validate()
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.logger.Warn("settings change requested over http", "remote", req.RemoteAddr,
+		"threads", updated.Threads, "write-threads", updated.WriteThreads,
+		"target-chunk-time", updated.TargetChunkTime, "replica-max-lag", updated.ReplicaMaxLag)
+	if err := r.Reconfigure(req.Context(), updated); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toSettingsJSON(r.Settings()))
+}
+
+func tablesResponse(tables []TableProgress) []tableResponse {
+	resp := make([]tableResponse, 0, len(tables))
+	for _, t := range tables {
+		resp = append(resp, tableResponse(t))
+	}
+	return resp
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
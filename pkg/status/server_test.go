@@ -0,0 +1,273 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeControlTask extends fakeTask with the optional server capabilities.
+type fakeControlTask struct {
+	*fakeTask
+	released bool
+}
+
+func (f *fakeControlTask) ThrottlerStatus() ThrottlerStatus {
+	return ThrottlerStatus{Throttled: true}
+}
+
+func (f *fakeControlTask) ChecksumStatus() ChecksumStatus {
+	return ChecksumStatus{Running: true, Progress: "5/10 50.00%", DifferencesFound: 1}
+}
+
+func (f *fakeControlTask) ReleaseCutover(_ context.Context) error {
+	f.released = true
+	return nil
+}
+
+// fakeReconfigurableTask records the settings it is reconfigured with.
+type fakeReconfigurableTask struct {
+	*fakeTask
+	mu       sync.Mutex
+	settings Settings
+	err      error
+}
+
+func (f *fakeReconfigurableTask) Settings() Settings {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.settings
+}
+
+func (f *fakeReconfigurableTask) Reconfigure(_ context.Context, settings Settings) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	f.settings = settings
+	return nil
+}
+
+// fakePausableTask pauses with a real Pause, and refuses to from cutover on.
+type fakePausableTask struct {
+	*fakeTask
+	pause Pause
+}
+
+func (f *fakePausableTask) Progress() Progress {
+	progress := f.fakeTask.Progress()
+	progress.Paused, _ = f.pause.Paused()
+	return progress
+}
+
+func (f *fakePausableTask) Pause(_ context.Context) error {
+	if f.Progress().CurrentState >= CutOver {
+		return errors.New("too late to pause")
+	}
+	f.pause.Pause()
+	return nil
+}
+
+func (f *fakePausableTask) Resume(_ context.Context) error {
+	f.pause.Resume()
+	return nil
+}
+
+func startTestServer(t *testing.T, task Task, token string) string {
+	t.Helper()
+	srv := NewServer(task, token, slog.Default())
+	require.NoError(t, srv.Start("127.0.0.1:0"))
+	t.Cleanup(func() { require.NoError(t, srv.Close()) })
+	return "http://" + srv.Addr()
+}
+
+// testClient doesn't reuse connections. With keep-alives the transport can
+// race a spare dial against an idle connection, and Shutdown waits up to
+// five seconds for a connection that was dialed but never used.
+var testClient = &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+func doRequest(t *testing.T, method, url, token string) (int, map[string]any) {
+	t.Helper()
+	return doRequestWithBody(t, method, url, token, "")
+}
+
+func doRequestWithBody(t *testing.T, method, url, token, body string) (int, map[string]any) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(t.Context(), method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := testClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var respBody map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&respBody))
+	return resp.StatusCode, respBody
+}
+
+func TestServerStatus(t *testing.T) {
+	task := &fakeControlTask{fakeTask: newFakeTask(CopyRows)}
+	base := startTestServer(t, task, "")
+
+	code, body := doRequest(t, http.MethodGet, base+"/v1/status", "")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "copyRows", body["state"])
+	require.Equal(t, "fake", body["summary"])
+	require.Equal(t, true, body["throttler"].(map[string]any)["throttled"])
+	require.InDelta(t, 1, body["checksum"].(map[string]any)["differences_found"], 0)
+}
+
+func TestServerStatusWithoutOptionalCapabilities(t *testing.T) {
+	base := startTestServer(t, newFakeTask(Initial), "")
+	code, body := doRequest(t, http.MethodGet, base+"/v1/status", "")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "initial", body["state"])
+	require.NotContains(t, body, "throttler")
+	require.NotContains(t, body, "checksum")
+}
+
+func TestServerControlRequiresToken(t *testing.T) {
+	task := &fakeControlTask{fakeTask: newFakeTask(CopyRows)}
+
+	// No token configured: control endpoints are disabled.
+	base := startTestServer(t, task, "")
+	code, _ := doRequest(t, http.MethodPost, base+"/v1/cancel", "anything")
+	require.Equal(t, http.StatusForbidden, code)
+	require.False(t, task.cancelled())
+
+	// Token configured: a wrong or missing token is rejected.
+	base = startTestServer(t, task, "s3cret")
+	code, _ = doRequest(t, http.MethodPost, base+"/v1/cancel", "")
+	require.Equal(t, http.StatusUnauthorized, code)
+	code, _ = doRequest(t, http.MethodPost, base+"/v1/cancel", "wrong")
+	require.Equal(t, http.StatusUnauthorized, code)
+	require.False(t, task.cancelled())
+
+	code, _ = doRequest(t, http.MethodPost, base+"/v1/cancel", "s3cret")
+	require.Equal(t, http.StatusAccepted, code)
+	require.True(t, task.cancelled())
+}
+
+func TestServerCheckpoint(t *testing.T) {
+	task := newFakeTask(CopyRows)
+	base := startTestServer(t, task, "s3cret")
+
+	code, _ := doRequest(t, http.MethodPost, base+"/v1/checkpoint", "s3cret")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, int64(1), task.checkpointCount.Load())
+
+	task.dumpErr = func() error { return ErrWatermarkNotReady }
+	code, body := doRequest(t, http.MethodPost, base+"/v1/checkpoint", "s3cret")
+	require.Equal(t, http.StatusConflict, code)
+	require.Contains(t, body["error"], "watermark not ready")
+
+	task.dumpErr = func() error { return errors.New("disk full") }
+	code, _ = doRequest(t, http.MethodPost, base+"/v1/checkpoint", "s3cret")
+	require.Equal(t, http.StatusInternalServerError, code)
+}
+
+func TestServerCutover(t *testing.T) {
+	task := &fakeControlTask{fakeTask: newFakeTask(WaitingOnSentinelTable)}
+	base := startTestServer(t, task, "s3cret")
+	code, _ := doRequest(t, http.MethodPost, base+"/v1/cutover", "s3cret")
+	require.Equal(t, http.StatusOK, code)
+	require.True(t, task.released)
+
+	// A task without a deferred cutover reports 501.
+	base = startTestServer(t, newFakeTask(CopyRows), "s3cret")
+	code, body := doRequest(t, http.MethodPost, base+"/v1/cutover", "s3cret")
+	require.Equal(t, http.StatusNotImplemented, code)
+	require.True(t, strings.Contains(body["error"].(string), "not supported"))
+}
+
+func TestServerCloseWithoutStart(t *testing.T) {
+	srv := NewServer(newFakeTask(Initial), "", slog.Default())
+	require.NoError(t, srv.Close())
+	require.Empty(t, srv.Addr())
+}
+
+func TestServerConfig(t *testing.T) {
+	task := &fakeReconfigurableTask{
+		fakeTask: newFakeTask(CopyRows),
+		settings: Settings{Threads: 4, WriteThreads: 4, TargetChunkTime: 500 * time.Millisecond, ReplicaMaxLag: 2 * time.Minute},
+	}
+	base := startTestServer(t, task, "s3cret")
+
+	code, body := doRequest(t, http.MethodGet, base+"/v1/config", "")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, map[string]any{"threads": 4.0, "write_threads": 4.0, "target_chunk_time": "500ms", "replica_max_lag": "2m0s"}, body)
+
+	// Changing settings requires the token.
+	code, _ = doRequestWithBody(t, http.MethodPost, base+"/v1/config", "", `{"threads": 2}`)
+	require.Equal(t, http.StatusUnauthorized, code)
+
+	// Omitted fields keep their current value.
+	code, body = doRequestWithBody(t, http.MethodPost, base+"/v1/config", "s3cret", `{"threads": 2, "target_chunk_time": "250ms"}`)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "250ms", body["target_chunk_time"])
+	require.Equal(t, Settings{Threads: 2, WriteThreads: 4, TargetChunkTime: 250 * time.Millisecond, ReplicaMaxLag: 2 * time.Minute}, task.Settings())
+
+	// Invalid values, bad durations and unknown fields are rejected
+	// before the task sees them.
+	for _, bad := range []string{`{"threads": 0}`, `{"replica_max_lag": "soon"}`, `{"thread": 2}`, `not json`} {
+		code, _ = doRequestWithBody(t, http.MethodPost, base+"/v1/config", "s3cret", bad)
+		require.Equal(t, http.StatusBadRequest, code, bad)
+	}
+	require.Equal(t, 2, task.Settings().Threads)
+
+	// The task refusing the change is a conflict.
+	task.err = errors.New("not now")
+	code, body = doRequestWithBody(t, http.MethodPost, base+"/v1/config", "s3cret", `{"threads": 8}`)
+	require.Equal(t, http.StatusConflict, code)
+	require.Equal(t, "not now", body["error"])
+
+	// Tasks that can't be reconfigured report 501.
+	base = startTestServer(t, newFakeTask(CopyRows), "s3cret")
+	code, _ = doRequest(t, http.MethodGet, base+"/v1/config", "")
+	require.Equal(t, http.StatusNotImplemented, code)
+	code, _ = doRequestWithBody(t, http.MethodPost, base+"/v1/config", "s3cret", `{"threads": 2}`)
+	require.Equal(t, http.StatusNotImplemented, code)
+}
+
+func TestServerPause(t *testing.T) {
+	task := &fakePausableTask{fakeTask: newFakeTask(CopyRows)}
+	base := startTestServer(t, task, "s3cret")
+
+	code, _ := doRequest(t, http.MethodPost, base+"/v1/pause", "")
+	require.Equal(t, http.StatusUnauthorized, code)
+
+	code, body := doRequest(t, http.MethodPost, base+"/v1/pause", "s3cret")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "paused", body["result"])
+	_, body = doRequest(t, http.MethodGet, base+"/v1/status", "")
+	require.Equal(t, true, body["paused"])
+	require.Equal(t, "copyRows", body["state"])
+
+	code, _ = doRequest(t, http.MethodPost, base+"/v1/resume", "s3cret")
+	require.Equal(t, http.StatusOK, code)
+	_, body = doRequest(t, http.MethodGet, base+"/v1/status", "")
+	require.Equal(t, false, body["paused"])
+
+	// The task refusing the pause is a conflict.
+	task.setState(CutOver)
+	code, body = doRequest(t, http.MethodPost, base+"/v1/pause", "s3cret")
+	require.Equal(t, http.StatusConflict, code)
+	require.Equal(t, "too late to pause", body["error"])
+
+	// Tasks that can't be paused report 501.
+	base = startTestServer(t, newFakeTask(CopyRows), "s3cret")
+	code, _ = doRequest(t, http.MethodPost, base+"/v1/pause", "s3cret")
+	require.Equal(t, http.StatusNotImplemented, code)
+	code, _ = doRequest(t, http.MethodPost, base+"/v1/resume", "s3cret")
+	require.Equal(t, http.StatusNotImplemented, code)
+}
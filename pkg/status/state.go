@@ -30,7 +30,15 @@ const (
 	// During this state Spirit also runs the "continuous checksum" loop
 	// described in docs/migrate.md.
 	WaitingOnSentinelTable
+	// WaitingOnCutoverWindow is entered when a --cutover-window is set and
+	// the task is ready to cut over outside of it. The continuous checksum
+	// keeps running, as in WaitingOnSentinelTable.
+	WaitingOnCutoverWindow
 	CutOver
+	// RevertWindow is entered after a successful cutover when a migration
+	// has a --revert-window: changes to the table are streamed back into
+	// the old one, so that the migration can still be reverted.
+	RevertWindow
 	Close
 	ErrCleanup
 )
@@ -43,6 +51,8 @@ func (s State) String() string {
 		return "copyRows"
 	case WaitingOnSentinelTable:
 		return "waitingOnSentinelTable"
+	case WaitingOnCutoverWindow:
+		return "waitingOnCutoverWindow"
 	case ApplyChangeset:
 		return "applyChangeset"
 	case RestoreSecondaryIndexes:
@@ -55,6 +65,8 @@ func (s State) String() string {
 		return "postChecksum"
 	case CutOver:
 		return "cutOver"
+	case RevertWindow:
+		return "revertWindow"
 	case Close:
 		return "close"
 	case ErrCleanup:
@@ -15,9 +15,11 @@ func TestStateString(t *testing.T) {
 	require.Equal(t, "initial", Initial.String())
 	require.Equal(t, "copyRows", CopyRows.String())
 	require.Equal(t, "waitingOnSentinelTable", WaitingOnSentinelTable.String())
+	require.Equal(t, "waitingOnCutoverWindow", WaitingOnCutoverWindow.String())
 	require.Equal(t, "applyChangeset", ApplyChangeset.String())
 	require.Equal(t, "checksum", Checksum.String())
 	require.Equal(t, "cutOver", CutOver.String())
+	require.Equal(t, "revertWindow", RevertWindow.String())
 	require.Equal(t, "errCleanup", ErrCleanup.String())
 	require.Equal(t, "analyzeTable", AnalyzeTable.String())
 	require.Equal(t, "close", Close.String())
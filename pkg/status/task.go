@@ -11,6 +11,7 @@ import (
 var (
 	CheckpointDumpInterval = 50 * time.Second
 	StatusInterval         = 30 * time.Second
+	MetricsInterval        = 10 * time.Second
 )
 
 type Task interface {
@@ -20,6 +21,13 @@ type Task interface {
 	Cancel() // a callback to be able to cancel the task.
 }
 
+// MetricsReporter is implemented by tasks that have metrics which need to
+// be sampled (e.g. replication lag) rather than sent as work happens.
+// WatchTask calls SendMetrics every MetricsInterval.
+type MetricsReporter interface {
+	SendMetrics(ctx context.Context)
+}
+
 // WatchTask periodically does the status reporting for a task.
 // This includes writing to the logger the current state,
 // and dumping checkpoints.
@@ -34,6 +42,9 @@ func WatchTask(ctx context.Context, task Task, logger *slog.Logger) (wait func()
 	var wg sync.WaitGroup
 	wg.Go(func() { continuallyDumpStatus(ctx, task, logger) })
 	wg.Go(func() { continuallyDumpCheckpoint(ctx, task, logger) })
+	if reporter, ok := task.(MetricsReporter); ok {
+		wg.Go(func() { continuallySendMetrics(ctx, task, reporter) })
+	}
 	return wg.Wait
 }
 
@@ -45,8 +56,10 @@ func continuallyDumpStatus(ctx context.Context, task Task, logger *slog.Logger)
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
+			// A revert window can last hours after the cutover, so
+			// it keeps being reported.
 			state := task.Progress().CurrentState
-			if state > CutOver {
+			if state > CutOver && state != RevertWindow {
 				return
 			}
 			logger.Info(task.Status()) // call the task to write the status
@@ -54,6 +67,22 @@ func continuallyDumpStatus(ctx context.Context, task Task, logger *slog.Logger)
 	}
 }
 
+func continuallySendMetrics(ctx context.Context, task Task, reporter MetricsReporter) {
+	ticker := time.NewTicker(MetricsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if task.Progress().CurrentState > CutOver {
+				return
+			}
+			reporter.SendMetrics(ctx)
+		}
+	}
+}
+
 func continuallyDumpCheckpoint(ctx context.Context, task Task, logger *slog.Logger) {
 	ticker := time.NewTicker(CheckpointDumpInterval)
 	defer ticker.Stop()
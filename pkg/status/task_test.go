@@ -123,7 +123,8 @@ func TestWatchTaskFiresAndStopsOnCancel(t *testing.T) {
 
 // TestWatchTaskStopsPastCutover verifies both loops exit on their own
 // (no ctx cancellation) once the task reports a state past CutOver:
-// the status loop on state > CutOver, the checkpoint loop on
+// the status loop on state > CutOver (other than RevertWindow), the
+// checkpoint loop on
 // state >= CutOver. Neither dump should do any work.
 func TestWatchTaskStopsPastCutover(t *testing.T) {
 	setTestIntervals(t, 2*time.Millisecond, 2*time.Millisecond)
@@ -159,6 +160,25 @@ func TestContinuallyDumpStatusStopsWhenStateAdvances(t *testing.T) {
 	waitSignal(t, done, "status loop exit after state advanced past CutOver")
 }
 
+// TestContinuallyDumpStatusDuringRevertWindow verifies the status loop
+// keeps running through a revert window, which comes after the cutover,
+// and exits once the migration closes.
+func TestContinuallyDumpStatusDuringRevertWindow(t *testing.T) {
+	setTestIntervals(t, 2*time.Millisecond, time.Hour)
+	task := newFakeTask(RevertWindow)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		continuallyDumpStatus(t.Context(), task, slog.Default())
+	}()
+
+	waitSignal(t, task.statusCh, "first status dump")
+	waitSignal(t, task.statusCh, "second status dump")
+	task.setState(Close)
+	waitSignal(t, done, "status loop exit after the revert window")
+}
+
 // TestContinuallyDumpCheckpointWatermarkNotReady verifies that
 // ErrWatermarkNotReady is non-fatal: the loop logs, continues ticking,
 // and never calls task.Cancel.
@@ -254,3 +274,45 @@ func TestContinuallyDumpCheckpointErrorDuringCutover(t *testing.T) {
 		t.Fatal("a checkpoint error after reaching CutOver must not cancel the task")
 	}
 }
+
+// metricsTask adds MetricsReporter to fakeTask.
+type metricsTask struct {
+	*fakeTask
+	metricsCh chan struct{}
+}
+
+func (m *metricsTask) SendMetrics(_ context.Context) {
+	select {
+	case m.metricsCh <- struct{}{}:
+	default:
+	}
+}
+
+// TestWatchTaskSendsMetrics verifies WatchTask only runs the metrics
+// loop for tasks that implement MetricsReporter, and that the loop exits
+// once the state advances past CutOver.
+func TestWatchTaskSendsMetrics(t *testing.T) {
+	setTestIntervals(t, time.Hour, time.Hour)
+	oldMetrics := MetricsInterval
+	MetricsInterval = 2 * time.Millisecond
+	t.Cleanup(func() { MetricsInterval = oldMetrics })
+
+	task := &metricsTask{fakeTask: newFakeTask(CopyRows), metricsCh: make(chan struct{}, 64)}
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	wait := WatchTask(ctx, task, slog.Default())
+
+	waitSignal(t, task.metricsCh, "first metrics send")
+	waitSignal(t, task.metricsCh, "second metrics send")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		continuallySendMetrics(t.Context(), task, task)
+	}()
+	task.setState(Close)
+	waitSignal(t, done, "metrics loop exit after state advanced past CutOver")
+
+	cancel()
+	wait()
+}
@@ -47,6 +47,14 @@ type Chunker interface {
 	Tables() []*TableInfo
 }
 
+// TargetChunkTimeSetter is implemented by chunkers that size chunks
+// dynamically, so that the target chunk time can be changed while they
+// are in use (see migration.Runner.Reconfigure). Callers should
+// type-assert for it.
+type TargetChunkTimeSetter interface {
+	SetTargetChunkTime(target time.Duration)
+}
+
 // MappedChunker is a Chunker that operates on a single source→target table pair
 // and carries a ColumnMapping describing the column relationship between them.
 // The multiChunker does not implement this interface because it wraps multiple
@@ -81,6 +89,20 @@ type ChunkerConfig struct {
 	Where string
 }
 
+// The chunker types that NewChunker selects between, as returned by ChunkerType.
+const (
+	ChunkerTypeOptimistic = "optimistic"
+	ChunkerTypeComposite  = "composite"
+)
+
+// ChunkerType returns the type of chunker NewChunker selects for t and config.
+func ChunkerType(t *TableInfo, config ChunkerConfig) string {
+	if len(t.KeyColumns) == 1 && t.KeyIsAutoInc && config.Key == "" && config.Where == "" {
+		return ChunkerTypeOptimistic
+	}
+	return ChunkerTypeComposite
+}
+
 // NewChunker creates a new MappedChunker for the given source table.
 // It selects the optimistic chunker for single-column auto-increment primary keys
 // (unless Key/Where overrides are specified), and the composite chunker otherwise.
@@ -103,7 +125,7 @@ func NewChunker(t *TableInfo, config ChunkerConfig) (MappedChunker, error) {
 	}
 	// Use the optimistic chunker for auto_increment tables with a single
 	// column key, unless a specific key/where is requested.
-	if len(t.KeyColumns) == 1 && t.KeyIsAutoInc && config.Key == "" && config.Where == "" {
+	if ChunkerType(t, config) == ChunkerTypeOptimistic {
 		return &chunkerOptimistic{
 			Ti:                t,
 			NewTi:             newTable,
@@ -48,6 +48,7 @@ type compositeWatermark struct {
 }
 
 var _ MappedChunker = &chunkerComposite{}
+var _ TargetChunkTimeSetter = &chunkerComposite{}
 
 func (t *chunkerComposite) additionalConditionsSQL(whereSent bool) string {
 	if t.where == "" {
@@ -330,6 +331,13 @@ func (t *chunkerComposite) Reset() error {
 	return nil
 }
 
+// SetTargetChunkTime changes the target time of each chunk.
+func (t *chunkerComposite) SetTargetChunkTime(target time.Duration) {
+	t.Lock()
+	defer t.Unlock()
+	t.setTargetChunkTime(target)
+}
+
 // Feedback is a way for consumers of chunks to give feedback on how long
 // processing the chunk took. It is incorporated into the calculation of future
 // chunk sizes.
@@ -418,7 +426,7 @@ func (t *chunkerComposite) open() (err error) {
 	if len(t.chunkKeys) == 0 {
 		// No key specified; default to primary key.
 		t.chunkKeys = t.Ti.KeyColumns
-		t.keyName = "PRIMARY"
+		t.keyName = t.Ti.KeyName()
 	}
 	t.finalChunkSent = false
 	t.chunkSize = StartingChunkSize
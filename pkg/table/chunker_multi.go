@@ -19,6 +19,7 @@ type multiChunker struct {
 }
 
 var _ Chunker = &multiChunker{}
+var _ TargetChunkTimeSetter = &multiChunker{}
 
 // NewMultiChunker creates a new multi-chunker that wraps multiple chunkers
 func NewMultiChunker(c ...Chunker) Chunker {
@@ -208,6 +209,18 @@ type TableProgress struct {
 	IsComplete bool
 }
 
+// SetTargetChunkTime forwards the new target to each child chunker that
+// supports it.
+func (m *multiChunker) SetTargetChunkTime(target time.Duration) {
+	m.Lock()
+	defer m.Unlock()
+	for _, chunker := range m.chunkers {
+		if s, ok := chunker.(TargetChunkTimeSetter); ok {
+			s.SetTargetChunkTime(target)
+		}
+	}
+}
+
 // PerTableProgress returns progress for each table in the multi-chunker.
 // This is used by wrappers to show per-table progress for multi-table migrations.
 func (m *multiChunker) PerTableProgress() []TableProgress {
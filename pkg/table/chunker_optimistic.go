@@ -41,6 +41,7 @@ type chunkerOptimistic struct {
 }
 
 var _ MappedChunker = &chunkerOptimistic{}
+var _ TargetChunkTimeSetter = &chunkerOptimistic{}
 
 // nextChunkByPrefetching uses prefetching instead of feedback to determine the chunk size.
 // It is used when the chunker detects that there are very large gaps in the sequence.
@@ -351,6 +352,13 @@ func (t *chunkerOptimistic) Reset() error {
 	return nil
 }
 
+// SetTargetChunkTime changes the target time of each chunk.
+func (t *chunkerOptimistic) SetTargetChunkTime(target time.Duration) {
+	t.Lock()
+	defer t.Unlock()
+	t.setTargetChunkTime(target)
+}
+
 // Feedback is a way for consumers of chunks to give feedback on how long
 // processing the chunk took. It is incorporated into the calculation of future
 // chunk sizes.
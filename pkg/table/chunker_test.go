@@ -31,6 +31,7 @@ func TestCompositeChunker(t *testing.T) {
 	chunker, err := NewChunker(t1, ChunkerConfig{})
 	require.NoError(t, err)
 	require.IsType(t, &chunkerComposite{}, chunker)
+	require.Equal(t, ChunkerTypeComposite, ChunkerType(t1, ChunkerConfig{}))
 }
 
 func TestOptimisticChunker(t *testing.T) {
@@ -55,6 +56,8 @@ func TestOptimisticChunker(t *testing.T) {
 	chunker, err := NewChunker(t1, ChunkerConfig{})
 	require.NoError(t, err)
 	require.IsType(t, &chunkerOptimistic{}, chunker)
+	require.Equal(t, ChunkerTypeOptimistic, ChunkerType(t1, ChunkerConfig{}))
+	require.Equal(t, ChunkerTypeComposite, ChunkerType(t1, ChunkerConfig{Key: "PRIMARY"}))
 }
 
 func TestNewCompositeChunkerWithKeyAndWhere(t *testing.T) {
@@ -1,6 +1,8 @@
 package table
 
 import (
+	"fmt"
+	"slices"
 	"strings"
 )
 
@@ -18,6 +20,11 @@ type ColumnMapping struct {
 	// Pre-computed intersection results
 	sourceColumns []string // non-generated source columns that exist in target
 	targetColumns []string // corresponding target column names (renamed where applicable)
+
+	// Target columns whose values are computed by an SQL expression over
+	// the source columns, instead of copied (see SetExpressions).
+	exprColumns []string // target column names, in target table order
+	exprs       []string // the expressions, parallel to exprColumns
 }
 
 // NewColumnMapping creates a ColumnMapping between source and target tables,
@@ -85,24 +92,105 @@ func (m *ColumnMapping) computeIntersection() ([]string, []string) {
 	return srcCols, tgtCols
 }
 
-// Columns returns two comma-separated, backtick-quoted column lists
-// for source and target. When there are no renames, both strings are identical.
+// SetExpressions sets SQL expressions that the values of target columns
+// are computed from, keyed by the target column name, e.g.
+// full_name=CONCAT(first, ' ', last). The expressions are in terms of the
+// source table's columns, and replace the source column (if any) that the
+// target column would otherwise be copied from. The columns of the key
+// that identifies the rows can't be computed. It must be called before the
+// mapping is shared.
+func (m *ColumnMapping) SetExpressions(exprs map[string]string) error {
+	declared := make(map[string]string, len(m.targetTable.NonGeneratedColumns))
+	for _, col := range m.targetTable.NonGeneratedColumns {
+		declared[strings.ToLower(col)] = col
+	}
+	byColumn := make(map[string]string, len(exprs))
+	for col, expr := range exprs {
+		targetCol, ok := declared[strings.ToLower(col)]
+		if !ok {
+			return fmt.Errorf("column %q not found in table %s, or is a generated column", col, m.targetTable.TableName)
+		}
+		if slices.Contains(m.targetTable.KeyColumns, targetCol) {
+			return fmt.Errorf("column %q identifies the rows, so it can't be computed by an expression", targetCol)
+		}
+		if _, ok := byColumn[targetCol]; ok {
+			return fmt.Errorf("column %q has more than one expression", targetCol)
+		}
+		if strings.TrimSpace(expr) == "" {
+			return fmt.Errorf("column %q has an empty expression", targetCol)
+		}
+		byColumn[targetCol] = expr
+	}
+	m.exprColumns, m.exprs = nil, nil
+	for _, col := range m.targetTable.NonGeneratedColumns {
+		if expr, ok := byColumn[col]; ok {
+			m.exprColumns = append(m.exprColumns, col)
+			m.exprs = append(m.exprs, expr)
+		}
+	}
+	m.sourceColumns, m.targetColumns = m.computeIntersection()
+	// The columns with an expression are no longer copied.
+	for i := len(m.targetColumns) - 1; i >= 0; i-- {
+		if _, ok := byColumn[m.targetColumns[i]]; ok {
+			m.sourceColumns = slices.Delete(m.sourceColumns, i, i+1)
+			m.targetColumns = slices.Delete(m.targetColumns, i, i+1)
+		}
+	}
+	return nil
+}
+
+// Expressions returns the target columns that are computed by an
+// expression, and the parallel slice of expressions.
+func (m *ColumnMapping) Expressions() (targetColumns, exprs []string) {
+	return m.exprColumns, m.exprs
+}
+
+// HasExpressions returns true if any target column is computed by an
+// expression.
+func (m *ColumnMapping) HasExpressions() bool {
+	return len(m.exprs) > 0
+}
+
+// Columns returns two comma-separated column lists for source and target.
+// The target list is backtick-quoted column names. The source list is what
+// to SELECT from the source table for them: backtick-quoted column names,
+// followed by the parenthesized expressions of any computed columns. When
+// there are no renames or expressions, both strings are identical.
 func (m *ColumnMapping) Columns() (source, target string) {
-	srcQuoted := make([]string, len(m.sourceColumns))
-	tgtQuoted := make([]string, len(m.targetColumns))
+	srcQuoted := make([]string, 0, len(m.sourceColumns)+len(m.exprs))
+	tgtQuoted := make([]string, 0, len(m.targetColumns)+len(m.exprColumns))
 	for i := range m.sourceColumns {
-		srcQuoted[i] = "`" + m.sourceColumns[i] + "`"
-		tgtQuoted[i] = "`" + m.targetColumns[i] + "`"
+		srcQuoted = append(srcQuoted, "`"+m.sourceColumns[i]+"`")
+		tgtQuoted = append(tgtQuoted, "`"+m.targetColumns[i]+"`")
+	}
+	for i := range m.exprs {
+		srcQuoted = append(srcQuoted, "("+m.exprs[i]+")")
+		tgtQuoted = append(tgtQuoted, "`"+m.exprColumns[i]+"`")
 	}
 	return strings.Join(srcQuoted, ", "), strings.Join(tgtQuoted, ", ")
 }
 
 // ColumnsSlice returns parallel slices of source and target column names.
-// sourceColumns[i] corresponds to targetColumns[i].
+// sourceColumns[i] corresponds to targetColumns[i]. Columns computed by an
+// expression are not included (see Expressions).
 func (m *ColumnMapping) ColumnsSlice() (sourceColumns, targetColumns []string) {
 	return m.sourceColumns, m.targetColumns
 }
 
+// ValueType returns the MySQL type of the i-th value of a row that was read
+// with the source list of Columns(). For a copied column this is the type
+// of the source column, and for a computed column the type of the target
+// column, since there is no source column to take it from.
+func (m *ColumnMapping) ValueType(i int) (string, bool) {
+	if i < len(m.sourceColumns) {
+		return m.sourceTable.GetColumnMySQLType(m.sourceColumns[i])
+	}
+	if i-len(m.sourceColumns) < len(m.exprColumns) {
+		return m.targetTable.GetColumnMySQLType(m.exprColumns[i-len(m.sourceColumns)])
+	}
+	return "", false
+}
+
 // checksumSeparator is interleaved between every value in the checksum
 // CONCAT() so that content cannot shift across adjacent column boundaries
 // undetected. Without it, the rows ('x0','y') and ('x','0y') concatenate to
@@ -119,7 +207,8 @@ const checksumSeparator = ", '#', "
 // CONCAT()) for source and target, wrapping each column in IFNULL(), ISNULL()
 // and CAST, with a '#' separator literal between every value (see
 // checksumSeparator). The CAST type always comes from the target table's type
-// definition. When there are no renames, both expressions are identical.
+// definition. When there are no renames or computed columns, both
+// expressions are identical.
 func (m *ColumnMapping) ChecksumExprs() (source, target string, err error) {
 	sourceExprs := make([]string, len(m.sourceColumns))
 	targetExprs := make([]string, len(m.targetColumns))
@@ -139,6 +228,20 @@ func (m *ColumnMapping) ChecksumExprs() (source, target string, err error) {
 		sourceExprs[i] = "IFNULL(" + srcCast + ",'')" + checksumSeparator + "ISNULL(`" + m.sourceColumns[i] + "`)"
 		targetExprs[i] = "IFNULL(" + tgtCast + ",'')" + checksumSeparator + "ISNULL(`" + m.targetColumns[i] + "`)"
 	}
+	// Computed columns compare the expression over the source row with
+	// the value that was written to the target.
+	for i := range m.exprs {
+		srcCast, err := m.targetTable.wrapCastExprAs("("+m.exprs[i]+")", m.exprColumns[i])
+		if err != nil {
+			return "", "", err
+		}
+		tgtCast, err := m.targetTable.wrapCastType(m.exprColumns[i])
+		if err != nil {
+			return "", "", err
+		}
+		sourceExprs = append(sourceExprs, "IFNULL("+srcCast+",'')"+checksumSeparator+"ISNULL(("+m.exprs[i]+"))")
+		targetExprs = append(targetExprs, "IFNULL("+tgtCast+",'')"+checksumSeparator+"ISNULL(`"+m.exprColumns[i]+"`)")
+	}
 	return strings.Join(sourceExprs, checksumSeparator), strings.Join(targetExprs, checksumSeparator), nil
 }
 
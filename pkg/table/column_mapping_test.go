@@ -192,3 +192,49 @@ func TestColumnMappingTargetNil(t *testing.T) {
 	require.Equal(t, "`a`, `b`, `c`", tgt)
 	require.Equal(t, t1, m.TargetTable())
 }
+
+func TestColumnMappingExpressions(t *testing.T) {
+	t1 := NewTableInfo(nil, "test", "t1")
+	t1new := NewTableInfo(nil, "test", "t1_new")
+	t1.NonGeneratedColumns = []string{"id", "first", "last", "email"}
+	t1.columnsMySQLTps = map[string]string{"id": "int", "first": "varchar(50)", "last": "varchar(50)", "email": "varchar(100)"}
+	t1new.NonGeneratedColumns = []string{"id", "email", "full_name"}
+	t1new.columnsMySQLTps = map[string]string{"id": "int", "email": "varchar(100)", "full_name": "varchar(101)"}
+	t1new.KeyColumns = []string{"id"}
+
+	m := NewColumnMapping(t1, t1new, nil)
+	require.False(t, m.HasExpressions())
+	require.NoError(t, m.SetExpressions(map[string]string{
+		"FULL_NAME": "CONCAT(first, ' ', last)",
+		"email":     "LOWER(email)",
+	}))
+	require.True(t, m.HasExpressions())
+	src, tgt := m.Columns()
+	require.Equal(t, "`id`, (LOWER(email)), (CONCAT(first, ' ', last))", src)
+	require.Equal(t, "`id`, `email`, `full_name`", tgt)
+	srcSlice, tgtSlice := m.ColumnsSlice()
+	require.Equal(t, []string{"id"}, srcSlice)
+	require.Equal(t, []string{"id"}, tgtSlice)
+	exprCols, exprs := m.Expressions()
+	require.Equal(t, []string{"email", "full_name"}, exprCols)
+	require.Equal(t, []string{"LOWER(email)", "CONCAT(first, ' ', last)"}, exprs)
+
+	tp, ok := m.ValueType(0)
+	require.True(t, ok)
+	require.Equal(t, "int", tp)
+	tp, ok = m.ValueType(2)
+	require.True(t, ok)
+	require.Equal(t, "varchar(101)", tp)
+	_, ok = m.ValueType(3)
+	require.False(t, ok)
+
+	srcExprs, tgtExprs, err := m.ChecksumExprs()
+	require.NoError(t, err)
+	require.Contains(t, srcExprs, "IFNULL(CAST((CONCAT(first, ' ', last)) AS char CHARACTER SET utf8mb4),''), '#', ISNULL((CONCAT(first, ' ', last)))")
+	require.Contains(t, tgtExprs, "IFNULL(CAST(`full_name` AS char CHARACTER SET utf8mb4),''), '#', ISNULL(`full_name`)")
+
+	require.ErrorContains(t, m.SetExpressions(map[string]string{"nope": "1"}), `column "nope" not found in table t1_new`)
+	require.ErrorContains(t, m.SetExpressions(map[string]string{"id": "id + 1"}), `column "id" identifies the rows`)
+	require.ErrorContains(t, m.SetExpressions(map[string]string{"email": " "}), `column "email" has an empty expression`)
+	require.ErrorContains(t, m.SetExpressions(map[string]string{"email": "1", "EMAIL": "2"}), `column "email" has more than one expression`)
+}
@@ -17,6 +17,16 @@ type dynamicChunkSizer struct {
 	disableDynamicChunker bool          // only used by the test suite
 }
 
+// setTargetChunkTime changes ChunkerTarget on a running chunker. The
+// timing history was measured against the old target, so it is dropped;
+// chunkSize is kept and converges on the new target through the usual
+// feedback (immediately, via the panic path, if the new target is much
+// smaller). Caller must hold the chunker's mutex.
+func (d *dynamicChunkSizer) setTargetChunkTime(target time.Duration) {
+	d.ChunkerTarget = target
+	d.chunkTimingInfo = []time.Duration{}
+}
+
 // updateChunkerTarget applies a recalculated row target after clamping
 // it to safe bounds (no more than 1.5x growth per step, capped at
 // MaxDynamicRowSize, floored at MinDynamicRowSize). Resets the timing
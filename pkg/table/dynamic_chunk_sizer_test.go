@@ -61,3 +61,26 @@ func TestCalculateNewTargetChunkSize(t *testing.T) {
 	require.Equal(t, uint64(5000), newTarget,
 		"newTarget = chunkSize * ChunkerTarget / p90")
 }
+
+// TestSetTargetChunkTime verifies that a new target reaches every child of
+// a multi-chunker, and that the timing history measured against the old
+// target is dropped while the current chunk size is kept.
+func TestSetTargetChunkTime(t *testing.T) {
+	optimistic := &chunkerOptimistic{dynamicChunkSizer: dynamicChunkSizer{
+		chunkSize:       2000,
+		chunkTimingInfo: []time.Duration{time.Second},
+		ChunkerTarget:   500 * time.Millisecond,
+	}}
+	composite := &chunkerComposite{dynamicChunkSizer: dynamicChunkSizer{
+		chunkSize:     3000,
+		ChunkerTarget: 500 * time.Millisecond,
+	}}
+	multi := &multiChunker{chunkers: map[string]Chunker{"a": optimistic, "b": composite}}
+	multi.SetTargetChunkTime(100 * time.Millisecond)
+
+	require.Equal(t, 100*time.Millisecond, optimistic.ChunkerTarget)
+	require.Equal(t, 100*time.Millisecond, composite.ChunkerTarget)
+	require.Empty(t, optimistic.chunkTimingInfo)
+	require.Equal(t, uint64(2000), optimistic.chunkSize)
+	require.Equal(t, uint64(3000), composite.chunkSize)
+}
@@ -0,0 +1,121 @@
+package table
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/block/spirit/pkg/utils"
+)
+
+// ForeignKey is a foreign key constraint, as read from
+// information_schema.key_column_usage and referential_constraints.
+type ForeignKey struct {
+	Name              string
+	Schema            string // the schema of the child table, which the constraint is on
+	Table             string // the child table
+	Columns           []string
+	ReferencedSchema  string
+	ReferencedTable   string
+	ReferencedColumns []string
+	OnUpdate          string // e.g. CASCADE or RESTRICT
+	OnDelete          string
+}
+
+const foreignKeysQuery = `SELECT k.constraint_name, k.table_schema, k.table_name, k.column_name,
+	k.referenced_table_schema, k.referenced_table_name, k.referenced_column_name,
+	rc.update_rule, rc.delete_rule
+	FROM information_schema.key_column_usage k
+	JOIN information_schema.referential_constraints rc
+	ON rc.constraint_schema = k.constraint_schema AND rc.constraint_name = k.constraint_name AND rc.table_name = k.table_name
+	WHERE k.referenced_table_name IS NOT NULL AND `
+
+// LoadForeignKeys returns the foreign keys on schema.tableName, i.e. the
+// constraints for which it is the child table.
+func LoadForeignKeys(ctx context.Context, db *sql.DB, schema, tableName string) ([]ForeignKey, error) {
+	return loadForeignKeys(ctx, db, `k.table_schema=? AND k.table_name=?`, schema, tableName)
+}
+
+// LoadReferencingForeignKeys returns the foreign keys on other tables that
+// reference schema.tableName, i.e. the constraints for which it is the
+// parent table. A foreign key of the table that references itself is
+// returned by LoadForeignKeys instead.
+func LoadReferencingForeignKeys(ctx context.Context, db *sql.DB, schema, tableName string) ([]ForeignKey, error) {
+	return loadForeignKeys(ctx, db, `k.referenced_table_schema=? AND k.referenced_table_name=?
+		AND NOT (k.table_schema=? AND k.table_name=?)`, schema, tableName, schema, tableName)
+}
+
+func loadForeignKeys(ctx context.Context, db *sql.DB, where string, args ...any) ([]ForeignKey, error) {
+	rows, err := db.QueryContext(ctx, foreignKeysQuery+where+`
+		ORDER BY k.table_schema, k.table_name, k.constraint_name, k.ordinal_position`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer utils.CloseAndLog(rows)
+	var fks []ForeignKey
+	for rows.Next() {
+		var fk ForeignKey
+		var column, referencedColumn string
+		if err := rows.Scan(&fk.Name, &fk.Schema, &fk.Table, &column,
+			&fk.ReferencedSchema, &fk.ReferencedTable, &referencedColumn,
+			&fk.OnUpdate, &fk.OnDelete); err != nil {
+			return nil, err
+		}
+		// A constraint with more than one column has a row per column.
+		if n := len(fks); n > 0 && fks[n-1].Name == fk.Name && fks[n-1].Schema == fk.Schema && fks[n-1].Table == fk.Table {
+			fks[n-1].Columns = append(fks[n-1].Columns, column)
+			fks[n-1].ReferencedColumns = append(fks[n-1].ReferencedColumns, referencedColumn)
+			continue
+		}
+		fk.Columns = []string{column}
+		fk.ReferencedColumns = []string{referencedColumn}
+		fks = append(fks, fk)
+	}
+	return fks, rows.Err()
+}
+
+// Definition returns the constraint definition of fk, for use in
+// ALTER TABLE .. ADD, e.g.
+// CONSTRAINT `fk` FOREIGN KEY (`a`) REFERENCES `test`.`p` (`id`) ON DELETE CASCADE ON UPDATE RESTRICT
+func (fk ForeignKey) Definition() string {
+	return fmt.Sprintf("CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s.%s (%s) ON DELETE %s ON UPDATE %s",
+		quoteIdentifier(fk.Name),
+		quoteIdentifiers(fk.Columns),
+		quoteIdentifier(fk.ReferencedSchema),
+		quoteIdentifier(fk.ReferencedTable),
+		quoteIdentifiers(fk.ReferencedColumns),
+		fk.OnDelete,
+		fk.OnUpdate,
+	)
+}
+
+// OrphanedRowsQuery returns a query that counts the rows of the child
+// table that match where, e.g. a chunk's condition, and have no matching
+// row in the parent table. Rows with a NULL in any of the columns are not
+// checked by the constraint, so they are not counted.
+func (fk ForeignKey) OrphanedRowsQuery(where string) string {
+	conds := make([]string, 0, len(fk.Columns))
+	joins := make([]string, 0, len(fk.Columns))
+	for i, col := range fk.Columns {
+		conds = append(conds, "c."+quoteIdentifier(col)+" IS NOT NULL")
+		joins = append(joins, "p."+quoteIdentifier(fk.ReferencedColumns[i])+" = c."+quoteIdentifier(col))
+	}
+	return fmt.Sprintf("SELECT COUNT(*) FROM %s.%s c WHERE (%s) AND %s AND NOT EXISTS (SELECT 1 FROM %s.%s p WHERE %s)",
+		quoteIdentifier(fk.Schema),
+		quoteIdentifier(fk.Table),
+		where,
+		strings.Join(conds, " AND "),
+		quoteIdentifier(fk.ReferencedSchema),
+		quoteIdentifier(fk.ReferencedTable),
+		strings.Join(joins, " AND "),
+	)
+}
+
+func quoteIdentifiers(names []string) string {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, quoteIdentifier(name))
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,78 @@
+package table
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/block/spirit/pkg/testutils"
+	"github.com/block/spirit/pkg/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForeignKeyDefinition(t *testing.T) {
+	fk := ForeignKey{
+		Name:              "_fk_orders_customer",
+		Schema:            "test",
+		Table:             "orders",
+		Columns:           []string{"customer_id", "region"},
+		ReferencedSchema:  "test",
+		ReferencedTable:   "customers",
+		ReferencedColumns: []string{"id", "region"},
+		OnUpdate:          "RESTRICT",
+		OnDelete:          "CASCADE",
+	}
+	require.Equal(t, "CONSTRAINT `_fk_orders_customer` FOREIGN KEY (`customer_id`, `region`) REFERENCES `test`.`customers` (`id`, `region`) ON DELETE CASCADE ON UPDATE RESTRICT",
+		fk.Definition())
+	require.Equal(t, "SELECT COUNT(*) FROM `test`.`orders` c WHERE (`id` < 100) AND c.`customer_id` IS NOT NULL AND c.`region` IS NOT NULL AND NOT EXISTS (SELECT 1 FROM `test`.`customers` p WHERE p.`id` = c.`customer_id` AND p.`region` = c.`region`)",
+		fk.OrphanedRowsQuery("`id` < 100"))
+}
+
+func TestLoadForeignKeys(t *testing.T) {
+	testutils.NewTestTable(t, "loadfkparent", `CREATE TABLE loadfkparent (
+		id int NOT NULL,
+		region int NOT NULL,
+		PRIMARY KEY (id, region)
+	)`)
+	testutils.NewTestTable(t, "loadfkchild", `CREATE TABLE loadfkchild (
+		id int NOT NULL PRIMARY KEY,
+		parent_id int,
+		parent_region int,
+		self_id int,
+		CONSTRAINT loadfkchild_parent FOREIGN KEY (parent_id, parent_region) REFERENCES loadfkparent (id, region) ON DELETE CASCADE,
+		CONSTRAINT loadfkchild_self FOREIGN KEY (self_id) REFERENCES loadfkchild (id)
+	)`)
+
+	db, err := sql.Open("mysql", testutils.DSN())
+	require.NoError(t, err)
+	defer utils.CloseAndLog(db)
+	fks, err := LoadForeignKeys(t.Context(), db, "test", "loadfkchild")
+	require.NoError(t, err)
+	require.Len(t, fks, 2)
+	require.Equal(t, ForeignKey{
+		Name:              "loadfkchild_parent",
+		Schema:            "test",
+		Table:             "loadfkchild",
+		Columns:           []string{"parent_id", "parent_region"},
+		ReferencedSchema:  "test",
+		ReferencedTable:   "loadfkparent",
+		ReferencedColumns: []string{"id", "region"},
+		OnUpdate:          "NO ACTION",
+		OnDelete:          "CASCADE",
+	}, fks[0])
+	require.Equal(t, "loadfkchild_self", fks[1].Name)
+	require.Equal(t, "loadfkchild", fks[1].ReferencedTable)
+
+	// The self-reference is only returned by LoadForeignKeys.
+	fks, err = LoadReferencingForeignKeys(t.Context(), db, "test", "loadfkchild")
+	require.NoError(t, err)
+	require.Empty(t, fks)
+	fks, err = LoadReferencingForeignKeys(t.Context(), db, "test", "loadfkparent")
+	require.NoError(t, err)
+	require.Len(t, fks, 1)
+	require.Equal(t, "loadfkchild_parent", fks[0].Name)
+	require.Equal(t, "loadfkchild", fks[0].Table)
+
+	fks, err = LoadForeignKeys(t.Context(), db, "test", "loadfkparent")
+	require.NoError(t, err)
+	require.Empty(t, fks)
+}
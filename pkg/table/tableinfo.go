@@ -32,6 +32,7 @@ type TableInfo struct {
 
 	db                          *sql.DB
 	EstimatedRows               uint64 // used by the composite chunker for Max
+	EstimatedDataSize           uint64 // data_length + index_length in bytes, from information_schema
 	SchemaName                  string
 	TableName                   string
 	QuotedTableName             string            // `table` - backtick-quoted table name without schema
@@ -42,6 +43,8 @@ type TableInfo struct {
 	enumSetElements             map[int][]string  // parsed ENUM/SET element list, keyed by column ordinal; only present for ENUM/SET columns
 	binaryColumnWidths          map[int]int       // declared width of BINARY(N) columns, keyed by column ordinal; only present for fixed-width BINARY columns
 	KeyColumns                  []string          // the column names of the primaryKey
+	keyName                     string            // the index of KeyColumns: PRIMARY, or the unique key used in its place
+	keyOverride                 string            // the key set by SetKey, if any
 	keyColumnsMySQLTp           []string          // the MySQL types of the primaryKey
 	KeyIsAutoInc                bool              // if pk[0] is an auto_increment column
 	keyDatums                   []datumTp         // the datum type of pk
@@ -159,8 +162,8 @@ func (t *TableInfo) setRowEstimate(ctx context.Context) error {
 	// (chunker_composite.go / chunker_optimistic.go), so it is accessed
 	// atomically rather than under the lock. Scan into a local and publish with
 	// an atomic store.
-	var estimatedRows uint64
-	err := t.db.QueryRowContext(ctx, "SELECT IFNULL(table_rows,0) FROM information_schema.tables WHERE table_schema=DATABASE() AND table_name=?", t.TableName).Scan(&estimatedRows)
+	var estimatedRows, estimatedDataSize uint64
+	err := t.db.QueryRowContext(ctx, "SELECT IFNULL(table_rows,0), IFNULL(data_length,0)+IFNULL(index_length,0) FROM information_schema.tables WHERE table_schema=DATABASE() AND table_name=?", t.TableName).Scan(&estimatedRows, &estimatedDataSize)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return fmt.Errorf("table %s.%s does not exist", t.SchemaName, t.TableName)
@@ -168,6 +171,7 @@ func (t *TableInfo) setRowEstimate(ctx context.Context) error {
 		return err
 	}
 	atomic.StoreUint64(&t.EstimatedRows, estimatedRows)
+	atomic.StoreUint64(&t.EstimatedDataSize, estimatedDataSize)
 	return nil
 }
 
@@ -281,6 +285,34 @@ func (t *TableInfo) DescIndex(keyName string) ([]string, error) {
 // setPrimaryKey sets the primary key and also the primary key type.
 // A primary key can contain multiple columns.
 func (t *TableInfo) setPrimaryKey(ctx context.Context) error {
+	if t.keyOverride != "" {
+		if err := t.setOverrideKey(ctx); err != nil {
+			return err
+		}
+	} else if err := t.setPrimaryKeyColumns(ctx); err != nil {
+		return err
+	}
+	t.keyColumnsMySQLTp = nil
+	t.keyDatums = nil
+	for i, col := range t.KeyColumns {
+		// Get primary key type and auto_inc info.
+		query := "SELECT column_type, extra FROM information_schema.columns WHERE table_schema=DATABASE() AND table_name=? and column_name=?"
+		var extra, pkType string
+		err := t.db.QueryRowContext(ctx, query, t.TableName, col).Scan(&pkType, &extra)
+		if err != nil {
+			return err
+		}
+		pkType = removeWidth(pkType)
+		t.keyColumnsMySQLTp = append(t.keyColumnsMySQLTp, pkType)
+		t.keyDatums = append(t.keyDatums, mySQLTypeToDatumTp(pkType))
+		if i == 0 {
+			t.KeyIsAutoInc = (extra == "auto_increment")
+		}
+	}
+	return nil
+}
+
+func (t *TableInfo) setPrimaryKeyColumns(ctx context.Context) error {
 	rows, err := t.db.QueryContext(ctx, "SELECT column_name FROM information_schema.key_column_usage WHERE table_schema=DATABASE() and table_name=? and constraint_name='PRIMARY' ORDER BY ORDINAL_POSITION",
 		t.TableName,
 	)
@@ -303,27 +335,124 @@ func (t *TableInfo) setPrimaryKey(ctx context.Context) error {
 	if rows.Err() != nil {
 		return rows.Err()
 	}
+	t.keyName = "PRIMARY"
 	if len(t.KeyColumns) == 0 {
-		return errors.New("no primary key found (not supported)")
+		return t.setUniqueKey(ctx)
 	}
-	for i, col := range t.KeyColumns {
-		// Get primary key type and auto_inc info.
-		query := "SELECT column_type, extra FROM information_schema.columns WHERE table_schema=DATABASE() AND table_name=? and column_name=?"
-		var extra, pkType string
-		err = t.db.QueryRowContext(ctx, query, t.TableName, col).Scan(&pkType, &extra)
-		if err != nil {
-			return err
-		}
-		pkType = removeWidth(pkType)
-		t.keyColumnsMySQLTp = append(t.keyColumnsMySQLTp, pkType)
-		t.keyDatums = append(t.keyDatums, mySQLTypeToDatumTp(pkType))
-		if i == 0 {
-			t.KeyIsAutoInc = (extra == "auto_increment")
+	return nil
+}
+
+// setUniqueKey sets KeyColumns to the columns of a unique key that can
+// identify rows in place of a PRIMARY KEY. If there is more than one, the
+// one with the fewest columns is used. A generated invisible primary
+// key (my_row_id) is a PRIMARY KEY, so it is only missing here if it is
+// hidden from information_schema.
+func (t *TableInfo) setUniqueKey(ctx context.Context) error {
+	keys, err := t.UniqueKeys(ctx)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if len(t.KeyColumns) == 0 || len(key.Columns) < len(t.KeyColumns) {
+			t.keyName, t.KeyColumns = key.Name, key.Columns
 		}
 	}
+	if len(t.KeyColumns) == 0 {
+		return fmt.Errorf("no primary key found on table %s, and no unique key with only NOT NULL columns to use in its place (not supported). "+
+			"If the table has a generated invisible primary key, set show_gipk_in_create_table_and_information_schema=ON", t.TableName)
+	}
 	return nil
 }
 
+// setOverrideKey sets KeyColumns to the columns of the key set by SetKey.
+func (t *TableInfo) setOverrideKey(ctx context.Context) error {
+	keys, err := t.UniqueKeys(ctx)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if key.Name == t.keyOverride {
+			t.keyName, t.KeyColumns = key.Name, key.Columns
+			return nil
+		}
+	}
+	return fmt.Errorf("key %s can't identify the rows of table %s: it doesn't exist, or isn't unique with only NOT NULL columns", t.keyOverride, t.TableName)
+}
+
+// UniqueKey is an index that can identify the rows of a table: the
+// PRIMARY KEY, or a unique key whose columns are all NOT NULL, and none
+// of them a prefix or an expression.
+type UniqueKey struct {
+	Name    string
+	Columns []string
+}
+
+// UniqueKeys returns the indexes that can identify the rows of the table,
+// ordered by name.
+func (t *TableInfo) UniqueKeys(ctx context.Context) ([]UniqueKey, error) {
+	rows, err := t.db.QueryContext(ctx, `SELECT s.index_name, s.column_name, s.sub_part IS NULL AND c.is_nullable = 'NO' AND s.is_visible = 'YES'
+		FROM information_schema.statistics s
+		LEFT JOIN information_schema.columns c ON c.table_schema = s.table_schema AND c.table_name = s.table_name AND c.column_name = s.column_name
+		WHERE s.table_schema=DATABASE() AND s.table_name=? AND s.non_unique = 0
+		ORDER BY s.index_name, s.seq_in_index`,
+		t.TableName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Error("failed to close rows", "error", err)
+		}
+	}()
+	var keys []UniqueKey
+	qualifies := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		var col sql.NullString
+		var ok sql.NullBool
+		if err := rows.Scan(&name, &col, &ok); err != nil {
+			return nil, err
+		}
+		if _, seen := qualifies[name]; !seen {
+			keys = append(keys, UniqueKey{Name: name})
+			qualifies[name] = true
+		}
+		// An expression has no column name, and so isn't joined to a column.
+		qualifies[name] = qualifies[name] && col.Valid && ok.Valid && ok.Bool
+		keys[len(keys)-1].Columns = append(keys[len(keys)-1].Columns, col.String)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return slices.DeleteFunc(keys, func(key UniqueKey) bool { return !qualifies[key.Name] }), nil
+}
+
+// SetKey makes the unique key keyName, which must be one of UniqueKeys,
+// identify the rows of the table in place of its PRIMARY KEY. The
+// migration uses this when the ALTER changes the PRIMARY KEY, so that
+// rows are matched by a key that both tables have. It lasts for
+// subsequent calls to SetInfo.
+func (t *TableInfo) SetKey(ctx context.Context, keyName string) error {
+	t.statisticsLock.Lock()
+	defer t.statisticsLock.Unlock()
+	t.keyOverride = keyName
+	if err := t.setPrimaryKey(ctx); err != nil {
+		return err
+	}
+	return t.setMinMax(ctx)
+}
+
+// KeyName returns the name of the index on KeyColumns: PRIMARY, or for a
+// table without a PRIMARY KEY, the unique key that identifies its rows
+// instead.
+func (t *TableInfo) KeyName() string {
+	if t.keyName == "" {
+		return "PRIMARY"
+	}
+	return t.keyName
+}
+
 // PrimaryKeyIsMemoryComparable checks that the PRIMARY KEY type is compatible.
 // We no longer need this check for the chunker, since it can
 // handle any type of key in the composite chunker.
@@ -485,11 +614,16 @@ func (t *TableInfo) wrapCastType(col string) (string, error) {
 // type-lookup column name (e.g., source table uses old name, but cast type
 // comes from the target table's new name).
 func (t *TableInfo) wrapCastTypeAs(sqlCol, typeCol string) (string, error) {
+	return t.wrapCastExprAs("`"+sqlCol+"`", typeCol)
+}
+
+// wrapCastExprAs is like wrapCastTypeAs, but casts an SQL expression.
+func (t *TableInfo) wrapCastExprAs(expr, typeCol string) (string, error) {
 	tp, ok := t.columnsMySQLTps[typeCol]
 	if !ok {
 		return "", fmt.Errorf("column %q not found for type lookup in table %s", typeCol, t.TableName)
 	}
-	return fmt.Sprintf("CAST(`%s` AS %s)", sqlCol, castableTp(tp)), nil
+	return fmt.Sprintf("CAST(%s AS %s)", expr, castableTp(tp)), nil
 }
 
 func (t *TableInfo) datumTp(col string) (datumTp, error) {
@@ -198,6 +198,63 @@ func TestDiscoveryNoKeyColumnsOrNoTable(t *testing.T) {
 	require.ErrorContains(t, t2.SetInfo(t.Context()), "table test.t2fdsfds does not exist")
 }
 
+func TestDiscoveryUniqueKey(t *testing.T) {
+	testutils.RunSQL(t, `DROP TABLE IF EXISTS discoveryuniquekeyt1`)
+	testutils.RunSQL(t, `CREATE TABLE discoveryuniquekeyt1 (
+		a int NOT NULL,
+		b int NOT NULL,
+		c varchar(255) NOT NULL,
+		d int,
+		UNIQUE KEY d (d),
+		UNIQUE KEY ab (a, b),
+		UNIQUE KEY c_prefix (c(10)),
+		UNIQUE KEY b (b)
+	)`)
+	db, err := sql.Open("mysql", testutils.DSN())
+	require.NoError(t, err)
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Logf("failed to close db: %v", err)
+		}
+	}()
+
+	// d is nullable, and c_prefix is a prefix, so b is used as it has
+	// fewer columns than ab.
+	t1 := NewTableInfo(db, "test", "discoveryuniquekeyt1")
+	require.NoError(t, t1.SetInfo(t.Context()))
+	require.Equal(t, "b", t1.KeyName())
+	require.Equal(t, []string{"b"}, t1.KeyColumns)
+	require.Equal(t, []string{"int"}, t1.keyColumnsMySQLTp)
+
+	testutils.RunSQL(t, `ALTER TABLE discoveryuniquekeyt1 DROP INDEX b`)
+	t1 = NewTableInfo(db, "test", "discoveryuniquekeyt1")
+	require.NoError(t, t1.SetInfo(t.Context()))
+	require.Equal(t, "ab", t1.KeyName())
+	require.Equal(t, []string{"a", "b"}, t1.KeyColumns)
+
+	// A table with a PRIMARY KEY uses it.
+	testutils.RunSQL(t, `ALTER TABLE discoveryuniquekeyt1 ADD PRIMARY KEY (c)`)
+	t1 = NewTableInfo(db, "test", "discoveryuniquekeyt1")
+	require.NoError(t, t1.SetInfo(t.Context()))
+	require.Equal(t, "PRIMARY", t1.KeyName())
+	require.Equal(t, []string{"c"}, t1.KeyColumns)
+	keys, err := t1.UniqueKeys(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, []UniqueKey{
+		{Name: "PRIMARY", Columns: []string{"c"}},
+		{Name: "ab", Columns: []string{"a", "b"}},
+	}, keys)
+
+	// Unless another key is set, which lasts for SetInfo.
+	require.NoError(t, t1.SetKey(t.Context(), "ab"))
+	require.Equal(t, "ab", t1.KeyName())
+	require.Equal(t, []string{"a", "b"}, t1.KeyColumns)
+	require.Equal(t, []string{"int", "int"}, t1.keyColumnsMySQLTp)
+	require.NoError(t, t1.SetInfo(t.Context()))
+	require.Equal(t, []string{"a", "b"}, t1.KeyColumns)
+	require.ErrorContains(t, t1.SetKey(t.Context(), "d"), "key d can't identify the rows")
+}
+
 func TestDiscoveryBalancesTable(t *testing.T) {
 	// This is not a bad test, since there is a PRIMARY KEY and a UNIQUE KEY
 	// and the discovery has to discover the primary key as the constraint
@@ -0,0 +1,78 @@
+package table
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/block/spirit/pkg/utils"
+)
+
+// Trigger is a trigger on a table, as read from information_schema.triggers.
+type Trigger struct {
+	Name      string
+	Timing    string // BEFORE or AFTER
+	Event     string // INSERT, UPDATE or DELETE
+	Order     int    // the order it fires in among the triggers with the same timing and event
+	Statement string // the body, e.g. SET NEW.updated = NOW()
+	Definer   string // user@host
+	SQLMode   string // the sql_mode it was created with
+	Collation string // the collation_connection it was created with
+}
+
+// LoadTriggers returns the triggers on schema.tableName, in the order
+// they fire for each timing and event.
+func LoadTriggers(ctx context.Context, db *sql.DB, schema, tableName string) ([]Trigger, error) {
+	rows, err := db.QueryContext(ctx, `SELECT trigger_name, action_timing, event_manipulation, action_order,
+		action_statement, definer, sql_mode, collation_connection
+		FROM information_schema.triggers
+		WHERE event_object_schema=? AND event_object_table=?
+		ORDER BY event_manipulation, action_timing, action_order`,
+		schema, tableName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer utils.CloseAndLog(rows)
+	var triggers []Trigger
+	for rows.Next() {
+		var trg Trigger
+		if err := rows.Scan(&trg.Name, &trg.Timing, &trg.Event, &trg.Order, &trg.Statement, &trg.Definer, &trg.SQLMode, &trg.Collation); err != nil {
+			return nil, err
+		}
+		triggers = append(triggers, trg)
+	}
+	return triggers, rows.Err()
+}
+
+// CreateStatement returns the CREATE TRIGGER statement that recreates trg
+// on tableName, in the schema of the connection. It doesn't set the
+// sql_mode and collation_connection the trigger was created with: the
+// caller has to set them on the session first. Triggers with the same
+// timing and event fire in the order they are created, so the triggers
+// returned by LoadTriggers have to be created in that order.
+func (trg Trigger) CreateStatement(tableName string) string {
+	return fmt.Sprintf("CREATE DEFINER=%s TRIGGER %s %s %s ON %s FOR EACH ROW %s",
+		quoteDefiner(trg.Definer),
+		quoteIdentifier(trg.Name),
+		trg.Timing,
+		trg.Event,
+		quoteIdentifier(tableName),
+		trg.Statement,
+	)
+}
+
+// quoteDefiner quotes the user and host of a user@host definer. The
+// user name may itself contain an @, but the host name can't.
+func quoteDefiner(definer string) string {
+	i := strings.LastIndex(definer, "@")
+	if i < 0 {
+		return quoteIdentifier(definer)
+	}
+	return quoteIdentifier(definer[:i]) + "@" + quoteIdentifier(definer[i+1:])
+}
+
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
@@ -0,0 +1,54 @@
+package table
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/block/spirit/pkg/testutils"
+	"github.com/block/spirit/pkg/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTriggerCreateStatement(t *testing.T) {
+	trg := Trigger{
+		Name:      "t1_bi",
+		Timing:    "BEFORE",
+		Event:     "INSERT",
+		Order:     1,
+		Statement: "SET NEW.b = NEW.a * 2",
+		Definer:   "spirit@app@%",
+	}
+	require.Equal(t, "CREATE DEFINER=`spirit@app`@`%` TRIGGER `t1_bi` BEFORE INSERT ON `_t1_new` FOR EACH ROW SET NEW.b = NEW.a * 2",
+		trg.CreateStatement("_t1_new"))
+	trg.Name = "odd`name"
+	require.Contains(t, trg.CreateStatement("t1"), "TRIGGER `odd``name` BEFORE")
+}
+
+func TestLoadTriggers(t *testing.T) {
+	testutils.NewTestTable(t, "loadtriggerst1", `CREATE TABLE loadtriggerst1 (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		a int NOT NULL,
+		b int NOT NULL
+	)`)
+	testutils.RunSQL(t, `CREATE TRIGGER loadtriggerst1_bi2 BEFORE INSERT ON loadtriggerst1 FOR EACH ROW SET NEW.b = NEW.b + 1`)
+	testutils.RunSQL(t, `CREATE TRIGGER loadtriggerst1_bi1 BEFORE INSERT ON loadtriggerst1 FOR EACH ROW PRECEDES loadtriggerst1_bi2 SET NEW.b = NEW.a`)
+	testutils.RunSQL(t, `CREATE TRIGGER loadtriggerst1_bu BEFORE UPDATE ON loadtriggerst1 FOR EACH ROW SET NEW.b = NEW.a`)
+
+	db, err := sql.Open("mysql", testutils.DSN())
+	require.NoError(t, err)
+	defer utils.CloseAndLog(db)
+	triggers, err := LoadTriggers(t.Context(), db, "test", "loadtriggerst1")
+	require.NoError(t, err)
+	require.Len(t, triggers, 3)
+	require.Equal(t, "loadtriggerst1_bi1", triggers[0].Name)
+	require.Equal(t, "loadtriggerst1_bi2", triggers[1].Name)
+	require.Equal(t, 2, triggers[1].Order)
+	require.Equal(t, "loadtriggerst1_bu", triggers[2].Name)
+	require.Equal(t, "BEFORE", triggers[2].Timing)
+	require.Equal(t, "UPDATE", triggers[2].Event)
+	require.Equal(t, "SET NEW.b = NEW.a", triggers[2].Statement)
+
+	triggers, err = LoadTriggers(t.Context(), db, "test", "loadtriggerst1_doesnotexist")
+	require.NoError(t, err)
+	require.Empty(t, triggers)
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"slices"
 	"sync"
+	"time"
 )
 
 // multiThrottler wraps multiple throttlers and throttles if any child is throttled.
@@ -15,6 +16,7 @@ type multiThrottler struct {
 }
 
 var _ Throttler = &multiThrottler{}
+var _ LagToleranceSetter = &multiThrottler{}
 
 // gradualMultiThrottler is the multiThrottler variant returned when at least
 // one child implements GradualThrottler, so that asserting GradualThrottler on
@@ -112,6 +114,17 @@ func (m *multiThrottler) BlockWait(ctx context.Context) {
 	wg.Wait()
 }
 
+// SetLagTolerance forwards the new tolerance to every child that has one.
+// Children without a lag tolerance (e.g. the Aurora throttlers) are left
+// alone.
+func (m *multiThrottler) SetLagTolerance(lagTolerance time.Duration) {
+	for _, t := range m.throttlers {
+		if s, ok := t.(LagToleranceSetter); ok {
+			s.SetLagTolerance(lagTolerance)
+		}
+	}
+}
+
 // UpdateLag updates lag on all child throttlers.
 // Returns the first error encountered but continues updating all.
 func (m *multiThrottler) UpdateLag(ctx context.Context) error {
@@ -250,3 +250,17 @@ func TestMultiThrottler_UpdateLag_ReturnsFirstError(t *testing.T) {
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "lag error")
 }
+
+func TestMultiThrottler_SetLagTolerance(t *testing.T) {
+	replica := &Replica{}
+	replica.SetLagTolerance(time.Minute)
+	replica.currentLagInMs.Store(30_000)
+	multi := NewMultiThrottler(replica, &testThrottler{})
+	require.False(t, multi.IsThrottled())
+
+	setter, ok := multi.(LagToleranceSetter)
+	require.True(t, ok)
+	setter.SetLagTolerance(10 * time.Second)
+	require.True(t, multi.IsThrottled())
+	require.Equal(t, 10*time.Second, replica.tolerance())
+}
@@ -16,7 +16,7 @@ var (
 
 type Replica struct {
 	replica        *sql.DB
-	lagTolerance   time.Duration
+	lagTolerance   atomic.Int64 // a time.Duration, see SetLagTolerance
 	currentLagInMs atomic.Int64
 	logger         *slog.Logger
 	isClosed       atomic.Bool
@@ -48,6 +48,7 @@ const MySQL8LagQuery = `WITH applier_latency AS (
 `
 
 var _ Throttler = &Replica{}
+var _ LagToleranceSetter = &Replica{}
 
 // Open starts the lag monitor. This is not gh-ost. The lag monitor is primitive
 // because the requirement is only for DR, and not for up-to-date read-replicas.
@@ -84,7 +85,18 @@ func (l *Replica) Close() error {
 }
 
 func (l *Replica) IsThrottled() bool {
-	return l.currentLagInMs.Load() >= l.lagTolerance.Milliseconds()
+	return l.currentLagInMs.Load() >= l.tolerance().Milliseconds()
+}
+
+// SetLagTolerance changes the lag at which the replica throttles. It is
+// safe to call while the throttler is open; it takes effect on the next
+// IsThrottled/BlockWait check.
+func (l *Replica) SetLagTolerance(lagTolerance time.Duration) {
+	l.lagTolerance.Store(int64(lagTolerance))
+}
+
+func (l *Replica) tolerance() time.Duration {
+	return time.Duration(l.lagTolerance.Load())
 }
 
 // Replica deliberately does NOT implement GradualThrottler: replication lag
@@ -102,7 +114,7 @@ func (l *Replica) BlockWait(ctx context.Context) {
 	defer timer.Stop()
 
 	for range 60 {
-		if l.currentLagInMs.Load() < l.lagTolerance.Milliseconds() {
+		if l.currentLagInMs.Load() < l.tolerance().Milliseconds() {
 			return
 		}
 
@@ -114,7 +126,7 @@ func (l *Replica) BlockWait(ctx context.Context) {
 			// Continue checking
 		}
 	}
-	l.logger.Warn("lag monitor timed out", "lag_ms", l.currentLagInMs.Load(), "tolerance", l.lagTolerance.String())
+	l.logger.Warn("lag monitor timed out", "lag_ms", l.currentLagInMs.Load(), "tolerance", l.tolerance().String())
 }
 
 // UpdateLag is a MySQL 8.0+ implementation of lag that is a better approximation than "seconds_behind_source".
@@ -128,7 +140,7 @@ func (l *Replica) UpdateLag(ctx context.Context) error {
 	if l.IsThrottled() {
 		l.logger.Warn("replication delayed, throttling in progress",
 			"lag_ms", l.currentLagInMs.Load(),
-			"tolerance", l.lagTolerance.String())
+			"tolerance", l.tolerance().String())
 	}
 	return nil
 }
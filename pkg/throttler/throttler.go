@@ -34,12 +34,20 @@ type GradualThrottler interface {
 	Utilization() float64
 }
 
+// LagToleranceSetter is an optional extension implemented by throttlers
+// with a replica lag tolerance (Replica, and a multi-throttler wrapping
+// one), so that --replica-max-lag can be changed on a running migration.
+type LagToleranceSetter interface {
+	SetLagTolerance(lagTolerance time.Duration)
+}
+
 // NewReplicationThrottler returns a Throttler for MySQL 8.0+ replicas.
 // It uses performance_schema to monitor replication lag.
 func NewReplicationThrottler(replica *sql.DB, lagTolerance time.Duration, logger *slog.Logger) (Throttler, error) {
-	return &Replica{
-		replica:      replica,
-		lagTolerance: lagTolerance,
-		logger:       logger,
-	}, nil
+	r := &Replica{
+		replica: replica,
+		logger:  logger,
+	}
+	r.SetLagTolerance(lagTolerance)
+	return r, nil
 }
@@ -5,9 +5,13 @@ const (
 	// _<table>_old_<timestamp> name when SkipDropAfterCutover is set.
 	NameFormatTimestamp = "20060102_150405"
 
-	suffixCheckpoint = "_chkpnt"
-	suffixNew        = "_new"
-	suffixOld        = "_old"
+	suffixCheckpoint        = "_chkpnt"
+	suffixDMLCheckpoint     = "_dml_chkpnt"
+	suffixArchiveCheckpoint = "_archive_chkpnt"
+	suffixNew               = "_new"
+	suffixOld               = "_old"
+	suffixRevert            = "_revert"
+	suffixReverted          = "_reverted"
 )
 
 // AuxTableName builds a deterministic auxiliary table name for the given
@@ -55,3 +59,29 @@ func OldTableName(tableName string) string {
 func OldTableNameWithTimestamp(tableName, timestamp string) string {
 	return AuxTableName(tableName, suffixOld+"_"+timestamp)
 }
+
+// RevertTableName returns the auxiliary _revert table name for the given
+// original table. It holds the state that `spirit revert` needs while a
+// migration's revert window is open.
+func RevertTableName(tableName string) string {
+	return AuxTableName(tableName, suffixRevert)
+}
+
+// RevertedTableNameWithTimestamp returns the auxiliary _reverted_<timestamp>
+// table name for the given original table and timestamp string. It is the
+// name that `spirit revert` gives to the migrated table.
+func RevertedTableNameWithTimestamp(tableName, timestamp string) string {
+	return AuxTableName(tableName, suffixReverted+"_"+timestamp)
+}
+
+// DMLCheckpointTableName returns the auxiliary checkpoint table name that
+// `spirit dml` records its progress through the given table in.
+func DMLCheckpointTableName(tableName string) string {
+	return AuxTableName(tableName, suffixDMLCheckpoint)
+}
+
+// ArchiveCheckpointTableName returns the auxiliary checkpoint table name
+// that `spirit archive` records its progress through the given table in.
+func ArchiveCheckpointTableName(tableName string) string {
+	return AuxTableName(tableName, suffixArchiveCheckpoint)
+}
@@ -48,4 +48,8 @@ func TestAuxTableNameTypedHelpers(t *testing.T) {
 	require.Equal(t, "_t_new", NewTableName("t"))
 	require.Equal(t, "_t_old", OldTableName("t"))
 	require.Equal(t, "_t_old_20260101_000000", OldTableNameWithTimestamp("t", "20260101_000000"))
+	require.Equal(t, "_t_revert", RevertTableName("t"))
+	require.Equal(t, "_t_reverted_20260101_000000", RevertedTableNameWithTimestamp("t", "20260101_000000"))
+	require.Equal(t, "_t_dml_chkpnt", DMLCheckpointTableName("t"))
+	require.Equal(t, "_t_archive_chkpnt", ArchiveCheckpointTableName("t"))
 }
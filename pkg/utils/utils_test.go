@@ -96,3 +96,10 @@ func TestTruncateTableName(t *testing.T) {
 	name70 := strings.Repeat("e", 70)
 	require.Equal(t, strings.Repeat("e", 64), TruncateTableName(name70, 0))
 }
+
+func TestFormatBytes(t *testing.T) {
+	require.Equal(t, "0 B", FormatBytes(0))
+	require.Equal(t, "1023 B", FormatBytes(1023))
+	require.Equal(t, "1.0 KiB", FormatBytes(1024))
+	require.Equal(t, "1.5 GiB", FormatBytes(3<<29))
+}
@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// weekdays maps the day names accepted by ParseWindow to time.Weekday.
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Window is a recurring weekly time window, such as the hours in which a
+// cutover is allowed. Create one with ParseWindow.
+type Window struct {
+	spec  string
+	days  [7]bool // indexed by time.Weekday: the days on which the window opens
+	start int     // minutes after midnight at which the window opens
+	end   int     // minutes after midnight at which it closes; <= start if it spans midnight
+	loc   *time.Location
+}
+
+// ParseWindow parses a window specification of the form
+//
+//	[DAYS] HH:MM-HH:MM [TIMEZONE]
+//
+// for example "Mon-Fri 02:00-04:00 UTC" or "Sat,Sun 22:00-02:00
+// America/New_York". DAYS is a comma-separated list of days (Mon, Tue, ...)
+// or ranges of days (Mon-Fri, Fri-Mon), and defaults to every day.
+// TIMEZONE is UTC or an IANA time zone name, and defaults to UTC. The end
+// may be 24:00. A window whose end is not after its start spans midnight,
+// and belongs to the day on which it opens.
+func ParseWindow(spec string) (*Window, error) {
+	w := &Window{spec: spec, loc: time.UTC}
+	fields := strings.Fields(spec)
+	// The time range is the only field that contains ':'.
+	timeIdx := -1
+	for i, f := range fields {
+		if strings.Contains(f, ":") {
+			timeIdx = i
+			break
+		}
+	}
+	if timeIdx < 0 || timeIdx > 1 || len(fields) > timeIdx+2 {
+		return nil, fmt.Errorf("invalid window %q: expected [DAYS] HH:MM-HH:MM [TIMEZONE]", spec)
+	}
+	if timeIdx == 1 {
+		if err := w.parseDays(fields[0]); err != nil {
+			return nil, fmt.Errorf("invalid window %q: %w", spec, err)
+		}
+	} else {
+		for i := range w.days {
+			w.days[i] = true
+		}
+	}
+	startStr, endStr, ok := strings.Cut(fields[timeIdx], "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid window %q: expected a time range such as 02:00-04:00", spec)
+	}
+	var err error
+	if w.start, err = parseTimeOfDay(startStr, false); err != nil {
+		return nil, fmt.Errorf("invalid window %q: %w", spec, err)
+	}
+	if w.end, err = parseTimeOfDay(endStr, true); err != nil {
+		return nil, fmt.Errorf("invalid window %q: %w", spec, err)
+	}
+	if w.start == w.end {
+		return nil, fmt.Errorf("invalid window %q: start and end are the same", spec)
+	}
+	if len(fields) == timeIdx+2 {
+		if w.loc, err = time.LoadLocation(fields[timeIdx+1]); err != nil {
+			return nil, fmt.Errorf("invalid window %q: %w", spec, err)
+		}
+	}
+	return w, nil
+}
+
+func (w *Window) parseDays(s string) error {
+	for part := range strings.SplitSeq(s, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		first, ok := weekdays[strings.ToLower(from)]
+		if !ok {
+			return fmt.Errorf("unknown day %q", from)
+		}
+		last := first
+		if isRange {
+			if last, ok = weekdays[strings.ToLower(to)]; !ok {
+				return fmt.Errorf("unknown day %q", to)
+			}
+		}
+		// Ranges may wrap around the end of the week, e.g. Fri-Mon.
+		for d := first; ; d = (d + 1) % 7 {
+			w.days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// parseTimeOfDay parses HH:MM into minutes after midnight. 24:00 is only
+// accepted as an end time.
+func parseTimeOfDay(s string, isEnd bool) (int, error) {
+	if isEnd && s == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, errors.New("times must be in HH:MM format")
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// String returns the specification the window was parsed from.
+func (w *Window) String() string {
+	return w.spec
+}
+
+// Contains reports whether t is inside the window.
+func (w *Window) Contains(t time.Time) bool {
+	t = t.In(w.loc)
+	minute := t.Hour()*60 + t.Minute()
+	today := t.Weekday()
+	if w.start < w.end {
+		return w.days[today] && minute >= w.start && minute < w.end
+	}
+	// The window spans midnight: t is either in the part that opened
+	// today, or in the part that opened yesterday.
+	yesterday := (today + 6) % 7
+	return (w.days[today] && minute >= w.start) || (w.days[yesterday] && minute < w.end)
+}
+
+// NextOpen returns t if it is inside the window, and otherwise the time at
+// which the window next opens.
+func (w *Window) NextOpen(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+	local := t.In(w.loc)
+	for i := range 8 {
+		day := local.AddDate(0, 0, i)
+		if !w.days[day.Weekday()] {
+			continue
+		}
+		opens := time.Date(day.Year(), day.Month(), day.Day(), w.start/60, w.start%60, 0, 0, w.loc)
+		if opens.After(t) {
+			return opens
+		}
+	}
+	return t // unreachable: ParseWindow requires at least one day
+}
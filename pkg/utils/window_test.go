@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWindow(t *testing.T) {
+	for _, spec := range []string{
+		"Mon-Fri 02:00-04:00 UTC",
+		"02:00-04:00",
+		"sat,sun 22:00-02:00 America/New_York",
+		"Fri-Mon,Wed 00:00-24:00",
+	} {
+		w, err := ParseWindow(spec)
+		require.NoError(t, err, spec)
+		require.Equal(t, spec, w.String())
+	}
+	for _, spec := range []string{
+		"",
+		"Mon-Fri",
+		"Mon-Fri 02:00",
+		"Mon-Fri 2am-4am",
+		"Mon-Fry 02:00-04:00",
+		"Mon-Fri 02:00-02:00",
+		"Mon-Fri 24:00-02:00",
+		"Mon-Fri 02:00-04:00 Mars/Olympus",
+		"Mon Fri 02:00-04:00",
+		"Mon-Fri 02:00-04:00 UTC extra",
+	} {
+		_, err := ParseWindow(spec)
+		require.Error(t, err, spec)
+	}
+}
+
+func TestWindowContains(t *testing.T) {
+	w, err := ParseWindow("Mon-Fri 02:00-04:00 UTC")
+	require.NoError(t, err)
+	monday := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	require.Equal(t, time.Monday, monday.Weekday())
+
+	require.False(t, w.Contains(monday.Add(time.Hour+59*time.Minute)))
+	require.True(t, w.Contains(monday.Add(2*time.Hour)))
+	require.True(t, w.Contains(monday.Add(3*time.Hour+59*time.Minute)))
+	require.False(t, w.Contains(monday.Add(4*time.Hour)))
+	require.False(t, w.Contains(monday.AddDate(0, 0, 5).Add(3*time.Hour))) // Saturday
+	// The same instant in another zone.
+	require.True(t, w.Contains(monday.Add(3*time.Hour).In(time.FixedZone("UTC+10", 10*3600))))
+
+	// A window that spans midnight belongs to the day it opens.
+	w, err = ParseWindow("Fri 22:00-02:00 UTC")
+	require.NoError(t, err)
+	friday := monday.AddDate(0, 0, 4)
+	require.True(t, w.Contains(friday.Add(23*time.Hour)))
+	require.True(t, w.Contains(friday.Add(25*time.Hour)))  // Saturday 01:00
+	require.False(t, w.Contains(friday.Add(1*time.Hour)))  // Friday 01:00
+	require.False(t, w.Contains(friday.Add(26*time.Hour))) // Saturday 02:00
+}
+
+func TestWindowNextOpen(t *testing.T) {
+	w, err := ParseWindow("Mon-Fri 02:00-04:00 UTC")
+	require.NoError(t, err)
+	monday := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+
+	inside := monday.Add(3 * time.Hour)
+	require.Equal(t, inside, w.NextOpen(inside))
+	require.Equal(t, monday.Add(2*time.Hour), w.NextOpen(monday))
+	require.Equal(t, monday.AddDate(0, 0, 1).Add(2*time.Hour), w.NextOpen(monday.Add(5*time.Hour)))
+	// From Friday after the window, the next one is on Monday.
+	require.Equal(t, monday.AddDate(0, 0, 7).Add(2*time.Hour), w.NextOpen(monday.AddDate(0, 0, 4).Add(5*time.Hour)))
+
+	// A single day a week, asked just after it closed.
+	w, err = ParseWindow("Wed 02:00-04:00 UTC")
+	require.NoError(t, err)
+	wednesday := monday.AddDate(0, 0, 2)
+	require.Equal(t, wednesday.AddDate(0, 0, 7).Add(2*time.Hour), w.NextOpen(wednesday.Add(4*time.Hour)))
+}